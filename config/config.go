@@ -65,10 +65,14 @@ const (
 
 const (
 	MailProviderSmtp = "smtp"
+	MailProviderFile = "file"
+	MailProviderHttp = "http"
 )
 
 var emailProviders = []string{
 	MailProviderSmtp,
+	MailProviderFile,
+	MailProviderHttp,
 }
 
 // first wakatime commit was on this day ;-) so no real heartbeats should exist before
@@ -92,6 +96,9 @@ type appConfig struct {
 	ImportBackoffMin          int                          `yaml:"import_backoff_min" default:"5" env:"WAKAPI_IMPORT_BACKOFF_MIN"`
 	ImportMaxRate             int                          `yaml:"import_max_rate" default:"24" env:"WAKAPI_IMPORT_MAX_RATE"` // at max one successful import every x hours
 	ImportBatchSize           int                          `yaml:"import_batch_size" default:"50" env:"WAKAPI_IMPORT_BATCH_SIZE"`
+	ImportDir                 string                       `yaml:"import_dir" default:"imports" env:"WAKAPI_IMPORT_DIR"` // must be shared with the process running background jobs
+	ExportDir                 string                       `yaml:"export_dir" default:"exports" env:"WAKAPI_EXPORT_DIR"`
+	ExportTtlHours            int                          `yaml:"export_ttl_hours" default:"24" env:"WAKAPI_EXPORT_TTL_HOURS"` // how long a finished data export remains available for download
 	InactiveDays              int                          `yaml:"inactive_days" default:"7" env:"WAKAPI_INACTIVE_DAYS"`
 	HeartbeatMaxAge           string                       `yaml:"heartbeat_max_age" default:"4320h" env:"WAKAPI_HEARTBEAT_MAX_AGE"`
 	CountCacheTTLMin          int                          `yaml:"count_cache_ttl_min" default:"30" env:"WAKAPI_COUNT_CACHE_TTL_MIN"`
@@ -116,6 +123,7 @@ type securityConfig struct {
 	ExposeMetrics      bool   `yaml:"expose_metrics" default:"false" env:"WAKAPI_EXPOSE_METRICS"`
 	EnableProxy        bool   `yaml:"enable_proxy" default:"false" env:"WAKAPI_ENABLE_PROXY"` // only intended for production instance at wakana.io
 	DisableFrontpage   bool   `yaml:"disable_frontpage" default:"false" env:"WAKAPI_DISABLE_FRONTPAGE"`
+	RequireTwoFactor   bool   `yaml:"require_two_factor" default:"false" env:"WAKAPI_REQUIRE_TWO_FACTOR"` // users have to enroll in totp 2fa upon their next login
 	// this is actually a pepper (https://en.wikipedia.org/wiki/Pepper_(cryptography))
 	PasswordSalt               string                     `yaml:"password_salt" default:"" env:"WAKAPI_PASSWORD_SALT"`
 	JWT_SECRET                 string                     `yaml:"jwt_secret" default:"" env:"JWT_SECRET"`
@@ -127,6 +135,7 @@ type securityConfig struct {
 	SignupMaxRate              string                     `yaml:"signup_max_rate" default:"5/1h" env:"WAKAPI_SIGNUP_MAX_RATE"`
 	LoginMaxRate               string                     `yaml:"login_max_rate" default:"10/1m" env:"WAKAPI_LOGIN_MAX_RATE"`
 	PasswordResetMaxRate       string                     `yaml:"password_reset_max_rate" default:"5/1h" env:"WAKAPI_PASSWORD_RESET_MAX_RATE"`
+	Oidc                       OIDCConfig                 `yaml:"oidc"`
 	SecureCookie               *securecookie.SecureCookie `yaml:"-"`
 	SessionKey                 []byte                     `yaml:"-"`
 	trustReverseProxyIpsParsed []net.IPNet
@@ -184,9 +193,52 @@ type mailConfig struct {
 	Enabled  bool           `env:"WAKAPI_MAIL_ENABLED" default:"true"`
 	Provider string         `env:"WAKAPI_MAIL_PROVIDER" default:"smtp"`
 	Smtp     SMTPMailConfig `yaml:"smtp"`
+	File     FileMailConfig `yaml:"file"`
+	Http     HTTPMailConfig `yaml:"http"`
 	Sender   string         `env:"WAKAPI_MAIL_SENDER" yaml:"sender"`
 }
 
+type FileMailConfig struct {
+	Dir string `env:"WAKAPI_MAIL_FILE_DIR" default:"data/mails"`
+}
+
+// HTTPMailConfig configures sending mails through a provider's http api, e.g. postmark or mailgun. The request body is
+// rendered from a text/template given the mail, see mail.HTTPTemplateData.
+type HTTPMailConfig struct {
+	Url         string            `env:"WAKAPI_MAIL_HTTP_URL"`
+	Method      string            `env:"WAKAPI_MAIL_HTTP_METHOD" default:"POST"`
+	ContentType string            `yaml:"content_type" env:"WAKAPI_MAIL_HTTP_CONTENT_TYPE" default:"application/json"`
+	Headers     map[string]string `env:"WAKAPI_MAIL_HTTP_HEADERS"` // yaml or json, e.g. {"X-Postmark-Server-Token": "xyz"}
+	Template    string            `env:"WAKAPI_MAIL_HTTP_TEMPLATE"`
+	Timeout     int               `env:"WAKAPI_MAIL_HTTP_TIMEOUT" default:"10"` // in seconds
+}
+
+// OIDCConfig configures logging in through a generic OpenID Connect provider, e.g. a self-hosted Keycloak or Authentik.
+// Endpoints are discovered from the issuer. The client can be a public one, relying on PKCE only, or a confidential one.
+type OIDCConfig struct {
+	Name          string `yaml:"name" default:"oidc" env:"WAKAPI_OIDC_NAME"` // identifies the provider of linked accounts, don't change once in use
+	DisplayName   string `yaml:"display_name" default:"Single Sign-On" env:"WAKAPI_OIDC_DISPLAY_NAME"`
+	Issuer        string `yaml:"issuer" env:"WAKAPI_OIDC_ISSUER"`
+	ClientId      string `yaml:"client_id" env:"WAKAPI_OIDC_CLIENT_ID"`
+	ClientSecret  string `yaml:"client_secret" env:"WAKAPI_OIDC_CLIENT_SECRET"`
+	Scopes        string `yaml:"scopes" default:"openid email profile" env:"WAKAPI_OIDC_SCOPES"`
+	RedirectUri   string `yaml:"redirect_uri" env:"WAKAPI_OIDC_REDIRECT_URI"` // defaults to <frontend_uri>/api/oauth/callback/oidc
+	LinkByEmail   bool   `yaml:"link_by_email" default:"true" env:"WAKAPI_OIDC_LINK_BY_EMAIL"`
+	AutoProvision bool   `yaml:"auto_provision" default:"false" env:"WAKAPI_OIDC_AUTO_PROVISION"`
+}
+
+func (c *OIDCConfig) IsEnabled() bool {
+	return c.Issuer != "" && c.ClientId != ""
+}
+
+func (c *OIDCConfig) GetScopes() []string {
+	scopes := strings.Fields(strings.ReplaceAll(c.Scopes, ",", " "))
+	if !slice.Contain(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	return scopes
+}
+
 type SMTPMailConfig struct {
 	Host       string `env:"WAKAPI_MAIL_SMTP_HOST"`
 	Port       uint   `env:"WAKAPI_MAIL_SMTP_PORT"`
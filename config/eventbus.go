@@ -8,20 +8,55 @@ type ApplicationEvent struct {
 }
 
 const (
-	TopicUser               = "user.*"
-	TopicHeartbeat          = "heartbeat.*"
-	TopicProjectLabel       = "project_label.*"
-	EventUserUpdate         = "user.update"
-	EventUserDelete         = "user.delete"
-	EventHeartbeatCreate    = "heartbeat.create"
-	EventProjectLabelCreate = "project_label.create"
-	EventProjectLabelDelete = "project_label.delete"
-	EventWakatimeFailure    = "wakatime.failure"
-	FieldPayload            = "payload"
-	FieldUser               = "user"
-	FieldUserId             = "user.id"
+	TopicUser                  = "user.*"
+	TopicHeartbeat             = "heartbeat.*"
+	TopicProjectLabel          = "project_label.*"
+	TopicAlias                 = "alias.*"
+	TopicLanguageMapping       = "language_mapping.*"
+	TopicGoal                  = "goal.*"
+	TopicReport                = "report.*"
+	TopicImport                = "import.*"
+	TopicInvoice               = "invoice.*"
+	EventUserUpdate            = "user.update"
+	EventUserDelete            = "user.delete"
+	EventHeartbeatCreate       = "heartbeat.create"
+	EventHeartbeatBatchCreate  = "heartbeat_batch.create" // once per batch and user, only for heartbeats sent by clients
+	EventProjectLabelCreate    = "project_label.create"
+	EventProjectLabelDelete    = "project_label.delete"
+	EventAliasCreate           = "alias.create"
+	EventAliasDelete           = "alias.delete"
+	EventLanguageMappingCreate = "language_mapping.create"
+	EventLanguageMappingDelete = "language_mapping.delete"
+	EventWakatimeFailure       = "wakatime.failure"
+	EventGoalReached           = "goal.reached"
+	EventGoalFailed            = "goal.failed"
+	EventReportSent            = "report.sent"
+	EventImportFinished        = "import.finished"
+	EventInvoiceGenerated      = "invoice.generated"
+	FieldPayload               = "payload"
+	FieldUser                  = "user"
+	FieldUserId                = "user.id"
 )
 
+// WebhookEvents lists all events users can subscribe to through webhooks
+var WebhookEvents = []string{
+	EventHeartbeatCreate,
+	EventUserUpdate,
+	EventUserDelete,
+	EventProjectLabelCreate,
+	EventProjectLabelDelete,
+	EventAliasCreate,
+	EventAliasDelete,
+	EventLanguageMappingCreate,
+	EventLanguageMappingDelete,
+	EventWakatimeFailure,
+	EventGoalReached,
+	EventGoalFailed,
+	EventReportSent,
+	EventImportFinished,
+	EventInvoiceGenerated,
+}
+
 var eventHub *hub.Hub
 
 func init() {
@@ -45,19 +45,20 @@ func ExtractUser(r *http.Request) *models.User {
 	return nil
 }
 
-func MakeLoginJWT(userId string, conf *conf.Config) (string, int64, error) {
-	ttl := time.Now().Add(JWT_TOKEN_DURATION).Unix()
+// MakeLoginJWT issues the token for a login session, identified by its jti claim, so it can be revoked before it expires
+func MakeLoginJWT(session *models.Session, conf *conf.Config) (string, error) {
 	atClaims := jwt.MapClaims{}
-	atClaims["exp"] = ttl
-	atClaims["uid"] = userId
+	atClaims["exp"] = session.ExpiresAt.T().Unix()
+	atClaims["uid"] = session.UserID
+	atClaims["jti"] = session.ID
 	at := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
 
 	token, err := at.SignedString([]byte(conf.Security.JWT_SECRET))
 	if err != nil {
-		return "", 0, err
+		return "", err
 	}
 
-	return token, ttl / 1000000, nil // kinda wonder if its bad ide to return ttl in seconds
+	return token, nil
 }
 
 type AuthSuccessResponse struct {
@@ -65,6 +66,7 @@ type AuthSuccessResponse struct {
 	User      *models.User
 	OauthUser *models.UserOauth
 	IsNewUser bool
+	Session   *models.Session // the login session to issue the token for
 }
 
 func MakeAuthSuccessResponse(payload *AuthSuccessResponse) (map[string]interface{}, error) {
@@ -72,11 +74,14 @@ func MakeAuthSuccessResponse(payload *AuthSuccessResponse) (map[string]interface
 	user := payload.User
 	avatar := conf.Server.PublicUrl + "/" + user.AvatarURL(conf.App.AvatarURLTemplate)
 
-	if payload.OauthUser != nil {
+	if payload.OauthUser != nil && payload.OauthUser.AvatarUrl != nil && *payload.OauthUser.AvatarUrl != "" {
 		avatar = *payload.OauthUser.AvatarUrl
 	}
 
-	token, _, err := MakeLoginJWT(user.ID, conf)
+	if payload.Session == nil || payload.Session.UserID != user.ID {
+		return nil, errors.New("missing login session")
+	}
+	token, err := MakeLoginJWT(payload.Session, conf)
 
 	if err != nil {
 		return nil, err
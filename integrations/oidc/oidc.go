@@ -0,0 +1,354 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+)
+
+// a minimal OpenID Connect relying party for the authorization code flow with PKCE, see
+// https://openid.net/specs/openid-connect-core-1_0.html and https://datatracker.ietf.org/doc/html/rfc7636
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+type Discovery struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	UserinfoEndpoint              string   `json:"userinfo_endpoint"`
+	JwksUri                       string   `json:"jwks_uri"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+type TokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IdToken          string `json:"id_token"`
+	TokenType        string `json:"token_type"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Claims are the standard claims identifying the user, taken from the id token and the userinfo endpoint
+type Claims struct {
+	Subject           string   `json:"sub"`
+	Email             string   `json:"email"`
+	EmailVerified     flexBool `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Picture           string   `json:"picture"`
+}
+
+func (c *Claims) IsEmailVerified() bool {
+	return c.Email != "" && bool(c.EmailVerified)
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// Provider talks to a single identity provider. Discovery and signing keys are fetched lazily and cached.
+type Provider struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string // empty for public clients
+	RedirectUri  string
+	Scopes       []string
+	HttpClient   *http.Client
+
+	lock      sync.Mutex
+	discovery *Discovery
+	keys      map[string]*rsa.PublicKey
+}
+
+func NewProvider(issuer, clientId, clientSecret, redirectUri string, scopes []string) *Provider {
+	return &Provider{
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		RedirectUri:  redirectUri,
+		Scopes:       scopes,
+		HttpClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Discover fetches the provider's metadata, which has to be issued for the configured issuer
+func (p *Provider) Discover() (*Discovery, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery Discovery
+	if err := p.getJson(p.Issuer+discoveryPath, "", &discovery); err != nil {
+		return nil, errors.Wrap(err, "failed to fetch provider metadata")
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("provider metadata issued for %s instead of %s", discovery.Issuer, p.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JwksUri == "" {
+		return nil, errors.New("provider metadata lacks required endpoints")
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// AuthCodeUrl returns the url to send the user to for logging in at the provider
+func (p *Provider) AuthCodeUrl(state, nonce, codeVerifier string) (string, error) {
+	discovery, err := p.Discover()
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.ClientId)
+	params.Set("redirect_uri", p.RedirectUri)
+	params.Set("scope", strings.Join(p.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", PkceChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems the authorization code for tokens
+func (p *Provider) Exchange(code, codeVerifier string) (*TokenResponse, error) {
+	discovery, err := p.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectUri)
+	form.Set("client_id", p.ClientId)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.ClientId), url.QueryEscape(p.ClientSecret))
+	}
+
+	res, err := p.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var token TokenResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, errors.Wrap(err, "failed to decode token response")
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("token request failed: %s %s", token.Error, token.ErrorDescription)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed with status %d", res.StatusCode)
+	}
+	if token.IdToken == "" {
+		return nil, errors.New("token response lacks an id token")
+	}
+	return &token, nil
+}
+
+// VerifyIdToken checks the id token's signature against the provider's keys and validates its claims
+func (p *Provider) VerifyIdToken(raw, nonce string) (*Claims, error) {
+	if _, err := p.Discover(); err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unsupported signing method %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.getKey(kid)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid id token")
+	}
+
+	mapClaims := token.Claims.(jwt.MapClaims)
+	if iss, _ := mapClaims["iss"].(string); strings.TrimSuffix(iss, "/") != p.Issuer {
+		return nil, errors.New("id token issued by a different provider")
+	}
+	if !mapClaims.VerifyAudience(p.ClientId, true) {
+		return nil, errors.New("id token issued for a different client")
+	}
+	if _, ok := mapClaims["exp"]; !ok {
+		return nil, errors.New("id token lacks an expiry")
+	}
+	if n, _ := mapClaims["nonce"].(string); n != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	var claims Claims
+	if err := remarshal(mapClaims, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("id token lacks a subject")
+	}
+	return &claims, nil
+}
+
+// UserInfo fetches the user's claims from the userinfo endpoint, e.g. if the id token doesn't include the email
+func (p *Provider) UserInfo(accessToken string) (*Claims, error) {
+	discovery, err := p.Discover()
+	if err != nil {
+		return nil, err
+	}
+	if discovery.UserinfoEndpoint == "" {
+		return nil, errors.New("provider has no userinfo endpoint")
+	}
+
+	var claims Claims
+	if err := p.getJson(discovery.UserinfoEndpoint, accessToken, &claims); err != nil {
+		return nil, errors.Wrap(err, "failed to fetch userinfo")
+	}
+	return &claims, nil
+}
+
+// getKey returns the signing key with the given id, refetching the key set once in case keys were rotated
+func (p *Provider) getKey(kid string) (*rsa.PublicKey, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if key := p.findKey(kid); key != nil {
+		return key, nil
+	}
+
+	var keySet struct {
+		Keys []*jwk `json:"keys"`
+	}
+	if err := p.getJson(p.discovery.JwksUri, "", &keySet); err != nil {
+		return nil, errors.Wrap(err, "failed to fetch signing keys")
+	}
+
+	p.keys = make(map[string]*rsa.PublicKey)
+	for _, k := range keySet.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		if key, err := k.rsaPublicKey(); err == nil {
+			p.keys[k.Kid] = key
+		}
+	}
+
+	if key := p.findKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %s", kid)
+}
+
+func (p *Provider) findKey(kid string) *rsa.PublicKey {
+	if key, ok := p.keys[kid]; ok {
+		return key
+	}
+	// tokens may omit the key id if the provider only has a single key
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return nil
+}
+
+func (p *Provider) getJson(url, accessToken string, target interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	res, err := p.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed with status %d", url, res.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(target)
+}
+
+func (k *jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+// RandomToken returns a random, url-safe string, e.g. to use as state, nonce or pkce code verifier
+func RandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// PkceChallenge derives the S256 code challenge from a code verifier
+func PkceChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func remarshal(from, to interface{}) error {
+	b, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, to)
+}
+
+// flexBool accepts both booleans and strings, as some providers send email_verified as "true"
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	*b = flexBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
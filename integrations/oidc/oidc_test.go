@@ -0,0 +1,186 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientId     = "wakana"
+	testClientSecret = "secret"
+	testRedirectUri  = "http://localhost:3000/api/oauth/callback/oidc"
+)
+
+// mockIdp is a minimal identity provider, which issues an id token for a single, fixed authorization code
+type mockIdp struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	code     string
+	verifier string
+	nonce    string
+	claims   jwt.MapClaims
+}
+
+func newMockIdp(t *testing.T) *mockIdp {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &mockIdp{key: key, code: "auth-code"}
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"userinfo_endpoint":      idp.server.URL + "/userinfo",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "key-1",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientId, clientSecret, _ := r.BasicAuth()
+		r.ParseForm()
+		if r.Form.Get("code") != idp.code || PkceChallenge(r.Form.Get("code_verifier")) != PkceChallenge(idp.verifier) ||
+			clientId != testClientId || clientSecret != testClientSecret || r.Form.Get("redirect_uri") != testRedirectUri {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"id_token":     idp.sign(t, idp.claims),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"sub": "user-1", "email": "jane@example.org", "email_verified": "true"})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	idp.claims = jwt.MapClaims{
+		"iss":   idp.server.URL,
+		"sub":   "user-1",
+		"aud":   testClientId,
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"email": "jane@example.org",
+		"name":  "Jane Doe",
+	}
+	return idp
+}
+
+func (idp *mockIdp) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(idp.key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestProvider_AuthCodeUrl(t *testing.T) {
+	idp := newMockIdp(t)
+	sut := NewProvider(idp.server.URL+"/", testClientId, testClientSecret, testRedirectUri, []string{"openid", "email"})
+
+	authUrl, err := sut.AuthCodeUrl("state-1", "nonce-1", "verifier-1")
+	require.NoError(t, err)
+
+	parsed, err := url.Parse(authUrl)
+	require.NoError(t, err)
+	assert.Equal(t, "/authorize", parsed.Path)
+	assert.Equal(t, "code", parsed.Query().Get("response_type"))
+	assert.Equal(t, testClientId, parsed.Query().Get("client_id"))
+	assert.Equal(t, "openid email", parsed.Query().Get("scope"))
+	assert.Equal(t, "state-1", parsed.Query().Get("state"))
+	assert.Equal(t, "nonce-1", parsed.Query().Get("nonce"))
+	assert.Equal(t, PkceChallenge("verifier-1"), parsed.Query().Get("code_challenge"))
+	assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+}
+
+func TestProvider_ExchangeAndVerify(t *testing.T) {
+	idp := newMockIdp(t)
+	idp.verifier, idp.nonce = "verifier-1", "nonce-1"
+	idp.claims["nonce"] = idp.nonce
+	sut := NewProvider(idp.server.URL, testClientId, testClientSecret, testRedirectUri, []string{"openid"})
+
+	_, err := sut.Exchange(idp.code, "wrong-verifier")
+	assert.Error(t, err)
+
+	token, err := sut.Exchange(idp.code, idp.verifier)
+	require.NoError(t, err)
+
+	claims, err := sut.VerifyIdToken(token.IdToken, idp.nonce)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject)
+	assert.Equal(t, "Jane Doe", claims.Name)
+	assert.False(t, claims.IsEmailVerified())
+
+	_, err = sut.VerifyIdToken(token.IdToken, "other-nonce")
+	assert.Error(t, err)
+
+	userInfo, err := sut.UserInfo(token.AccessToken)
+	require.NoError(t, err)
+	assert.True(t, userInfo.IsEmailVerified())
+}
+
+func TestProvider_VerifyIdToken_Invalid(t *testing.T) {
+	idp := newMockIdp(t)
+	sut := NewProvider(idp.server.URL, testClientId, testClientSecret, testRedirectUri, []string{"openid"})
+
+	claims := func(key string, value interface{}) jwt.MapClaims {
+		c := jwt.MapClaims{}
+		for k, v := range idp.claims {
+			c[k] = v
+		}
+		c[key] = value
+		return c
+	}
+
+	_, err := sut.VerifyIdToken(idp.sign(t, idp.claims), "")
+	assert.NoError(t, err)
+
+	_, err = sut.VerifyIdToken(idp.sign(t, claims("aud", "other-client")), "")
+	assert.Error(t, err)
+	_, err = sut.VerifyIdToken(idp.sign(t, claims("aud", []string{"other-client", testClientId})), "")
+	assert.NoError(t, err)
+	_, err = sut.VerifyIdToken(idp.sign(t, claims("iss", "https://evil.example.org")), "")
+	assert.Error(t, err)
+	_, err = sut.VerifyIdToken(idp.sign(t, claims("exp", time.Now().Add(-time.Minute).Unix())), "")
+	assert.Error(t, err)
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims)
+	forged.Header["kid"] = "key-1"
+	signed, _ := forged.SignedString(otherKey)
+	_, err = sut.VerifyIdToken(signed, "")
+	assert.Error(t, err)
+
+	hmacSigned, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, idp.claims).SignedString([]byte(testClientSecret))
+	_, err = sut.VerifyIdToken(hmacSigned, "")
+	assert.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
@@ -9,6 +11,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/utils"
@@ -41,35 +44,32 @@ var userWithExtPattern = regexp.MustCompile(`\.svg$`)
 // 	router.Mount("/activity", r)
 // }
 
+// GetActivityChart serves a GitHub-style chart of daily totals, e.g. /api/chart/{user}.svg?interval=this_year or
+// ?from=2024-01-01&to=2024-06-30, covering the past 12 months by default
 func (a *APIv1) GetActivityChart(w http.ResponseWriter, r *http.Request) {
 	authorizedUser := middlewares.GetPrincipal(r)
 
-	// chi currently doesn't support dots in parameters of routes containing a dot themselves, this is a workaround
-	// https://github.com/go-chi/chi/issues/758
-	// https://github.com/go-chi/chi/pull/811
-	userWithExt := chi.URLParam(r, "userWithExt")
-	if !strings.HasSuffix(userWithExt, ".svg") {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte(conf.ErrNotFound))
+	requestedUser, ok := a.getChartUser(w, r)
+	if !ok {
 		return
 	}
-	requestedUser, err := a.services.Users().GetUserById(userWithExtPattern.ReplaceAllString(userWithExt, ""))
+
+	from, to, err := parseActivityRange(r, requestedUser)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
 		return
 	}
 
-	if authorizedUser == nil || authorizedUser.ID != requestedUser.ID {
-		if _, userRange := helpers.ResolveMaximumRange(requestedUser.ShareDataMaxDays); userRange != models.IntervalPast12Months && userRange != models.IntervalAny { // TODO: build "hierarchy" of intervals to easily check if one is contained in another
-			w.WriteHeader(http.StatusForbidden)
-			return
-		}
+	if (authorizedUser == nil || authorizedUser.ID != requestedUser.ID) && !isSharedSince(requestedUser, from) {
+		w.WriteHeader(http.StatusForbidden)
+		return
 	}
 
 	paramDark := r.URL.Query().Has("dark") && r.URL.Query().Get("dark") != "false"
 	paramNoAttr := r.URL.Query().Has("noattr") && r.URL.Query().Get("noattr") != "false" // no attribution (no wakapi logo in bottom left corner)
 
-	chart, err := a.services.Activity().GetChart(requestedUser, models.IntervalPast12Months, paramDark, paramNoAttr, utils.IsNoCache(r, 6*time.Hour))
+	chart, err := a.services.Activity().GetChart(requestedUser, from, to, paramDark, paramNoAttr, utils.IsNoCache(r, 6*time.Hour))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		conf.Log().Request(r).Error("failed to get activity chart for user", "userID", requestedUser.ID, "error", err)
@@ -81,3 +81,159 @@ func (a *APIv1) GetActivityChart(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(chart))
 }
+
+// GetActivityHeatmapChart serves the weekday by hour heatmap as svg, e.g. /api/chart/heatmap/{user}.svg?interval=last_30_days&language=Go.
+// Others may only filter by the kinds of entities the user shares.
+func (a *APIv1) GetActivityHeatmapChart(w http.ResponseWriter, r *http.Request) {
+	authorizedUser := middlewares.GetPrincipal(r)
+
+	requestedUser, ok := a.getChartUser(w, r)
+	if !ok {
+		return
+	}
+
+	from, to, err := parseActivityRange(r, requestedUser)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	filters := helpers.ParseSummaryFilters(r)
+	if (authorizedUser == nil || authorizedUser.ID != requestedUser.ID) && (!isSharedSince(requestedUser, from) || !isFilterShared(requestedUser, filters)) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	paramDark := r.URL.Query().Has("dark") && r.URL.Query().Get("dark") != "false"
+	paramNoAttr := r.URL.Query().Has("noattr") && r.URL.Query().Get("noattr") != "false"
+
+	chart, err := a.services.Activity().GetHeatmapChart(requestedUser, from, to, filters, paramDark, paramNoAttr, utils.IsNoCache(r, 6*time.Hour))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to get activity heatmap for user", "userID", requestedUser.ID, "error", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "max-age=21600") // 6 hours
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(chart))
+}
+
+// @Summary Retrieve coding time per weekday and hour of day
+// @Description Seconds are indexed by weekday (0 for sunday to 6) and hour of day in the user's timezone. Takes the same filters as summaries.
+// @ID get-activity-heatmap
+// @Tags activity
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param interval query string false "Interval identifier, defaults to the past 12 months" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 6_months, last_6_months, 12_months, last_12_months, last_year, any, all_time)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param project query string false "Project to filter by"
+// @Param language query string false "Language to filter by"
+// @Param editor query string false "Editor to filter by"
+// @Param operating_system query string false "OS to filter by"
+// @Param machine query string false "Machine to filter by"
+// @Param label query string false "Project label to filter by"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ActivityHeatmap
+// @Router /v1/users/{user}/activity/heatmap [get]
+func (a *APIv1) GetActivityHeatmap(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	from, to, err := parseActivityRange(r, user)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": fmt.Sprintf("Invalid Input: %s", err.Error()),
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	heatmap, err := a.services.Activity().GetHeatmap(user, from, to, helpers.ParseSummaryFilters(r), utils.IsNoCache(r, 6*time.Hour))
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": heatmap,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+func (a *APIv1) getChartUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	// chi currently doesn't support dots in parameters of routes containing a dot themselves, this is a workaround
+	// https://github.com/go-chi/chi/issues/758
+	// https://github.com/go-chi/chi/pull/811
+	userWithExt := chi.URLParam(r, "userWithExt")
+	if !strings.HasSuffix(userWithExt, ".svg") {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return nil, false
+	}
+	requestedUser, err := a.services.Users().GetUserById(userWithExtPattern.ReplaceAllString(userWithExt, ""))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, false
+	}
+	return requestedUser, true
+}
+
+// parseActivityRange reads the time range from either an interval or from and to parameters in the user's timezone,
+// defaulting to the past 12 months
+func parseActivityRange(r *http.Request, user *models.User) (time.Time, time.Time, error) {
+	params := r.URL.Query()
+
+	if interval := params.Get("interval"); interval != "" {
+		err, from, to := helpers.ResolveIntervalRawTZ(interval, user.TZ())
+		return from, to, err
+	}
+	if !params.Has("from") && !params.Has("to") {
+		err, from, to := helpers.ResolveIntervalTZ(models.IntervalPast12Months, user.TZ())
+		return from, to, err
+	}
+
+	from, err := helpers.ParseDateTimeTZ(params.Get("from"), user.TZ())
+	if err != nil {
+		return from, from, errors.New("missing or invalid 'from' parameter")
+	}
+	to, err := helpers.ParseDateTimeTZ(params.Get("to"), user.TZ())
+	if err != nil {
+		return from, to, errors.New("missing or invalid 'to' parameter")
+	}
+	if !from.Before(to) {
+		return from, to, errors.New("'from' must be before 'to'")
+	}
+	return from, to, nil
+}
+
+// isSharedSince returns whether others may see the user's coding activity reaching back to the given time
+func isSharedSince(user *models.User, from time.Time) bool {
+	return user.ShareDataMaxDays < 0 || (user.ShareDataMaxDays > 0 && !from.Before(time.Now().AddDate(0, 0, -user.ShareDataMaxDays)))
+}
+
+// isFilterShared returns whether others may filter the user's coding activity by all the given kinds of entities
+func isFilterShared(user *models.User, filters *models.Filters) bool {
+	shared := map[uint8]bool{
+		models.SummaryProject:  user.ShareProjects,
+		models.SummaryLanguage: user.ShareLanguages,
+		models.SummaryEditor:   user.ShareEditors,
+		models.SummaryOS:       user.ShareOSs,
+		models.SummaryMachine:  user.ShareMachines,
+		models.SummaryLabel:    user.ShareLabels,
+	}
+	for _, t := range models.SummaryTypes() {
+		if filters.ResolveType(t).Exists() && !shared[t] {
+			return false
+		}
+	}
+	return true
+}
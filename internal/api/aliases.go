@@ -0,0 +1,304 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
+	"github.com/muety/wakapi/models"
+)
+
+// @Summary Retrieve the user's aliases
+// @ID get-aliases
+// @Tags aliases
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param type query int false "Only return aliases of this entity type"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Alias
+// @Router /v1/users/{user}/aliases [get]
+func (a *APIv1) FetchUserAliases(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var aliases []*models.Alias
+	if typeParam := r.URL.Query().Get("type"); typeParam != "" {
+		summaryType, parseErr := strconv.ParseUint(typeParam, 10, 8)
+		if parseErr != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": "Invalid type",
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+		aliases, err = a.services.Alias().GetByUserAndType(user.ID, uint8(summaryType))
+	} else {
+		aliases, err = a.services.Alias().GetByUser(user.ID)
+	}
+
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching aliases",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": aliases,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Create an alias
+// @ID create-alias
+// @Tags aliases
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to create the alias for (or 'current')"
+// @Param alias body models.Alias true "Alias to create"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.Alias
+// @Router /v1/users/{user}/aliases [post]
+func (a *APIv1) CreateAlias(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params = &models.Alias{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || !prepareAlias(params, user) {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: key, value and a valid type are required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if a.aliasExists(params) {
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": "Alias already exists",
+			"status":  http.StatusConflict,
+		})
+		return
+	}
+
+	alias, err := a.services.Alias().Create(params)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": alias,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+// @Summary Create multiple aliases at once
+// @ID create-aliases-bulk
+// @Tags aliases
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to create the aliases for (or 'current')"
+// @Param aliases body []models.Alias true "Aliases to create"
+// @Security ApiKeyAuth
+// @Success 201 {array} models.Alias
+// @Router /v1/users/{user}/aliases/bulk [post]
+func (a *APIv1) CreateAliasesBulk(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params []*models.Alias
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil || len(params) == 0 {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a non-empty list of aliases is required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	// validate everything upfront to not end up with half of the batch created
+	for i, alias := range params {
+		if !prepareAlias(alias, user) {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": fmt.Sprintf("Invalid Input: alias at index %d is invalid", i),
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	// aliases that exist already, or are contained in the batch more than once, are only created once
+	newAliases := make([]*models.Alias, 0, len(params))
+	for _, alias := range params {
+		isDuplicate := slices.ContainsFunc(newAliases, func(other *models.Alias) bool {
+			return other.Type == alias.Type && other.Key == alias.Key && other.Value == alias.Value
+		})
+		if !isDuplicate && !a.aliasExists(alias) {
+			newAliases = append(newAliases, alias)
+		}
+	}
+
+	created := make([]*models.Alias, 0)
+	if len(newAliases) > 0 {
+		if created, err = a.services.Alias().CreateMulti(newAliases); err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message":       "An unexpected error occurred. Try again later",
+				"error_message": err.Error(),
+			})
+			return
+		}
+	}
+
+	response := map[string]interface{}{
+		"data": created,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+// @Summary Delete an alias
+// @ID delete-alias
+// @Tags aliases
+// @Produce json
+// @Param user path string true "User ID to delete the alias for (or 'current')"
+// @Param id path int true "Alias ID"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/aliases/{id} [delete]
+func (a *APIv1) DeleteAlias(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	aliasID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Bad Request",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	aliases, err := a.findUserAliases(user, []uint{uint(aliasID)})
+	if err != nil || len(aliases) == 0 {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Alias Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	if err := a.services.Alias().Delete(aliases[0]); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Alias Cannot Be Deleted",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"message": "Alias deleted successfully",
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+// @Summary Delete multiple aliases at once
+// @ID delete-aliases-bulk
+// @Tags aliases
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to delete the aliases for (or 'current')"
+// @Param ids body models.BulkDelete true "IDs of the aliases to delete"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/aliases/bulk [delete]
+func (a *APIv1) DeleteAliasesBulk(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params = &models.BulkDelete{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || len(params.IDs) == 0 {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a non-empty list of ids is required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	// ids not belonging to the user are silently skipped
+	aliases, err := a.findUserAliases(user, params.IDs)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching aliases",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	if len(aliases) > 0 {
+		if err := a.services.Alias().DeleteMulti(aliases); err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": "Aliases Cannot Be Deleted",
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+	}
+	response := map[string]interface{}{
+		"message": fmt.Sprintf("%d aliases deleted successfully", len(aliases)),
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+// prepareAlias binds the alias to the given user and reports whether it is valid
+func prepareAlias(alias *models.Alias, user *models.User) bool {
+	alias.ID = 0
+	alias.UserID = user.ID
+	return alias.IsValid()
+}
+
+func (a *APIv1) aliasExists(alias *models.Alias) bool {
+	existing, err := a.services.Alias().GetByUserAndKeyAndType(alias.UserID, alias.Key, alias.Type)
+	if err != nil {
+		return false
+	}
+	for _, e := range existing {
+		if e.Value == alias.Value {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *APIv1) findUserAliases(user *models.User, ids []uint) ([]*models.Alias, error) {
+	aliases, err := a.services.Alias().GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	matches := make([]*models.Alias, 0, len(ids))
+	for _, alias := range aliases {
+		if wanted[alias.ID] {
+			matches = append(matches, alias)
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var existingAliases = []*models.Alias{
+	{ID: 1, UserID: "user1", Type: models.SummaryProject, Key: "wakapi", Value: "wakapi-*"},
+	{ID: 2, UserID: "user1", Type: models.SummaryLanguage, Key: "Go", Value: "golang"},
+}
+
+func newAliasServiceMock(mockServices *services.ServicesMock) *mocks.AliasServiceMock {
+	aliasServiceMock := new(mocks.AliasServiceMock)
+	aliasServiceMock.On("GetByUser", "user1").Return(existingAliases, nil)
+	aliasServiceMock.On("GetByUserAndType", "user1", models.SummaryLanguage).Return(existingAliases[1:], nil)
+	aliasServiceMock.On("GetByUserAndKeyAndType", "user1", "wakapi", models.SummaryProject).Return(existingAliases[:1], nil)
+	aliasServiceMock.On("GetByUserAndKeyAndType", "user1", mock.Anything, mock.Anything).Return([]*models.Alias{}, nil)
+	mockServices.AliasFunc = func() services.IAliasService {
+		return aliasServiceMock
+	}
+	return aliasServiceMock
+}
+
+// behavior common to all kinds of user data is covered by TestUserDataHandlers
+func TestAliasesHandler(t *testing.T) {
+	config.Set(config.Empty())
+
+	mockServices := &services.ServicesMock{}
+	router := newUserDataTestRouter(mockServices, userDataUser, func(r chi.Router, api *APIv1) {
+		r.Get("/v1/users/{user}/aliases", api.FetchUserAliases)
+		r.Post("/v1/users/{user}/aliases", api.CreateAlias)
+		r.Post("/v1/users/{user}/aliases/bulk", api.CreateAliasesBulk)
+	})
+
+	t.Run("when fetching aliases", func(t *testing.T) {
+		t.Run("should only return aliases of the given type", func(t *testing.T) {
+			newAliasServiceMock(mockServices)
+			res := serveUserDataRequest(router, http.MethodGet, "/api/v1/users/current/aliases?type=1", "")
+
+			assert.Equal(t, http.StatusOK, res.Code)
+			var body struct{ Data []*models.Alias }
+			assert.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+			assert.Len(t, body.Data, 1)
+			assert.Equal(t, "Go", body.Data[0].Key)
+		})
+
+		t.Run("should reject invalid types", func(t *testing.T) {
+			newAliasServiceMock(mockServices)
+			res := serveUserDataRequest(router, http.MethodGet, "/api/v1/users/current/aliases?type=foo", "")
+			assert.Equal(t, http.StatusBadRequest, res.Code)
+		})
+	})
+
+	t.Run("when creating an alias", func(t *testing.T) {
+		t.Run("should reject existing aliases", func(t *testing.T) {
+			aliasServiceMock := newAliasServiceMock(mockServices)
+			res := serveUserDataRequest(router, http.MethodPost, "/api/v1/users/current/aliases", `{"type": 0, "key": "wakapi", "value": "wakapi-*"}`)
+
+			assert.Equal(t, http.StatusConflict, res.Code)
+			aliasServiceMock.AssertNotCalled(t, "Create", mock.Anything)
+		})
+	})
+
+	t.Run("when creating aliases in bulk", func(t *testing.T) {
+		t.Run("should skip existing and duplicate ones", func(t *testing.T) {
+			aliasServiceMock := newAliasServiceMock(mockServices)
+			aliasServiceMock.On("CreateMulti", mock.Anything).Return([]*models.Alias{
+				{ID: 3, UserID: "user1", Type: models.SummaryEditor, Key: "VSCode", Value: "Code"},
+				{ID: 4, UserID: "user1", Type: models.SummaryOS, Key: "Linux", Value: "Ubuntu"},
+			}, nil)
+
+			res := serveUserDataRequest(router, http.MethodPost, "/api/v1/users/current/aliases/bulk", `[
+				{"type": 2, "key": "VSCode", "value": "Code"},
+				{"type": 0, "key": "wakapi", "value": "wakapi-*"},
+				{"type": 3, "key": "Linux", "value": "Ubuntu"},
+				{"type": 2, "key": "VSCode", "value": "Code"}
+			]`)
+
+			assert.Equal(t, http.StatusCreated, res.Code)
+			created := aliasServiceMock.Calls[len(aliasServiceMock.Calls)-1].Arguments.Get(0).([]*models.Alias)
+			assert.Len(t, created, 2)
+			assert.Equal(t, "VSCode", created[0].Key)
+			assert.Equal(t, "Linux", created[1].Key)
+		})
+	})
+}
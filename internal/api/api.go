@@ -23,6 +23,7 @@ import (
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/routes/relay"
 	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
 	"github.com/patrickmn/go-cache"
 	"github.com/riverqueue/river"
 	"github.com/sebest/xff"
@@ -35,14 +36,16 @@ type APIv1 struct {
 	config *conf.Config
 
 	// overrideTime can be used to override the clock used by handlers. Should only be used in tests!
-	overrideTime func() time.Time
-	mailService  mail.IMailService
-	services     services.IServices
-	httpClient   *http.Client
-	cache        *cache.Cache
-	lruCache     *lru.Cache
-	workers      *river.Workers
-	river        *river.Client[pgx.Tx]
+	overrideTime  func() time.Time
+	mailService   mail.IMailService
+	mailOutbox    *mail.Outbox
+	services      services.IServices
+	httpClient    *http.Client
+	webhookClient *http.Client
+	cache         *cache.Cache
+	lruCache      *lru.Cache
+	workers       *river.Workers
+	river         *river.Client[pgx.Tx]
 }
 
 func (a *APIv1) Now() time.Time {
@@ -87,13 +90,15 @@ func (a *APIv1) StartRiverJobs() {
 
 func NewAPIv1(globalConfig *conf.Config, db *gorm.DB) *APIv1 {
 	api := &APIv1{
-		config:      globalConfig,
-		db:          db,
-		mailService: mail.NewMailService(),
-		services:    services.NewServices(db),
-		httpClient:  &http.Client{Timeout: 10 * time.Second},
-		cache:       cache.New(6*time.Hour, 6*time.Hour),
-		workers:     river.NewWorkers(),
+		config:        globalConfig,
+		db:            db,
+		mailOutbox:    mail.InitOutbox(db),
+		mailService:   mail.NewMailService(),
+		services:      services.NewServices(db),
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		webhookClient: utils.NewPublicHttpClient(10 * time.Second),
+		cache:         cache.New(6*time.Hour, 6*time.Hour),
+		workers:       river.NewWorkers(),
 	}
 
 	lruCache, err := lru.New(1 * 1000 * 64)
@@ -103,7 +108,7 @@ func NewAPIv1(globalConfig *conf.Config, db *gorm.DB) *APIv1 {
 
 	api.lruCache = lruCache
 
-	if err := river.AddWorkerSafely(api.workers, river.WorkFunc(api.weeklyReportWorker)); err != nil {
+	if err := river.AddWorkerSafely(api.workers, river.WorkFunc(api.scheduledReportWorker)); err != nil {
 		fmt.Println(fmt.Errorf("failed to add worker: %w", err))
 	}
 
@@ -123,6 +128,38 @@ func NewAPIv1(globalConfig *conf.Config, db *gorm.DB) *APIv1 {
 		fmt.Println(fmt.Errorf("failed to add housekeeping inactive users worker: %w", err))
 	}
 
+	if err := river.AddWorkerSafely(api.workers, river.WorkFunc(api.wakatimeImportWorker)); err != nil {
+		fmt.Println(fmt.Errorf("failed to add wakatime import worker: %w", err))
+	}
+
+	if err := river.AddWorkerSafely(api.workers, river.WorkFunc(api.fileImportWorker)); err != nil {
+		fmt.Println(fmt.Errorf("failed to add file import worker: %w", err))
+	}
+
+	if err := river.AddWorkerSafely(api.workers, river.WorkFunc(api.dataExportWorker)); err != nil {
+		fmt.Println(fmt.Errorf("failed to add data export worker: %w", err))
+	}
+
+	if err := river.AddWorkerSafely(api.workers, river.WorkFunc(api.dataExportCleanupWorker)); err != nil {
+		fmt.Println(fmt.Errorf("failed to add data export cleanup worker: %w", err))
+	}
+
+	if err := river.AddWorkerSafely(api.workers, river.WorkFunc(api.goalEvaluationWorker)); err != nil {
+		fmt.Println(fmt.Errorf("failed to add goal evaluation worker: %w", err))
+	}
+
+	if err := river.AddWorkerSafely(api.workers, river.WorkFunc(api.webhookDeliveryWorker)); err != nil {
+		fmt.Println(fmt.Errorf("failed to add webhook delivery worker: %w", err))
+	}
+
+	if err := river.AddWorkerSafely(api.workers, river.WorkFunc(api.invoiceOverdueWorker)); err != nil {
+		fmt.Println(fmt.Errorf("failed to add invoice overdue worker: %w", err))
+	}
+
+	if err := river.AddWorkerSafely(api.workers, river.WorkFunc(api.recurringInvoiceWorker)); err != nil {
+		fmt.Println(fmt.Errorf("failed to add recurring invoice worker: %w", err))
+	}
+
 	riverClient, err := jobs.NewRiverClient(context.Background(), api.workers, globalConfig)
 	if err != nil {
 		panic(err)
@@ -135,10 +172,9 @@ func NewAPIv1(globalConfig *conf.Config, db *gorm.DB) *APIv1 {
 func (a *APIv1) RegisterPeriodicJobs() error {
 	periodicJobs := []*river.PeriodicJob{
 		river.NewPeriodicJob(
-			jobs.EVERY_MONDAY_MORNING,
-			// river.PeriodicInterval(time.Minute*5),
+			jobs.EVERY_FIVE_MINUTES,
 			func() (river.JobArgs, *river.InsertOpts) {
-				return WeeklyReportArgs{}, nil
+				return ScheduledReportArgs{}, nil
 			},
 			&river.PeriodicJobOpts{RunOnStart: true},
 		),
@@ -185,6 +221,42 @@ func (a *APIv1) RegisterPeriodicJobs() error {
 	)
 	periodicJobs = append(periodicJobs, housekeepingUsersJob)
 
+	exportCleanupJob := river.NewPeriodicJob(
+		jobs.HOURLY_EXPORT_CLEANUP,
+		func() (river.JobArgs, *river.InsertOpts) {
+			return DataExportCleanupArgs{}, nil
+		},
+		&river.PeriodicJobOpts{RunOnStart: false},
+	)
+	periodicJobs = append(periodicJobs, exportCleanupJob)
+
+	goalEvaluationJob := river.NewPeriodicJob(
+		jobs.HOURLY_GOAL_EVALUATION,
+		func() (river.JobArgs, *river.InsertOpts) {
+			return GoalEvaluationArgs{}, nil
+		},
+		&river.PeriodicJobOpts{RunOnStart: false},
+	)
+	periodicJobs = append(periodicJobs, goalEvaluationJob)
+
+	invoiceOverdueJob := river.NewPeriodicJob(
+		jobs.HOURLY_INVOICE_OVERDUE,
+		func() (river.JobArgs, *river.InsertOpts) {
+			return InvoiceOverdueArgs{}, nil
+		},
+		&river.PeriodicJobOpts{RunOnStart: false},
+	)
+	periodicJobs = append(periodicJobs, invoiceOverdueJob)
+
+	recurringInvoiceJob := river.NewPeriodicJob(
+		jobs.HOURLY_RECURRING_INVOICES,
+		func() (river.JobArgs, *river.InsertOpts) {
+			return RecurringInvoiceArgs{}, nil
+		},
+		&river.PeriodicJobOpts{RunOnStart: false},
+	)
+	periodicJobs = append(periodicJobs, recurringInvoiceJob)
+
 	a.river.PeriodicJobs().AddMany(periodicJobs)
 	return nil
 }
@@ -193,6 +265,10 @@ func (a *APIv1) initializeJobs() {
 	// Schedule background tasks
 	// migrate all cron jobs to periodic river jobs
 	go conf.StartJobs()
+	go a.subscribeWebhooks()
+
+	// retry mails that failed to be sent
+	a.mailOutbox.Schedule()
 
 	// Legacy cron jobs (TODO: migrate remaining to River)
 
@@ -232,6 +308,10 @@ func StartApiRiverClient(config *conf.Config) {
 	defer sqlDB.Close()
 
 	api := NewAPIv1(config, db)
+
+	// events are published in whichever process they occur in, so both the api and the worker process need to listen
+	go api.subscribeWebhooks()
+
 	err = api.RegisterPeriodicJobs()
 	if err != nil {
 		slog.Error("error setting up river jobs", "error", err)
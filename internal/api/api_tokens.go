@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
+	"github.com/muety/wakapi/models"
+)
+
+// @Summary Retrieve the user's personal access tokens
+// @Description Tokens themselves are never included, only a hint of their last characters
+// @ID get-api-tokens
+// @Tags tokens
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.ApiToken
+// @Router /v1/users/{user}/tokens [get]
+func (a *APIv1) FetchUserApiTokens(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	tokens, err := a.services.ApiToken().FetchUserTokens(user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching api tokens",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": tokens,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Create a personal access token
+// @Description Scopes are either `admin` for full access or of the form `<resource>:<read|write|*>`, where resource is one of heartbeats, stats, projects, goals, clients, invoices, settings, data and orgs, e.g. `heartbeats:write` or `invoices:*`. Read access covers GET requests, write access all others.
+// @Description The token is only included in this response, it is stored hashed. Use it like an api key, e.g. as `Authorization: Bearer <base64 encoded token>`.
+// @ID create-api-token
+// @Tags tokens
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to create the token for (or 'current')"
+// @Param token body models.NewApiToken true "Token to create"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.ApiToken
+// @Router /v1/users/{user}/tokens [post]
+func (a *APIv1) CreateApiToken(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params = &models.NewApiToken{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || !params.IsValid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a name, at least one known scope and an expiry in the future (if any) are required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	token, err := a.services.ApiToken().Create(user, params)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": token,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+// @Summary Revoke a personal access token
+// @ID delete-api-token
+// @Tags tokens
+// @Produce json
+// @Param user path string true "User ID to delete the token for (or 'current')"
+// @Param id path string true "Token ID"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/tokens/{id} [delete]
+func (a *APIv1) DeleteApiToken(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	token, err := a.services.ApiToken().GetById(chi.URLParam(r, "id"))
+	if err != nil || token.UserID != user.ID {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Api Token Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	if err := a.services.ApiToken().Delete(token); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Api Token Cannot Be Deleted",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"message": "Api token deleted successfully",
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
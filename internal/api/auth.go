@@ -68,15 +68,29 @@ func (a *APIv1) Signin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if a.respondTwoFactorChallenge(w, r, user) {
+		return
+	}
+
 	user.LastLoggedInAt = models.CustomTime(time.Now())
 	a.services.Users().Update(user)
 
+	session, err := a.startSession(r, user)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
 	response, err := helpers.MakeAuthSuccessResponse(
 		&helpers.AuthSuccessResponse{
 			Message:   "Login Successful",
 			User:      user,
 			OauthUser: nil,
 			IsNewUser: false,
+			Session:   session,
 		},
 	)
 	if err != nil {
@@ -247,15 +261,29 @@ func (a *APIv1) GithubOauth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if a.respondTwoFactorChallenge(w, r, oauthUser) {
+		return
+	}
+
 	oauthUser.LastLoggedInAt = models.CustomTime(time.Now())
 	a.services.Users().Update(oauthUser)
 
+	session, err := a.startSession(r, oauthUser)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
 	response, err := helpers.MakeAuthSuccessResponse(
 		&helpers.AuthSuccessResponse{
 			Message:   "Signup Successful",
 			User:      oauthUser,
 			OauthUser: &userOauthDetails,
 			IsNewUser: provider == nil,
+			Session:   session,
 		},
 	)
 
@@ -327,15 +355,29 @@ func (a *APIv1) Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if a.respondTwoFactorChallenge(w, r, user) {
+		return
+	}
+
 	user.LastLoggedInAt = models.CustomTime(time.Now())
 	a.services.Users().Update(user)
 
+	session, err := a.startSession(r, user)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
 	response, err := helpers.MakeAuthSuccessResponse(
 		&helpers.AuthSuccessResponse{
 			Message:   "Signup Successful",
 			User:      user,
 			OauthUser: nil,
 			IsNewUser: true,
+			Session:   session,
 		},
 	)
 	if err != nil {
@@ -368,6 +410,14 @@ func (a *APIv1) ValidateAuthToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if session, err := a.services.Sessions().GetActive(claim.JTI); err != nil || session.UserID != claim.UID {
+		helpers.RespondJSON(w, r, http.StatusUnauthorized, map[string]interface{}{
+			"message": "Unauthorized: Session was revoked or has expired",
+			"status":  http.StatusUnauthorized,
+		})
+		return
+	}
+
 	helpers.RespondJSON(w, r, http.StatusAccepted, map[string]interface{}{
 		"message": "Token is valid",
 		"status":  http.StatusAccepted,
@@ -413,12 +463,83 @@ func (a *APIv1) RefreshApiKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// whoever got hold of the old key might have used it to log in elsewhere
+	if _, err := a.services.Sessions().RevokeAll(user.ID, a.currentSessionId(r)); err != nil {
+		conf.Log().Request(r).Error("failed to revoke sessions after api key refresh", "userID", user.ID, "error", err)
+	}
+
 	helpers.RespondJSON(w, r, http.StatusAccepted, map[string]interface{}{
 		"status": http.StatusAccepted,
 		"apiKey": user.ApiKey,
 	})
 }
 
+// @Summary Change the password
+// @Description Sets a new password, given the current one, and logs out all other sessions
+// @ID change-password
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.CredentialsReset true "Current and new password"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/auth/password [post]
+func (a *APIv1) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+	if user == nil {
+		helpers.RespondJSON(w, r, http.StatusUnauthorized, map[string]interface{}{
+			"message": "Unauthorized",
+			"status":  http.StatusUnauthorized,
+		})
+		return
+	}
+
+	var params = &models.CredentialsReset{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || !params.IsValid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: the new password must have at least 6 characters and match its repetition",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if !utils.ComparePassword(user.Password, params.PasswordOld, a.config.Security.PasswordSalt) {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid credentials",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	hash, err := utils.HashPassword(params.PasswordNew, a.config.Security.PasswordSalt)
+	if err == nil {
+		user.Password = hash
+		_, err = a.services.Users().Update(user)
+	}
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	revoked, err := a.services.Sessions().RevokeAll(user.ID, a.currentSessionId(r))
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusAccepted, map[string]interface{}{
+		"message":          "Password changed",
+		"status":           http.StatusAccepted,
+		"revoked_sessions": revoked,
+	})
+}
+
 func (a *APIv1) handlePasswordReset(user *models.User) error {
 	updatedUser, err := a.services.Users().GenerateResetToken(user)
 	if err != nil {
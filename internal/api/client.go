@@ -35,11 +35,14 @@ func (a *APIv1) UpdateClient(w http.ResponseWriter, r *http.Request) {
 	jsonDecoder := json.NewDecoder(r.Body)
 	err := jsonDecoder.Decode(params)
 
-	if err != nil {
+	params.Currency = models.NormalizeCurrency(params.Currency)
+
+	if err != nil || !params.HasValidContact() || (params.Rounding != nil && !params.Rounding.IsValid()) || (params.Currency != "" && !models.IsValidCurrency(params.Currency)) {
 		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
 			"message": "Invalid Input",
 			"status":  http.StatusBadRequest,
 		})
+		return
 	}
 
 	client, err := a.services.Client().GetClientForUser(clientID, user.ID)
@@ -133,6 +136,31 @@ func (a *APIv1) CreateClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !params.HasValidContact() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: invalid email address",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	params.Currency = models.NormalizeCurrency(params.Currency)
+	if !models.IsValidCurrency(params.Currency) {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: currency must be a valid ISO 4217 code",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if !params.Rounding.IsValid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: invalid rounding policy",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
 	params.UserID = user.ID
 	params.ID = uuid.NewV4().String()
 
@@ -210,7 +238,7 @@ func (a *APIv1) FetchInvoiceLineItemsForClient(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	summary, err := a.services.Client().FetchClientInvoiceLineItems(client, user, a.services.Summary(), start, end)
+	lineItems, err := a.services.Client().FetchClientInvoiceLineItems(client, user, a.services.Summary(), start, end)
 	if err != nil {
 		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
 			"message": "Error fetching invoice data for client. Try later.",
@@ -221,7 +249,8 @@ func (a *APIv1) FetchInvoiceLineItemsForClient(w http.ResponseWriter, r *http.Re
 
 	response := map[string]interface{}{
 		"client":     client,
-		"line_items": summary.Projects,
+		"line_items": lineItems,
+		"subtotal":   (&models.Invoice{Client: *client, LineItems: lineItems}).Subtotal(),
 	}
 	sendJSONSuccess(w, http.StatusAccepted, response)
 }
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+// @Summary List a client's billing rates
+// @ID get-client-rates
+// @Tags clients
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Client ID"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.ClientRate
+// @Router /v1/users/{user}/clients/{id}/rates [get]
+func (a *APIv1) FetchClientRates(w http.ResponseWriter, r *http.Request) {
+	client, ok := a.loadUserClient(w, r)
+	if !ok {
+		return
+	}
+
+	rates, err := a.services.Client().FetchRates(client.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch client rates", "clientID", client.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data": rates,
+	})
+}
+
+// @Summary Add a billing rate to a client
+// @Description Rates override the client's hourly rate for time spent on a project and / or category, optionally starting at a given date. The most specific rate applies.
+// @ID create-client-rate
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Client ID"
+// @Param rate body models.NewClientRate true "Rate to add"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.ClientRate
+// @Router /v1/users/{user}/clients/{id}/rates [post]
+func (a *APIv1) CreateClientRate(w http.ResponseWriter, r *http.Request) {
+	client, ok := a.loadUserClient(w, r)
+	if !ok {
+		return
+	}
+
+	rate := &models.ClientRate{ClientID: client.ID}
+	if !a.decodeClientRate(w, r, rate) {
+		return
+	}
+
+	created, err := a.services.Client().CreateRate(rate)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to create client rate", "clientID", client.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, map[string]interface{}{
+		"data": created,
+	})
+}
+
+// @Summary Update a client's billing rate
+// @ID update-client-rate
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Client ID"
+// @Param rateId path string true "Rate ID"
+// @Param rate body models.NewClientRate true "Updated rate"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ClientRate
+// @Router /v1/users/{user}/clients/{id}/rates/{rateId} [put]
+func (a *APIv1) UpdateClientRate(w http.ResponseWriter, r *http.Request) {
+	client, ok := a.loadUserClient(w, r)
+	if !ok {
+		return
+	}
+
+	rate, err := a.services.Client().GetRate(chi.URLParam(r, "rateId"), client.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+				"message": "Rate not found",
+				"status":  http.StatusNotFound,
+			})
+			return
+		}
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	if !a.decodeClientRate(w, r, rate) {
+		return
+	}
+
+	updated, err := a.services.Client().UpdateRate(rate)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to update client rate", "rateID", rate.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data": updated,
+	})
+}
+
+// @Summary Delete a client's billing rate
+// @ID delete-client-rate
+// @Tags clients
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Client ID"
+// @Param rateId path string true "Rate ID"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/clients/{id}/rates/{rateId} [delete]
+func (a *APIv1) DeleteClientRate(w http.ResponseWriter, r *http.Request) {
+	client, ok := a.loadUserClient(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.services.Client().DeleteRate(chi.URLParam(r, "rateId"), client.ID); err != nil {
+		conf.Log().Request(r).Error("failed to delete client rate", "clientID", client.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusAccepted, map[string]interface{}{
+		"message": "Rate deleted successfully",
+	})
+}
+
+func (a *APIv1) loadUserClient(w http.ResponseWriter, r *http.Request) (*models.Client, bool) {
+	user := helpers.ExtractUser(r)
+
+	client, err := a.services.Client().GetClientForUser(chi.URLParam(r, "id"), user.ID)
+	if err != nil || client == nil {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Client Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return nil, false
+	}
+	return client, true
+}
+
+// decodeClientRate applies the request body to the given rate, responding with an error if it's invalid
+func (a *APIv1) decodeClientRate(w http.ResponseWriter, r *http.Request, rate *models.ClientRate) bool {
+	user := helpers.ExtractUser(r)
+
+	var params = &models.NewClientRate{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || !params.IsValid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input",
+			"status":  http.StatusBadRequest,
+		})
+		return false
+	}
+
+	rate.Project = params.Project
+	rate.Category = params.Category
+	rate.HourlyRate = params.HourlyRate
+	rate.Description = params.Description
+	rate.EffectiveFrom = nil
+
+	if params.EffectiveFrom != "" {
+		effectiveFrom, err := helpers.ParseDateTimeTZ(params.EffectiveFrom, user.TZ())
+		if err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": "Invalid Input: invalid effective_from date",
+				"status":  http.StatusBadRequest,
+			})
+			return false
+		}
+		rate.EffectiveFrom = &effectiveFrom
+	}
+
+	return true
+}
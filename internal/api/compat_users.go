@@ -34,3 +34,29 @@ func (a *APIv1) GetUser(w http.ResponseWriter, r *http.Request) {
 
 	helpers.RespondJSON(w, r, http.StatusOK, v1.UserViewModel{Data: user})
 }
+
+// @Summary Retrieve the machines the given user has sent heartbeats from
+// @Description Mimics https://wakatime.com/api/v1/users/current/machine_names
+// @ID get-wakatime-machine-names
+// @Tags wakatime
+// @Produce json
+// @Param user path string true "User ID to fetch (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.MachineViewModel
+// @Router /compat/wakatime/v1/users/{user}/machine_names [get]
+func (a *APIv1) GetMachineNames(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	machines, err := a.services.Machine().FetchUserMachines(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch machines", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(conf.ErrInternalServerError))
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, v1.NewMachinesFrom(machines))
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+// @Summary List the user's exchange rates
+// @ID get-exchange-rates
+// @Tags invoices
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.ExchangeRate
+// @Router /v1/users/{user}/invoices/exchange-rates [get]
+func (a *APIv1) FetchExchangeRates(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+
+	rates, err := a.services.Invoice().FetchExchangeRates(user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data": rates,
+	})
+}
+
+// @Summary Add an exchange rate
+// @Description One unit of the base currency is worth rate units of the quote currency, starting at the given date. Rates apply in both directions.
+// @ID create-exchange-rate
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param rate body models.NewExchangeRate true "Rate to add"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.ExchangeRate
+// @Router /v1/users/{user}/invoices/exchange-rates [post]
+func (a *APIv1) CreateExchangeRate(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+
+	var params = &models.NewExchangeRate{}
+	err := json.NewDecoder(r.Body).Decode(params)
+	params.BaseCurrency = models.NormalizeCurrency(params.BaseCurrency)
+	params.QuoteCurrency = models.NormalizeCurrency(params.QuoteCurrency)
+	if err != nil || !params.IsValid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: base and quote currency must be distinct ISO 4217 codes and the rate positive",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	validFrom := datetime.BeginOfDay(time.Now().In(user.TZ()))
+	if params.ValidFrom != "" {
+		if validFrom, err = helpers.ParseDateTimeTZ(params.ValidFrom, user.TZ()); err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": "Invalid Input: invalid valid_from date",
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	rate, err := a.services.Invoice().CreateExchangeRate(&models.ExchangeRate{
+		UserID:        user.ID,
+		BaseCurrency:  params.BaseCurrency,
+		QuoteCurrency: params.QuoteCurrency,
+		Rate:          params.Rate,
+		ValidFrom:     validFrom,
+	})
+	if err != nil {
+		conf.Log().Request(r).Error("failed to create exchange rate", "userID", user.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, map[string]interface{}{
+		"data": rate,
+	})
+}
+
+// @Summary Delete an exchange rate
+// @ID delete-exchange-rate
+// @Tags invoices
+// @Param user path string true "User ID (or 'current')"
+// @Param rateId path int true "Exchange rate ID"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/invoices/exchange-rates/{rateId} [delete]
+func (a *APIv1) DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+
+	rateID, err := strconv.ParseUint(chi.URLParam(r, "rateId"), 10, 64)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid exchange rate id",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := a.services.Invoice().DeleteExchangeRate(uint(rateID), user.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+				"message": "Exchange rate not found",
+				"status":  http.StatusNotFound,
+			})
+			return
+		}
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusAccepted, map[string]interface{}{
+		"message": "Exchange rate deleted successfully",
+	})
+}
+
+// @Summary Get the revenue per month
+// @Description Sums up issued invoices per month of issue, converted into the user's home currency using their exchange rates. Defaults to the last twelve months.
+// @ID get-invoice-revenue
+// @Tags invoices
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param from query string false "Start date (inclusive)"
+// @Param to query string false "End date (inclusive)"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.RevenueReport
+// @Router /v1/users/{user}/invoices/revenue [get]
+func (a *APIv1) GetInvoiceRevenue(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+
+	now := time.Now().In(user.TZ())
+	from, to := datetime.BeginOfMonth(now).AddDate(0, -11, 0), datetime.BeginOfMonth(now).AddDate(0, 1, 0)
+
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := helpers.ParseDateTimeTZ(v, user.TZ())
+		if err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": "Invalid from date",
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+		from = t
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := helpers.ParseDateTimeTZ(v, user.TZ())
+		if err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": "Invalid to date",
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+		to = datetime.BeginOfDay(t).AddDate(0, 0, 1)
+	}
+
+	report, err := a.services.Invoice().GetRevenueReport(user, from, to)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data": report,
+	})
+}
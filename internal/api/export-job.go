@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/riverqueue/river"
+)
+
+type DataExportArgs struct {
+	ExportID string `json:"export_id"`
+	UserID   string `json:"user_id"`
+}
+
+type DataExportCleanupArgs struct{}
+
+func (DataExportArgs) Kind() string        { return "data_export" }
+func (DataExportCleanupArgs) Kind() string { return "data_export_cleanup" }
+
+func (a *APIv1) dataExportWorker(_ context.Context, job *river.Job[DataExportArgs]) error {
+	export, err := a.services.DataExport().GetById(job.Args.ExportID)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.services.Users().GetUserById(job.Args.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := a.services.DataExport().Generate(export, user); err != nil {
+		conf.Log().Error("failed to generate data export", "userID", user.ID, "exportID", export.ID, "error", err)
+		return err
+	}
+
+	slog.Info("generated data export for user", "userID", user.ID, "exportID", export.ID, "size", export.Size)
+	return nil
+}
+
+func (a *APIv1) dataExportCleanupWorker(_ context.Context, _ *river.Job[DataExportCleanupArgs]) error {
+	count, err := a.services.DataExport().DeleteExpired()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		slog.Info("deleted expired data exports", "count", count)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/riverqueue/river"
+)
+
+const (
+	// download links are handed out with a short validity, independent of how long the archive itself is kept
+	exportDownloadLinkTtl     = 1 * time.Hour
+	exportDownloadLinkPurpose = "export-download"
+)
+
+// @Summary Request an export of all of the user's data
+// @Description Queues a background job that bundles heartbeats (in WakaTime's data dump format), summaries, settings, goals, clients, invoices, aliases and labels into a zip archive
+// @ID post-export
+// @Tags exports
+// @Produce json
+// @Param user path string true "User ID to export data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 202 {object} models.DataExport
+// @Router /v1/users/{user}/exports [post]
+func (a *APIv1) CreateDataExport(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	if active, err := a.services.DataExport().HasActiveExport(user.ID); err != nil || active {
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": "An export is already in progress",
+			"status":  http.StatusConflict,
+		})
+		return
+	}
+
+	export, err := a.services.DataExport().Create(user)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	if _, err := a.river.Insert(r.Context(), DataExportArgs{ExportID: export.ID, UserID: user.ID}, &river.InsertOpts{MaxAttempts: 3}); err != nil {
+		conf.Log().Request(r).Error("failed to enqueue data export", "userID", user.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": export,
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+// @Summary Retrieve the user's data exports
+// @ID get-exports
+// @Tags exports
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.DataExport
+// @Router /v1/users/{user}/exports [get]
+func (a *APIv1) FetchUserDataExports(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	exports, err := a.services.DataExport().FetchUserExports(user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching exports",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	for _, export := range exports {
+		a.withExportDownloadUrl(export)
+	}
+	response := map[string]interface{}{
+		"data": exports,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Retrieve status of a data export
+// @Description Once completed, the response includes a signed, time-limited download url
+// @ID get-export
+// @Tags exports
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param id path string true "Export ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.DataExport
+// @Router /v1/users/{user}/exports/{id} [get]
+func (a *APIv1) GetDataExport(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	export, err := a.services.DataExport().GetById(chi.URLParam(r, "id"))
+	if err != nil || export.UserID != user.ID {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Export Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": a.withExportDownloadUrl(export),
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Download a data export archive
+// @Description Authorization happens through the url's signature, so the link can be opened directly in a browser
+// @ID download-export
+// @Tags exports
+// @Produce application/zip
+// @Param id path string true "Export ID"
+// @Param expires query int true "Unix timestamp at which the link expires"
+// @Param signature query string true "Signature of the link"
+// @Success 200 {file} file
+// @Router /v1/exports/{id}/download [get]
+func (a *APIv1) DownloadDataExport(w http.ResponseWriter, r *http.Request) {
+	exportID := chi.URLParam(r, "id")
+	expiresParam := r.URL.Query().Get("expires")
+	signature := r.URL.Query().Get("signature")
+
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil || !utils.VerifySignature(a.exportDownloadKey(), signature, exportID, expiresParam) {
+		helpers.RespondJSON(w, r, http.StatusForbidden, map[string]interface{}{
+			"message": "Invalid download link",
+			"status":  http.StatusForbidden,
+		})
+		return
+	}
+
+	if time.Now().After(time.Unix(expires, 0)) {
+		helpers.RespondJSON(w, r, http.StatusGone, map[string]interface{}{
+			"message": "Download link has expired",
+			"status":  http.StatusGone,
+		})
+		return
+	}
+
+	export, err := a.services.DataExport().GetById(exportID)
+	if err != nil || !export.IsDownloadable() {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Export Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.FileName()))
+	http.ServeFile(w, r, a.services.DataExport().FilePath(export))
+}
+
+// withExportDownloadUrl attaches a freshly signed download link to the export, given its archive is available
+func (a *APIv1) withExportDownloadUrl(export *models.DataExport) *models.DataExport {
+	if !export.IsDownloadable() {
+		return export
+	}
+
+	expires := time.Now().Add(exportDownloadLinkTtl)
+	if export.ExpiresAt.T().Before(expires) {
+		expires = export.ExpiresAt.T()
+	}
+
+	expiresParam := strconv.FormatInt(expires.Unix(), 10)
+	export.DownloadUrl = fmt.Sprintf(
+		"%s/api/v1/exports/%s/download?expires=%s&signature=%s",
+		a.config.Server.PublicUrl,
+		export.ID,
+		expiresParam,
+		utils.SignValues(a.exportDownloadKey(), export.ID, expiresParam),
+	)
+	return export
+}
+
+// exportDownloadKey is derived from the jwt secret, so download link signatures can't be used as signatures elsewhere
+func (a *APIv1) exportDownloadKey() string {
+	return utils.SignValues(a.config.Security.JWT_SECRET, exportDownloadLinkPurpose)
+}
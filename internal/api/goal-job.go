@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/riverqueue/river"
+)
+
+type GoalEvaluationArgs struct{}
+
+func (GoalEvaluationArgs) Kind() string { return "goal_evaluation" }
+
+// goalEvaluationWorker runs every hour, as days and weeks end at different times depending on the users' timezones
+func (a *APIv1) goalEvaluationWorker(_ context.Context, _ *river.Job[GoalEvaluationArgs]) error {
+	goals, err := a.services.Goal().FetchEnabledGoals()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	users := make(map[string]*models.User)
+
+	for _, goal := range goals {
+		user, ok := users[goal.UserID]
+		if !ok {
+			if user, err = a.services.Users().GetUserById(goal.UserID); err != nil {
+				conf.Log().Error("failed to fetch user for goal evaluation", "userID", goal.UserID, "goalID", goal.ID, "error", err)
+				continue
+			}
+			users[goal.UserID] = user
+		}
+
+		periods, err := a.services.Goal().Evaluate(goal, user, a.services.Summary(), now)
+		if err != nil {
+			conf.Log().Error("failed to evaluate goal", "userID", user.ID, "goalID", goal.ID, "error", err)
+			continue
+		}
+		if len(periods) == 0 {
+			continue
+		}
+
+		conf.Log().Info("evaluated goal", "userID", user.ID, "goalID", goal.ID, "periods", len(periods), "status", goal.Status)
+
+		// when catching up on multiple periods, only the most recent one is worth a notification
+		a.notifyGoalStatus(user, goal, periods[len(periods)-1])
+	}
+
+	return nil
+}
+
+func (a *APIv1) notifyGoalStatus(user *models.User, goal *models.Goal, period *models.GoalPeriod) {
+	var event string
+	switch period.Status {
+	case models.GoalStatusSuccess:
+		event = conf.EventGoalReached
+	case models.GoalStatusFailed:
+		event = conf.EventGoalFailed
+	default:
+		return
+	}
+
+	conf.EventBus().Publish(hub.Message{
+		Name: event,
+		Fields: map[string]interface{}{
+			conf.FieldPayload: map[string]interface{}{"goal": goal, "period": period},
+			conf.FieldUserId:  user.ID,
+		},
+	})
+
+	if user.Email == "" || !a.services.Notification().IsEnabled(user, models.NotificationGoalAlerts, models.NotificationChannelEmail) {
+		return
+	}
+	if err := a.mailService.SendGoalNotification(user, goal, period); err != nil {
+		conf.Log().Error("failed to send goal notification mail", "userID", user.ID, "goalID", goal.ID, "error", err)
+	}
+}
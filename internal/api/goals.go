@@ -10,6 +10,8 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
+const goalHistoryLimit = 90
+
 func (a *APIv1) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 	user := helpers.ExtractUser(r)
 	goalID := chi.URLParam(r, "id")
@@ -22,7 +24,7 @@ func (a *APIv1) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var params = &models.Goal{}
+	var params = &models.GoalUpdate{}
 
 	jsonDecoder := json.NewDecoder(r.Body)
 	err := jsonDecoder.Decode(params)
@@ -32,6 +34,7 @@ func (a *APIv1) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 			"message": "Invalid Input",
 			"status":  http.StatusBadRequest,
 		})
+		return
 	}
 
 	goal, err := a.services.Goal().GetGoalForUser(goalID, user.ID)
@@ -43,7 +46,7 @@ func (a *APIv1) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	goal.CustomTitle = &params.Title
+	params.Apply(goal)
 
 	_, err = a.services.Goal().Update(goal)
 	if err != nil {
@@ -139,6 +142,7 @@ func (a *APIv1) CreateGoal(w http.ResponseWriter, r *http.Request) {
 	params.UserID = user.ID
 	params.ID = uuid.NewV4().String()
 	params.Title = params.GetTitle()
+	params.IsEnabled = true
 
 	_, err = a.services.Goal().Create(params)
 	if err != nil {
@@ -189,3 +193,39 @@ func (a *APIv1) FetchUserGoals(w http.ResponseWriter, r *http.Request) {
 	}
 	helpers.RespondJSON(w, r, http.StatusCreated, response)
 }
+
+// @Summary Retrieve the evaluation history of a goal
+// @Description Lists the most recently evaluated days or weeks, newest first, each with the time spent and whether the goal was reached
+// @ID get-goal-history
+// @Tags goals
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param id path string true "Goal ID"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.GoalPeriod
+// @Router /v1/users/{user}/goals/{id}/history [get]
+func (a *APIv1) GetGoalHistory(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+
+	goal, err := a.services.Goal().GetGoalForUser(chi.URLParam(r, "id"), user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Goal Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	history, err := a.services.Goal().FetchGoalHistory(goal.ID, goalHistoryLimit)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching goal history",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": history,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
@@ -80,6 +80,11 @@ func (a *APIv1) ProcessHeartBeat(w http.ResponseWriter, r *http.Request) {
 		editor = agentEditor
 	}
 	machineName := r.Header.Get("X-Machine-Name")
+	// a machine key pins the machine name, so a key copied elsewhere can't be used to send heartbeats in another machine's name
+	pinnedMachine := middlewares.GetPrincipalMachine(r)
+	if pinnedMachine != nil {
+		machineName = pinnedMachine.Name
+	}
 
 	for _, hb := range heartbeats {
 		if hb == nil {
@@ -101,7 +106,7 @@ func (a *APIv1) ProcessHeartBeat(w http.ResponseWriter, r *http.Request) {
 				editor = condition.TernaryOperator(localEditor != "", localEditor, editor)
 			}
 		}
-		if hb.Machine != "" {
+		if hb.Machine != "" && pinnedMachine == nil {
 			machineName = hb.Machine
 		}
 
@@ -146,7 +151,7 @@ func (a *APIv1) ProcessHeartBeat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	go func() {
-		_, err := a.services.UserAgentPlugin().CreateOrUpdate(userAgent, user.ID) // fire and forget
+		_, err := a.services.UserAgentPlugin().CreateOrUpdate(userAgent, user.ID, machineName) // fire and forget
 		if err != nil {
 			conf.Log().Error("failed to create/update user agent plugin - %v", err.Error(), err)
 		}
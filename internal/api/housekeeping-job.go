@@ -32,8 +32,9 @@ func (a *APIv1) housekeepingDataCleanupWorker(_ context.Context, _ *river.Job[Ho
 			continue
 		}
 	}
-	
-	return nil
+
+	// webhook delivery logs are only kept for a limited time, independent of the users' data retention
+	return a.services.Webhook().DeleteDeliveriesBefore(time.Now().Add(-webhookDeliveryRetention))
 }
 
 func (a *APIv1) housekeepingInactiveUsersWorker(_ context.Context, _ *river.Job[HousekeepingInactiveUsersArgs]) error {
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/services/imports"
+	"github.com/riverqueue/river"
+)
+
+type WakatimeImportArgs struct {
+	JobID  string `json:"job_id"`
+	UserID string `json:"user_id"`
+}
+
+type FileImportArgs struct {
+	JobID  string `json:"job_id"`
+	UserID string `json:"user_id"`
+	Format string `json:"format"`
+	Path   string `json:"path"` // uploaded file, removed once the job has run
+}
+
+// importJobKeepAliveInterval is how often a running import job is marked as alive, well below the timeout after which jobs
+// are considered stale
+const importJobKeepAliveInterval = 5 * time.Minute
+
+func (WakatimeImportArgs) Kind() string { return "wakatime_import" }
+func (FileImportArgs) Kind() string     { return "file_import" }
+
+func (a *APIv1) wakatimeImportWorker(_ context.Context, job *river.Job[WakatimeImportArgs]) error {
+	importJob, user, err := a.startImportJob(job.Args.JobID, job.Args.UserID)
+	if err != nil {
+		return err
+	}
+	defer a.keepImportJobAlive(importJob)()
+
+	start := time.Now()
+	importer := imports.NewWakatimeImporter(user.WakatimeApiKey, false)
+
+	var stream <-chan *models.Heartbeat
+	if latest, latestErr := a.services.Heartbeat().GetLatestByOriginAndUser(imports.OriginWakatime, user); latest == nil || latestErr != nil {
+		stream, err = importer.ImportAll(user)
+	} else {
+		// if an import has happened before, only import heartbeats newer than the latest of the last import
+		stream, err = importer.Import(user, latest.Time.T(), time.Now())
+	}
+	if err == nil {
+		err = a.importHeartbeats(importJob, stream)
+	}
+	if err != nil {
+		conf.Log().Error("wakatime import failed", "userID", user.ID, "jobID", importJob.ID, "error", err)
+		a.finishImportJob(importJob, err)
+		return err
+	}
+
+	if err := a.services.KeyValue().PutString(&models.KeyStringValue{
+		Key:   fmt.Sprintf("%s_%s", conf.KeyLastImportSuccess, user.ID),
+		Value: time.Now().Format(time.RFC822),
+	}); err != nil {
+		conf.Log().Error("failed to save last import success timestamp", "userID", user.ID, "error", err)
+	}
+
+	a.completeImportJob(importJob, user, time.Since(start))
+	return nil
+}
+
+func (a *APIv1) fileImportWorker(_ context.Context, job *river.Job[FileImportArgs]) error {
+	defer os.Remove(job.Args.Path)
+
+	importJob, user, err := a.startImportJob(job.Args.JobID, job.Args.UserID)
+	if err != nil {
+		return err
+	}
+	defer a.keepImportJobAlive(importJob)()
+
+	start := time.Now()
+
+	importer, err := imports.NewFileImporter(job.Args.Format, job.Args.Path)
+	var stream <-chan *models.Heartbeat
+	if err == nil {
+		stream, err = importer.ImportAll(user)
+	}
+	if err == nil {
+		err = a.importHeartbeats(importJob, stream)
+	}
+	if err != nil {
+		conf.Log().Error("file import failed", "userID", user.ID, "jobID", importJob.ID, "format", job.Args.Format, "error", err)
+		a.finishImportJob(importJob, err)
+		return err
+	}
+
+	a.completeImportJob(importJob, user, time.Since(start))
+	return nil
+}
+
+func (a *APIv1) startImportJob(jobID, userID string) (*models.ImportJob, *models.User, error) {
+	importJob, err := a.services.ImportJob().GetById(jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if importJob.IsFinished() {
+		// e.g. given up on as stale in the meantime, the user may already have started another import
+		return nil, nil, river.JobCancel(fmt.Errorf("import job %s is already %s", importJob.ID, importJob.Status))
+	}
+
+	user, err := a.services.Users().GetUserById(userID)
+	if err != nil {
+		a.finishImportJob(importJob, err)
+		return nil, nil, err
+	}
+
+	if err := a.services.ImportJob().MarkRunning(importJob); err != nil {
+		return nil, nil, err
+	}
+	return importJob, user, nil
+}
+
+// keepImportJobAlive periodically marks the job as alive until the returned function is called
+func (a *APIv1) keepImportJobAlive(importJob *models.ImportJob) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(importJobKeepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := a.services.ImportJob().KeepAlive(&models.ImportJob{ID: importJob.ID}); err != nil {
+					conf.Log().Warn("failed to keep import job alive", "jobID", importJob.ID, "error", err)
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// importHeartbeats consumes the importer's stream batch by batch, skipping heartbeats that are already present
+func (a *APIv1) importHeartbeats(importJob *models.ImportJob, stream <-chan *models.Heartbeat) error {
+	var insertErr error
+	batch := make([]*models.Heartbeat, 0, a.config.App.ImportBatchSize)
+
+	insert := func(batch []*models.Heartbeat) {
+		newHeartbeats, err := a.services.Heartbeat().FilterExisting(batch)
+		if err != nil {
+			insertErr = err
+			return
+		}
+		if err := a.services.Heartbeat().InsertBatch(newHeartbeats); err != nil {
+			insertErr = err
+			return
+		}
+
+		importJob.Imported += int64(len(newHeartbeats))
+		importJob.Skipped += int64(len(batch) - len(newHeartbeats))
+		if err := a.services.ImportJob().UpdateProgress(importJob); errors.Is(err, services.ErrImportJobAborted) {
+			insertErr = err // don't import any further batches
+		} else if err != nil {
+			conf.Log().Error("failed to update import progress", "jobID", importJob.ID, "error", err)
+		}
+	}
+
+	// keep draining the stream after a failed insert, otherwise the importer would block forever
+	for hb := range stream {
+		if insertErr != nil {
+			continue
+		}
+		importJob.Received++
+		batch = append(batch, hb)
+		if len(batch) == a.config.App.ImportBatchSize {
+			insert(batch)
+			batch = make([]*models.Heartbeat, 0, a.config.App.ImportBatchSize)
+		}
+	}
+	if len(batch) > 0 && insertErr == nil {
+		insert(batch)
+	}
+
+	return insertErr
+}
+
+// completeImportJob regenerates the user's summaries and notifies them about the finished import
+func (a *APIv1) completeImportJob(importJob *models.ImportJob, user *models.User, duration time.Duration) {
+	slog.Info("imported heartbeats for user", "userID", user.ID, "source", importJob.Source, "received", importJob.Received, "imported", importJob.Imported, "skipped", importJob.Skipped)
+
+	// imported heartbeats are usually older than the summaries generated so far
+	if err := a.regenerateSummaries(user); err != nil {
+		conf.Log().Error("failed to regenerate summaries after import", "userID", user.ID, "error", err)
+	}
+
+	if !user.HasData && importJob.Imported > 0 {
+		user.HasData = true
+		if _, err := a.services.Users().Update(user); err != nil {
+			conf.Log().Error("failed to set 'has_data' flag for user", "userID", user.ID, "error", err)
+		}
+	}
+
+	a.finishImportJob(importJob, nil)
+
+	if user.Email != "" && a.services.Notification().IsEnabled(user, models.NotificationImportFinished, models.NotificationChannelEmail) {
+		if err := a.mailService.SendImportNotification(user, duration, int(importJob.Imported)); err != nil {
+			conf.Log().Error("failed to send import notification mail", "userID", user.ID, "error", err)
+		}
+	}
+}
+
+func (a *APIv1) finishImportJob(importJob *models.ImportJob, jobErr error) {
+	if err := a.services.ImportJob().Finish(importJob, jobErr); err != nil {
+		conf.Log().Error("failed to update import job", "jobID", importJob.ID, "error", err)
+	}
+
+	conf.EventBus().Publish(hub.Message{
+		Name:   conf.EventImportFinished,
+		Fields: map[string]interface{}{conf.FieldPayload: importJob, conf.FieldUserId: importJob.UserID},
+	})
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services/imports"
+	"github.com/riverqueue/river"
+)
+
+const maxImportFileSize = 256 << 20
+
+var importFileSources = map[string]string{
+	imports.FileFormatWakatime: models.ImportSourceWakatimeFile,
+	imports.FileFormatWakapi:   models.ImportSourceWakapiFile,
+	imports.FileFormatCsv:      models.ImportSourceCsvFile,
+}
+
+// @Summary Start importing the user's history from WakaTime
+// @Description Queues a background job that downloads all heartbeats from the user's configured WakaTime account. If an import has run before, only newer heartbeats are fetched.
+// @ID post-import
+// @Tags imports
+// @Produce json
+// @Param user path string true "User ID to import data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 202 {object} models.ImportJob
+// @Failure 429 {object} map[string]interface{}
+// @Router /v1/users/{user}/imports [post]
+func (a *APIv1) StartWakatimeImport(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	if !a.config.App.ImportEnabled {
+		helpers.RespondJSON(w, r, http.StatusForbidden, map[string]interface{}{
+			"message": "Imports are disabled on this server",
+			"status":  http.StatusForbidden,
+		})
+		return
+	}
+
+	if user.WakatimeApiKey == "" {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "No WakaTime API key configured",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if active, err := a.services.ImportJob().HasActiveJob(user.ID); err != nil || active {
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": "An import is already in progress",
+			"status":  http.StatusConflict,
+		})
+		return
+	}
+
+	kvKeyLastImport := fmt.Sprintf("%s_%s", conf.KeyLastImport, user.ID)
+	kvKeyLastImportSuccess := fmt.Sprintf("%s_%s", conf.KeyLastImportSuccess, user.ID)
+
+	if !a.config.IsDev() {
+		lastImport, _ := time.Parse(time.RFC822, a.services.KeyValue().MustGetString(kvKeyLastImport).Value)
+		if time.Since(lastImport) < time.Duration(a.config.App.ImportBackoffMin)*time.Minute {
+			helpers.RespondJSON(w, r, http.StatusTooManyRequests, map[string]interface{}{
+				"message": fmt.Sprintf("Too many data imports. You are only allowed to request an import every %d minutes.", a.config.App.ImportBackoffMin),
+				"status":  http.StatusTooManyRequests,
+			})
+			return
+		}
+
+		lastImportSuccess, _ := time.Parse(time.RFC822, a.services.KeyValue().MustGetString(kvKeyLastImportSuccess).Value)
+		if time.Since(lastImportSuccess) < time.Duration(a.config.App.ImportMaxRate)*time.Hour {
+			helpers.RespondJSON(w, r, http.StatusTooManyRequests, map[string]interface{}{
+				"message": fmt.Sprintf("Too many data imports. You are only allowed to run a successful import every %d hours.", a.config.App.ImportMaxRate),
+				"status":  http.StatusTooManyRequests,
+			})
+			return
+		}
+	}
+
+	importJob, err := a.services.ImportJob().Create(user, models.ImportSourceWakatime)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	// a failed import is not retried automatically, as every attempt counts towards the rate limit
+	if _, err := a.river.Insert(r.Context(), WakatimeImportArgs{JobID: importJob.ID, UserID: user.ID}, &river.InsertOpts{MaxAttempts: 1}); err != nil {
+		a.finishImportJob(importJob, err)
+		conf.Log().Request(r).Error("failed to enqueue wakatime import", "userID", user.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	if err := a.services.KeyValue().PutString(&models.KeyStringValue{
+		Key:   kvKeyLastImport,
+		Value: time.Now().Format(time.RFC822),
+	}); err != nil {
+		conf.Log().Request(r).Error("failed to save last import timestamp", "userID", user.ID, "error", err)
+	}
+
+	response := map[string]interface{}{
+		"data": importJob,
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+// @Summary Import heartbeats from an uploaded file
+// @Description Supported formats are a WakaTime data dump (`wakatime`), an archive created through this instance's data export (`wakapi`) and a csv file (`csv`).
+// @Description CSV files require a header row, columns are matched by name: time (unix seconds or RFC 3339, required), entity (required), type, category, project, branch, language, is_write, editor, operating_system, machine, user_agent.
+// @Description Heartbeats already present are skipped, the job reports the number of imported and skipped heartbeats.
+// @ID post-import-file
+// @Tags imports
+// @Accept multipart/form-data
+// @Produce json
+// @Param user path string true "User ID to import data for (or 'current')"
+// @Param format formData string true "File format" Enums(wakatime, wakapi, csv)
+// @Param file formData file true "File to import"
+// @Security ApiKeyAuth
+// @Success 202 {object} models.ImportJob
+// @Router /v1/users/{user}/imports/file [post]
+func (a *APIv1) StartFileImport(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	if !a.config.App.ImportEnabled {
+		helpers.RespondJSON(w, r, http.StatusForbidden, map[string]interface{}{
+			"message": "Imports are disabled on this server",
+			"status":  http.StatusForbidden,
+		})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a file of at most 256 MB is required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	format := r.FormValue("format")
+	source, ok := importFileSources[format]
+	if !ok {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: format must be one of wakatime, wakapi or csv",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	upload, _, err := r.FormFile("file")
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: file is required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	defer upload.Close()
+
+	if active, err := a.services.ImportJob().HasActiveJob(user.ID); err != nil || active {
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": "An import is already in progress",
+			"status":  http.StatusConflict,
+		})
+		return
+	}
+
+	// multipart files are cleaned up once the request is done, so keep a copy for the background job
+	path, err := storeImportFile(upload, a.config.App.ImportDir)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to store uploaded import file", "userID", user.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	importJob, err := a.services.ImportJob().Create(user, source)
+	if err != nil {
+		os.Remove(path)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	args := FileImportArgs{JobID: importJob.ID, UserID: user.ID, Format: format, Path: path}
+	if _, err := a.river.Insert(r.Context(), args, &river.InsertOpts{MaxAttempts: 1}); err != nil {
+		os.Remove(path)
+		a.finishImportJob(importJob, err)
+		conf.Log().Request(r).Error("failed to enqueue file import", "userID", user.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": importJob,
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+// @Summary Retrieve the user's data imports
+// @ID get-imports
+// @Tags imports
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.ImportJob
+// @Router /v1/users/{user}/imports [get]
+func (a *APIv1) FetchUserImportJobs(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	importJobs, err := a.services.ImportJob().FetchUserImportJobs(user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching imports",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": importJobs,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Retrieve status and progress of a data import
+// @ID get-import
+// @Tags imports
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param id path string true "Import ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ImportJob
+// @Router /v1/users/{user}/imports/{id} [get]
+func (a *APIv1) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	importJob, err := a.services.ImportJob().GetById(chi.URLParam(r, "id"))
+	if err != nil || importJob.UserID != user.ID {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Import Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": importJob,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// storeImportFile keeps the upload in the import directory rather than the host's temp dir, as the import job might run in another process
+func storeImportFile(upload multipart.File, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+
+	file, err := os.CreateTemp(dir, "import-*")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, upload); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/riverqueue/river"
+)
+
+// upper bound of billing periods caught up on at once, e.g. for schedules that started far in the past
+const recurringInvoiceMaxPeriods = 12
+
+type InvoiceOverdueArgs struct{}
+
+func (InvoiceOverdueArgs) Kind() string { return "invoice_overdue" }
+
+type RecurringInvoiceArgs struct{}
+
+func (RecurringInvoiceArgs) Kind() string { return "recurring_invoices" }
+
+func (a *APIv1) invoiceOverdueWorker(_ context.Context, _ *river.Job[InvoiceOverdueArgs]) error {
+	n, err := a.services.Invoice().MarkOverdue(time.Now())
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		slog.Info("marked invoices as overdue", "count", n)
+	}
+	return nil
+}
+
+// recurringInvoiceWorker runs every hour, as billing periods end at midnight in the users' respective timezones
+func (a *APIv1) recurringInvoiceWorker(_ context.Context, _ *river.Job[RecurringInvoiceArgs]) error {
+	now := time.Now()
+
+	schedules, err := a.services.Invoice().FetchDueSchedules(now)
+	if err != nil {
+		return err
+	}
+
+	users := make(map[string]*models.User)
+
+	for _, schedule := range schedules {
+		user, ok := users[schedule.UserID]
+		if !ok {
+			if user, err = a.services.Users().GetUserById(schedule.UserID); err != nil {
+				conf.Log().Error("failed to fetch user for recurring invoice", "userID", schedule.UserID, "scheduleID", schedule.ID, "error", err)
+				continue
+			}
+			users[schedule.UserID] = user
+		}
+
+		client, err := a.services.Client().GetClientForUser(schedule.ClientID, user.ID)
+		if err != nil {
+			conf.Log().Error("failed to fetch client for recurring invoice", "userID", user.ID, "clientID", schedule.ClientID, "error", err)
+			continue
+		}
+
+		for i := 0; i < recurringInvoiceMaxPeriods && schedule.IsDue(now); i++ {
+			if err := a.generateRecurringInvoice(user, client, schedule); err != nil {
+				conf.Log().Error("failed to generate recurring invoice", "userID", user.ID, "clientID", client.ID, "error", err)
+				break
+			}
+
+			schedule.LastRunAt = &now
+			if err := schedule.Advance(user.TZ()); err != nil {
+				conf.Log().Error("failed to advance invoice schedule", "userID", user.ID, "scheduleID", schedule.ID, "error", err)
+				break
+			}
+			if _, err := a.services.Invoice().SaveSchedule(schedule); err != nil {
+				conf.Log().Error("failed to save invoice schedule", "userID", user.ID, "scheduleID", schedule.ID, "error", err)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// generateRecurringInvoice drafts an invoice for the schedule's current billing period, unless one exists already or no time was tracked
+func (a *APIv1) generateRecurringInvoice(user *models.User, client *models.Client, schedule *models.InvoiceSchedule) error {
+	start, end := schedule.Period(user.TZ())
+
+	exists, err := a.services.Invoice().HasInvoiceForPeriod(client.ID, start, end)
+	if err != nil {
+		return err
+	}
+	if exists {
+		conf.Log().Info("skipping recurring invoice, period already invoiced", "userID", user.ID, "clientID", client.ID, "start", start, "end", end)
+		return nil
+	}
+
+	lineItems, err := a.services.Client().FetchClientInvoiceLineItems(client, user, a.services.Summary(), start, end)
+	if err != nil {
+		return err
+	}
+	if len(lineItems) == 0 {
+		return nil
+	}
+
+	invoice := newDraftInvoice(user, client, &models.NewlyGeneratedInvoiceData{StartDate: start, EndDate: end, LineItems: lineItems})
+	if _, err := a.services.Invoice().Create(invoice); err != nil {
+		return err
+	}
+	invoice.Client = *client
+
+	conf.Log().Info("generated recurring invoice", "userID", user.ID, "clientID", client.ID, "invoiceID", invoice.InvoiceID)
+	a.notifyInvoiceGenerated(user, invoice)
+	return nil
+}
+
+func (a *APIv1) notifyInvoiceGenerated(user *models.User, invoice *models.Invoice) {
+	conf.EventBus().Publish(hub.Message{
+		Name: conf.EventInvoiceGenerated,
+		Fields: map[string]interface{}{
+			conf.FieldPayload: invoice,
+			conf.FieldUserId:  user.ID,
+		},
+	})
+
+	if user.Email == "" {
+		return
+	}
+	if err := a.mailService.SendInvoiceGenerated(user, invoice); err != nil {
+		conf.Log().Error("failed to send invoice notification mail", "userID", user.ID, "invoiceID", invoice.ID, "error", err)
+	}
+}
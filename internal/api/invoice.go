@@ -1,15 +1,23 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
-	"github.com/duke-git/lancet/v2/slice"
 	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/pdf"
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
 	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
 )
 
 func (a *APIv1) UpdateInvoice(w http.ResponseWriter, r *http.Request) {
@@ -35,10 +43,11 @@ func (a *APIv1) UpdateInvoice(w http.ResponseWriter, r *http.Request) {
 			"status":        http.StatusBadRequest,
 			"error_message": err.Error(),
 		})
+		return
 	}
 
 	invoice, err := a.services.Invoice().GetInvoiceForUser(InvoiceID, user.ID)
-	if err != nil {
+	if err != nil || invoice == nil {
 		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
 			"message": "Invoice Cannot Be Found",
 			"status":  http.StatusNotFound,
@@ -47,6 +56,13 @@ func (a *APIv1) UpdateInvoice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	_, err = a.services.Invoice().Update(invoice, params)
+	if errors.Is(err, services.ErrInvoiceNotEditable) {
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": "Only draft invoices can be edited, void the invoice and create a new one instead",
+			"status":  http.StatusConflict,
+		})
+		return
+	}
 	if err != nil {
 		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
 			"message":       "Error updating Invoice",
@@ -73,7 +89,7 @@ func (a *APIv1) GetInvoice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	Invoice, err := a.services.Invoice().GetInvoiceForUser(InvoiceID, user.ID)
-	if err != nil {
+	if err != nil || Invoice == nil {
 		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
 			"message": "Invoice Cannot Be Found",
 			"status":  http.StatusBadRequest,
@@ -99,6 +115,13 @@ func (a *APIv1) DeleteInvoice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err := a.services.Invoice().DeleteInvoice(invoiceID, user.ID)
+	if errors.Is(err, services.ErrInvoiceNotEditable) {
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": "Only draft invoices can be deleted, void the invoice instead",
+			"status":  http.StatusConflict,
+		})
+		return
+	}
 	if err != nil {
 		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
 			"message": "Invoice Cannot Be Deleted",
@@ -146,18 +169,7 @@ func (a *APIv1) CreateInvoice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newInvoice := &models.Invoice{
-		ID:             uuid.NewV4().String(),
-		UserID:         user.ID,
-		ClientID:       params.ClientID,
-		StartDate:      generatedData.StartDate,
-		EndDate:        generatedData.EndDate,
-		LineItems:      generatedData.LineItems,
-		InvoiceSummary: fmt.Sprintf("Invoice for work done from %s to %s", helpers.FormatDate(generatedData.StartDate), helpers.FormatDate(generatedData.EndDate)),
-		Destination:    client.Name,
-		Origin:         fmt.Sprintf("%s \nFreelancer", user.Email),
-		Tax:            0,
-	}
+	newInvoice := newDraftInvoice(user, client, generatedData)
 
 	_, err = a.services.Invoice().Create(newInvoice)
 	if err != nil {
@@ -175,6 +187,22 @@ func (a *APIv1) CreateInvoice(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func newDraftInvoice(user *models.User, client *models.Client, generatedData *models.NewlyGeneratedInvoiceData) *models.Invoice {
+	return &models.Invoice{
+		ID:             uuid.NewV4().String(),
+		UserID:         user.ID,
+		ClientID:       client.ID,
+		Currency:       models.NormalizeCurrency(client.Currency),
+		StartDate:      generatedData.StartDate,
+		EndDate:        generatedData.EndDate,
+		LineItems:      generatedData.LineItems,
+		InvoiceSummary: fmt.Sprintf("Invoice for work done from %s to %s", helpers.FormatDate(generatedData.StartDate), helpers.FormatDate(generatedData.EndDate)),
+		Destination:    client.BillingAddress(),
+		Origin:         fmt.Sprintf("%s \nFreelancer", user.Email),
+		Tax:            0,
+	}
+}
+
 func (a *APIv1) FetchUserInvoices(w http.ResponseWriter, r *http.Request) {
 	user := helpers.ExtractUser(r)
 	query := r.URL.Query().Get("q")
@@ -204,7 +232,7 @@ func (a *APIv1) FetchInvoiceLineItems(user *models.User, client *models.Client,
 		return nil, fmt.Errorf("invalid date %s provided", end_date)
 	}
 
-	summary, err := a.services.Client().FetchClientInvoiceLineItems(client, user, a.services.Summary(), start, end)
+	lineItems, err := a.services.Client().FetchClientInvoiceLineItems(client, user, a.services.Summary(), start, end)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching invoice data for client. try later")
 	}
@@ -212,12 +240,400 @@ func (a *APIv1) FetchInvoiceLineItems(user *models.User, client *models.Client,
 	return &models.NewlyGeneratedInvoiceData{
 		StartDate: start,
 		EndDate:   end,
-		LineItems: slice.Map(summary.Projects, func(index int, project *models.SummaryItem) models.InvoiceLineItem {
-			return models.InvoiceLineItem{
-				Title:         project.Key,
-				TotalSeconds:  int64(project.TotalFixed().Seconds()),
-				AutoGenerated: true,
-			}
-		}),
+		LineItems: lineItems,
+		Subtotal:  (&models.Invoice{Client: *client, LineItems: lineItems}).Subtotal(),
 	}, nil
 }
+
+// @Summary Send or void an invoice
+// @Description Sending assigns a due date based on the user's payment terms, unless one is given. Paid and overdue can't be set explicitly, they follow from recorded payments and the due date.
+// @ID update-invoice-status
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Invoice ID"
+// @Param status body models.InvoiceStatusUpdate true "Status to transition to"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Invoice
+// @Router /v1/users/{user}/invoices/{id}/status [put]
+func (a *APIv1) UpdateInvoiceStatus(w http.ResponseWriter, r *http.Request) {
+	user, invoice, ok := a.loadUserInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	var params = &models.InvoiceStatusUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	var dueDate *time.Time
+	if params.DueDate != "" {
+		t, err := helpers.ParseDateTimeTZ(params.DueDate, user.TZ())
+		if err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": fmt.Sprintf("Invalid Input: invalid due date %s provided", params.DueDate),
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+		dueDate = &t
+	}
+
+	updated, err := a.services.Invoice().UpdateStatus(invoice, params.Status, dueDate)
+	if errors.Is(err, services.ErrInvoiceInvalidTransition) {
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": fmt.Sprintf("Invoice can't be transitioned from %s to %s", invoice.Status, params.Status),
+			"status":  http.StatusConflict,
+		})
+		return
+	}
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": updated,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Email an invoice to the client
+// @Description Renders the invoice as pdf and mails it to the client's email address (or the one given), then marks the invoice as sent. Replies go to the user's email address.
+// @ID send-invoice
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Invoice ID"
+// @Param request body models.InvoiceSendRequest false "Recipient, message and due date"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Invoice
+// @Router /v1/users/{user}/invoices/{id}/send [post]
+func (a *APIv1) SendInvoice(w http.ResponseWriter, r *http.Request) {
+	user, invoice, ok := a.loadUserInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	var params = &models.InvoiceSendRequest{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil && !errors.Is(err, io.EOF) {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if !a.config.Mail.Enabled {
+		helpers.RespondJSON(w, r, http.StatusServiceUnavailable, map[string]interface{}{
+			"message": "Sending mails is not enabled on this server, download the invoice instead",
+			"status":  http.StatusServiceUnavailable,
+		})
+		return
+	}
+
+	recipient := params.To
+	if recipient == "" {
+		recipient = invoice.Client.Email
+	}
+	if recipient == "" || !models.MailAddress(recipient).Valid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: the client has no valid email address and no recipient was given",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	var dueDate *time.Time
+	if params.DueDate != "" {
+		t, err := helpers.ParseDateTimeTZ(params.DueDate, user.TZ())
+		if err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": fmt.Sprintf("Invalid Input: invalid due date %s provided", params.DueDate),
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+		dueDate = &t
+	}
+
+	status := invoice.Status
+	sent, err := a.services.Invoice().Send(invoice, dueDate, func(invoice *models.Invoice) error {
+		var buf bytes.Buffer
+		if err := pdf.RenderInvoice(&buf, invoice); err != nil {
+			return err
+		}
+		return a.mailService.SendInvoice(user, invoice, recipient, params.Message, buf.Bytes())
+	})
+	if errors.Is(err, services.ErrInvoiceInvalidTransition) {
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": fmt.Sprintf("Invoice can't be sent, it is %s", status),
+			"status":  http.StatusConflict,
+		})
+		return
+	}
+	if err != nil {
+		conf.Log().Request(r).Error("failed to send invoice", "invoiceID", invoice.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": sent,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Record a payment for an invoice
+// @Description Payments can be partial, the invoice is marked as paid once the total amount was received
+// @ID create-invoice-payment
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Invoice ID"
+// @Param payment body models.NewInvoicePayment true "Payment to record"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.Invoice
+// @Router /v1/users/{user}/invoices/{id}/payments [post]
+func (a *APIv1) CreateInvoicePayment(w http.ResponseWriter, r *http.Request) {
+	user, invoice, ok := a.loadUserInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	var params = &models.NewInvoicePayment{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || !params.IsValid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a positive amount is required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	paidAt := time.Now()
+	if params.PaidAt != "" {
+		t, err := helpers.ParseDateTimeTZ(params.PaidAt, user.TZ())
+		if err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": fmt.Sprintf("Invalid Input: invalid date %s provided", params.PaidAt),
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+		paidAt = t
+	}
+
+	invoice, err := a.services.Invoice().AddPayment(invoice, &models.InvoicePayment{
+		Amount: params.Amount,
+		Method: params.Method,
+		Note:   params.Note,
+		PaidAt: paidAt,
+	})
+	if errors.Is(err, services.ErrInvoiceNotOpen) || errors.Is(err, services.ErrInvoicePaymentExceeds) {
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": err.Error(),
+			"status":  http.StatusConflict,
+		})
+		return
+	}
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": invoice,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+// @Summary Delete a payment recorded for an invoice
+// @ID delete-invoice-payment
+// @Tags invoices
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Invoice ID"
+// @Param paymentId path string true "Payment ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Invoice
+// @Router /v1/users/{user}/invoices/{id}/payments/{paymentId} [delete]
+func (a *APIv1) DeleteInvoicePayment(w http.ResponseWriter, r *http.Request) {
+	_, invoice, ok := a.loadUserInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	invoice, err := a.services.Invoice().DeletePayment(invoice, chi.URLParam(r, "paymentId"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Payment Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+	if errors.Is(err, services.ErrInvoiceNotOpen) {
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": "Payments of voided invoices can't be changed",
+			"status":  http.StatusConflict,
+		})
+		return
+	}
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": invoice,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Download an invoice as pdf
+// @ID get-invoice-pdf
+// @Tags invoices
+// @Produce application/pdf
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Invoice ID"
+// @Security ApiKeyAuth
+// @Success 200 {file} file
+// @Router /v1/users/{user}/invoices/{id}.pdf [get]
+func (a *APIv1) GetInvoicePdf(w http.ResponseWriter, r *http.Request) {
+	_, invoice, ok := a.loadUserInvoice(w, r)
+	if !ok {
+		return
+	}
+
+	// render to a buffer first, so a failure can still be reported properly
+	var buf bytes.Buffer
+	if err := pdf.RenderInvoice(&buf, invoice); err != nil {
+		conf.Log().Request(r).Error("failed to render invoice pdf", "invoiceID", invoice.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s.pdf\"", invoiceFilename(invoice)))
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// @Summary Retrieve the user's invoice settings
+// @ID get-invoice-settings
+// @Tags invoices
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.InvoiceSettings
+// @Router /v1/users/{user}/invoices/settings [get]
+func (a *APIv1) GetInvoiceSettings(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+
+	settings, err := a.services.Invoice().GetSettings(user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": settings,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Update the user's invoice settings
+// @Description The number prefix only applies to invoices created afterwards, numbers keep counting up per user
+// @ID update-invoice-settings
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param settings body models.InvoiceSettings true "Invoice settings"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.InvoiceSettings
+// @Router /v1/users/{user}/invoices/settings [put]
+func (a *APIv1) UpdateInvoiceSettings(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+
+	settings, err := a.services.Invoice().GetSettings(user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(settings)
+	settings.HomeCurrency = models.NormalizeCurrency(settings.HomeCurrency)
+	if err != nil || !settings.IsValid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: prefix must be at most 32 characters, payment terms between 0 and 365 days and home currency a valid ISO 4217 code",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	settings.UserID = user.ID
+
+	if _, err := a.services.Invoice().UpdateSettings(settings); err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": settings,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// loadUserInvoice resolves the invoice addressed by the request, responding with an error if it doesn't exist for the user
+func (a *APIv1) loadUserInvoice(w http.ResponseWriter, r *http.Request) (*models.User, *models.Invoice, bool) {
+	user := helpers.ExtractUser(r)
+
+	invoice, err := a.services.Invoice().GetInvoiceForUser(chi.URLParam(r, "id"), user.ID)
+	if err != nil || invoice == nil {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Invoice Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return nil, nil, false
+	}
+	return user, invoice, true
+}
+
+func invoiceFilename(invoice *models.Invoice) string {
+	name := invoice.InvoiceID
+	if name == "" {
+		name = invoice.ID
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '"' || r == '/' || r == '\\' || r < 32 {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+)
+
+// @Summary Get a client's recurring invoice schedule
+// @ID get-invoice-schedule
+// @Tags clients
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Client ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.InvoiceSchedule
+// @Router /v1/users/{user}/clients/{id}/schedule [get]
+func (a *APIv1) GetInvoiceSchedule(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+	client, ok := a.loadUserClient(w, r)
+	if !ok {
+		return
+	}
+
+	schedule, err := a.services.Invoice().GetSchedule(client.ID, user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	if schedule == nil {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "No invoice schedule set up for this client",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data": schedule,
+	})
+}
+
+// @Summary Set up or update a client's recurring invoice schedule
+// @Description A draft invoice is generated at the end of every monthly, biweekly or cron-defined billing period, unless the period has been invoiced already. Changing the frequency or start date, or re-enabling it, restarts the schedule.
+// @ID update-invoice-schedule
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Client ID"
+// @Param schedule body models.InvoiceScheduleUpdate true "Schedule settings"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.InvoiceSchedule
+// @Router /v1/users/{user}/clients/{id}/schedule [put]
+func (a *APIv1) UpdateInvoiceSchedule(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+	client, ok := a.loadUserClient(w, r)
+	if !ok {
+		return
+	}
+
+	var params = &models.InvoiceScheduleUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || !params.IsValid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: frequency must be monthly, biweekly or cron with a valid cron_expression",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	var start *time.Time
+	if params.StartDate != "" {
+		startDate, err := helpers.ParseDateTimeTZ(params.StartDate, user.TZ())
+		if err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": "Invalid Input: invalid start_date",
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+		start = &startDate
+	}
+
+	schedule, err := a.services.Invoice().GetSchedule(client.ID, user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	// re-enabled schedules start over as well, rather than catching up on the periods missed in the meantime
+	restart := schedule == nil || start != nil || schedule.Frequency != params.Frequency || schedule.CronExpression != params.CronExpression ||
+		(!schedule.IsEnabled && params.IsEnabled != nil && *params.IsEnabled)
+	if schedule == nil {
+		schedule = &models.InvoiceSchedule{UserID: user.ID, ClientID: client.ID, IsEnabled: true}
+	}
+	schedule.Frequency = params.Frequency
+	schedule.CronExpression = params.CronExpression
+	if schedule.Frequency != models.InvoiceFrequencyCron {
+		schedule.CronExpression = ""
+	}
+	if params.IsEnabled != nil {
+		schedule.IsEnabled = *params.IsEnabled
+	}
+
+	if restart {
+		if err := schedule.Reset(start, time.Now().In(user.TZ())); err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": "Invalid Input: " + err.Error(),
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	if _, err := a.services.Invoice().SaveSchedule(schedule); err != nil {
+		conf.Log().Request(r).Error("failed to save invoice schedule", "clientID", client.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data": schedule,
+	})
+}
+
+// @Summary Stop generating recurring invoices for a client
+// @ID delete-invoice-schedule
+// @Tags clients
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Client ID"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/clients/{id}/schedule [delete]
+func (a *APIv1) DeleteInvoiceSchedule(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+	client, ok := a.loadUserClient(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.services.Invoice().DeleteSchedule(client.ID, user.ID); err != nil {
+		conf.Log().Request(r).Error("failed to delete invoice schedule", "clientID", client.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusAccepted, map[string]interface{}{
+		"message": "Invoice schedule deleted successfully",
+	})
+}
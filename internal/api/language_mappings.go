@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
+	"github.com/muety/wakapi/models"
+)
+
+// @Summary Retrieve the user's language mappings
+// @ID get-language-mappings
+// @Tags language-mappings
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.LanguageMapping
+// @Router /v1/users/{user}/language-mappings [get]
+func (a *APIv1) FetchUserLanguageMappings(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	mappings, err := a.services.LanguageMapping().GetByUser(user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching language mappings",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": mappings,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Create a language mapping
+// @ID create-language-mapping
+// @Tags language-mappings
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to create the mapping for (or 'current')"
+// @Param mapping body models.LanguageMapping true "Language mapping to create"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.LanguageMapping
+// @Router /v1/users/{user}/language-mappings [post]
+func (a *APIv1) CreateLanguageMapping(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params = &models.LanguageMapping{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || !prepareLanguageMapping(params, user) {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: extension and language are required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	mapping, err := a.services.LanguageMapping().Create(params)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": mapping,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+// @Summary Create multiple language mappings at once
+// @ID create-language-mappings-bulk
+// @Tags language-mappings
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to create the mappings for (or 'current')"
+// @Param mappings body []models.LanguageMapping true "Language mappings to create"
+// @Security ApiKeyAuth
+// @Success 201 {array} models.LanguageMapping
+// @Router /v1/users/{user}/language-mappings/bulk [post]
+func (a *APIv1) CreateLanguageMappingsBulk(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params []*models.LanguageMapping
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil || len(params) == 0 {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a non-empty list of language mappings is required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	for i, mapping := range params {
+		if !prepareLanguageMapping(mapping, user) {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": fmt.Sprintf("Invalid Input: language mapping at index %d is invalid", i),
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	created, err := a.services.LanguageMapping().CreateMulti(params)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": created,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+// @Summary Delete a language mapping
+// @ID delete-language-mapping
+// @Tags language-mappings
+// @Produce json
+// @Param user path string true "User ID to delete the mapping for (or 'current')"
+// @Param id path int true "Language mapping ID"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/language-mappings/{id} [delete]
+func (a *APIv1) DeleteLanguageMapping(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	mappingID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Bad Request",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	mapping, err := a.services.LanguageMapping().GetById(uint(mappingID))
+	if err != nil || mapping.UserID != user.ID {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Language Mapping Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	if err := a.services.LanguageMapping().Delete(mapping); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Language Mapping Cannot Be Deleted",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"message": "Language mapping deleted successfully",
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+// @Summary Delete multiple language mappings at once
+// @ID delete-language-mappings-bulk
+// @Tags language-mappings
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to delete the mappings for (or 'current')"
+// @Param ids body models.BulkDelete true "IDs of the language mappings to delete"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/language-mappings/bulk [delete]
+func (a *APIv1) DeleteLanguageMappingsBulk(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params = &models.BulkDelete{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || len(params.IDs) == 0 {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a non-empty list of ids is required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	var deleted int
+	for _, id := range params.IDs {
+		// ids not belonging to the user are silently skipped
+		mapping, err := a.services.LanguageMapping().GetById(id)
+		if err != nil || mapping.UserID != user.ID {
+			continue
+		}
+		if err := a.services.LanguageMapping().Delete(mapping); err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": "Language Mappings Cannot Be Deleted",
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+		deleted++
+	}
+
+	response := map[string]interface{}{
+		"message": fmt.Sprintf("%d language mappings deleted successfully", deleted),
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+func prepareLanguageMapping(mapping *models.LanguageMapping, user *models.User) bool {
+	mapping.ID = 0
+	mapping.UserID = user.ID
+	return mapping.IsValid()
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+// @Summary Retrieve the user's machines
+// @Description Machines are registered upon their first heartbeat. Each includes when it was last seen and the plugins seen on it, keys themselves are never included.
+// @ID get-machines
+// @Tags machines
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Machine
+// @Router /v1/users/{user}/machines [get]
+func (a *APIv1) FetchUserMachines(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	machines, err := a.services.Machine().FetchUserMachines(user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching machines",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": machines,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Issue a key for a machine
+// @Description Registers the machine unless already known and issues a dedicated key for it. Heartbeats sent with the key are attributed to the machine, regardless of the machine name they specify. The key only allows to send heartbeats and read stats.
+// @Description The key is only included in this response, it is stored hashed. Configure it as the api key of the plugins on the machine.
+// @ID create-machine
+// @Tags machines
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to create the machine for (or 'current')"
+// @Param machine body models.NewMachine true "Machine to issue a key for"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.Machine
+// @Router /v1/users/{user}/machines [post]
+func (a *APIv1) CreateMachine(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params = &models.NewMachine{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || !params.IsValid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a machine name is required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	machine, err := a.services.Machine().Create(user, params)
+	if errors.Is(err, services.ErrMachineHasKey) {
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": "Machine already has a key, rotate it instead",
+			"status":  http.StatusConflict,
+		})
+		return
+	}
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": machine,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+// @Summary Remove a machine
+// @Description Revokes the machine's key. The machine is registered again upon its next heartbeat sent with a different key.
+// @ID delete-machine
+// @Tags machines
+// @Produce json
+// @Param user path string true "User ID to delete the machine for (or 'current')"
+// @Param id path string true "Machine ID"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/machines/{id} [delete]
+func (a *APIv1) DeleteMachine(w http.ResponseWriter, r *http.Request) {
+	machine, ok := a.loadUserMachine(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.services.Machine().Delete(machine); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Machine Cannot Be Deleted",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"message": "Machine deleted successfully",
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+// @Summary Issue a new key for a machine
+// @Description The machine's previous key, if any, stops working immediately. The key is only included in this response.
+// @ID rotate-machine-key
+// @Tags machines
+// @Produce json
+// @Param user path string true "User ID the machine belongs to (or 'current')"
+// @Param id path string true "Machine ID"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.Machine
+// @Router /v1/users/{user}/machines/{id}/key [post]
+func (a *APIv1) RotateMachineKey(w http.ResponseWriter, r *http.Request) {
+	machine, ok := a.loadUserMachine(w, r)
+	if !ok {
+		return
+	}
+
+	machine, err := a.services.Machine().RotateKey(machine)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": machine,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+// @Summary Revoke a machine's key
+// @Description The machine remains registered, its plugins have to use a different key from now on
+// @ID revoke-machine-key
+// @Tags machines
+// @Produce json
+// @Param user path string true "User ID the machine belongs to (or 'current')"
+// @Param id path string true "Machine ID"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/machines/{id}/key [delete]
+func (a *APIv1) RevokeMachineKey(w http.ResponseWriter, r *http.Request) {
+	machine, ok := a.loadUserMachine(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.services.Machine().RevokeKey(machine); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Machine Key Cannot Be Revoked",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"message": "Machine key revoked successfully",
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+// loadUserMachine resolves the machine addressed by the request, responding with an error unless it belongs to the effective user
+func (a *APIv1) loadUserMachine(w http.ResponseWriter, r *http.Request) (*models.Machine, bool) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return nil, false // response was already sent by util function
+	}
+
+	machine, err := a.services.Machine().GetById(chi.URLParam(r, "id"))
+	if err != nil || machine.UserID != user.ID {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Machine Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return nil, false
+	}
+	return machine, true
+}
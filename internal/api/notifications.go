@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
+	"github.com/muety/wakapi/models"
+)
+
+// @Summary Retrieve the user's notification preferences
+// @Description Contains one entry per kind of notification, i.e. weekly_report, daily_digest, monthly_report, yearly_report, goal_alerts, wakatime_failure and import_finished. Delivery times of reports and digests are given in the user's timezone.
+// @ID get-notification-preferences
+// @Tags notifications
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.NotificationPreference
+// @Router /v1/users/{user}/notifications [get]
+func (a *APIv1) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	prefs, err := a.services.Notification().GetPreferences(user)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching notification preferences",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": prefs,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Update the user's notification preferences
+// @Description Only the kinds given are changed. Channel is one of none or email. Delivery times (hh:mm) apply to reports and digests, the weekday (0 for sunday to 6) to weekly reports and the day of month (1 to 28) to monthly and yearly reports, the latter being sent in january.
+// @ID update-notification-preferences
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to update the preferences for (or 'current')"
+// @Param preferences body []models.NotificationPreferenceUpdate true "Updated preferences"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.NotificationPreference
+// @Router /v1/users/{user}/notifications [put]
+func (a *APIv1) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params []*models.NotificationPreferenceUpdate
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	for _, p := range params {
+		if p == nil || !p.IsValid() {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": "Invalid Input: a known kind and channel are required, delivery times must be given as hh:mm",
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	prefs, err := a.services.Notification().UpdatePreferences(user, params)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	a.services.Users().FlushUserCache(user.ID)
+
+	response := map[string]interface{}{
+		"data": prefs,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+type OidcCallback struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// @Summary Start a single sign-on login
+// @Description Returns the url to send the user to at the configured OpenID Connect provider and sets a cookie binding the login to the browser. The provider redirects back to the frontend with a code and state, which are then posted to this endpoint from the same browser.
+// @ID begin-oidc-login
+// @Tags auth
+// @Produce json
+// @Success 200
+// @Router /v1/auth/oauth/oidc [get]
+func (a *APIv1) BeginOidcLogin(w http.ResponseWriter, r *http.Request) {
+	a.respondOidcAuthorizationUrl(w, r, "")
+}
+
+// @Summary Complete a single sign-on login
+// @Description Logs in the user linked to the identity returned by the OpenID Connect provider. Depending on the configuration, existing users are matched by their verified email and unknown ones signed up.
+// @ID complete-oidc-login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param callback body api.OidcCallback true "Code and state the provider redirected back with"
+// @Success 201
+// @Router /v1/auth/oauth/oidc [post]
+func (a *APIv1) CompleteOidcLogin(w http.ResponseWriter, r *http.Request) {
+	var params = &OidcCallback{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || params.Code == "" || params.State == "" {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a code and state are required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if !a.checkOidcState(w, r, params.State) {
+		return
+	}
+
+	user, userOauth, isNewUser, err := a.services.Oidc().CompleteLogin(params.Code, params.State)
+	if err != nil {
+		a.respondOidcError(w, r, err)
+		return
+	}
+
+	if a.respondTwoFactorChallenge(w, r, user) {
+		return
+	}
+
+	user.LastLoggedInAt = models.CustomTime(time.Now())
+	a.services.Users().Update(user)
+
+	message := "Login Successful"
+	if isNewUser {
+		message = "Signup Successful"
+	}
+	session, err := a.startSession(r, user)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	response, err := helpers.MakeAuthSuccessResponse(
+		&helpers.AuthSuccessResponse{
+			Message:   message,
+			User:      user,
+			OauthUser: userOauth,
+			IsNewUser: isNewUser,
+			Session:   session,
+		},
+	)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+// @Summary Link a single sign-on identity
+// @Description Returns the url to send the user to at the configured OpenID Connect provider. Once the code and state the provider redirects back with are posted to /v1/users/{user}/oauth/oidc/callback, the identity is linked to the user.
+// @ID link-oidc-identity
+// @Tags auth
+// @Produce json
+// @Param user path string true "User ID to link the identity to (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200
+// @Router /v1/users/{user}/oauth/oidc [post]
+func (a *APIv1) LinkOidcIdentity(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+	a.respondOidcAuthorizationUrl(w, r, user.ID)
+}
+
+// @Summary Complete linking a single sign-on identity
+// @Description Links the identity returned by the OpenID Connect provider to the user, who must be the one that started linking it. Unlike logging in, this doesn't start a new session.
+// @ID complete-oidc-link
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to link the identity to (or 'current')"
+// @Param callback body api.OidcCallback true "Code and state the provider redirected back with"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.UserOauth
+// @Router /v1/users/{user}/oauth/oidc/callback [post]
+func (a *APIv1) CompleteOidcLink(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params = &OidcCallback{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || params.Code == "" || params.State == "" {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a code and state are required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if !a.checkOidcState(w, r, params.State) {
+		return
+	}
+
+	userOauth, err := a.services.Oidc().CompleteLink(params.Code, params.State, user)
+	if err != nil {
+		a.respondOidcError(w, r, err)
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data": userOauth,
+	})
+}
+
+func (a *APIv1) respondOidcAuthorizationUrl(w http.ResponseWriter, r *http.Request, linkUserId string) {
+	authUrl, state, err := a.services.Oidc().BeginLogin(linkUserId)
+	if err != nil {
+		a.respondOidcError(w, r, err)
+		return
+	}
+
+	// the state is only accepted back from the browser that started the login, so nobody can be logged in to (or
+	// link) someone else's identity by being tricked into posting a foreign code and state
+	cookie := a.config.CreateCookie(models.OidcStateCookieKey, state)
+	cookie.MaxAge = int(services.OidcLoginTTL.Seconds())
+	http.SetCookie(w, cookie)
+
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"authorization_url": authUrl,
+			"provider":          a.config.Security.Oidc.Name,
+			"display_name":      a.config.Security.Oidc.DisplayName,
+		},
+	})
+}
+
+// checkOidcState verifies the state posted back matches the one the browser got when starting the login and clears it
+func (a *APIv1) checkOidcState(w http.ResponseWriter, r *http.Request, state string) bool {
+	cookie, err := r.Cookie(models.OidcStateCookieKey)
+	http.SetCookie(w, a.config.GetClearCookie(models.OidcStateCookieKey))
+
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		a.respondOidcError(w, r, services.ErrOidcInvalidState)
+		return false
+	}
+	return true
+}
+
+func (a *APIv1) respondOidcError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrOidcDisabled):
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Single Sign-On Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+	case errors.Is(err, services.ErrOidcInvalidState):
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: the login request is invalid or has expired, try again",
+			"status":  http.StatusBadRequest,
+		})
+	case errors.Is(err, services.ErrOidcRejected):
+		helpers.RespondJSON(w, r, http.StatusUnauthorized, map[string]interface{}{
+			"message":       "Invalid credentials",
+			"status":        http.StatusUnauthorized,
+			"error_message": err.Error(),
+		})
+	case errors.Is(err, services.ErrOidcNoAccount):
+		helpers.RespondJSON(w, r, http.StatusForbidden, map[string]interface{}{
+			"message": "No account is linked to this identity. Log in and link it from your account settings first.",
+			"status":  http.StatusForbidden,
+		})
+	case errors.Is(err, services.ErrOidcAlreadyLinked):
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": "This identity is already linked to another account",
+			"status":  http.StatusConflict,
+		})
+	default:
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,544 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+func (a *APIv1) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+
+	var params = &models.NewOrganization{}
+
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(params)
+
+	organization := &models.Organization{
+		Name:        params.Name,
+		Slug:        params.Slug,
+		Description: params.Description,
+	}
+
+	if err != nil || !organization.IsValid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: Name and a valid slug (lowercase letters, digits and dashes) are required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if _, err := a.services.Organization().GetBySlug(organization.Slug); err == nil {
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": "Slug is already taken",
+			"status":  http.StatusConflict,
+		})
+		return
+	}
+
+	_, err = a.services.Organization().Create(organization, user)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": organization,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+func (a *APIv1) FetchUserOrganizations(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+
+	organizations, err := a.services.Organization().FetchUserOrganizations(user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching organizations",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": organizations,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+func (a *APIv1) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	organization, member, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"organization": organization,
+			"membership":   member,
+		},
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+func (a *APIv1) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	organization, member, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+	if !member.CanManage() {
+		respondOrganizationForbidden(w, r)
+		return
+	}
+
+	var params = &models.OrganizationUpdate{}
+
+	jsonDecoder := json.NewDecoder(r.Body)
+	if err := jsonDecoder.Decode(params); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	updated, err := a.services.Organization().Update(organization, params)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "Error updating organization",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": updated,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+func (a *APIv1) DeleteOrganization(w http.ResponseWriter, r *http.Request) {
+	organization, member, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+	if !member.IsOwner() {
+		respondOrganizationForbidden(w, r)
+		return
+	}
+
+	if err := a.services.Organization().DeleteOrganization(organization.ID); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Organization Cannot Be Deleted",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"message": "Organization deleted successfully",
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+func (a *APIv1) FetchOrganizationMembers(w http.ResponseWriter, r *http.Request) {
+	organization, _, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := a.services.Organization().FetchMembers(organization.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching members",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": members,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+func (a *APIv1) UpdateOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	organization, member, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+
+	var params = &models.OrganizationMemberUpdate{}
+
+	jsonDecoder := json.NewDecoder(r.Body)
+	if err := jsonDecoder.Decode(params); err != nil || !models.ValidateOrganizationRole(params.Role) {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: role must be one of owner, admin or member",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	target, err := a.services.Organization().GetMember(organization.ID, chi.URLParam(r, "userId"))
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Member Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	// changing a member's role requires the permission to both revoke the current and grant the new one
+	if !member.CanAssignRole(params.Role) || !member.CanAssignRole(target.Role) {
+		respondOrganizationForbidden(w, r)
+		return
+	}
+
+	_, err = a.services.Organization().UpdateMemberRole(target, params.Role)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "Error updating member",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": target,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+func (a *APIv1) RemoveOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	organization, member, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+
+	target, err := a.services.Organization().GetMember(organization.ID, chi.URLParam(r, "userId"))
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Member Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	// members may always leave on their own
+	if target.UserID != member.UserID && !member.CanAssignRole(target.Role) {
+		respondOrganizationForbidden(w, r)
+		return
+	}
+
+	if err := a.services.Organization().RemoveMember(target); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "Member Cannot Be Removed",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"message": "Member removed successfully",
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+func (a *APIv1) FetchOrganizationInvitations(w http.ResponseWriter, r *http.Request) {
+	organization, member, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+	if !member.CanManage() {
+		respondOrganizationForbidden(w, r)
+		return
+	}
+
+	invitations, err := a.services.Organization().FetchPendingInvitations(organization.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching invitations",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": invitations,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+func (a *APIv1) CreateOrganizationInvitation(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+	organization, member, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+
+	var params = &models.NewOrganizationInvitation{}
+
+	jsonDecoder := json.NewDecoder(r.Body)
+	err := jsonDecoder.Decode(params)
+	if params.Role == "" {
+		params.Role = models.OrgRoleMember
+	}
+
+	if err != nil || !models.ValidateEmail(params.Email) || !models.ValidateOrganizationRole(params.Role) {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a valid email and role are required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if !member.CanAssignRole(params.Role) {
+		respondOrganizationForbidden(w, r)
+		return
+	}
+
+	invitation, err := a.services.Organization().CreateInvitation(organization, user, params.Email, params.Role)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	go a.sendOrganizationInvitationEmail(invitation, organization, user)
+
+	response := map[string]interface{}{
+		"data": invitation,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+func (a *APIv1) DeleteOrganizationInvitation(w http.ResponseWriter, r *http.Request) {
+	organization, member, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+	if !member.CanManage() {
+		respondOrganizationForbidden(w, r)
+		return
+	}
+
+	invitationID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Bad Request",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := a.services.Organization().DeleteInvitation(organization.ID, uint(invitationID)); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invitation Cannot Be Deleted",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"message": "Invitation deleted successfully",
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+func (a *APIv1) AcceptOrganizationInvitation(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+
+	invitation, err := a.services.Organization().GetInvitationByToken(chi.URLParam(r, "token"))
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Invitation Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	member, err := a.services.Organization().AcceptInvitation(invitation, user)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "Invitation Cannot Be Accepted",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"organization": invitation.Organization,
+			"membership":   member,
+		},
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+func (a *APIv1) FetchOrganizationProjects(w http.ResponseWriter, r *http.Request) {
+	organization, _, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+
+	projects, err := a.services.Organization().FetchProjects(organization.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching projects",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": projects,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+func (a *APIv1) AddOrganizationProject(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+	organization, member, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+	if !member.CanManage() {
+		respondOrganizationForbidden(w, r)
+		return
+	}
+
+	var params = &models.NewOrganizationProject{}
+
+	jsonDecoder := json.NewDecoder(r.Body)
+	if err := jsonDecoder.Decode(params); err != nil || params.ProjectName == "" {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: project_name is required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	project, err := a.services.Organization().AddProject(organization, params.ProjectName, user)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": project,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+func (a *APIv1) RemoveOrganizationProject(w http.ResponseWriter, r *http.Request) {
+	organization, member, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+	if !member.CanManage() {
+		respondOrganizationForbidden(w, r)
+		return
+	}
+
+	projectID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Bad Request",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := a.services.Organization().RemoveProject(organization.ID, uint(projectID)); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Project Cannot Be Removed",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"message": "Project removed successfully",
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+func (a *APIv1) GetOrganizationSummary(w http.ResponseWriter, r *http.Request) {
+	organization, member, ok := a.loadOrganizationMembership(w, r)
+	if !ok {
+		return
+	}
+
+	params, err := helpers.ParseSummaryParams(r)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "Invalid summary parameters",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	summary, err := a.services.Organization().GenerateTeamSummary(organization, params.From, params.To, a.services.Summary(), a.services.Users())
+	if errors.Is(err, services.ErrTeamSummaryInvalidRange) || errors.Is(err, services.ErrTeamSummaryRangeTooLarge) {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "Invalid summary parameters",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error generating team summary",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	if !member.CanManage() {
+		// regular members only get to see the team's combined time, not how much each of the others contributed
+		summary.Members = nil
+	}
+	response := map[string]interface{}{
+		"data": summary,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// loadOrganizationMembership resolves the organization from the url's slug and the current user's membership in it.
+// Non-members get a 404 rather than a 403 to not leak which organizations exist.
+func (a *APIv1) loadOrganizationMembership(w http.ResponseWriter, r *http.Request) (*models.Organization, *models.OrganizationMember, bool) {
+	user := helpers.ExtractUser(r)
+
+	organization, err := a.services.Organization().GetBySlug(chi.URLParam(r, "slug"))
+	if err == nil {
+		var member *models.OrganizationMember
+		if member, err = a.services.Organization().GetMember(organization.ID, user.ID); err == nil {
+			return organization, member, true
+		}
+	}
+
+	helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+		"message": "Organization Cannot Be Found",
+		"status":  http.StatusNotFound,
+	})
+	return nil, nil, false
+}
+
+func respondOrganizationForbidden(w http.ResponseWriter, r *http.Request) {
+	helpers.RespondJSON(w, r, http.StatusForbidden, map[string]interface{}{
+		"message": "You are not allowed to perform this action",
+		"status":  http.StatusForbidden,
+	})
+}
+
+func (a *APIv1) sendOrganizationInvitationEmail(invitation *models.OrganizationInvitation, organization *models.Organization, inviter *models.User) {
+	invitationLink := fmt.Sprintf("%s/orgs/invitations/%s", a.config.Server.GetFrontendUri(), invitation.Token)
+
+	if err := a.mailService.SendOrganizationInvitation(invitation, organization, inviter, invitationLink); err != nil {
+		conf.Log().Error("failed to send organization invitation mail",
+			"organizationID", organization.ID,
+			"error", err,
+		)
+	}
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
+	"github.com/muety/wakapi/models"
+)
+
+// @Summary Retrieve the user's project labels
+// @ID get-project-labels
+// @Tags project-labels
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.ProjectLabel
+// @Router /v1/users/{user}/project-labels [get]
+func (a *APIv1) FetchUserProjectLabels(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	labels, err := a.services.ProjectLabel().GetByUser(user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching project labels",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": labels,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Create a project label
+// @ID create-project-label
+// @Tags project-labels
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to create the label for (or 'current')"
+// @Param label body models.ProjectLabel true "Project label to create"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.ProjectLabel
+// @Router /v1/users/{user}/project-labels [post]
+func (a *APIv1) CreateProjectLabel(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params = &models.ProjectLabel{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || !prepareProjectLabel(params, user) {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: project and label are required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	label, err := a.services.ProjectLabel().Create(params)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": label,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+// @Summary Create multiple project labels at once
+// @ID create-project-labels-bulk
+// @Tags project-labels
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to create the labels for (or 'current')"
+// @Param labels body []models.ProjectLabel true "Project labels to create"
+// @Security ApiKeyAuth
+// @Success 201 {array} models.ProjectLabel
+// @Router /v1/users/{user}/project-labels/bulk [post]
+func (a *APIv1) CreateProjectLabelsBulk(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params []*models.ProjectLabel
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil || len(params) == 0 {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a non-empty list of project labels is required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	for i, label := range params {
+		if !prepareProjectLabel(label, user) {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": fmt.Sprintf("Invalid Input: project label at index %d is invalid", i),
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	created, err := a.services.ProjectLabel().CreateMulti(params)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": created,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+// @Summary Delete a project label
+// @ID delete-project-label
+// @Tags project-labels
+// @Produce json
+// @Param user path string true "User ID to delete the label for (or 'current')"
+// @Param id path int true "Project label ID"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/project-labels/{id} [delete]
+func (a *APIv1) DeleteProjectLabel(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	labelID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Bad Request",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	label, err := a.services.ProjectLabel().GetById(uint(labelID))
+	if err != nil || label.UserID != user.ID {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Project Label Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	if err := a.services.ProjectLabel().Delete(label); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Project Label Cannot Be Deleted",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"message": "Project label deleted successfully",
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+// @Summary Delete multiple project labels at once
+// @ID delete-project-labels-bulk
+// @Tags project-labels
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to delete the labels for (or 'current')"
+// @Param ids body models.BulkDelete true "IDs of the project labels to delete"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/project-labels/bulk [delete]
+func (a *APIv1) DeleteProjectLabelsBulk(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params = &models.BulkDelete{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || len(params.IDs) == 0 {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a non-empty list of ids is required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	var deleted int
+	for _, id := range params.IDs {
+		// ids not belonging to the user are silently skipped
+		label, err := a.services.ProjectLabel().GetById(id)
+		if err != nil || label.UserID != user.ID {
+			continue
+		}
+		if err := a.services.ProjectLabel().Delete(label); err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": "Project Labels Cannot Be Deleted",
+				"status":  http.StatusBadRequest,
+			})
+			return
+		}
+		deleted++
+	}
+
+	response := map[string]interface{}{
+		"message": fmt.Sprintf("%d project labels deleted successfully", deleted),
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+func prepareProjectLabel(label *models.ProjectLabel, user *models.User) bool {
+	label.ID = 0
+	label.UserID = user.ID
+	return label.IsValid()
+}
@@ -1,10 +1,11 @@
 package api
 
 import (
+	"encoding/json"
+	"math"
 	"net/http"
 	"net/url"
-	"strings"
-	"time"
+	"strconv"
 
 	"github.com/muety/wakapi/internal/utilities"
 	"github.com/muety/wakapi/models"
@@ -15,6 +16,7 @@ import (
 	conf "github.com/muety/wakapi/config"
 	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
 	"github.com/muety/wakapi/utils"
+	"gorm.io/gorm"
 )
 
 // @Summary Retrieve and filter the user's projects
@@ -24,6 +26,11 @@ import (
 // @Produce json
 // @Param user path string true "User ID to fetch data for (or 'current')"
 // @Param q query string false "Query to filter projects by"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Number of projects per page"
+// @Param sort_by query string false "One of last_heartbeat_at (default), first_heartbeat_at or name"
+// @Param sort_order query string false "Either asc or desc (default)"
+// @Param archived query bool false "Whether to include archived projects"
 // @Security ApiKeyAuth
 // @Success 200 {object} v1.ProjectsViewModel
 // @Router /compat/wakatime/v1/users/{user}/projects [get]
@@ -33,7 +40,15 @@ func (a *APIv1) GetProjects(w http.ResponseWriter, r *http.Request) {
 		return // response was already sent by util function
 	}
 
-	projects, err := a.loadProjects(user, r.URL.Query().Get("q"), false)
+	pageParams := utils.ParsePageParamsWithDefault(r, 1, 100)
+	query := &models.ProjectQuery{
+		Prefix:          r.URL.Query().Get("q"),
+		SortBy:          r.URL.Query().Get("sort_by"),
+		SortDesc:        r.URL.Query().Get("sort_order") != "asc",
+		IncludeArchived: r.URL.Query().Get("archived") == "true",
+	}
+
+	projects, total, err := a.services.Project().GetByUser(user.ID, query, pageParams)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("something went wrong"))
@@ -41,7 +56,17 @@ func (a *APIv1) GetProjects(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vm := &v1.ProjectsViewModel{Data: projects}
+	data := make([]*v1.Project, len(projects))
+	for i, p := range projects {
+		data[i] = projectToCompat(p)
+	}
+
+	vm := &v1.ProjectsViewModel{
+		Data:       data,
+		Total:      total,
+		Page:       pageParams.Page,
+		TotalPages: int(math.Ceil(float64(total) / float64(pageParams.PageSize))),
+	}
 	helpers.RespondJSON(w, r, http.StatusOK, vm)
 }
 
@@ -51,7 +76,7 @@ func (a *APIv1) GetProjects(w http.ResponseWriter, r *http.Request) {
 // @Tags wakatime
 // @Produce json
 // @Param user path string true "User ID to fetch data for (or 'current')"
-// @Param id path string true "Project ID to fetch"
+// @Param id path string true "Project ID or name to fetch"
 // @Security ApiKeyAuth
 // @Success 200 {object} v1.ProjectViewModel
 // @Router /compat/wakatime/v1/users/{user}/projects/{id} [get]
@@ -61,43 +86,98 @@ func (a *APIv1) GetProject(w http.ResponseWriter, r *http.Request) {
 		return // response was already sent by util function
 	}
 
-	projects, err := a.loadProjects(user, chi.URLParam(r, "id"), true)
+	project, err := a.loadProject(user, chi.URLParam(r, "id"))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(conf.ErrInternalServerError))
-		conf.Log().Request(r).Error("error occurred", "error", err)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
 		return
 	}
 
-	if len(projects) != 1 {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte(conf.ErrNotFound))
+	vm := &v1.ProjectViewModel{Data: projectToCompat(project)}
+	helpers.RespondJSON(w, r, http.StatusOK, vm)
+}
+
+// @Summary Update a project's metadata
+// @ID update-project
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to update data for (or 'current')"
+// @Param id path string true "Project ID or name to update"
+// @Param project body models.ProjectUpdate true "Fields to update"
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.ProjectViewModel
+// @Router /v1/users/{user}/projects/{id} [put]
+func (a *APIv1) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	project, err := a.loadProject(user, chi.URLParam(r, "id"))
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Project Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	var params = &models.ProjectUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || !params.IsValid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: color must be a hex code like #1a2b3c",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	if _, err := a.services.Project().Update(project, params); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "Error updating project",
+			"error_message": err.Error(),
+		})
 		return
 	}
 
-	vm := &v1.ProjectViewModel{Data: projects[0]}
+	vm := &v1.ProjectViewModel{Data: projectToCompat(project)}
 	helpers.RespondJSON(w, r, http.StatusOK, vm)
 }
 
-func (a *APIv1) loadProjects(user *models.User, q string, exact bool) ([]*v1.Project, error) {
-	results, err := a.services.Heartbeat().GetUserProjectStats(user, time.Time{}, utils.BeginOfToday(time.Local), nil, false)
+// loadProject looks up a project by its name, falling back to its numeric id
+func (a *APIv1) loadProject(user *models.User, id string) (*models.Project, error) {
+	project, err := a.services.Project().GetByUserAndName(user.ID, id)
+	if err == nil {
+		return project, nil
+	}
+
+	numericId, parseErr := strconv.ParseUint(id, 10, 32)
+	if parseErr != nil {
+		return nil, err
+	}
+	project, err = a.services.Project().GetById(uint(numericId))
 	if err != nil {
 		return nil, err
 	}
-
-	projects := make([]*v1.Project, 0, len(results))
-	for _, p := range results {
-		if (exact && p.Project == q) || (!exact && strings.HasPrefix(p.Project, q)) {
-			projects = append(projects, &v1.Project{
-				ID:                           p.Project,
-				Name:                         p.Project,
-				LastHeartbeatAt:              p.Last.T(),
-				HumanReadableLastHeartbeatAt: helpers.FormatDateTimeHuman(p.Last.T()),
-				UrlencodedName:               url.QueryEscape(p.Project),
-				CreatedAt:                    p.First.T(),
-			})
-		}
+	if project.UserID != user.ID {
+		return nil, gorm.ErrRecordNotFound
 	}
+	return project, nil
+}
 
-	return projects, nil
+func projectToCompat(p *models.Project) *v1.Project {
+	return &v1.Project{
+		ID:                           p.Name,
+		Name:                         p.Name,
+		Color:                        p.Color,
+		Description:                  p.Description,
+		RepositoryUrl:                p.RepositoryUrl,
+		IsArchived:                   p.IsArchived,
+		TopLanguage:                  p.TopLanguage,
+		FirstHeartbeatAt:             p.FirstHeartbeatAt.T(),
+		LastHeartbeatAt:              p.LastHeartbeatAt.T(),
+		HumanReadableLastHeartbeatAt: helpers.FormatDateTimeHuman(p.LastHeartbeatAt.T()),
+		UrlencodedName:               url.QueryEscape(p.Name),
+		CreatedAt:                    p.FirstHeartbeatAt.T(), // rows of projects that existed before were only created when populating the table
+	}
 }
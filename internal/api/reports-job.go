@@ -2,18 +2,16 @@ package api
 
 import (
 	"context"
+	"time"
 
 	"github.com/riverqueue/river"
 )
 
-type WeeklyReportArgs struct{}
+type ScheduledReportArgs struct{}
 
-func (WeeklyReportArgs) Kind() string { return "weekly_reports" }
+func (ScheduledReportArgs) Kind() string { return "scheduled_reports" }
 
-func (a *APIv1) weeklyReportWorker(_ context.Context, _ *river.Job[WeeklyReportArgs]) error {
-	err := a.services.Report().SendWeeklyReports()
-	if err != nil {
-		return err
-	}
-	return nil
+// scheduledReportWorker runs every few minutes, as every user picks their own delivery time in their own timezone
+func (a *APIv1) scheduledReportWorker(_ context.Context, _ *river.Job[ScheduledReportArgs]) error {
+	return a.services.Report().SendScheduledReports(time.Now())
 }
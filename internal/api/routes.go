@@ -9,6 +9,7 @@ import (
 	"github.com/muety/wakapi/helpers"
 	"github.com/muety/wakapi/middlewares"
 	customMiddleware "github.com/muety/wakapi/middlewares/custom"
+	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/services"
 	"github.com/rs/cors"
 )
@@ -27,16 +28,40 @@ func (api *APIv1) RegisterApiV1Routes(r *chi.Mux) {
 	r.Get("/captcha/{id}.png", captcha.Server(captcha.StdWidth, captcha.StdHeight).ServeHTTP)
 
 	r.Group(func(r chi.Router) {
-		r.Use(middlewares.NewAuthenticateMiddleware(api.services.Users()).WithOptionalFor("/api/badge/").Handler)
+		r.Use(
+			middlewares.NewAuthenticateMiddleware(api.services.Users()).WithOptionalFor("/api/badge/").Handler,
+			middlewares.NewScopeMiddleware(models.ApiTokenResourceStats),
+		)
 		r.Get("/api/badge/{user}/*", api.GetBadge)
 	})
 
 	r.Route("/api/chart", func(r chi.Router) {
 		r.Use(
 			middlewares.NewAuthenticateMiddleware(api.services.Users()).WithOptionalFor("/api/activity/chart/").Handler,
+			middlewares.NewScopeMiddleware(models.ApiTokenResourceStats),
 			mw.Compress(9, "image/svg+xml"),
 		)
 		r.Get("/{userWithExt}", api.GetActivityChart)
+		r.Get("/heatmap/{userWithExt}", api.GetActivityHeatmapChart)
+	})
+
+	r.Route("/api/wrapped", func(r chi.Router) {
+		r.Use(
+			middlewares.NewAuthenticateMiddleware(api.services.Users()).WithOptionalFor("/api/wrapped/").Handler,
+			middlewares.NewScopeMiddleware(models.ApiTokenResourceStats),
+			mw.Compress(9, "image/svg+xml"),
+		)
+		r.Get("/{userWithExt}", api.GetWrappedCard)
+	})
+
+	r.Route("/api/card", func(r chi.Router) {
+		r.Use(
+			middlewares.NewAuthenticateMiddleware(api.services.Users()).WithOptionalFor("/api/card/").Handler,
+			middlewares.NewScopeMiddleware(models.ApiTokenResourceStats),
+			mw.Compress(9, "image/svg+xml"),
+		)
+		r.Get("/goal/{user}/{goalWithExt}", api.GetGoalCard)
+		r.Get("/{card}/{userWithExt}", api.GetStatsCard)
 	})
 
 	// Avatar routes
@@ -49,6 +74,7 @@ func (api *APIv1) RegisterApiV1Routes(r *chi.Mux) {
 	r.Group(func(r chi.Router) {
 		r.Use(
 			middlewares.NewAuthenticateMiddleware(api.services.Users()).WithOptionalForMethods(http.MethodOptions).Handler,
+			middlewares.NewScopeMiddleware(models.ApiTokenResourceHeartbeats),
 			customMiddleware.NewWakatimeRelayMiddleware().Handler,
 		)
 
@@ -76,17 +102,25 @@ func (api *APIv1) RegisterApiV1Routes(r *chi.Mux) {
 
 	r.Route("/api/compat/wakatime/v1/users/{user}", func(r chi.Router) {
 		r.Use(middlewares.NewAuthenticateMiddleware(api.services.Users()).Handler)
-		r.Get("/all_time_since_today", api.GetAllTime)
-		r.Get("/heartbeats", api.GetHeartBeats)
-		r.Get("/", api.GetUser)
-		r.Get("/stats", api.GetUserStats)
-		r.Get("/stats/{range}", api.GetUserStats)
-		r.Get("/statusbar/{range}", api.GetStatusBarRange)
+		r.With(middlewares.NewScopeMiddleware(models.ApiTokenResourceHeartbeats)).Get("/heartbeats", api.GetHeartBeats)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceStats))
+			r.Get("/all_time_since_today", api.GetAllTime)
+			r.Get("/", api.GetUser)
+			r.Get("/stats", api.GetUserStats)
+			r.Get("/stats/{range}", api.GetUserStats)
+			r.Get("/statusbar/{range}", api.GetStatusBarRange)
+			r.Get("/machine_names", api.GetMachineNames)
+		})
 	})
 	r.Get("/api/v1/leaders", api.GetLeaderboard)
 
 	r.Route("/api", func(r chi.Router) {
-		r.Use(middlewares.NewAuthenticateMiddleware(api.services.Users()).Handler)
+		r.Use(
+			middlewares.NewAuthenticateMiddleware(api.services.Users()).Handler,
+			middlewares.NewScopeMiddleware(models.ApiTokenResourceStats),
+		)
 		r.Get("/users/{user}/statusbar/today", api.GetStatusBarRange)
 	})
 
@@ -97,74 +131,260 @@ func (api *APIv1) RegisterApiV1Routes(r *chi.Mux) {
 			r.Post("/login", api.Signin)
 			r.Post("/signup", api.Signup)
 			r.Post("/oauth/github", api.GithubOauth)
+			r.Get("/oauth/oidc", api.BeginOidcLogin)
+			r.Post("/oauth/oidc", api.CompleteOidcLogin)
 			r.Get("/validate", api.ValidateAuthToken)
 			r.Post("/forgot-password", api.ForgotPassword)
 
 			r.Post("/otp/create", services.CreateOTPHandler(api.services.Otp()))
-			r.Post("/otp/verify", services.VerifyOTPHandler(api.services.Otp()))
+			r.Post("/otp/verify", services.VerifyOTPHandler(api.services.Otp(), api.services.TwoFactor(), api.services.Sessions()))
+
+			r.Post("/2fa/verify", api.VerifyTwoFactorLogin)
+			r.Post("/2fa/enroll", api.EnrollTwoFactorLogin)
+			r.Post("/2fa/enroll/confirm", api.ConfirmTwoFactorLogin)
 
 			r.Group(func(r chi.Router) {
-				r.Use(middlewares.NewAuthenticateMiddleware(api.services.Users()).Handler)
+				r.Use(
+					middlewares.NewAuthenticateMiddleware(api.services.Users()).Handler,
+					middlewares.NewScopeMiddleware(models.ApiTokenScopeAdmin),
+				)
 				r.Get("/api-key", api.GetApiKey)
 				r.Post("/api-key/refresh", api.RefreshApiKey)
+				r.Post("/password", api.ChangePassword)
 			})
 		})
 
+		r.Route("/sessions", func(r chi.Router) {
+			r.Use(
+				middlewares.NewAuthenticateMiddleware(api.services.Users()).Handler,
+				middlewares.NewScopeMiddleware(models.ApiTokenScopeAdmin),
+			)
+			r.Get("/", api.FetchSessions)
+			r.Delete("/", api.RevokeSessions)
+			r.Delete("/{id}", api.RevokeSession)
+		})
+
 		// Authenticated profile endpoints
 		r.Group(func(r chi.Router) {
 			r.Use(middlewares.NewAuthenticateMiddleware(api.services.Users()).Handler)
-			r.Post("/settings", api.UpdateWakatimeSettings)
-			r.Get("/profile", api.GetProfile)
-			r.Put("/profile", api.SaveProfile)
-			r.Get("/summary", api.GetSummary)
-
-			if api.config.Security.ExposeMetrics {
-				r.Get("/metrics", api.GetMetrics)
-			}
+
+			r.Group(func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceSettings))
+				r.Post("/settings", api.UpdateWakatimeSettings)
+				r.Get("/profile", api.GetProfile)
+				r.Put("/profile", api.SaveProfile)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceStats))
+				r.Get("/summary", api.GetSummary)
+
+				if api.config.Security.ExposeMetrics {
+					r.Get("/metrics", api.GetMetrics)
+				}
+			})
 		})
+		// signed links, authorized through their signature instead of the user's credentials
+		r.Get("/exports/{id}/download", api.DownloadDataExport)
+
+		r.Route("/orgs", func(r chi.Router) {
+			r.Use(
+				middlewares.NewAuthenticateMiddleware(api.services.Users()).Handler,
+				middlewares.NewScopeMiddleware(models.ApiTokenResourceOrgs),
+			)
+
+			r.Post("/", api.CreateOrganization)
+			r.Get("/", api.FetchUserOrganizations)
+			r.Post("/invitations/{token}/accept", api.AcceptOrganizationInvitation)
+
+			r.Route("/{slug}", func(r chi.Router) {
+				r.Get("/", api.GetOrganization)
+				r.Put("/", api.UpdateOrganization)
+				r.Delete("/", api.DeleteOrganization)
+				r.Get("/summary", api.GetOrganizationSummary)
+
+				r.Get("/members", api.FetchOrganizationMembers)
+				r.Put("/members/{userId}", api.UpdateOrganizationMember)
+				r.Delete("/members/{userId}", api.RemoveOrganizationMember)
+
+				r.Get("/invitations", api.FetchOrganizationInvitations)
+				r.Post("/invitations", api.CreateOrganizationInvitation)
+				r.Delete("/invitations/{id}", api.DeleteOrganizationInvitation)
+
+				r.Get("/projects", api.FetchOrganizationProjects)
+				r.Post("/projects", api.AddOrganizationProject)
+				r.Delete("/projects/{id}", api.RemoveOrganizationProject)
+			})
+		})
+
 		r.Route("/users/{user}", func(r chi.Router) {
 			r.Use(middlewares.NewAuthenticateMiddleware(api.services.Users()).Handler)
 
-			r.Get("/user-agents", api.FetchUserAgents)
-			r.Get("/summaries", api.GetSummaries)
-			r.Get("/projects", api.GetProjects)
-			r.Get("/projects/{id}", api.GetProject)
-			r.Get("/durations", api.GetDurations)
-			r.Get("/report", api.SendReport)
+			r.Group(func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceStats))
+				r.Get("/summaries", api.GetSummaries)
+				r.Get("/durations", api.GetDurations)
+				r.Get("/activity/heatmap", api.GetActivityHeatmap)
+				r.Get("/report", api.SendReport)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceProjects))
+				r.Get("/projects", api.GetProjects)
+				r.Get("/projects/{id}", api.GetProject)
+				r.Put("/projects/{id}", api.UpdateProject)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceSettings))
+				r.Get("/user-agents", api.FetchUserAgents)
+				r.Get("/notifications", api.GetNotificationPreferences)
+				r.Put("/notifications", api.UpdateNotificationPreferences)
+			})
+
+			r.With(middlewares.NewScopeMiddleware(models.ApiTokenResourceData)).Post("/regenerate-summaries", api.RegenerateSummaries)
+
+			r.Route("/tokens", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenScopeAdmin))
+				r.Get("/", api.FetchUserApiTokens)
+				r.Post("/", api.CreateApiToken)
+				r.Delete("/{id}", api.DeleteApiToken)
+			})
+
+			r.Route("/2fa", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenScopeAdmin))
+				r.Get("/", api.GetTwoFactor)
+				r.Post("/", api.EnrollTwoFactor)
+				r.Delete("/", api.DisableTwoFactor)
+				r.Post("/confirm", api.ConfirmTwoFactor)
+				r.Post("/recovery-codes", api.RegenerateRecoveryCodes)
+			})
+
+			r.Route("/oauth/oidc", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenScopeAdmin))
+				r.Post("/", api.LinkOidcIdentity)
+				r.Post("/callback", api.CompleteOidcLink)
+			})
 
-			r.Post("/regenerate-summaries", api.RegenerateSummaries)
+			r.Route("/machines", func(r chi.Router) {
+				// keys grant access on their own, so managing them requires full access just like personal access tokens
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenScopeAdmin))
+				r.Get("/", api.FetchUserMachines)
+				r.Post("/", api.CreateMachine)
+				r.Delete("/{id}", api.DeleteMachine)
+				r.Post("/{id}/key", api.RotateMachineKey)
+				r.Delete("/{id}/key", api.RevokeMachineKey)
+			})
 
 			r.Route("/clients", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceClients))
 				r.Post("/", api.CreateClient)
 				r.Get("/", api.FetchUserClients)
 				r.Get("/{id}", api.GetClient)
 				r.Put("/{id}", api.UpdateClient)
 				r.Delete("/{id}", api.DeleteClient)
 				r.Get("/{id}/invoice/items", api.FetchInvoiceLineItemsForClient)
+				r.Get("/{id}/rates", api.FetchClientRates)
+				r.Post("/{id}/rates", api.CreateClientRate)
+				r.Put("/{id}/rates/{rateId}", api.UpdateClientRate)
+				r.Delete("/{id}/rates/{rateId}", api.DeleteClientRate)
+				r.Get("/{id}/schedule", api.GetInvoiceSchedule)
+				r.Put("/{id}/schedule", api.UpdateInvoiceSchedule)
+				r.Delete("/{id}/schedule", api.DeleteInvoiceSchedule)
 			})
 
 			r.Route("/goals", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceGoals))
 				r.Post("/", api.CreateGoal)
 				r.Get("/", api.FetchUserGoals)
 				r.Get("/{id}", api.GetGoal)
 				r.Put("/{id}", api.UpdateGoal)
 				r.Delete("/{id}", api.DeleteGoal)
+				r.Get("/{id}/history", api.GetGoalHistory)
 			})
 
 			r.Route("/invoices", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceInvoices))
 				r.Post("/", api.CreateInvoice)
 				r.Get("/", api.FetchUserInvoices)
+				r.Get("/settings", api.GetInvoiceSettings)
+				r.Put("/settings", api.UpdateInvoiceSettings)
+				r.Get("/revenue", api.GetInvoiceRevenue)
+				r.Get("/exchange-rates", api.FetchExchangeRates)
+				r.Post("/exchange-rates", api.CreateExchangeRate)
+				r.Delete("/exchange-rates/{rateId}", api.DeleteExchangeRate)
+				r.Get("/{id}.pdf", api.GetInvoicePdf)
 				r.Get("/{id}", api.GetInvoice)
 				r.Put("/{id}", api.UpdateInvoice)
 				r.Delete("/{id}", api.DeleteInvoice)
+				r.Put("/{id}/status", api.UpdateInvoiceStatus)
+				r.Post("/{id}/send", api.SendInvoice)
+				r.Post("/{id}/payments", api.CreateInvoicePayment)
+				r.Delete("/{id}/payments/{paymentId}", api.DeleteInvoicePayment)
+			})
+
+			r.Route("/aliases", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceSettings))
+				r.Get("/", api.FetchUserAliases)
+				r.Post("/", api.CreateAlias)
+				r.Post("/bulk", api.CreateAliasesBulk)
+				r.Delete("/bulk", api.DeleteAliasesBulk)
+				r.Delete("/{id}", api.DeleteAlias)
+			})
+
+			r.Route("/project-labels", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceSettings))
+				r.Get("/", api.FetchUserProjectLabels)
+				r.Post("/", api.CreateProjectLabel)
+				r.Post("/bulk", api.CreateProjectLabelsBulk)
+				r.Delete("/bulk", api.DeleteProjectLabelsBulk)
+				r.Delete("/{id}", api.DeleteProjectLabel)
+			})
+
+			r.Route("/language-mappings", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceSettings))
+				r.Get("/", api.FetchUserLanguageMappings)
+				r.Post("/", api.CreateLanguageMapping)
+				r.Post("/bulk", api.CreateLanguageMappingsBulk)
+				r.Delete("/bulk", api.DeleteLanguageMappingsBulk)
+				r.Delete("/{id}", api.DeleteLanguageMapping)
+			})
+
+			r.Route("/imports", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceData))
+				r.Get("/", api.FetchUserImportJobs)
+				r.Post("/", api.StartWakatimeImport)
+				r.Post("/file", api.StartFileImport)
+				r.Get("/{id}", api.GetImportJob)
+			})
+
+			r.Route("/exports", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceData))
+				r.Get("/", api.FetchUserDataExports)
+				r.Post("/", api.CreateDataExport)
+				r.Get("/{id}", api.GetDataExport)
+			})
+
+			r.Route("/webhooks", func(r chi.Router) {
+				// webhooks send heartbeats and stats to any url, so managing them requires full access as well
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenScopeAdmin))
+				r.Get("/", api.FetchUserWebhooks)
+				r.Post("/", api.CreateWebhook)
+				r.Get("/{id}", api.GetWebhook)
+				r.Put("/{id}", api.UpdateWebhook)
+				r.Delete("/{id}", api.DeleteWebhook)
+				r.Get("/{id}/deliveries", api.FetchWebhookDeliveries)
+				r.Post("/{id}/deliveries/{deliveryId}/redeliver", api.RedeliverWebhookDelivery)
 			})
 
 			r.Route("/stats", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceStats))
 				r.Get("/", api.GetUserStats)
 				r.Get("/{range}", api.GetUserStats)
 			})
 
 			r.Route("/statusbar", func(r chi.Router) {
+				r.Use(middlewares.NewScopeMiddleware(models.ApiTokenResourceStats))
 				r.Get("/", api.GetStatusBarRange)
 				r.Get("/{range}", api.GetStatusBarRange)
 			})
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+// @Summary List active sessions
+// @Description Lists the logins of the authenticated user, which haven't expired or been revoked. The session the request was made with is marked as current.
+// @ID get-sessions
+// @Tags auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Session
+// @Router /v1/sessions [get]
+func (a *APIv1) FetchSessions(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+	if user == nil {
+		helpers.RespondJSON(w, r, http.StatusUnauthorized, map[string]interface{}{
+			"message": "Unauthorized",
+			"status":  http.StatusUnauthorized,
+		})
+		return
+	}
+
+	sessions, err := a.services.Sessions().FetchUserSessions(user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	currentSessionId := a.currentSessionId(r)
+	for _, s := range sessions {
+		s.Current = s.ID == currentSessionId
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data": sessions,
+	})
+}
+
+// @Summary Revoke all other sessions
+// @Description Logs out everywhere, except for the session the request is made with
+// @ID revoke-sessions
+// @Tags auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/sessions [delete]
+func (a *APIv1) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+	if user == nil {
+		helpers.RespondJSON(w, r, http.StatusUnauthorized, map[string]interface{}{
+			"message": "Unauthorized",
+			"status":  http.StatusUnauthorized,
+		})
+		return
+	}
+
+	revoked, err := a.services.Sessions().RevokeAll(user.ID, a.currentSessionId(r))
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusAccepted, map[string]interface{}{
+		"message":          "Sessions revoked",
+		"status":           http.StatusAccepted,
+		"revoked_sessions": revoked,
+	})
+}
+
+// @Summary Revoke a session
+// @Description Logs out the given session, which may also be the current one
+// @ID revoke-session
+// @Tags auth
+// @Produce json
+// @Param id path string true "Session ID"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/sessions/{id} [delete]
+func (a *APIv1) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	user := helpers.ExtractUser(r)
+	if user == nil {
+		helpers.RespondJSON(w, r, http.StatusUnauthorized, map[string]interface{}{
+			"message": "Unauthorized",
+			"status":  http.StatusUnauthorized,
+		})
+		return
+	}
+
+	session, err := a.services.Sessions().GetActive(chi.URLParam(r, "id"))
+	if err != nil || session.UserID != user.ID {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Session Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	if err := a.services.Sessions().Revoke(session); err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, r, http.StatusAccepted, map[string]interface{}{
+		"message": "Session revoked",
+		"status":  http.StatusAccepted,
+	})
+}
+
+// startSession records a new login for the user, to issue the token for
+func (a *APIv1) startSession(r *http.Request, user *models.User) (*models.Session, error) {
+	return a.services.Sessions().Create(user, r.UserAgent(), utils.ReadRemoteIP(r, a.config.Security.TrustReverseProxyIPs()))
+}
+
+// currentSessionId returns the id of the session the request was authenticated by, or an empty string if it was
+// authenticated otherwise, e.g. by an api key
+func (a *APIv1) currentSessionId(r *http.Request) string {
+	if session := middlewares.GetPrincipalSession(r); session != nil {
+		return session.ID
+	}
+	return ""
+}
@@ -82,6 +82,12 @@ func (a *APIv1) GetProfile(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *APIv1) SendReport(w http.ResponseWriter, r *http.Request) {
+	// monthly and yearly reports are returned rather than mailed
+	if r.URL.Query().Has("period") {
+		a.GetWrappedReport(w, r)
+		return
+	}
+
 	user := middlewares.GetPrincipal(r)
 	err := a.services.Report().SendReport(user, true)
 	helpers.RespondJSON(w, r, 200, map[string]any{
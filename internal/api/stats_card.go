@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+// GetStatsCard serves an embeddable card of a user's top languages or projects or their last seven days as svg, e.g.
+// /api/card/languages/{user}.svg?interval=last_30_days&layout=donut&theme=dark. Ranges default to the past 30 days.
+// Others may only see languages and projects if the user shares them.
+func (a *APIv1) GetStatsCard(w http.ResponseWriter, r *http.Request) {
+	authorizedUser := middlewares.GetPrincipal(r)
+
+	card := chi.URLParam(r, "card")
+	if !models.IsValidStatsCard(card) || card == models.StatsCardGoal {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	requestedUser, ok := a.getChartUser(w, r)
+	if !ok {
+		return
+	}
+
+	var (
+		from, to time.Time
+		err      error
+	)
+	query := r.URL.Query()
+	if card == models.StatsCardWeek {
+		err, from, to = helpers.ResolveIntervalTZ(models.IntervalPast7Days, requestedUser.TZ())
+	} else if !query.Has("interval") && !query.Has("from") && !query.Has("to") {
+		err, from, to = helpers.ResolveIntervalTZ(models.IntervalPast30Days, requestedUser.TZ())
+	} else {
+		from, to, err = parseActivityRange(r, requestedUser)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if authorizedUser == nil || authorizedUser.ID != requestedUser.ID {
+		shared := isSharedSince(requestedUser, from) &&
+			(card != models.StatsCardLanguages || requestedUser.ShareLanguages) &&
+			(card != models.StatsCardProjects || requestedUser.ShareProjects)
+		if !shared {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
+	params := parseStatsCardParams(r)
+	params.Card, params.User, params.From, params.To = card, requestedUser, from, to
+	a.respondStatsCard(w, r, params)
+}
+
+// GetGoalCard serves an embeddable card of a goal's progress within the current day or week as svg, e.g.
+// /api/card/goal/{user}/{goal}.svg?theme=dark. Others may only see it if the user shares all kinds of entities the goal
+// is restricted to.
+func (a *APIv1) GetGoalCard(w http.ResponseWriter, r *http.Request) {
+	authorizedUser := middlewares.GetPrincipal(r)
+
+	goalWithExt := chi.URLParam(r, "goalWithExt")
+	if !strings.HasSuffix(goalWithExt, ".svg") {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(conf.ErrNotFound))
+		return
+	}
+
+	requestedUser, err := a.services.Users().GetUserById(chi.URLParam(r, "user"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	goal, err := a.services.Goal().GetGoalForUser(userWithExtPattern.ReplaceAllString(goalWithExt, ""), requestedUser.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	from, to := goal.PeriodAt(time.Now().In(requestedUser.TZ()))
+	if (authorizedUser == nil || authorizedUser.ID != requestedUser.ID) && (!isSharedSince(requestedUser, from) || !isFilterShared(requestedUser, goal.GetGoalSummaryFilter())) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	params := parseStatsCardParams(r)
+	params.Card, params.User, params.Goal, params.From, params.To = models.StatsCardGoal, requestedUser, goal, from, to
+	a.respondStatsCard(w, r, params)
+}
+
+func (a *APIv1) respondStatsCard(w http.ResponseWriter, r *http.Request, params *models.StatsCardParams) {
+	card, err := a.services.StatsCard().GetCard(params, utils.IsNoCache(r, 1*time.Hour))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to get stats card for user", "userID", params.User.ID, "card", params.Card, "error", err)
+		return
+	}
+	respondSvg(w, []byte(card))
+}
+
+// parseStatsCardParams reads the card's theme, colors, title, layout and number of items from the query
+func parseStatsCardParams(r *http.Request) *models.StatsCardParams {
+	query := r.URL.Query()
+
+	theme := models.GetCardTheme(query.Get("theme")).WithColors(
+		query.Get("bg_color"),
+		query.Get("border_color"),
+		query.Get("title_color"),
+		query.Get("text_color"),
+		query.Get("accent_color"),
+	)
+	if query.Has("hide_border") && query.Get("hide_border") != "false" {
+		theme.HideBorder = true
+	}
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = models.StatsCardDefaultLimit
+	}
+
+	layout := models.StatsCardLayoutBar
+	if query.Get("layout") == models.StatsCardLayoutDonut {
+		layout = models.StatsCardLayoutDonut
+	}
+
+	return &models.StatsCardParams{
+		Theme:  theme,
+		Title:  query.Get("custom_title"),
+		Layout: layout,
+		Limit:  min(limit, models.StatsCardMaxLimit),
+	}
+}
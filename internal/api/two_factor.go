@@ -0,0 +1,366 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+// @Summary Complete a login with a second factor
+// @Description Exchanges the two-factor token returned upon login, along with a totp or recovery code, for a session token
+// @ID verify-two-factor-login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param login body models.TwoFactorLogin true "Two-factor token and code"
+// @Success 201
+// @Router /v1/auth/2fa/verify [post]
+func (a *APIv1) VerifyTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	var params = &models.TwoFactorLogin{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || params.Token == "" || params.Code == "" {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a two-factor token and code are required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	user, ok := a.resolveTwoFactorChallenge(w, r, params.Token)
+	if !ok {
+		return
+	}
+
+	if err := a.services.TwoFactor().Verify(user, params.Code); err != nil {
+		a.respondTwoFactorError(w, r, err)
+		return
+	}
+
+	a.respondTwoFactorLogin(w, r, user, nil)
+}
+
+// @Summary Start the two-factor enrollment required to log in
+// @Description For servers requiring two-factor authentication, users without one set it up with the two-factor token returned upon login
+// @ID enroll-two-factor-login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param login body models.TwoFactorLogin true "Two-factor token"
+// @Success 200 {object} models.TwoFactorEnrollment
+// @Router /v1/auth/2fa/enroll [post]
+func (a *APIv1) EnrollTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	var params = &models.TwoFactorLogin{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || params.Token == "" {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a two-factor token is required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	user, ok := a.resolveTwoFactorChallenge(w, r, params.Token)
+	if !ok {
+		return
+	}
+
+	enrollment, err := a.services.TwoFactor().BeginEnrollment(user)
+	if err != nil {
+		a.respondTwoFactorError(w, r, err)
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data": enrollment,
+	})
+}
+
+// @Summary Complete the two-factor enrollment required to log in
+// @Description Enables the second factor given a valid code and logs the user in. The response includes the user's recovery codes, which are only shown once.
+// @ID confirm-two-factor-login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param login body models.TwoFactorLogin true "Two-factor token and totp code"
+// @Success 201
+// @Router /v1/auth/2fa/enroll/confirm [post]
+func (a *APIv1) ConfirmTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	var params = &models.TwoFactorLogin{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || params.Token == "" || params.Code == "" {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a two-factor token and code are required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	user, ok := a.resolveTwoFactorChallenge(w, r, params.Token)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := a.services.TwoFactor().ConfirmEnrollment(user, params.Code)
+	if err != nil {
+		a.respondTwoFactorError(w, r, err)
+		return
+	}
+
+	a.respondTwoFactorLogin(w, r, user, recoveryCodes)
+}
+
+// @Summary Retrieve the user's two-factor authentication status
+// @ID get-two-factor
+// @Tags auth
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.TwoFactorStatus
+// @Router /v1/users/{user}/2fa [get]
+func (a *APIv1) GetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	status, err := a.services.TwoFactor().GetStatus(user)
+	if err != nil {
+		a.respondTwoFactorError(w, r, err)
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data": status,
+	})
+}
+
+// @Summary Start setting up two-factor authentication
+// @Description Returns a new totp secret and its otpauth:// uri to show as qr code. It takes effect once confirmed by a valid code.
+// @ID enroll-two-factor
+// @Tags auth
+// @Produce json
+// @Param user path string true "User ID to set up two-factor authentication for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.TwoFactorEnrollment
+// @Router /v1/users/{user}/2fa [post]
+func (a *APIv1) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	enrollment, err := a.services.TwoFactor().BeginEnrollment(user)
+	if err != nil {
+		a.respondTwoFactorError(w, r, err)
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"data": enrollment,
+	})
+}
+
+// @Summary Complete setting up two-factor authentication
+// @Description Enables the second factor given a valid code and returns the user's recovery codes, which are only shown once
+// @ID confirm-two-factor
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to set up two-factor authentication for (or 'current')"
+// @Param code body models.TwoFactorCode true "Totp code"
+// @Security ApiKeyAuth
+// @Success 201 {array} string
+// @Router /v1/users/{user}/2fa/confirm [post]
+func (a *APIv1) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, code, ok := a.parseTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := a.services.TwoFactor().ConfirmEnrollment(user, code)
+	if err != nil {
+		a.respondTwoFactorError(w, r, err)
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, map[string]interface{}{
+		"data": recoveryCodes,
+	})
+}
+
+// @Summary Replace the user's recovery codes
+// @Description All remaining recovery codes are invalidated, the new ones are only shown once
+// @ID regenerate-recovery-codes
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to regenerate recovery codes for (or 'current')"
+// @Param code body models.TwoFactorCode true "Totp or recovery code"
+// @Security ApiKeyAuth
+// @Success 201 {array} string
+// @Router /v1/users/{user}/2fa/recovery-codes [post]
+func (a *APIv1) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, code, ok := a.parseTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := a.services.TwoFactor().RegenerateRecoveryCodes(user, code)
+	if err != nil {
+		a.respondTwoFactorError(w, r, err)
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, map[string]interface{}{
+		"data": recoveryCodes,
+	})
+}
+
+// @Summary Disable two-factor authentication
+// @Description Not possible if the server requires two-factor authentication
+// @ID delete-two-factor
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to disable two-factor authentication for (or 'current')"
+// @Param code body models.TwoFactorCode true "Totp or recovery code"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/2fa [delete]
+func (a *APIv1) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, code, ok := a.parseTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.services.TwoFactor().Disable(user, code); err != nil {
+		a.respondTwoFactorError(w, r, err)
+		return
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, map[string]interface{}{
+		"message": "Two-factor authentication disabled successfully",
+	})
+}
+
+// respondTwoFactorChallenge responds with a two-factor challenge instead of a session token if the user needs to pass
+// one to log in, returning whether it did so
+func (a *APIv1) respondTwoFactorChallenge(w http.ResponseWriter, r *http.Request, user *models.User) bool {
+	challenge, err := a.services.TwoFactor().Challenge(user)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return true
+	}
+	if challenge == nil {
+		return false
+	}
+
+	helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"message": "Two-factor authentication required",
+		"status":  http.StatusOK,
+		"data":    challenge,
+	})
+	return true
+}
+
+func (a *APIv1) respondTwoFactorLogin(w http.ResponseWriter, r *http.Request, user *models.User, recoveryCodes []string) {
+	user.LastLoggedInAt = models.CustomTime(time.Now())
+	a.services.Users().Update(user)
+
+	session, err := a.startSession(r, user)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	response, err := helpers.MakeAuthSuccessResponse(
+		&helpers.AuthSuccessResponse{
+			Message:   "Login Successful",
+			User:      user,
+			OauthUser: nil,
+			IsNewUser: false,
+			Session:   session,
+		},
+	)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Internal Server Error. Try again",
+			"status":  http.StatusInternalServerError,
+		})
+		return
+	}
+	if recoveryCodes != nil {
+		response["data"].(map[string]interface{})["recovery_codes"] = recoveryCodes
+	}
+
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+func (a *APIv1) resolveTwoFactorChallenge(w http.ResponseWriter, r *http.Request, token string) (*models.User, bool) {
+	userId, err := a.services.TwoFactor().ResolveChallenge(token)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusUnauthorized, map[string]interface{}{
+			"message": "Unauthorized: Invalid or expired two-factor token",
+			"status":  http.StatusUnauthorized,
+		})
+		return nil, false
+	}
+
+	user, err := a.services.Users().GetUserById(userId)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusUnauthorized, map[string]interface{}{
+			"message": "Unauthorized: Invalid or expired two-factor token",
+			"status":  http.StatusUnauthorized,
+		})
+		return nil, false
+	}
+	return user, true
+}
+
+func (a *APIv1) parseTwoFactorCode(w http.ResponseWriter, r *http.Request) (*models.User, string, bool) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return nil, "", false // response was already sent by util function
+	}
+
+	var params = &models.TwoFactorCode{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || params.Code == "" {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: a two-factor code is required",
+			"status":  http.StatusBadRequest,
+		})
+		return nil, "", false
+	}
+	return user, params.Code, true
+}
+
+func (a *APIv1) respondTwoFactorError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorInvalidCode):
+		helpers.RespondJSON(w, r, http.StatusUnauthorized, map[string]interface{}{
+			"message": "Invalid two-factor code",
+			"status":  http.StatusUnauthorized,
+		})
+	case errors.Is(err, services.ErrTwoFactorLocked):
+		helpers.RespondJSON(w, r, http.StatusTooManyRequests, map[string]interface{}{
+			"message": err.Error(),
+			"status":  http.StatusTooManyRequests,
+		})
+	case errors.Is(err, services.ErrTwoFactorEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnrolled),
+		errors.Is(err, services.ErrTwoFactorRequired):
+		helpers.RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"message": err.Error(),
+			"status":  http.StatusConflict,
+		})
+	default:
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+	}
+}
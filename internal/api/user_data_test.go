@@ -0,0 +1,340 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+var userDataUser = &models.User{ID: "user1"}
+
+// userDataHandlerTestCase describes one kind of per-user data (aliases, language mappings, project labels), all of which
+// are managed through the same set of endpoints
+type userDataHandlerTestCase struct {
+	name     string
+	path     string
+	register func(chi.Router, *APIv1)
+	newMock  func(*services.ServicesMock) *mock.Mock // sets up a fresh service mock, knowing the user's two existing items
+
+	created          interface{} // returned when creating a single item
+	createBody       string
+	invalidBody      string
+	createdBulk      interface{} // returned when creating the items of bulkBody
+	bulkBody         string
+	invalidBulk      string // second item is invalid
+	emptyBulk        interface{}
+	foreignID        string // id of an item not belonging to the user
+	deleteID         string
+	deleted          interface{} // existing item with deleteID
+	bulkDeleteBody   string      // containing one of the user's items, deleted through expectBulkDelete
+	bulkDeleted      string
+	expectBulkDelete func(*mock.Mock)
+	assertBulkDelete func(*testing.T, *mock.Mock)
+}
+
+func TestUserDataHandlers(t *testing.T) {
+	config.Set(config.Empty())
+
+	existingMappings := []*models.LanguageMapping{
+		{ID: 1, UserID: "user1", Extension: "tpl.html", Language: "Go Template"},
+		{ID: 2, UserID: "user1", Extension: "mdx", Language: "Markdown"},
+	}
+	existingLabels := []*models.ProjectLabel{
+		{ID: 1, UserID: "user1", ProjectKey: "wakapi", Label: "oss"},
+		{ID: 2, UserID: "user1", ProjectKey: "anchr", Label: "oss"},
+	}
+
+	testCases := []userDataHandlerTestCase{
+		{
+			name: "aliases",
+			path: "aliases",
+			register: func(r chi.Router, api *APIv1) {
+				r.Get("/v1/users/{user}/aliases", api.FetchUserAliases)
+				r.Post("/v1/users/{user}/aliases", api.CreateAlias)
+				r.Post("/v1/users/{user}/aliases/bulk", api.CreateAliasesBulk)
+				r.Delete("/v1/users/{user}/aliases/bulk", api.DeleteAliasesBulk)
+				r.Delete("/v1/users/{user}/aliases/{id}", api.DeleteAlias)
+			},
+			newMock: func(mockServices *services.ServicesMock) *mock.Mock {
+				return &newAliasServiceMock(mockServices).Mock
+			},
+			created:     &models.Alias{ID: 3, UserID: "user1", Type: models.SummaryEditor, Key: "VSCode", Value: "Code"},
+			createBody:  `{"type": 2, "key": "VSCode", "value": "Code"}`,
+			invalidBody: `{"type": 0, "key": "wakapi", "value": "*"}`,
+			createdBulk: []*models.Alias{
+				{ID: 3, UserID: "user1", Type: models.SummaryEditor, Key: "VSCode", Value: "Code"},
+				{ID: 4, UserID: "user1", Type: models.SummaryOS, Key: "Linux", Value: "Ubuntu"},
+			},
+			bulkBody:       `[{"type": 2, "key": "VSCode", "value": "Code"}, {"type": 3, "key": "Linux", "value": "Ubuntu"}]`,
+			invalidBulk:    `[{"type": 2, "key": "VSCode", "value": "Code"}, {"type": 2, "key": "", "value": "Code"}]`,
+			emptyBulk:      []*models.Alias{},
+			foreignID:      "99",
+			deleteID:       "2",
+			deleted:        existingAliases[1],
+			bulkDeleteBody: `{"ids": [1, 99]}`,
+			bulkDeleted:    "1 aliases deleted",
+			expectBulkDelete: func(m *mock.Mock) {
+				m.On("DeleteMulti", mock.Anything).Return(nil)
+			},
+			assertBulkDelete: func(t *testing.T, m *mock.Mock) {
+				m.AssertCalled(t, "DeleteMulti", existingAliases[:1])
+			},
+		},
+		{
+			name: "language mappings",
+			path: "language-mappings",
+			register: func(r chi.Router, api *APIv1) {
+				r.Get("/v1/users/{user}/language-mappings", api.FetchUserLanguageMappings)
+				r.Post("/v1/users/{user}/language-mappings", api.CreateLanguageMapping)
+				r.Post("/v1/users/{user}/language-mappings/bulk", api.CreateLanguageMappingsBulk)
+				r.Delete("/v1/users/{user}/language-mappings/bulk", api.DeleteLanguageMappingsBulk)
+				r.Delete("/v1/users/{user}/language-mappings/{id}", api.DeleteLanguageMapping)
+			},
+			newMock: func(mockServices *services.ServicesMock) *mock.Mock {
+				mappingServiceMock := new(mocks.LanguageMappingServiceMock)
+				mappingServiceMock.On("GetByUser", "user1").Return(existingMappings, nil)
+				mappingServiceMock.On("GetById", uint(1)).Return(existingMappings[0], nil)
+				mappingServiceMock.On("GetById", uint(2)).Return(existingMappings[1], nil)
+				mappingServiceMock.On("GetById", uint(3)).Return(&models.LanguageMapping{ID: 3, UserID: "user2", Extension: "svelte", Language: "Svelte"}, nil)
+				mappingServiceMock.On("GetById", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+				mockServices.LanguageMappingFunc = func() services.ILanguageMappingService {
+					return mappingServiceMock
+				}
+				return &mappingServiceMock.Mock
+			},
+			created:     &models.LanguageMapping{ID: 4, UserID: "user1", Extension: "astro", Language: "Astro"},
+			createBody:  `{"extension": "astro", "language": "Astro"}`,
+			invalidBody: `{"extension": "astro"}`,
+			createdBulk: []*models.LanguageMapping{
+				{ID: 4, UserID: "user1", Extension: "astro", Language: "Astro"},
+				{ID: 5, UserID: "user1", Extension: "templ", Language: "Templ"},
+			},
+			bulkBody:       `[{"extension": "astro", "language": "Astro"}, {"extension": "templ", "language": "Templ"}]`,
+			invalidBulk:    `[{"extension": "astro", "language": "Astro"}, {"language": "Templ"}]`,
+			emptyBulk:      []*models.LanguageMapping{},
+			foreignID:      "3",
+			deleteID:       "1",
+			deleted:        existingMappings[0],
+			bulkDeleteBody: `{"ids": [2, 3, 99]}`,
+			bulkDeleted:    "1 language mappings deleted",
+			expectBulkDelete: func(m *mock.Mock) {
+				m.On("Delete", mock.Anything).Return(nil)
+			},
+			assertBulkDelete: func(t *testing.T, m *mock.Mock) {
+				m.AssertNumberOfCalls(t, "Delete", 1)
+				m.AssertCalled(t, "Delete", existingMappings[1])
+			},
+		},
+		{
+			name: "project labels",
+			path: "project-labels",
+			register: func(r chi.Router, api *APIv1) {
+				r.Get("/v1/users/{user}/project-labels", api.FetchUserProjectLabels)
+				r.Post("/v1/users/{user}/project-labels", api.CreateProjectLabel)
+				r.Post("/v1/users/{user}/project-labels/bulk", api.CreateProjectLabelsBulk)
+				r.Delete("/v1/users/{user}/project-labels/bulk", api.DeleteProjectLabelsBulk)
+				r.Delete("/v1/users/{user}/project-labels/{id}", api.DeleteProjectLabel)
+			},
+			newMock: func(mockServices *services.ServicesMock) *mock.Mock {
+				labelServiceMock := new(mocks.ProjectLabelServiceMock)
+				labelServiceMock.On("GetByUser", "user1").Return(existingLabels, nil)
+				labelServiceMock.On("GetById", uint(1)).Return(existingLabels[0], nil)
+				labelServiceMock.On("GetById", uint(2)).Return(existingLabels[1], nil)
+				labelServiceMock.On("GetById", uint(3)).Return(&models.ProjectLabel{ID: 3, UserID: "user2", ProjectKey: "secret", Label: "work"}, nil)
+				labelServiceMock.On("GetById", mock.Anything).Return((*models.ProjectLabel)(nil), gorm.ErrRecordNotFound)
+				mockServices.ProjectLabelFunc = func() services.IProjectLabelService {
+					return labelServiceMock
+				}
+				return &labelServiceMock.Mock
+			},
+			created:     &models.ProjectLabel{ID: 4, UserID: "user1", ProjectKey: "wakapi", Label: "go"},
+			createBody:  `{"project": "wakapi", "label": "go"}`,
+			invalidBody: `{"label": "go"}`,
+			createdBulk: []*models.ProjectLabel{
+				{ID: 4, UserID: "user1", ProjectKey: "wakapi", Label: "go"},
+				{ID: 5, UserID: "user1", ProjectKey: "anchr", Label: "go"},
+			},
+			bulkBody:       `[{"project": "wakapi", "label": "go"}, {"project": "anchr", "label": "go"}]`,
+			invalidBulk:    `[{"project": "wakapi", "label": "go"}, {"project": "anchr"}]`,
+			emptyBulk:      []*models.ProjectLabel{},
+			foreignID:      "3",
+			deleteID:       "1",
+			deleted:        existingLabels[0],
+			bulkDeleteBody: `{"ids": [2, 3, 99]}`,
+			bulkDeleted:    "1 project labels deleted",
+			expectBulkDelete: func(m *mock.Mock) {
+				m.On("Delete", mock.Anything).Return(nil)
+			},
+			assertBulkDelete: func(t *testing.T, m *mock.Mock) {
+				m.AssertNumberOfCalls(t, "Delete", 1)
+				m.AssertCalled(t, "Delete", existingLabels[1])
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockServices := &services.ServicesMock{}
+			router := newUserDataTestRouter(mockServices, userDataUser, tc.register)
+			target := "/api/v1/users/current/" + tc.path
+
+			t.Run("should return all of the user's items", func(t *testing.T) {
+				tc.newMock(mockServices)
+				res := serveUserDataRequest(router, http.MethodGet, target, "")
+
+				assert.Equal(t, http.StatusOK, res.Code)
+				var body struct{ Data []json.RawMessage }
+				assert.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+				assert.Len(t, body.Data, 2)
+			})
+
+			t.Run("should not return other users' items", func(t *testing.T) {
+				serviceMock := tc.newMock(mockServices)
+				res := serveUserDataRequest(router, http.MethodGet, "/api/v1/users/user2/"+tc.path, "")
+
+				assert.Equal(t, http.StatusUnauthorized, res.Code)
+				serviceMock.AssertNotCalled(t, "GetByUser", mock.Anything)
+			})
+
+			t.Run("should create an item for the current user", func(t *testing.T) {
+				serviceMock := tc.newMock(mockServices)
+				serviceMock.On("Create", mock.Anything).Return(tc.created, nil)
+
+				res := serveUserDataRequest(router, http.MethodPost, target, tc.createBody)
+
+				assert.Equal(t, http.StatusCreated, res.Code)
+				assert.Equal(t, "user1", userIDOf(serviceMock.Calls[len(serviceMock.Calls)-1].Arguments.Get(0)))
+			})
+
+			t.Run("should reject invalid items", func(t *testing.T) {
+				serviceMock := tc.newMock(mockServices)
+				res := serveUserDataRequest(router, http.MethodPost, target, tc.invalidBody)
+
+				assert.Equal(t, http.StatusBadRequest, res.Code)
+				serviceMock.AssertNotCalled(t, "Create", mock.Anything)
+			})
+
+			t.Run("should create items in bulk at once", func(t *testing.T) {
+				serviceMock := tc.newMock(mockServices)
+				serviceMock.On("CreateMulti", mock.Anything).Return(tc.createdBulk, nil)
+
+				res := serveUserDataRequest(router, http.MethodPost, target+"/bulk", tc.bulkBody)
+
+				assert.Equal(t, http.StatusCreated, res.Code)
+				serviceMock.AssertNumberOfCalls(t, "CreateMulti", 1)
+				serviceMock.AssertNotCalled(t, "Create", mock.Anything)
+
+				created := reflect.ValueOf(serviceMock.Calls[len(serviceMock.Calls)-1].Arguments.Get(0))
+				assert.Equal(t, 2, created.Len())
+				for i := 0; i < created.Len(); i++ {
+					assert.Equal(t, "user1", userIDOf(created.Index(i).Interface()))
+				}
+
+				var body struct{ Data []json.RawMessage }
+				assert.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+				assert.Len(t, body.Data, 2)
+			})
+
+			t.Run("should not create anything in bulk if any item is invalid", func(t *testing.T) {
+				serviceMock := tc.newMock(mockServices)
+				res := serveUserDataRequest(router, http.MethodPost, target+"/bulk", tc.invalidBulk)
+
+				assert.Equal(t, http.StatusBadRequest, res.Code)
+				assert.Contains(t, res.Body.String(), "index 1")
+				serviceMock.AssertNotCalled(t, "CreateMulti", mock.Anything)
+			})
+
+			t.Run("should not return anything as created if creating in bulk fails", func(t *testing.T) {
+				serviceMock := tc.newMock(mockServices)
+				serviceMock.On("CreateMulti", mock.Anything).Return(tc.emptyBulk, errors.New("UNIQUE constraint failed"))
+
+				res := serveUserDataRequest(router, http.MethodPost, target+"/bulk", tc.bulkBody)
+
+				assert.Equal(t, http.StatusBadRequest, res.Code)
+				assert.NotContains(t, res.Body.String(), `"data"`)
+			})
+
+			t.Run("should delete the user's item", func(t *testing.T) {
+				serviceMock := tc.newMock(mockServices)
+				serviceMock.On("Delete", tc.deleted).Return(nil)
+
+				res := serveUserDataRequest(router, http.MethodDelete, target+"/"+tc.deleteID, "")
+
+				assert.Equal(t, http.StatusAccepted, res.Code)
+				serviceMock.AssertCalled(t, "Delete", tc.deleted)
+			})
+
+			t.Run("should not delete other users' items", func(t *testing.T) {
+				serviceMock := tc.newMock(mockServices)
+				res := serveUserDataRequest(router, http.MethodDelete, target+"/"+tc.foreignID, "")
+
+				assert.Equal(t, http.StatusNotFound, res.Code)
+				serviceMock.AssertNotCalled(t, "Delete", mock.Anything)
+			})
+
+			t.Run("should only delete the user's items in bulk", func(t *testing.T) {
+				serviceMock := tc.newMock(mockServices)
+				tc.expectBulkDelete(serviceMock)
+
+				res := serveUserDataRequest(router, http.MethodDelete, target+"/bulk", tc.bulkDeleteBody)
+
+				assert.Equal(t, http.StatusAccepted, res.Code)
+				assert.Contains(t, res.Body.String(), tc.bulkDeleted)
+				tc.assertBulkDelete(t, serviceMock)
+			})
+		})
+	}
+}
+
+// newUserDataTestRouter mounts the routes registered by the given function under /api, with requests being
+// authenticated as the given user
+func newUserDataTestRouter(mockServices *services.ServicesMock, principal *models.User, register func(chi.Router, *APIv1)) *chi.Mux {
+	router := chi.NewRouter()
+	apiRouter := chi.NewRouter()
+	apiRouter.Use(middlewares.NewPrincipalMiddleware())
+	apiRouter.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			middlewares.SetPrincipal(r, principal)
+			next.ServeHTTP(w, r)
+		})
+	})
+	router.Mount("/api", apiRouter)
+
+	api := &APIv1{
+		config:   config.Get(),
+		db:       nil,
+		services: mockServices,
+		cache:    cache.New(time.Hour, time.Hour),
+	}
+	register(apiRouter, api)
+
+	return router
+}
+
+func serveUserDataRequest(router http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// userIDOf returns the owner of any of the user data models, which don't expose it through json
+func userIDOf(item interface{}) string {
+	return reflect.ValueOf(item).Elem().FieldByName("UserID").String()
+}
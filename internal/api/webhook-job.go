@@ -0,0 +1,199 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/riverqueue/river"
+)
+
+const (
+	// river retries failed jobs with exponential backoff, the last attempt happens a little more than an hour after the first
+	webhookDeliveryMaxAttempts = 8
+	webhookResponseBodyLimit   = 4 << 10 // responses are only drained, to allow for reusing the connection
+	webhookDeliveryRetention   = 30 * 24 * time.Hour
+)
+
+type WebhookDeliveryArgs struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+func (WebhookDeliveryArgs) Kind() string { return "webhook_delivery" }
+
+// subscribeWebhooks forwards events published on the event bus to the webhooks subscribed to them. It blocks, so run it
+// in a goroutine of its own.
+func (a *APIv1) subscribeWebhooks() {
+	sub := conf.EventBus().Subscribe(
+		256,
+		conf.EventHeartbeatBatchCreate, // rather than a delivery per single heartbeat
+		conf.TopicUser,
+		conf.TopicProjectLabel,
+		conf.TopicAlias,
+		conf.TopicLanguageMapping,
+		conf.TopicGoal,
+		conf.TopicReport,
+		conf.TopicImport,
+		conf.TopicInvoice,
+		conf.EventWakatimeFailure,
+	)
+	for m := range sub.Receiver {
+		a.dispatchWebhookEvent(m)
+	}
+}
+
+func (a *APIv1) dispatchWebhookEvent(m hub.Message) {
+	userID := webhookEventUserId(m)
+	if userID == "" {
+		return
+	}
+
+	event := m.Name
+	if event == conf.EventHeartbeatBatchCreate {
+		event = conf.EventHeartbeatCreate // batches are what webhooks subscribe to as heartbeat.create
+	}
+
+	webhooks, err := a.services.Webhook().GetSubscribers(userID, event)
+	if err != nil {
+		conf.Log().Error("failed to fetch webhooks for event", "userID", userID, "event", event, "error", err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(webhookEventData(m))
+	if err != nil {
+		conf.Log().Error("failed to encode webhook event", "userID", userID, "event", event, "error", err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		delivery, err := a.services.Webhook().CreateDelivery(webhook, event, string(data))
+		if err != nil {
+			conf.Log().Error("failed to create webhook delivery", "webhookID", webhook.ID, "event", event, "error", err)
+			continue
+		}
+		if err := a.enqueueWebhookDelivery(context.Background(), delivery); err != nil {
+			conf.Log().Error("failed to enqueue webhook delivery", "webhookID", webhook.ID, "deliveryID", delivery.ID, "error", err)
+		}
+	}
+}
+
+func (a *APIv1) enqueueWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	_, err := a.river.Insert(ctx, WebhookDeliveryArgs{DeliveryID: delivery.ID}, &river.InsertOpts{MaxAttempts: webhookDeliveryMaxAttempts})
+	return err
+}
+
+func (a *APIv1) webhookDeliveryWorker(ctx context.Context, job *river.Job[WebhookDeliveryArgs]) error {
+	delivery, err := a.services.Webhook().GetDeliveryById(job.Args.DeliveryID)
+	if err != nil {
+		// webhook (and its deliveries) deleted in the meantime
+		return river.JobCancel(err)
+	}
+
+	delivery.Attempts++
+	if !delivery.Webhook.IsActive {
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.Error = "webhook is disabled"
+		return a.services.Webhook().UpdateDelivery(delivery)
+	}
+
+	sendErr := a.sendWebhook(ctx, delivery)
+	if sendErr == nil {
+		now := models.CustomTime(time.Now())
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.Error = ""
+	} else {
+		delivery.Error = sendErr.Error()
+		if job.Attempt >= job.MaxAttempts {
+			delivery.Status = models.WebhookDeliveryFailed
+		}
+	}
+
+	if err := a.services.Webhook().UpdateDelivery(delivery); err != nil {
+		conf.Log().Error("failed to update webhook delivery", "deliveryID", delivery.ID, "error", err)
+	}
+	return sendErr
+}
+
+// sendWebhook posts the delivery to the webhook's url. Receivers can verify requests by computing
+// the hex-encoded hmac-sha256 of "<X-Wakapi-Timestamp>|<request body>" using the webhook's secret.
+func (a *APIv1) sendWebhook(ctx context.Context, delivery *models.WebhookDelivery) error {
+	body, err := json.Marshal(&models.WebhookPayload{
+		ID:        delivery.ID,
+		Event:     delivery.Event,
+		UserID:    delivery.Webhook.UserID,
+		CreatedAt: delivery.CreatedAt,
+		Data:      json.RawMessage(delivery.Payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wakapi-webhook")
+	req.Header.Set("X-Wakapi-Event", delivery.Event)
+	req.Header.Set("X-Wakapi-Delivery", delivery.ID)
+	req.Header.Set("X-Wakapi-Timestamp", timestamp)
+	req.Header.Set("X-Wakapi-Signature", "sha256="+utils.SignValues(delivery.Webhook.Secret, timestamp, string(body)))
+
+	res, err := a.webhookClient.Do(req)
+	if err != nil {
+		delivery.ResponseStatus = 0
+		return err
+	}
+	defer res.Body.Close()
+
+	// response bodies are deliberately not kept, as they'd allow to read whatever the url responds with
+	io.Copy(io.Discard, io.LimitReader(res.Body, webhookResponseBodyLimit))
+	delivery.ResponseStatus = res.StatusCode
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// webhookEventUserId resolves the user an event belongs to, given the different shapes of messages published on the event bus
+func webhookEventUserId(m hub.Message) string {
+	if userID, ok := m.Fields[conf.FieldUserId].(string); ok {
+		return userID
+	}
+	if user, ok := m.Fields[conf.FieldUser].(*models.User); ok {
+		return user.ID
+	}
+	switch payload := m.Fields[conf.FieldPayload].(type) {
+	case *models.User:
+		return payload.ID
+	case *models.Heartbeat:
+		return payload.UserID
+	}
+	return ""
+}
+
+func webhookEventData(m hub.Message) interface{} {
+	switch m.Name {
+	case conf.EventWakatimeFailure:
+		return map[string]interface{}{"failed_attempts": m.Fields[conf.FieldPayload]}
+	case conf.EventHeartbeatBatchCreate:
+		return map[string]interface{}{"heartbeats": m.Fields[conf.FieldPayload]}
+	}
+	return m.Fields[conf.FieldPayload]
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
+	"github.com/muety/wakapi/models"
+)
+
+// @Summary Retrieve the user's webhooks
+// @ID get-webhooks
+// @Tags webhooks
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Webhook
+// @Router /v1/users/{user}/webhooks [get]
+func (a *APIv1) FetchUserWebhooks(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	webhooks, err := a.services.Webhook().FetchUserWebhooks(user.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching webhooks",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": webhooks,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Create a webhook
+// @Description Events can be given by name (e.g. `heartbeat.create`), by topic (e.g. `project_label.*`) or as `*` for all events. Available events are heartbeat.create, user.update, user.delete, project_label.create, project_label.delete, alias.create, alias.delete, language_mapping.create, language_mapping.delete, wakatime.failure, goal.reached, goal.failed, report.sent and import.finished.
+// @Description heartbeat.create is sent once per batch of heartbeats sent by a client, with the list of heartbeats as data. Imported heartbeats don't trigger it.
+// @Description Every request carries the headers `X-Wakapi-Event`, `X-Wakapi-Delivery`, `X-Wakapi-Timestamp` and `X-Wakapi-Signature`. The signature is `sha256=` followed by the hex-encoded HMAC-SHA256 of `<timestamp>|<body>`, keyed with the webhook's secret. A secret is generated if none is given. It is only returned in this response.
+// @Description Requests not answered with a 2xx status are retried with exponential backoff.
+// @ID create-webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to create the webhook for (or 'current')"
+// @Param webhook body models.NewWebhook true "Webhook to create"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.Webhook
+// @Router /v1/users/{user}/webhooks [post]
+func (a *APIv1) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	var params = &models.NewWebhook{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil || !params.IsValid() {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: an http(s) url and at least one known event are required",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	webhook, err := a.services.Webhook().Create(user, params)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": webhook,
+	}
+	helpers.RespondJSON(w, r, http.StatusCreated, response)
+}
+
+// @Summary Retrieve a webhook
+// @ID get-webhook
+// @Tags webhooks
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param id path string true "Webhook ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Webhook
+// @Router /v1/users/{user}/webhooks/{id} [get]
+func (a *APIv1) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := a.loadUserWebhook(w, r)
+	if !ok {
+		return
+	}
+	response := map[string]interface{}{
+		"data": webhook,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Update a webhook
+// @Description The secret is kept if none is given. A new one is returned once in the response.
+// @ID update-webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID to update the webhook for (or 'current')"
+// @Param id path string true "Webhook ID"
+// @Param webhook body models.NewWebhook true "Updated webhook"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Webhook
+// @Router /v1/users/{user}/webhooks/{id} [put]
+func (a *APIv1) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := a.loadUserWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	var params = &models.NewWebhook{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+
+	updated, err := a.services.Webhook().Update(webhook, params)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message":       "Invalid Input: an http(s) url and at least one known event are required",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": updated,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Delete a webhook
+// @ID delete-webhook
+// @Tags webhooks
+// @Produce json
+// @Param user path string true "User ID to delete the webhook for (or 'current')"
+// @Param id path string true "Webhook ID"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /v1/users/{user}/webhooks/{id} [delete]
+func (a *APIv1) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := a.loadUserWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.services.Webhook().Delete(webhook); err != nil {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Webhook Cannot Be Deleted",
+			"status":  http.StatusBadRequest,
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"message": "Webhook deleted successfully",
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+// @Summary Retrieve the most recent deliveries of a webhook
+// @ID get-webhook-deliveries
+// @Tags webhooks
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param id path string true "Webhook ID"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.WebhookDelivery
+// @Router /v1/users/{user}/webhooks/{id}/deliveries [get]
+func (a *APIv1) FetchWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := a.loadUserWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := a.services.Webhook().FetchDeliveries(webhook.ID)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "Error fetching webhook deliveries",
+			"error_message": err.Error(),
+		})
+		return
+	}
+	response := map[string]interface{}{
+		"data": deliveries,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// @Summary Send a previous delivery again
+// @Description Creates a new delivery with the same event and payload, the original delivery is kept as is
+// @ID redeliver-webhook-delivery
+// @Tags webhooks
+// @Produce json
+// @Param user path string true "User ID (or 'current')"
+// @Param id path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Security ApiKeyAuth
+// @Success 202 {object} models.WebhookDelivery
+// @Router /v1/users/{user}/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (a *APIv1) RedeliverWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := a.loadUserWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	delivery, err := a.services.Webhook().GetDeliveryById(chi.URLParam(r, "deliveryId"))
+	if err != nil || delivery.WebhookID != webhook.ID {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Delivery Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return
+	}
+
+	redelivery, err := a.services.Webhook().Redeliver(delivery)
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	if err := a.enqueueWebhookDelivery(r.Context(), redelivery); err != nil {
+		conf.Log().Request(r).Error("failed to enqueue webhook redelivery", "webhookID", webhook.ID, "deliveryID", redelivery.ID, "error", err)
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": redelivery,
+	}
+	helpers.RespondJSON(w, r, http.StatusAccepted, response)
+}
+
+// loadUserWebhook resolves the webhook addressed by the request, responding with an error if it doesn't belong to the effective user
+func (a *APIv1) loadUserWebhook(w http.ResponseWriter, r *http.Request) (*models.Webhook, bool) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return nil, false // response was already sent by util function
+	}
+
+	webhook, err := a.services.Webhook().GetById(chi.URLParam(r, "id"))
+	if err != nil || webhook.UserID != user.ID {
+		helpers.RespondJSON(w, r, http.StatusNotFound, map[string]interface{}{
+			"message": "Webhook Cannot Be Found",
+			"status":  http.StatusNotFound,
+		})
+		return nil, false
+	}
+	return webhook, true
+}
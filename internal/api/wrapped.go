@@ -0,0 +1,118 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/internal/utilities"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+// @Summary Retrieve a monthly or yearly "wrapped" report
+// @Description Compares the month or year to the one before and contains top projects, languages and editors with their trends, the busiest weekday and hour, the longest streak and languages used for the first time. Without a date, the latest complete month or year is reported.
+// @ID get-wrapped-report
+// @Tags reports
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param period query string true "Period to report on" Enums(month, year)
+// @Param date query string false "Any date within the period, e.g. 2024-03-01"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.WrappedReport
+// @Router /v1/users/{user}/report [get]
+func (a *APIv1) GetWrappedReport(w http.ResponseWriter, r *http.Request) {
+	user, err := utilities.CheckEffectiveUser(w, r, a.services.Users(), "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	period, date, ok := a.parseWrappedParams(w, r, user)
+	if !ok {
+		return
+	}
+
+	report, err := a.services.Report().GetWrappedReport(user, period, date, utils.IsNoCache(r, 6*time.Hour))
+	if err != nil {
+		helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+			"message":       "An unexpected error occurred. Try again later",
+			"error_message": err.Error(),
+		})
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": report,
+	}
+	helpers.RespondJSON(w, r, http.StatusOK, response)
+}
+
+// GetWrappedCard serves a user's wrapped report as a shareable svg card, e.g. /api/wrapped/{user}.svg?period=year&dark.
+// Others may only see it if the user shares their data for at least as far back as the period starts.
+func (a *APIv1) GetWrappedCard(w http.ResponseWriter, r *http.Request) {
+	authorizedUser := middlewares.GetPrincipal(r)
+
+	requestedUser, ok := a.getChartUser(w, r)
+	if !ok {
+		return
+	}
+
+	period, date, ok := a.parseWrappedParams(w, r, requestedUser)
+	if !ok {
+		return
+	}
+
+	if authorizedUser == nil || authorizedUser.ID != requestedUser.ID {
+		from, _ := models.LatestWrappedPeriodRange(period, time.Now().In(requestedUser.TZ()))
+		if !date.IsZero() {
+			from, _ = models.WrappedPeriodRange(period, date.In(requestedUser.TZ()))
+		}
+		if !isSharedSince(requestedUser, from) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
+	paramDark := r.URL.Query().Has("dark") && r.URL.Query().Get("dark") != "false"
+
+	card, err := a.services.Report().GetWrappedCard(requestedUser, period, date, paramDark, utils.IsNoCache(r, 6*time.Hour))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		conf.Log().Request(r).Error("failed to get wrapped card for user", "userID", requestedUser.ID, "error", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "max-age=21600") // 6 hours
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(card))
+}
+
+// parseWrappedParams reads period and optional date from the query, the date is zero if not given
+func (a *APIv1) parseWrappedParams(w http.ResponseWriter, r *http.Request, user *models.User) (string, time.Time, bool) {
+	period := r.URL.Query().Get("period")
+	if !models.IsValidWrappedPeriod(period) {
+		helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+			"message": "Invalid Input: period must be one of month or year",
+			"status":  http.StatusBadRequest,
+		})
+		return "", time.Time{}, false
+	}
+
+	var date time.Time
+	if dateParam := r.URL.Query().Get("date"); dateParam != "" {
+		parsed, err := helpers.ParseDateTimeTZ(dateParam, user.TZ())
+		if err != nil {
+			helpers.RespondJSON(w, r, http.StatusBadRequest, map[string]interface{}{
+				"message": "Invalid Input: date must be given as yyyy-mm-dd",
+				"status":  http.StatusBadRequest,
+			})
+			return "", time.Time{}, false
+		}
+		date = parsed
+	}
+
+	return period, date, true
+}
@@ -16,10 +16,14 @@ import (
 )
 
 // Cron schedules for River periodic jobs
-var EVERY_MONDAY_MORNING, _ = cron.ParseStandard("0 6 * * 1")     // Monday 6 AM
-var EVERY_SUNDAY_MIDNIGHT, _ = cron.ParseStandard("0 0 * * 0")    // Sunday midnight
-var DAILY_AGGREGATION, _ = cron.ParseStandard("15 2 * * *")       // 2:15 AM daily
-var WEEKLY_HOUSEKEEPING, _ = cron.ParseStandard("0 6 * * 0")      // 6 AM Sunday
+var EVERY_FIVE_MINUTES, _ = cron.ParseStandard("*/5 * * * *")       // every five minutes
+var EVERY_SUNDAY_MIDNIGHT, _ = cron.ParseStandard("0 0 * * 0")      // Sunday midnight
+var DAILY_AGGREGATION, _ = cron.ParseStandard("15 2 * * *")         // 2:15 AM daily
+var WEEKLY_HOUSEKEEPING, _ = cron.ParseStandard("0 6 * * 0")        // 6 AM Sunday
+var HOURLY_EXPORT_CLEANUP, _ = cron.ParseStandard("30 * * * *")     // half past every hour
+var HOURLY_GOAL_EVALUATION, _ = cron.ParseStandard("5 * * * *")     // five past every hour
+var HOURLY_INVOICE_OVERDUE, _ = cron.ParseStandard("10 * * * *")    // ten past every hour
+var HOURLY_RECURRING_INVOICES, _ = cron.ParseStandard("20 * * * *") // twenty past every hour
 
 type Jobs struct {
 	DB *gorm.DB
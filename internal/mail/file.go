@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+var unsafeFilenameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// FileSendingService writes every mail to a directory as an .eml file instead of sending it, so staging or development
+// instances can inspect exactly what would have been sent
+type FileSendingService struct {
+	config conf.FileMailConfig
+}
+
+func NewFileSendingService(config conf.FileMailConfig) (*FileSendingService, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("no directory set for file mail provider")
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSendingService{config: config}, nil
+}
+
+func (s *FileSendingService) Send(mail *models.Mail) error {
+	mail = mail.Sanitized()
+
+	id := unsafeFilenameChars.ReplaceAllString(mail.MessageID, "")
+	filename := fmt.Sprintf("%s-%s.eml", mail.Date.UTC().Format("20060102T150405"), id)
+	path := filepath.Join(s.config.Dir, filename)
+
+	// write to a temporary file first, so anyone watching the directory never sees partial mails
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(mail.String()), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSendingService_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mails")
+
+	sut, err := NewFileSendingService(conf.FileMailConfig{Dir: dir})
+	assert.Nil(t, err)
+
+	mail := (&models.Mail{
+		From:    "Wakana <noreply@wakana.io>",
+		To:      models.MailAddresses{"john@example.org"},
+		Subject: "Invoice INV-001",
+	}).WithHTML("<p>Hi</p>").WithAttachment("invoice.pdf", "application/pdf", []byte("pdf"))
+	assert.Nil(t, sut.Send(mail))
+
+	files, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), ".eml"))
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "Subject: Invoice INV-001\r\n")
+	assert.Contains(t, string(data), "To: john@example.org\r\n")
+	assert.Contains(t, string(data), "filename=\"invoice.pdf\"")
+}
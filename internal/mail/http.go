@@ -0,0 +1,141 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// generic json document used unless a template is configured, fields are easily mapped to most providers' apis
+const defaultHTTPTemplate = `{
+  "from": {{ json .From }},
+  "to": {{ json .To }},
+  "reply_to": {{ json .ReplyTo }},
+  "subject": {{ json .Subject }},
+  "{{ if .IsHTML }}html{{ else }}text{{ end }}": {{ json .Body }},
+  "message_id": {{ json .MessageID }},
+  "attachments": {{ json .Attachments }}
+}`
+
+const httpMaxErrorBodyLength = 512
+
+// HTTPTemplateData is what the request body template of the http mail provider gets to render, e.g. a postmark request
+// would be {"From": {{ json .From }}, "To": {{ json (join .To ",") }}, "Subject": {{ json .Subject }}, "HtmlBody": {{ json .Body }}}
+type HTTPTemplateData struct {
+	From        string                    `json:"from"`
+	To          []string                  `json:"to"`
+	ReplyTo     string                    `json:"reply_to"`
+	Subject     string                    `json:"subject"`
+	Body        string                    `json:"body"`
+	IsHTML      bool                      `json:"is_html"`
+	MessageID   string                    `json:"message_id"`
+	Attachments []*HTTPTemplateAttachment `json:"attachments"`
+}
+
+type HTTPTemplateAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content"` // base64-encoded
+}
+
+// HTTPSendingService sends mails through a provider's http api by rendering every mail into a request body
+type HTTPSendingService struct {
+	config     conf.HTTPMailConfig
+	template   *template.Template
+	httpClient *http.Client
+}
+
+func NewHTTPSendingService(config conf.HTTPMailConfig) (*HTTPSendingService, error) {
+	if config.Url == "" {
+		return nil, fmt.Errorf("no url set for http mail provider")
+	}
+	if config.Method == "" {
+		config.Method = http.MethodPost
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10
+	}
+
+	text := config.Template
+	if text == "" {
+		text = defaultHTTPTemplate
+	}
+	tpl, err := template.New("mail").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"join": strings.Join,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http mail template: %w", err)
+	}
+
+	return &HTTPSendingService{
+		config:     config,
+		template:   tpl,
+		httpClient: &http.Client{Timeout: time.Duration(config.Timeout) * time.Second},
+	}, nil
+}
+
+func (s *HTTPSendingService) Send(mail *models.Mail) error {
+	mail = mail.Sanitized()
+
+	var body bytes.Buffer
+	if err := s.template.Execute(&body, newHTTPTemplateData(mail)); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(s.config.Method, s.config.Url, &body)
+	if err != nil {
+		return err
+	}
+	if s.config.ContentType != "" {
+		req.Header.Set("Content-Type", s.config.ContentType)
+	}
+	for k, v := range s.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, httpMaxErrorBodyLength))
+		return fmt.Errorf("mail provider responded with status %d: %s", res.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+func newHTTPTemplateData(mail *models.Mail) *HTTPTemplateData {
+	data := &HTTPTemplateData{
+		From:        mail.From.String(),
+		To:          mail.To.Strings(),
+		ReplyTo:     mail.ReplyTo.String(),
+		Subject:     mail.Subject,
+		Body:        mail.Body,
+		IsHTML:      mail.Type == models.HtmlType,
+		MessageID:   mail.MessageID,
+		Attachments: make([]*HTTPTemplateAttachment, len(mail.Attachments)),
+	}
+	for i, a := range mail.Attachments {
+		data.Attachments[i] = &HTTPTemplateAttachment{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Content:     base64.StdEncoding.EncodeToString(a.Data),
+		}
+	}
+	return data
+}
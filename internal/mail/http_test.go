@@ -0,0 +1,86 @@
+package mail
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPSendingService_Send(t *testing.T) {
+	var body map[string]interface{}
+	var token string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Server-Token")
+		data, _ := io.ReadAll(r.Body)
+		assert.Nil(t, json.Unmarshal(data, &body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sut, err := NewHTTPSendingService(conf.HTTPMailConfig{
+		Url:         server.URL,
+		ContentType: "application/json",
+		Headers:     map[string]string{"X-Server-Token": "secret"},
+	})
+	assert.Nil(t, err)
+
+	mail := (&models.Mail{
+		From:    "Wakana <noreply@wakana.io>",
+		To:      models.MailAddresses{"john@example.org"},
+		Subject: "Hello \"John\"",
+	}).WithHTML("<p>Hi</p>").WithAttachment("invoice.pdf", "application/pdf", []byte("pdf"))
+
+	assert.Nil(t, sut.Send(mail))
+	assert.Equal(t, "secret", token)
+	assert.Equal(t, "Wakana <noreply@wakana.io>", body["from"])
+	assert.Equal(t, []interface{}{"john@example.org"}, body["to"])
+	assert.Equal(t, "Hello \"John\"", body["subject"])
+	assert.Equal(t, "<p>Hi</p>", body["html"])
+	assert.Equal(t, "cGRm", body["attachments"].([]interface{})[0].(map[string]interface{})["content"])
+}
+
+func TestHTTPSendingService_Send_CustomTemplate(t *testing.T) {
+	var body map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		assert.Nil(t, json.Unmarshal(data, &body))
+	}))
+	defer server.Close()
+
+	sut, err := NewHTTPSendingService(conf.HTTPMailConfig{
+		Url:      server.URL,
+		Template: `{"From": {{ json .From }}, "To": {{ json (join .To ",") }}, "HtmlBody": {{ json .Body }}}`,
+	})
+	assert.Nil(t, err)
+
+	mail := (&models.Mail{From: "noreply@wakana.io", To: models.MailAddresses{"a@example.org", "b@example.org"}}).WithHTML("Hi")
+	assert.Nil(t, sut.Send(mail))
+	assert.Equal(t, "a@example.org,b@example.org", body["To"])
+	assert.Equal(t, "Hi", body["HtmlBody"])
+}
+
+func TestHTTPSendingService_Send_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte("invalid sender"))
+	}))
+	defer server.Close()
+
+	sut, err := NewHTTPSendingService(conf.HTTPMailConfig{Url: server.URL})
+	assert.Nil(t, err)
+
+	err = sut.Send(&models.Mail{From: "noreply@wakana.io", To: models.MailAddresses{"john@example.org"}})
+	assert.ErrorContains(t, err, "422")
+	assert.ErrorContains(t, err, "invalid sender")
+
+	_, err = NewHTTPSendingService(conf.HTTPMailConfig{Url: server.URL, Template: "{{ .Unclosed "})
+	assert.NotNil(t, err)
+}
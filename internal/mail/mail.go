@@ -19,26 +19,44 @@ const (
 	tplNameImportNotification          = "import_finished"
 	tplNameWakatimeFailureNotification = "wakatime_connection_failure"
 	tplNameReport                      = "report"
+	tplNameWrappedReport               = "wrapped_report"
 	tplOtp                             = "otp"
 	tplNameSubscriptionNotification    = "subscription_expiring"
+	tplNameOrganizationInvitation      = "organization_invitation"
+	tplNameGoalNotification            = "goal_status"
+	tplNameInvoice                     = "invoice"
+	tplNameInvoiceGenerated            = "invoice_generated"
 	subjectPasswordReset               = "Wakana - Password Reset"
 	subjectWakanaOtp                   = "Wakana - OTP"
 	subjectImportNotification          = "Wakana - Data Import Finished"
 	subjectWakatimeFailureNotification = "Wakana - WakaTime Connection Failure"
 	subjectReport                      = "Wakana - Report from %s"
+	subjectWrappedReport               = "Wakana - Your %s Wrapped"
 	subjectSubscriptionNotification    = "Wakana - Subscription expiring / expired"
+	subjectOrganizationInvitation      = "Wakana - Invitation to join %s"
+	subjectGoalAchieved                = "Wakana - Goal achieved: %s"
+	subjectGoalMissed                  = "Wakana - Goal missed: %s"
+	subjectInvoice                     = "Invoice %s from %s"
+	subjectInvoiceGenerated            = "Wakana - Draft invoice %s for %s"
 )
 
 //go:embed templates/*.html
 var TemplateFiles embed.FS
 
+// IMailService sends the application's mails. Unless the noop provider is configured, mails that fail to be sent are put
+// into the outbox to be retried later on and no error is returned. Callers treat a queued mail the same as a sent one.
 type IMailService interface {
 	SendPasswordReset(*models.User, string) error
 	SendWakatimeFailureNotification(*models.User, int) error
 	SendImportNotification(*models.User, time.Duration, int) error
 	SendReport(*models.User, *models.Report) error
+	SendWrappedReport(*models.User, *models.WrappedReport) error
 	SendSubscriptionNotification(*models.User, bool) error
 	SendLoginOtp(string, string, time.Time) error
+	SendOrganizationInvitation(*models.OrganizationInvitation, *models.Organization, *models.User, string) error
+	SendGoalNotification(*models.User, *models.Goal, *models.GoalPeriod) error
+	SendInvoice(*models.User, *models.Invoice, string, string, []byte) error
+	SendInvoiceGenerated(*models.User, *models.Invoice) error
 }
 
 type SendingService interface {
@@ -54,13 +72,9 @@ type MailService struct {
 func NewMailService() IMailService {
 	config := conf.Get()
 
-	var sendingService SendingService
-	sendingService = &NoopSendingService{}
-
-	if config.Mail.Enabled {
-		if config.Mail.Provider == conf.MailProviderSmtp {
-			sendingService = NewSMTPSendingService(config.Mail.Smtp)
-		}
+	sendingService := mustSendingService(config)
+	if _, noop := sendingService.(*NoopSendingService); !noop {
+		sendingService = NewOutboxSendingService(sendingService)
 	}
 
 	// Use local file system when in 'dev' environment, go embed file system otherwise
@@ -156,6 +170,28 @@ func (m *MailService) SendReport(recipient *models.User, report *models.Report)
 	return m.sendingService.Send(mail)
 }
 
+func (m *MailService) SendWrappedReport(recipient *models.User, report *models.WrappedReport) error {
+	if report.TotalSeconds == 0 {
+		conf.Log().Info("skipping wrapped report email - user has no activity for this period", "userID", recipient.ID, "from", report.From, "to", report.To)
+		return nil
+	}
+
+	tpl, err := m.getWrappedReportTemplate(WrappedReportTplData{
+		Report:   report,
+		CardLink: fmt.Sprintf("%s/api/wrapped/%s.svg?period=%s&date=%s", m.config.Server.GetPublicUrl(), recipient.ID, report.Period, report.From.Format(time.DateOnly)),
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: fmt.Sprintf(subjectWrappedReport, report.Title()),
+	}
+	mail.WithHTML(tpl.String())
+	return m.sendingService.Send(mail)
+}
+
 func (m *MailService) SendSubscriptionNotification(recipient *models.User, hasExpired bool) error {
 	tpl, err := m.getSubscriptionNotificationTemplate(SubscriptionNotificationTplData{
 		PublicUrl:           m.config.Server.PublicUrl,
@@ -174,6 +210,125 @@ func (m *MailService) SendSubscriptionNotification(recipient *models.User, hasEx
 	return m.sendingService.Send(mail)
 }
 
+func (m *MailService) SendOrganizationInvitation(invitation *models.OrganizationInvitation, organization *models.Organization, inviter *models.User, invitationLink string) error {
+	inviterName := inviter.Name
+	if inviterName == "" {
+		inviterName = inviter.Email
+	}
+	tpl, err := m.getOrganizationInvitationTemplate(OrganizationInvitationTplData{
+		InvitationLink:   invitationLink,
+		OrganizationName: organization.Name,
+		InviterName:      inviterName,
+		Role:             invitation.Role,
+		ExpiryTime:       invitation.ExpiresAt.T().Format("Jan 2, 2006 15:04 (MST -07:00)"),
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(invitation.Email)}),
+		Subject: fmt.Sprintf(subjectOrganizationInvitation, organization.Name),
+	}
+	mail.WithHTML(tpl.String())
+	return m.sendingService.Send(mail)
+}
+
+func (m *MailService) SendGoalNotification(recipient *models.User, goal *models.Goal, period *models.GoalPeriod) error {
+	achieved := period.Status == models.GoalStatusSuccess
+
+	periodText := helpers.FormatDateHuman(period.PeriodStart.T())
+	if goal.Delta == models.GoalDeltaWeek {
+		periodText = fmt.Sprintf("the week from %s to %s", periodText, helpers.FormatDateHuman(period.PeriodEnd.T().AddDate(0, 0, -1)))
+	}
+
+	tpl, err := m.getGoalNotificationTemplate(GoalNotificationTplData{
+		PublicUrl:     m.config.Server.PublicUrl,
+		GoalTitle:     goal.DisplayTitle(),
+		Achieved:      achieved,
+		IsLimit:       goal.IsLimit(),
+		Period:        periodText,
+		ActualTime:    helpers.FmtWakatimeDuration(time.Duration(period.ActualSeconds) * time.Second),
+		TargetTime:    helpers.FmtWakatimeDuration(time.Duration(period.GoalSeconds) * time.Second),
+		CurrentStreak: goal.CurrentStreak,
+		LongestStreak: goal.LongestStreak,
+	})
+	if err != nil {
+		return err
+	}
+
+	subject := subjectGoalMissed
+	if achieved {
+		subject = subjectGoalAchieved
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: fmt.Sprintf(subject, goal.DisplayTitle()),
+	}
+	mail.WithHTML(tpl.String())
+	return m.sendingService.Send(mail)
+}
+
+// SendInvoice mails the rendered invoice to the given recipient on behalf of the user, who will receive any replies
+func (m *MailService) SendInvoice(sender *models.User, invoice *models.Invoice, recipient string, message string, pdf []byte) error {
+	senderName := sender.Name
+	if senderName == "" {
+		senderName = sender.Email
+	}
+	if senderName == "" {
+		senderName = sender.ID
+	}
+
+	var dueDate string
+	if invoice.DueDate != nil {
+		dueDate = helpers.FormatDateHuman(*invoice.DueDate)
+	}
+
+	tpl, err := m.getInvoiceTemplate(InvoiceTplData{
+		SenderName:    senderName,
+		ContactName:   invoice.Client.ContactName,
+		InvoiceNumber: invoice.InvoiceID,
+		Total:         fmt.Sprintf("%s %.2f", invoice.CurrencyCode(), invoice.Total()),
+		DueDate:       dueDate,
+		Message:       message,
+		ReplyTo:       sender.Email != "",
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient)}),
+		ReplyTo: models.MailAddress(sender.Email),
+		Subject: fmt.Sprintf(subjectInvoice, invoice.InvoiceID, senderName),
+	}
+	mail.WithHTML(tpl.String())
+	mail.WithAttachment(fmt.Sprintf("%s.pdf", invoice.InvoiceID), "application/pdf", pdf)
+	return m.sendingService.Send(mail)
+}
+
+// SendInvoiceGenerated lets the user know a recurring invoice has been drafted and is waiting to be reviewed and sent
+func (m *MailService) SendInvoiceGenerated(recipient *models.User, invoice *models.Invoice) error {
+	tpl, err := m.getInvoiceGeneratedTemplate(InvoiceGeneratedTplData{
+		PublicUrl:     m.config.Server.PublicUrl,
+		InvoiceNumber: invoice.InvoiceID,
+		ClientName:    invoice.Client.Name,
+		Period:        fmt.Sprintf("%s to %s", helpers.FormatDateHuman(invoice.StartDate), helpers.FormatDateHuman(invoice.EndDate)),
+		Total:         fmt.Sprintf("%s %.2f", invoice.CurrencyCode(), invoice.Total()),
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: fmt.Sprintf(subjectInvoiceGenerated, invoice.InvoiceID, invoice.Client.Name),
+	}
+	mail.WithHTML(tpl.String())
+	return m.sendingService.Send(mail)
+}
+
 func (m *MailService) getPasswordResetTemplate(data PasswordResetTplData) (*bytes.Buffer, error) {
 	var rendered bytes.Buffer
 	if err := m.templates[m.fmtName(tplNamePasswordReset)].Execute(&rendered, data); err != nil {
@@ -214,6 +369,14 @@ func (m *MailService) getReportTemplate(data ReportTplData) (*bytes.Buffer, erro
 	return &rendered, nil
 }
 
+func (m *MailService) getWrappedReportTemplate(data WrappedReportTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameWrappedReport)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
 func (m *MailService) getSubscriptionNotificationTemplate(data SubscriptionNotificationTplData) (*bytes.Buffer, error) {
 	var rendered bytes.Buffer
 	if err := m.templates[m.fmtName(tplNameSubscriptionNotification)].Execute(&rendered, data); err != nil {
@@ -222,6 +385,38 @@ func (m *MailService) getSubscriptionNotificationTemplate(data SubscriptionNotif
 	return &rendered, nil
 }
 
+func (m *MailService) getOrganizationInvitationTemplate(data OrganizationInvitationTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameOrganizationInvitation)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getGoalNotificationTemplate(data GoalNotificationTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameGoalNotification)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getInvoiceTemplate(data InvoiceTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameInvoice)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getInvoiceGeneratedTemplate(data InvoiceGeneratedTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameInvoiceGenerated)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
 func (m *MailService) fmtName(name string) string {
 	return fmt.Sprintf("%s.tpl.html", name)
 }
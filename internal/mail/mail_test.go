@@ -18,4 +18,13 @@ func TestTemplatesLoaded(t *testing.T) {
 
 	_, hasReport := templates[fmt.Sprintf("%s.tpl.html", tplNameReport)]
 	assert.True(t, hasReport, "The 'report.tpl.html' template should be loaded")
+
+	_, hasWrappedReport := templates[fmt.Sprintf("%s.tpl.html", tplNameWrappedReport)]
+	assert.True(t, hasWrappedReport, "The 'wrapped_report.tpl.html' template should be loaded")
+
+	_, hasInvoice := templates[fmt.Sprintf("%s.tpl.html", tplNameInvoice)]
+	assert.True(t, hasInvoice, "The 'invoice.tpl.html' template should be loaded")
+
+	_, hasInvoiceGenerated := templates[fmt.Sprintf("%s.tpl.html", tplNameInvoiceGenerated)]
+	assert.True(t, hasInvoiceGenerated, "The 'invoice_generated.tpl.html' template should be loaded")
 }
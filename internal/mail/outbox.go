@@ -0,0 +1,165 @@
+package mail
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/muety/artifex/v2"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+const (
+	outboxRetryEvery   = 1 * time.Minute
+	outboxBatchSize    = 50
+	outboxClaimTimeout = 10 * time.Minute // how long a mail is reserved for the process retrying it, in case it dies meanwhile
+)
+
+var (
+	outbox     *Outbox
+	outboxLock = sync.RWMutex{}
+)
+
+// Outbox persists mails that could not be sent and periodically retries them on the mail queue
+type Outbox struct {
+	config         *conf.Config
+	db             *gorm.DB
+	sendingService SendingService
+	queue          *artifex.Dispatcher
+	retryLock      sync.Mutex
+}
+
+// InitOutbox makes all mail services put failed mails into the outbox rather than giving up on them
+func InitOutbox(db *gorm.DB) *Outbox {
+	config := conf.Get()
+
+	outboxLock.Lock()
+	defer outboxLock.Unlock()
+	outbox = &Outbox{
+		config:         config,
+		db:             db,
+		sendingService: mustSendingService(config),
+		queue:          conf.GetQueue(conf.QueueMails),
+	}
+	return outbox
+}
+
+func getOutbox() *Outbox {
+	outboxLock.RLock()
+	defer outboxLock.RUnlock()
+	return outbox
+}
+
+func (o *Outbox) Schedule() {
+	slog.Info("scheduling mail outbox retries")
+	if _, err := o.queue.DispatchEvery(o.RetryPending, outboxRetryEvery); err != nil {
+		conf.Log().Error("failed to schedule mail outbox retries", "error", err)
+	}
+}
+
+func (o *Outbox) Enqueue(mail *models.Mail, sendErr error) error {
+	outboxMail, err := models.NewOutboxMail(mail, sendErr, time.Now())
+	if err != nil {
+		return err
+	}
+	return o.db.Create(outboxMail).Error
+}
+
+// RetryPending attempts to send all mails in the outbox that are due. Every process runs retries, so each mail is claimed
+// before sending it, to make sure only one of them does.
+func (o *Outbox) RetryPending() {
+	if ok := o.retryLock.TryLock(); !ok {
+		slog.Warn("mail outbox retries still running, skipping")
+		return
+	}
+	defer o.retryLock.Unlock()
+
+	var pending []*models.OutboxMail
+	if err := o.db.
+		Where("status = ? AND next_attempt_at <= ?", models.OutboxMailPending, time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(outboxBatchSize).
+		Find(&pending).Error; err != nil {
+		conf.Log().Error("failed to fetch pending mails from outbox", "error", err)
+		return
+	}
+
+	for _, outboxMail := range pending {
+		claimed, err := o.claim(outboxMail)
+		if err != nil {
+			conf.Log().Error("failed to claim mail in outbox", "id", outboxMail.ID, "error", err)
+			continue
+		}
+		if claimed {
+			o.retry(outboxMail)
+		}
+	}
+}
+
+// claim postpones the mail's next attempt, unless another process has done so in the meantime already
+func (o *Outbox) claim(outboxMail *models.OutboxMail) (bool, error) {
+	now := time.Now()
+	result := o.db.
+		Model(&models.OutboxMail{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", outboxMail.ID, models.OutboxMailPending, now).
+		Update("next_attempt_at", now.Add(outboxClaimTimeout))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (o *Outbox) retry(outboxMail *models.OutboxMail) {
+	mail, err := outboxMail.Mail()
+	if err == nil {
+		err = o.sendingService.Send(mail)
+	}
+
+	if err == nil {
+		if err := o.db.Delete(outboxMail).Error; err != nil {
+			conf.Log().Error("failed to remove sent mail from outbox", "id", outboxMail.ID, "error", err)
+		}
+		return
+	}
+
+	outboxMail.Failed(err, time.Now())
+	if outboxMail.Status == models.OutboxMailFailed {
+		conf.Log().Error("giving up on sending mail from outbox", "id", outboxMail.ID, "attempts", outboxMail.Attempts, "error", err)
+	}
+	if err := o.db.Save(outboxMail).Error; err != nil {
+		conf.Log().Error("failed to update mail in outbox", "id", outboxMail.ID, "error", err)
+	}
+}
+
+// OutboxSendingService sends mails through the given provider and puts failed ones into the outbox, if set up
+type OutboxSendingService struct {
+	sendingService SendingService
+}
+
+func NewOutboxSendingService(sendingService SendingService) *OutboxSendingService {
+	return &OutboxSendingService{sendingService: sendingService}
+}
+
+// Send returns nil once the mail was either sent or put into the outbox, as it'll be retried from there until it's sent or
+// given up on. Only if the outbox is unavailable, the original error is returned.
+func (s *OutboxSendingService) Send(mail *models.Mail) error {
+	mail = mail.Sanitized()
+
+	err := s.sendingService.Send(mail)
+	if err == nil {
+		return nil
+	}
+
+	outbox := getOutbox()
+	if outbox == nil {
+		return err
+	}
+	if outboxErr := outbox.Enqueue(mail, err); outboxErr != nil {
+		conf.Log().Error("failed to put mail into outbox", "to", mail.To.Strings(), "error", outboxErr)
+		return err
+	}
+	slog.Warn("failed to send mail, will retry from outbox", "to", mail.To.Strings(), "error", err)
+	return nil
+}
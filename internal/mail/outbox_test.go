@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type countingSendingService struct {
+	sent atomic.Int32
+}
+
+func (s *countingSendingService) Send(*models.Mail) error {
+	s.sent.Add(1)
+	return nil
+}
+
+func TestOutbox_RetryPending_Claimed(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&models.OutboxMail{}))
+
+	outboxMail, err := models.NewOutboxMail(&models.Mail{To: models.MailAddresses{"john@example.org"}, Subject: "Hi"}, errors.New("timeout"), time.Now().Add(-1*time.Hour))
+	assert.Nil(t, err)
+	assert.Nil(t, db.Create(outboxMail).Error)
+
+	// e.g. two replicas fetching the same due mail
+	sendingService := &countingSendingService{}
+	first, second := &Outbox{db: db, sendingService: sendingService}, &Outbox{db: db, sendingService: sendingService}
+	claimedFirst, err := first.claim(outboxMail)
+	assert.Nil(t, err)
+	claimedSecond, err := second.claim(outboxMail)
+	assert.Nil(t, err)
+	assert.True(t, claimedFirst)
+	assert.False(t, claimedSecond)
+
+	first.retry(outboxMail)
+	second.RetryPending()
+	assert.Equal(t, int32(1), sendingService.sent.Load())
+
+	var count int64
+	assert.Nil(t, db.Model(&models.OutboxMail{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+// ProviderFactory creates the sending service of a mail provider from the application's config
+type ProviderFactory func(config *conf.Config) (SendingService, error)
+
+var (
+	providers     = make(map[string]ProviderFactory)
+	providersLock = sync.RWMutex{}
+)
+
+func init() {
+	RegisterProvider(conf.MailProviderSmtp, func(config *conf.Config) (SendingService, error) {
+		return NewSMTPSendingService(config.Mail.Smtp), nil
+	})
+	RegisterProvider(conf.MailProviderFile, func(config *conf.Config) (SendingService, error) {
+		return NewFileSendingService(config.Mail.File)
+	})
+	RegisterProvider(conf.MailProviderHttp, func(config *conf.Config) (SendingService, error) {
+		return NewHTTPSendingService(config.Mail.Http)
+	})
+}
+
+// RegisterProvider makes a mail provider available under the given name, replacing any provider registered before
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+	providers[name] = factory
+}
+
+func getProvider(name string) (ProviderFactory, bool) {
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+	factory, ok := providers[name]
+	return factory, ok
+}
+
+// NewSendingService creates the sending service of the configured provider, or one that does nothing if mails are disabled
+func NewSendingService(config *conf.Config) (SendingService, error) {
+	if !config.Mail.Enabled || config.Mail.Provider == "" {
+		return &NoopSendingService{}, nil
+	}
+
+	factory, ok := getProvider(config.Mail.Provider)
+	if !ok {
+		return nil, fmt.Errorf("unknown mail provider '%s'", config.Mail.Provider)
+	}
+	return factory(config)
+}
+
+func mustSendingService(config *conf.Config) SendingService {
+	sendingService, err := NewSendingService(config)
+	if err != nil {
+		slog.Error("failed to set up mail provider, falling back to not sending mails", "provider", config.Mail.Provider, "error", err)
+		return &NoopSendingService{}
+	}
+	return sendingService
+}
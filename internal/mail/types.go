@@ -28,8 +28,51 @@ type ReportTplData struct {
 	Report *models.Report
 }
 
+type WrappedReportTplData struct {
+	Report   *models.WrappedReport
+	CardLink string // shareable svg card
+}
+
 type SubscriptionNotificationTplData struct {
 	PublicUrl           string
 	HasExpired          bool
 	DataRetentionMonths int
 }
+
+type OrganizationInvitationTplData struct {
+	InvitationLink   string
+	OrganizationName string
+	InviterName      string
+	Role             string
+	ExpiryTime       string
+}
+
+type GoalNotificationTplData struct {
+	PublicUrl     string
+	GoalTitle     string
+	Achieved      bool
+	IsLimit       bool
+	Period        string
+	ActualTime    string
+	TargetTime    string
+	CurrentStreak int
+	LongestStreak int
+}
+
+type InvoiceTplData struct {
+	SenderName    string
+	ContactName   string
+	InvoiceNumber string
+	Total         string
+	DueDate       string
+	Message       string
+	ReplyTo       bool
+}
+
+type InvoiceGeneratedTplData struct {
+	PublicUrl     string
+	InvoiceNumber string
+	ClientName    string
+	Period        string
+	Total         string
+}
@@ -0,0 +1,266 @@
+// Package pdf implements just enough of the PDF format to render simple, text-based documents like invoices on the server.
+// Only the standard Helvetica fonts are supported, which every PDF reader ships with, so nothing needs to be embedded.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// A4 in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+func (f Font) resourceName() string {
+	if f == HelveticaBold {
+		return "F2"
+	}
+	return "F1"
+}
+
+type Document struct {
+	title   string
+	created time.Time
+	pages   []*bytes.Buffer
+}
+
+func NewDocument(title string) *Document {
+	return &Document{title: title, created: time.Now()}
+}
+
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *Document) NumPages() int {
+	return len(d.pages)
+}
+
+// Text draws a single line of text with its baseline at y, measured from the top of the page
+func (d *Document) Text(x, y float64, font Font, size float64, gray float64, text string) {
+	fmt.Fprintf(d.page(), "BT %.3f g /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", gray, font.resourceName(), size, x, PageHeight-y, escape(encode(text)))
+}
+
+// TextRight draws a single line of text ending at x
+func (d *Document) TextRight(x, y float64, font Font, size float64, gray float64, text string) {
+	d.Text(x-TextWidth(font, size, text), y, font, size, gray, text)
+}
+
+func (d *Document) Line(x1, y1, x2, y2, width float64, gray float64) {
+	fmt.Fprintf(d.page(), "%.3f G %.2f w %.2f %.2f m %.2f %.2f l S\n", gray, width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// Rect draws a filled rectangle with its upper left corner at (x, y)
+func (d *Document) Rect(x, y, w, h float64, gray float64) {
+	fmt.Fprintf(d.page(), "%.3f g %.2f %.2f %.2f %.2f re f\n", gray, x, PageHeight-y-h, w, h)
+}
+
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	var offsets []int64
+
+	beginObject := func() int {
+		offsets = append(offsets, cw.n)
+		fmt.Fprintf(cw, "%d 0 obj\n", len(offsets))
+		return len(offsets)
+	}
+	endObject := func() {
+		fmt.Fprint(cw, "endobj\n")
+	}
+
+	// object numbers are fixed for the document-wide objects, pages follow with two objects each (page and content stream)
+	const catalogObj, pagesObj, fontObj, fontBoldObj, infoObj, firstPageObj = 1, 2, 3, 4, 5, 6
+
+	fmt.Fprint(cw, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	beginObject()
+	fmt.Fprintf(cw, "<< /Type /Catalog /Pages %d 0 R >>\n", pagesObj)
+	endObject()
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObj+2*i)
+	}
+	beginObject()
+	fmt.Fprintf(cw, "<< /Type /Pages /Kids [%s] /Count %d >>\n", strings.Join(kids, " "), len(d.pages))
+	endObject()
+
+	for _, name := range []string{"Helvetica", "Helvetica-Bold"} {
+		beginObject()
+		fmt.Fprintf(cw, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\n", name)
+		endObject()
+	}
+
+	beginObject()
+	fmt.Fprintf(cw, "<< /Title (%s) /Producer (Wakapi) /CreationDate (D:%s) >>\n", escape(encode(d.title)), d.created.UTC().Format("20060102150405Z"))
+	endObject()
+
+	for _, content := range d.pages {
+		pageObj := beginObject()
+		fmt.Fprintf(cw, "<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>\n",
+			pagesObj, PageWidth, PageHeight, fontObj, fontBoldObj, pageObj+1)
+		endObject()
+
+		beginObject()
+		fmt.Fprintf(cw, "<< /Length %d >>\nstream\n", content.Len())
+		cw.Write(content.Bytes())
+		fmt.Fprint(cw, "\nendstream\n")
+		endObject()
+	}
+
+	xrefOffset := cw.n
+	fmt.Fprintf(cw, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(cw, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(cw, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, catalogObj, infoObj, xrefOffset)
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, bw.Flush()
+}
+
+func (d *Document) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// TextWidth returns the width of the given text in points
+func TextWidth(font Font, size float64, text string) float64 {
+	widths := helveticaWidths
+	if font == HelveticaBold {
+		widths = helveticaBoldWidths
+	}
+
+	var total int
+	for _, c := range encode(text) {
+		if c >= 32 && c <= 126 {
+			total += widths[c-32]
+		} else {
+			total += 556 // roughly the average width of the remaining characters
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// WrapText breaks the text into lines no wider than maxWidth, keeping explicit line breaks
+func WrapText(font Font, size float64, text string, maxWidth float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		line := words[0]
+		for _, word := range words[1:] {
+			if TextWidth(font, size, line+" "+word) > maxWidth {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			line += " " + word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// encode converts utf-8 text to windows-1252 (which WinAnsiEncoding essentially is), replacing characters outside of it
+func encode(text string) []byte {
+	result := make([]byte, 0, len(text))
+	for len(text) > 0 {
+		r, size := utf8.DecodeRuneInString(text)
+		text = text[size:]
+
+		switch {
+		case r == '\t':
+			result = append(result, ' ')
+		case r < 32:
+			continue
+		case r < 127, r >= 0xa0 && r <= 0xff:
+			result = append(result, byte(r))
+		default:
+			if b, ok := winAnsiSpecials[r]; ok {
+				result = append(result, b)
+			} else {
+				result = append(result, '?')
+			}
+		}
+	}
+	return result
+}
+
+func escape(text []byte) string {
+	var sb strings.Builder
+	for _, c := range text {
+		if c == '(' || c == ')' || c == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
+
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// glyph widths of printable ascii characters (32 - 126) in thousandths of the font size, taken from the adobe font metrics
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [...]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
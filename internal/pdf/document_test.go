@@ -0,0 +1,102 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocument_WriteTo(t *testing.T) {
+	doc := NewDocument("Test (1)")
+	doc.Text(50, 50, Helvetica, 12, 0, "Hello (World) \\ Grüße 10€")
+	doc.AddPage()
+	doc.Line(50, 50, 100, 50, 1, 0)
+
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	assert.Nil(t, err)
+
+	out := buf.Bytes()
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+	assert.Contains(t, buf.String(), "/Count 2")
+	assert.Contains(t, buf.String(), "(Hello \\(World\\) \\\\ Gr\xfc\xdfe 10\x80) Tj")
+	assert.Contains(t, buf.String(), "/Title (Test \\(1\\))")
+
+	assertValidXref(t, out)
+}
+
+func TestTextWidth(t *testing.T) {
+	assert.InDelta(t, 5.56, TextWidth(Helvetica, 10, "  "), 0.001)
+	assert.InDelta(t, 19.45, TextWidth(Helvetica, 10, "Tax "), 0.001) // 611 + 556 + 500 + 278
+	assert.Greater(t, TextWidth(HelveticaBold, 10, "Total"), TextWidth(Helvetica, 10, "Total"))
+}
+
+func TestWrapText(t *testing.T) {
+	lines := WrapText(Helvetica, 10, "lorem ipsum dolor sit amet\nconsectetur", TextWidth(Helvetica, 10, "lorem ipsum dolor"))
+	assert.Equal(t, []string{"lorem ipsum dolor", "sit amet", "consectetur"}, lines)
+	assert.Equal(t, []string{"a", "", "b"}, WrapText(Helvetica, 10, "a\n\nb", 100))
+}
+
+func TestRenderInvoice(t *testing.T) {
+	due := time.Date(2026, 11, 16, 0, 0, 0, 0, time.UTC)
+	invoice := &models.Invoice{
+		InvoiceID:    "INV-0042",
+		Status:       models.InvoiceStatusSent,
+		Origin:       "Jane Doe\nSome Street 1",
+		Destination:  "ACME Inc.",
+		Heading:      "Thanks for your business",
+		FinalMessage: "Please transfer the amount due within 30 days.",
+		Tax:          19,
+		DueDate:      &due,
+		StartDate:    time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:      time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC),
+		Client:       models.Client{Currency: "EUR", HourlyRate: 1200},
+		Payments:     []*models.InvoicePayment{{Amount: 500, Method: "bank transfer", PaidAt: due}},
+	}
+	for i := 0; i < 80; i++ {
+		invoice.LineItems = append(invoice.LineItems, models.InvoiceLineItem{Title: fmt.Sprintf("project-%d", i), TotalSeconds: 3600})
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, RenderInvoice(&buf, invoice))
+
+	out := buf.String()
+	assert.Contains(t, out, "(INV-0042) Tj")
+	assert.Contains(t, out, "(Nov 16, 2026) Tj")
+	assert.Contains(t, out, "(EUR 114,240.00) Tj") // 80 * 1200 * 1.19
+	assert.Contains(t, out, "(EUR 113,740.00) Tj")
+	assert.Contains(t, out, "(project-79) Tj")
+	assert.Regexp(t, regexp.MustCompile(`/Count [2-9]`), out) // line items span multiple pages
+
+	assertValidXref(t, buf.Bytes())
+}
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "0.00", formatAmount(0))
+	assert.Equal(t, "999.50", formatAmount(999.5))
+	assert.Equal(t, "1,000.00", formatAmount(1000))
+	assert.Equal(t, "1,234,567.89", formatAmount(1234567.891))
+	assert.Equal(t, "-12,000.00", formatAmount(-12000))
+}
+
+// assertValidXref checks that every entry of the cross-reference table points to the beginning of its object
+func assertValidXref(t *testing.T, out []byte) {
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	assert.NotNil(t, startxref)
+	offset, _ := strconv.Atoi(string(startxref[1]))
+	assert.True(t, bytes.HasPrefix(out[offset:], []byte("xref\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[offset:], -1)
+	assert.NotEmpty(t, entries)
+	for i, entry := range entries {
+		objOffset, _ := strconv.Atoi(string(entry[1]))
+		assert.True(t, bytes.HasPrefix(out[objOffset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+	}
+}
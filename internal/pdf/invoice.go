@@ -0,0 +1,267 @@
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/muety/wakapi/models"
+)
+
+// layout roughly follows the invoice pdf rendered by the frontend
+const (
+	invoiceMargin       = 48.0
+	invoiceBottomMargin = 64.0
+	invoiceContentWidth = PageWidth - 2*invoiceMargin
+	invoiceFontSize     = 10.0
+	invoiceLineHeight   = 15.0
+	invoiceDateFormat   = "Jan 02, 2006"
+	invoiceMutedGray    = 0.4
+	invoiceLineGray     = 0.85
+)
+
+// table columns as (right-aligned) x positions, the first one being left-aligned
+var invoiceColumns = [...]float64{
+	invoiceMargin,
+	invoiceMargin + invoiceContentWidth*0.6,
+	invoiceMargin + invoiceContentWidth*0.8,
+	invoiceMargin + invoiceContentWidth,
+}
+
+type invoiceRenderer struct {
+	doc     *Document
+	invoice *models.Invoice
+	y       float64
+}
+
+// RenderInvoice writes the invoice, including its payments, as a pdf document. Client and payments are expected to be loaded.
+func RenderInvoice(w io.Writer, invoice *models.Invoice) error {
+	r := &invoiceRenderer{
+		doc:     NewDocument(fmt.Sprintf("Invoice %s", invoice.InvoiceID)),
+		invoice: invoice,
+	}
+	r.newPage()
+
+	r.header()
+	r.parties()
+	r.paragraph(invoice.Heading)
+	r.lineItems()
+	r.totals()
+	r.payments()
+	r.paragraph(invoice.FinalMessage)
+
+	_, err := r.doc.WriteTo(w)
+	return err
+}
+
+func (r *invoiceRenderer) newPage() {
+	r.doc.AddPage()
+	r.y = invoiceMargin
+}
+
+// ensureSpace starts a new page unless the given height still fits onto the current one
+func (r *invoiceRenderer) ensureSpace(height float64) bool {
+	if r.y+height <= PageHeight-invoiceBottomMargin {
+		return false
+	}
+	r.newPage()
+	return true
+}
+
+func (r *invoiceRenderer) header() {
+	inv := r.invoice
+
+	r.y += 22
+	r.doc.Text(invoiceMargin, r.y, HelveticaBold, 22, 0, "INVOICE")
+	if inv.Status == models.InvoiceStatusVoid {
+		r.doc.TextRight(invoiceMargin+invoiceContentWidth, r.y, HelveticaBold, 22, invoiceMutedGray, "VOID")
+	}
+
+	for _, line := range WrapText(Helvetica, 11, inv.InvoiceSummary, invoiceContentWidth) {
+		r.y += 16
+		r.doc.Text(invoiceMargin, r.y, Helvetica, 11, invoiceMutedGray, line)
+	}
+	r.y += 32
+}
+
+func (r *invoiceRenderer) parties() {
+	inv := r.invoice
+	top := r.y
+
+	// left column: sender and recipient
+	r.label(invoiceMargin, "FROM")
+	r.multiline(invoiceMargin, invoiceContentWidth/2, inv.Origin)
+	r.y += 16
+	r.label(invoiceMargin, "BILL TO")
+	r.multiline(invoiceMargin, invoiceContentWidth/2, inv.Destination)
+	left := r.y
+
+	// right column: number and dates
+	right := invoiceMargin + invoiceContentWidth
+	r.y = top
+	facts := [][2]string{
+		{"INVOICE", inv.InvoiceID},
+		{"DATE", formatInvoiceDate(invoiceIssueDate(inv))},
+	}
+	if inv.DueDate != nil {
+		facts = append(facts, [2]string{"DUE DATE", formatInvoiceDate(*inv.DueDate)})
+	}
+	facts = append(facts, [2]string{"PERIOD", fmt.Sprintf("%s - %s", formatInvoiceDate(inv.StartDate), formatInvoiceDate(inv.EndDate))})
+	for _, fact := range facts {
+		r.y += invoiceFontSize
+		r.doc.TextRight(right, r.y, HelveticaBold, invoiceFontSize, 0, fact[0])
+		r.y += invoiceLineHeight
+		r.doc.TextRight(right, r.y, Helvetica, invoiceFontSize, 0, fact[1])
+		r.y += 12
+	}
+
+	r.y = max(left, r.y) + 24
+}
+
+func (r *invoiceRenderer) lineItems() {
+	currency := r.invoice.CurrencyCode()
+
+	r.tableHeader(currency)
+	for _, item := range r.invoice.LineItems {
+		lines := WrapText(Helvetica, invoiceFontSize, item.Title, invoiceColumns[1]-invoiceColumns[0]-96)
+		height := float64(len(lines))*invoiceLineHeight + 10
+		if item.IsRounded() {
+			height += invoiceLineHeight
+		}
+		if r.ensureSpace(height) {
+			r.tableHeader(currency)
+		}
+
+		r.y += invoiceLineHeight
+		r.doc.TextRight(invoiceColumns[1], r.y, Helvetica, invoiceFontSize, 0, formatAmount(item.RateOr(r.invoice.Client.HourlyRate)))
+		r.doc.TextRight(invoiceColumns[2], r.y, Helvetica, invoiceFontSize, 0, fmt.Sprintf("%.2f", item.Hours()))
+		r.doc.TextRight(invoiceColumns[3], r.y, Helvetica, invoiceFontSize, 0, formatAmount(item.AmountOr(r.invoice.Client.HourlyRate)))
+		for i, line := range lines {
+			if i > 0 {
+				r.y += invoiceLineHeight
+			}
+			r.doc.Text(invoiceColumns[0], r.y, Helvetica, invoiceFontSize, 0, line)
+		}
+		if item.IsRounded() {
+			r.y += invoiceLineHeight
+			r.doc.Text(invoiceColumns[0], r.y, Helvetica, invoiceFontSize-1, invoiceMutedGray, fmt.Sprintf("%.2f hrs tracked", item.TrackedHours()))
+		}
+		r.y += 8
+		r.doc.Line(invoiceMargin, r.y, invoiceMargin+invoiceContentWidth, r.y, 0.5, invoiceLineGray)
+	}
+	r.y += 16
+}
+
+func (r *invoiceRenderer) tableHeader(currency string) {
+	r.doc.Rect(invoiceMargin, r.y, invoiceContentWidth, 22, 0.95)
+	r.y += 14.5
+	r.doc.Text(invoiceColumns[0]+6, r.y, HelveticaBold, 9, 0, "Item")
+	r.doc.TextRight(invoiceColumns[1], r.y, HelveticaBold, 9, 0, fmt.Sprintf("Price (%s)", currency))
+	r.doc.TextRight(invoiceColumns[2], r.y, HelveticaBold, 9, 0, "Qty (Hrs)")
+	r.doc.TextRight(invoiceColumns[3]-6, r.y, HelveticaBold, 9, 0, fmt.Sprintf("Amount (%s)", currency))
+	r.y += 7.5
+}
+
+func (r *invoiceRenderer) totals() {
+	inv := r.invoice
+
+	rows := [][2]string{
+		{"Total hours", fmt.Sprintf("%.2f", inv.TotalHours())},
+		{"Subtotal", formatAmount(inv.Subtotal())},
+	}
+	if !inv.ExcludeTax && inv.Tax > 0 {
+		rows = append(rows, [2]string{fmt.Sprintf("Tax (%s%%)", strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", inv.Tax), "0"), ".")), formatAmount(inv.TaxAmount())})
+	}
+
+	r.ensureSpace(float64(len(rows)+2) * invoiceLineHeight)
+	for _, row := range rows {
+		r.summaryRow(row[0], row[1], Helvetica)
+	}
+	r.y += 6
+	r.doc.Line(invoiceColumns[1]-60, r.y, invoiceMargin+invoiceContentWidth, r.y, 0.5, 0)
+	r.summaryRow("Total", fmt.Sprintf("%s %s", inv.CurrencyCode(), formatAmount(inv.Total())), HelveticaBold)
+	r.y += 16
+}
+
+func (r *invoiceRenderer) payments() {
+	inv := r.invoice
+	if len(inv.Payments) == 0 {
+		return
+	}
+
+	r.ensureSpace(float64(len(inv.Payments)+3) * invoiceLineHeight)
+	for _, p := range inv.Payments {
+		label := fmt.Sprintf("Payment %s", formatInvoiceDate(p.PaidAt))
+		if p.Method != "" {
+			label = fmt.Sprintf("%s (%s)", label, p.Method)
+		}
+		r.summaryRow(label, "-"+formatAmount(p.Amount), Helvetica)
+	}
+	r.y += 6
+	r.doc.Line(invoiceColumns[1]-60, r.y, invoiceMargin+invoiceContentWidth, r.y, 0.5, 0)
+	r.summaryRow("Amount due", fmt.Sprintf("%s %s", inv.CurrencyCode(), formatAmount(inv.AmountDue())), HelveticaBold)
+	r.y += 16
+}
+
+func (r *invoiceRenderer) summaryRow(label, value string, font Font) {
+	r.y += invoiceLineHeight
+	r.doc.TextRight(invoiceColumns[2], r.y, font, invoiceFontSize, 0, label)
+	r.doc.TextRight(invoiceColumns[3], r.y, font, invoiceFontSize, 0, value)
+}
+
+func (r *invoiceRenderer) paragraph(text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	for _, line := range WrapText(Helvetica, invoiceFontSize, strings.TrimSpace(text), invoiceContentWidth) {
+		r.ensureSpace(invoiceLineHeight)
+		r.y += invoiceLineHeight
+		r.doc.Text(invoiceMargin, r.y, Helvetica, invoiceFontSize, 0.2, line)
+	}
+	r.y += 16
+}
+
+func (r *invoiceRenderer) label(x float64, text string) {
+	r.y += invoiceFontSize
+	r.doc.Text(x, r.y, HelveticaBold, invoiceFontSize, 0, text)
+	r.y += 6
+}
+
+func (r *invoiceRenderer) multiline(x, width float64, text string) {
+	for _, line := range WrapText(Helvetica, invoiceFontSize, strings.TrimSpace(text), width) {
+		r.y += invoiceLineHeight
+		r.doc.Text(x, r.y, Helvetica, invoiceFontSize, 0, line)
+	}
+}
+
+// invoiceIssueDate is when the invoice was sent, falling back to its creation for drafts
+func invoiceIssueDate(invoice *models.Invoice) time.Time {
+	if invoice.SentAt != nil {
+		return *invoice.SentAt
+	}
+	return invoice.CreatedAt.T()
+}
+
+func formatInvoiceDate(t time.Time) string {
+	return t.Format(invoiceDateFormat)
+}
+
+// formatAmount renders a monetary amount with two decimals and thousands separators, e.g. 12,345.67
+func formatAmount(amount float64) string {
+	s := fmt.Sprintf("%.2f", amount)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	var sb strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(c)
+	}
+	return fmt.Sprintf("%s%s.%s", sign, sb.String(), fraction)
+}
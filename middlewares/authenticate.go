@@ -130,6 +130,12 @@ func (m *AuthenticateMiddleware) tryGetUserByApiKeyHeader(r *http.Request) (*mod
 
 	var user *models.User
 	userKey := strings.TrimSpace(key)
+	if strings.HasPrefix(userKey, models.ApiTokenPrefix) {
+		return m.tryGetUserByApiToken(r, userKey)
+	}
+	if strings.HasPrefix(userKey, models.MachineKeyPrefix) {
+		return m.tryGetUserByMachineKey(r, userKey)
+	}
 	user, err = m.userSrvc.GetUserByKey(userKey)
 	if err != nil {
 		return nil, err
@@ -143,16 +149,7 @@ func (m *AuthenticateMiddleware) tryGetUserByTokenHeader(r *http.Request) (*mode
 	if token == "" {
 		return nil, errors.New("failed to extract API Token from header")
 	}
-	userId, err := utils.ExtractUserIDFromAuthToken(token, m.config.Security.JWT_SECRET)
-	if err != nil {
-		return nil, err
-	}
-
-	user, err := m.userSrvc.GetUserById(userId)
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
+	return m.tryGetUserByLoginToken(r, token)
 }
 
 func (m *AuthenticateMiddleware) tryGetUserByTokenKeyQuery(r *http.Request) (*models.User, error) {
@@ -161,16 +158,28 @@ func (m *AuthenticateMiddleware) tryGetUserByTokenKeyQuery(r *http.Request) (*mo
 	if token == "" {
 		return nil, errEmptyKey
 	}
+	return m.tryGetUserByLoginToken(r, token)
+}
 
-	userId, err := utils.ExtractUserIDFromAuthToken(token, m.config.Security.JWT_SECRET)
+// tryGetUserByLoginToken authenticates by a jwt issued upon login, which is only valid as long as its session isn't revoked
+func (m *AuthenticateMiddleware) tryGetUserByLoginToken(r *http.Request, token string) (*models.User, error) {
+	claims, err := utils.GetTokenClaims(token, m.config.Security.JWT_SECRET)
 	if err != nil {
 		return nil, err
 	}
+	if claims.JTI == "" {
+		// tokens from before sessions were introduced can't be revoked, so they're rejected altogether (see readme)
+		return nil, errors.New("token was issued without a session")
+	}
 
-	user, err := m.userSrvc.GetUserById(userId)
+	user, session, err := m.userSrvc.GetUserBySession(claims.JTI, utils.ReadRemoteIP(r, m.config.Security.TrustReverseProxyIPs()))
 	if err != nil {
 		return nil, err
 	}
+	if user.ID != claims.UID {
+		return nil, errors.New("token was issued for a different user")
+	}
+	SetPrincipalSession(r, session)
 	return user, nil
 }
 
@@ -181,6 +190,12 @@ func (m *AuthenticateMiddleware) tryGetUserByApiKeyQuery(r *http.Request) (*mode
 	if userKey == "" {
 		return nil, errEmptyKey
 	}
+	if strings.HasPrefix(userKey, models.ApiTokenPrefix) {
+		return m.tryGetUserByApiToken(r, userKey)
+	}
+	if strings.HasPrefix(userKey, models.MachineKeyPrefix) {
+		return m.tryGetUserByMachineKey(r, userKey)
+	}
 	user, err := m.userSrvc.GetUserByKey(userKey)
 	if err != nil {
 		return nil, err
@@ -188,6 +203,28 @@ func (m *AuthenticateMiddleware) tryGetUserByApiKeyQuery(r *http.Request) (*mode
 	return user, nil
 }
 
+// tryGetUserByApiToken authenticates by a personal access token, which is remembered to restrict the request to the token's scopes
+func (m *AuthenticateMiddleware) tryGetUserByApiToken(r *http.Request, key string) (*models.User, error) {
+	user, token, err := m.userSrvc.GetUserByApiToken(key, utils.ReadRemoteIP(r, m.config.Security.TrustReverseProxyIPs()))
+	if err != nil {
+		return nil, err
+	}
+	SetPrincipalToken(r, token)
+	return user, nil
+}
+
+// tryGetUserByMachineKey authenticates by a machine key, which restricts the request to sending heartbeats and reading stats
+// and pins the machine name of the heartbeats
+func (m *AuthenticateMiddleware) tryGetUserByMachineKey(r *http.Request, key string) (*models.User, error) {
+	user, machine, err := m.userSrvc.GetUserByMachineKey(key)
+	if err != nil {
+		return nil, err
+	}
+	SetPrincipalToken(r, machine.ApiToken())
+	SetPrincipalMachine(r, machine)
+	return user, nil
+}
+
 func (m *AuthenticateMiddleware) tryGetUserByTrustedHeader(r *http.Request) (*models.User, error) {
 	if !m.config.Security.TrustedHeaderAuth {
 		return nil, errors.New("trusted header auth disabled")
@@ -1,13 +1,18 @@
 package middlewares
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/muety/wakapi/config"
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt"
+	"github.com/muety/wakapi/helpers"
 	"github.com/muety/wakapi/mocks"
 	"github.com/muety/wakapi/models"
 	"github.com/stretchr/testify/assert"
@@ -56,6 +61,34 @@ func TestAuthenticateMiddleware_tryGetUserByApiKeyHeader_Invalid(t *testing.T) {
 	assert.Nil(t, result)
 }
 
+func TestAuthenticateMiddleware_tryGetUserByApiKeyHeader_ApiToken(t *testing.T) {
+	config.Set(config.Empty())
+
+	testApiToken := models.ApiTokenPrefix + "0a1b2c3d4e5f"
+	testToken := base64.StdEncoding.EncodeToString([]byte(testApiToken))
+	testUser := &models.User{ID: "user01"}
+	testTokenModel := &models.ApiToken{UserID: testUser.ID, Scopes: []string{"heartbeats:write"}}
+
+	mockRequest := (&http.Request{
+		Header: http.Header{
+			"Authorization": []string{fmt.Sprintf("Bearer %s", testToken)},
+		},
+		RemoteAddr: "10.0.0.1:54654",
+	}).WithContext(context.WithValue(context.Background(), keyPrincipal, &PrincipalContainer{}))
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByApiToken", testApiToken, "10.0.0.1").Return(testUser, testTokenModel, nil)
+
+	sut := NewAuthenticateMiddleware(userServiceMock)
+
+	result, err := sut.tryGetUserByApiKeyHeader(mockRequest)
+
+	assert.Nil(t, err)
+	assert.Equal(t, testUser, result)
+	assert.Equal(t, testTokenModel, GetPrincipalToken(mockRequest))
+	userServiceMock.AssertNotCalled(t, "GetUserByKey", testApiToken)
+}
+
 func TestAuthenticateMiddleware_tryGetUserByApiKeyQuery_Success(t *testing.T) {
 	testApiKey := "z5uig69cn9ut93n"
 	testUser := &models.User{ApiKey: testApiKey}
@@ -185,4 +218,80 @@ func TestAuthenticateMiddleware_tryGetUserByTrustedHeader_Success(t *testing.T)
 	}
 }
 
+func TestAuthenticateMiddleware_tryGetUserByTokenHeader_Session(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Security.JWT_SECRET = "secret"
+	config.Set(cfg)
+
+	testUser := &models.User{ID: "user01"}
+	testSession := &models.Session{ID: "session01", UserID: testUser.ID, ExpiresAt: models.CustomTime(time.Now().Add(time.Hour))}
+	testToken, _ := helpers.MakeLoginJWT(testSession, cfg)
+
+	mockRequest := (&http.Request{
+		Header:     http.Header{"Token": []string{testToken}},
+		RemoteAddr: "10.0.0.1:54654",
+	}).WithContext(context.WithValue(context.Background(), keyPrincipal, &PrincipalContainer{}))
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserBySession", testSession.ID, "10.0.0.1").Return(testUser, testSession, nil)
+
+	sut := NewAuthenticateMiddleware(userServiceMock)
+
+	result, err := sut.tryGetUserByTokenHeader(mockRequest)
+
+	assert.Nil(t, err)
+	assert.Equal(t, testUser, result)
+	assert.Equal(t, testSession, GetPrincipalSession(mockRequest))
+}
+
+func TestAuthenticateMiddleware_tryGetUserByTokenHeader_Revoked(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Security.JWT_SECRET = "secret"
+	config.Set(cfg)
+
+	testSession := &models.Session{ID: "session01", UserID: "user01", ExpiresAt: models.CustomTime(time.Now().Add(time.Hour))}
+	testToken, _ := helpers.MakeLoginJWT(testSession, cfg)
+
+	mockRequest := &http.Request{
+		Header:     http.Header{"Token": []string{testToken}},
+		RemoteAddr: "10.0.0.1:54654",
+	}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserBySession", testSession.ID, "10.0.0.1").Return((*models.User)(nil), (*models.Session)(nil), errors.New("session is revoked or expired"))
+
+	sut := NewAuthenticateMiddleware(userServiceMock)
+
+	result, err := sut.tryGetUserByTokenHeader(mockRequest)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestAuthenticateMiddleware_tryGetUserByTokenHeader_WithoutSession(t *testing.T) {
+	cfg := config.Empty()
+	cfg.Security.JWT_SECRET = "secret"
+	config.Set(cfg)
+
+	// tokens issued before sessions were introduced can't be revoked and are therefore no longer accepted
+	testToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"uid": "user01",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(cfg.Security.JWT_SECRET))
+
+	mockRequest := &http.Request{
+		Header: http.Header{"Token": []string{testToken}},
+	}
+
+	userServiceMock := new(mocks.UserServiceMock)
+
+	sut := NewAuthenticateMiddleware(userServiceMock)
+
+	result, err := sut.tryGetUserByTokenHeader(mockRequest)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	userServiceMock.AssertNotCalled(t, "GetUserById", "user01")
+}
+
 // TODO: somehow test cookie auth function
@@ -10,6 +10,9 @@ const keyPrincipal = "principal"
 
 type PrincipalContainer struct {
 	principal *models.User
+	token     *models.ApiToken // set if authenticated by a personal access token or machine key
+	machine   *models.Machine  // set if authenticated by a machine key
+	session   *models.Session  // set if authenticated by a login token
 }
 
 func (c *PrincipalContainer) SetPrincipal(user *models.User) {
@@ -20,6 +23,30 @@ func (c *PrincipalContainer) GetPrincipal() *models.User {
 	return c.principal
 }
 
+func (c *PrincipalContainer) SetPrincipalToken(token *models.ApiToken) {
+	c.token = token
+}
+
+func (c *PrincipalContainer) GetPrincipalToken() *models.ApiToken {
+	return c.token
+}
+
+func (c *PrincipalContainer) SetPrincipalMachine(machine *models.Machine) {
+	c.machine = machine
+}
+
+func (c *PrincipalContainer) GetPrincipalMachine() *models.Machine {
+	return c.machine
+}
+
+func (c *PrincipalContainer) SetPrincipalSession(session *models.Session) {
+	c.session = session
+}
+
+func (c *PrincipalContainer) GetPrincipalSession() *models.Session {
+	return c.session
+}
+
 func (c *PrincipalContainer) GetPrincipalIdentity() string {
 	if c.principal == nil {
 		return ""
@@ -69,3 +96,45 @@ func GetPrincipal(r *http.Request) *models.User {
 	}
 	return nil
 }
+
+func SetPrincipalToken(r *http.Request, token *models.ApiToken) {
+	if p := r.Context().Value(keyPrincipal); p != nil {
+		p.(*PrincipalContainer).SetPrincipalToken(token)
+	}
+}
+
+// GetPrincipalToken returns the personal access token (or a machine key's equivalent) the request was authenticated by, if any
+func GetPrincipalToken(r *http.Request) *models.ApiToken {
+	if p := r.Context().Value(keyPrincipal); p != nil {
+		return p.(*PrincipalContainer).GetPrincipalToken()
+	}
+	return nil
+}
+
+func SetPrincipalMachine(r *http.Request, machine *models.Machine) {
+	if p := r.Context().Value(keyPrincipal); p != nil {
+		p.(*PrincipalContainer).SetPrincipalMachine(machine)
+	}
+}
+
+// GetPrincipalMachine returns the machine whose key the request was authenticated by, if any
+func GetPrincipalMachine(r *http.Request) *models.Machine {
+	if p := r.Context().Value(keyPrincipal); p != nil {
+		return p.(*PrincipalContainer).GetPrincipalMachine()
+	}
+	return nil
+}
+
+func SetPrincipalSession(r *http.Request, session *models.Session) {
+	if p := r.Context().Value(keyPrincipal); p != nil {
+		p.(*PrincipalContainer).SetPrincipalSession(session)
+	}
+}
+
+// GetPrincipalSession returns the login session the request was authenticated by, if any
+func GetPrincipalSession(r *http.Request) *models.Session {
+	if p := r.Context().Value(keyPrincipal); p != nil {
+		return p.(*PrincipalContainer).GetPrincipalSession()
+	}
+	return nil
+}
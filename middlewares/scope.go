@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+)
+
+// ScopeMiddleware restricts requests authenticated by a personal access token to the resources the token is scoped to,
+// requiring read access for safe methods and write access otherwise. Requests authenticated otherwise (or not at all)
+// pass, so it has to be placed after the authentication middleware.
+type ScopeMiddleware struct {
+	handler  http.Handler
+	resource string
+}
+
+func NewScopeMiddleware(resource string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &ScopeMiddleware{
+			handler:  h,
+			resource: resource,
+		}
+	}
+}
+
+func (m *ScopeMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if token := GetPrincipalToken(r); token != nil && !token.Grants(m.resource, models.ApiTokenActionOf(r.Method)) {
+		helpers.RespondJSON(w, r, http.StatusForbidden, map[string]string{
+			"error": "403 forbidden: api token lacks scope " + m.requiredScope(r),
+		})
+		return
+	}
+	m.handler.ServeHTTP(w, r)
+}
+
+func (m *ScopeMiddleware) requiredScope(r *http.Request) string {
+	if m.resource == models.ApiTokenScopeAdmin {
+		return models.ApiTokenScopeAdmin
+	}
+	return m.resource + ":" + models.ApiTokenActionOf(r.Method)
+}
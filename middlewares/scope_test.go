@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeMiddleware_ServeHTTP(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func(resource, method string, token *models.ApiToken) int {
+		container := &PrincipalContainer{}
+		container.SetPrincipal(&models.User{ID: "user01"})
+		container.SetPrincipalToken(token)
+
+		r := httptest.NewRequest(method, "/", nil)
+		r = r.WithContext(context.WithValue(r.Context(), keyPrincipal, container))
+		w := httptest.NewRecorder()
+		NewScopeMiddleware(resource)(next).ServeHTTP(w, r)
+		return w.Code
+	}
+
+	ciToken := &models.ApiToken{Scopes: []string{"heartbeats:write"}}
+	dashboardToken := &models.ApiToken{Scopes: []string{"stats:read"}}
+
+	assert.Equal(t, http.StatusOK, serve(models.ApiTokenResourceHeartbeats, http.MethodPost, ciToken))
+	assert.Equal(t, http.StatusForbidden, serve(models.ApiTokenResourceStats, http.MethodGet, ciToken))
+	assert.Equal(t, http.StatusOK, serve(models.ApiTokenResourceStats, http.MethodGet, dashboardToken))
+	assert.Equal(t, http.StatusForbidden, serve(models.ApiTokenResourceHeartbeats, http.MethodPost, dashboardToken))
+	assert.Equal(t, http.StatusForbidden, serve(models.ApiTokenScopeAdmin, http.MethodGet, dashboardToken))
+
+	// not authenticated by a token
+	assert.Equal(t, http.StatusOK, serve(models.ApiTokenScopeAdmin, http.MethodPost, nil))
+}
@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"log/slog"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	const name = "20261017-populate_projects"
+	f := migrationFunc{
+		name: name,
+		f: func(db *gorm.DB, cfg *config.Config) error {
+			if hasRun(name, db) {
+				return nil
+			}
+
+			// new heartbeats are tracked as they come in, so only existing ones need to be accounted for once
+			var ranges []*struct {
+				UserID  string
+				Project string
+				First   models.CustomTime
+				Last    models.CustomTime
+			}
+			if err := db.
+				Model(&models.Heartbeat{}).
+				Select("user_id, project, min(time) as first, max(time) as last").
+				Where("project != ''").
+				Group("user_id, project").
+				Scan(&ranges).Error; err != nil {
+				return err
+			}
+
+			var languages []*struct {
+				UserID   string
+				Project  string
+				Language string
+				Count    int64
+			}
+			if err := db.
+				Model(&models.Heartbeat{}).
+				Select("user_id, project, language, count(*) as count").
+				Where("project != '' and language != ''").
+				Group("user_id, project, language").
+				Scan(&languages).Error; err != nil {
+				return err
+			}
+
+			projects := make(map[string]map[string]*models.Project)
+			for _, r := range ranges {
+				if _, ok := projects[r.UserID]; !ok {
+					projects[r.UserID] = make(map[string]*models.Project)
+				}
+				p := models.NewProject(r.UserID, r.Project, r.First.T())
+				p.Track(r.First.T(), r.Last.T(), nil)
+				projects[r.UserID][r.Project] = p
+			}
+			for _, l := range languages {
+				if p, ok := projects[l.UserID][l.Project]; ok {
+					p.Track(p.FirstHeartbeatAt.T(), p.LastHeartbeatAt.T(), map[string]int64{l.Language: l.Count})
+				}
+			}
+
+			batch := make([]*models.Project, 0, len(ranges))
+			for _, userProjects := range projects {
+				for _, p := range userProjects {
+					batch = append(batch, p)
+				}
+			}
+
+			if len(batch) > 0 {
+				if err := db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(batch, 500).Error; err != nil {
+					return err
+				}
+			}
+
+			slog.Info("populated projects table", "count", len(batch))
+
+			setHasRun(name, db)
+			return nil
+		},
+	}
+
+	registerPostMigration(f)
+}
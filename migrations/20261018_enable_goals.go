@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+func init() {
+	const name = "20261018-enable_goals"
+	f := migrationFunc{
+		name: name,
+		f: func(db *gorm.DB, cfg *config.Config) error {
+			if hasRun(name, db) {
+				return nil
+			}
+
+			// goals without the flag set at all are enabled, now that it has an effect, but those explicitly disabled by
+			// their users stay disabled
+			if err := db.
+				Model(&models.Goal{}).
+				Where("is_enabled is null").
+				Update("is_enabled", true).Error; err != nil {
+				return err
+			}
+
+			setHasRun(name, db)
+			return nil
+		},
+	}
+
+	registerPostMigration(f)
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"log/slog"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+// invoices used to have random ids only. add the number column before auto migration creates the unique index on it and
+// number existing invoices per user in order of creation, so that new ones continue the sequence
+func init() {
+	const name = "20261019-number_invoices"
+	f := migrationFunc{
+		name: name,
+		f: func(db *gorm.DB, cfg *config.Config) error {
+			migrator := db.Migrator()
+
+			if !migrator.HasTable(&models.Invoice{}) || migrator.HasColumn(&models.Invoice{}, "number") {
+				return nil
+			}
+
+			slog.Info("running migration", "name", name)
+
+			if err := migrator.AddColumn(&models.Invoice{}, "Number"); err != nil {
+				return err
+			}
+
+			var invoices []*models.Invoice
+			if err := db.
+				Select("id", "user_id").
+				Order("user_id, created_at asc").
+				Find(&invoices).Error; err != nil {
+				return err
+			}
+
+			var userID string
+			var number int
+			return db.Transaction(func(tx *gorm.DB) error {
+				for _, invoice := range invoices {
+					if invoice.UserID != userID {
+						userID, number = invoice.UserID, 0
+					}
+					number++
+					if err := tx.
+						Model(&models.Invoice{}).
+						Where("id = ?", invoice.ID).
+						UpdateColumn("number", number).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		},
+	}
+
+	registerPreMigration(f)
+}
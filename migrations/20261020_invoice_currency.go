@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+func init() {
+	const name = "20261020-invoice_currency"
+	f := migrationFunc{
+		name: name,
+		f: func(db *gorm.DB, cfg *config.Config) error {
+			if hasRun(name, db) {
+				return nil
+			}
+
+			// currencies used to be free text, normalize them and keep existing invoices in the currency they were issued in
+			var clients []*models.Client
+			if err := db.Select("id", "currency").Find(&clients).Error; err != nil {
+				return err
+			}
+
+			err := db.Transaction(func(tx *gorm.DB) error {
+				for _, client := range clients {
+					currency := models.NormalizeCurrency(client.Currency)
+					if currency != client.Currency {
+						if err := tx.
+							Model(&models.Client{}).
+							Where("id = ?", client.ID).
+							Update("currency", currency).Error; err != nil {
+							return err
+						}
+					}
+					if len(currency) > 3 {
+						continue
+					}
+					if err := tx.
+						Model(&models.Invoice{}).
+						Where("client_id = ?", client.ID).
+						Where("currency = '' OR currency IS NULL").
+						Update("currency", currency).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			setHasRun(name, db)
+			return nil
+		},
+	}
+
+	registerPostMigration(f)
+}
@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"log/slog"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	const name = "20261021-populate_machines"
+	f := migrationFunc{
+		name: name,
+		f: func(db *gorm.DB, cfg *config.Config) error {
+			if hasRun(name, db) {
+				return nil
+			}
+
+			// new heartbeats are tracked as they come in, so only existing ones need to be accounted for once
+			var ranges []*struct {
+				UserID  string
+				Machine string
+				First   models.CustomTime
+				Last    models.CustomTime
+			}
+			if err := db.
+				Model(&models.Heartbeat{}).
+				Select("user_id, machine, min(time) as first, max(time) as last").
+				Where("machine != ''").
+				Group("user_id, machine").
+				Scan(&ranges).Error; err != nil {
+				return err
+			}
+
+			batch := make([]*models.Machine, 0, len(ranges))
+			for _, r := range ranges {
+				m := models.NewMachineSeenAt(r.UserID, r.Machine, r.First.T())
+				m.ID = uuid.Must(uuid.NewV4()).String()
+				m.Track(r.First.T(), r.Last.T())
+				batch = append(batch, m)
+			}
+
+			if len(batch) > 0 {
+				if err := db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(batch, 500).Error; err != nil {
+					return err
+				}
+			}
+
+			slog.Info("populated machines table", "count", len(batch))
+
+			setHasRun(name, db)
+			return nil
+		},
+	}
+
+	registerPostMigration(f)
+}
@@ -62,6 +62,9 @@ func GetMigrationFunc(cfg *config.Config) gormMigrationFunc {
 			if err := db.AutoMigrate(&models.Goal{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
 				return err
 			}
+			if err := db.AutoMigrate(&models.GoalPeriod{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
 			if err := db.AutoMigrate(&models.UserOauth{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
 				return err
 			}
@@ -71,15 +74,75 @@ func GetMigrationFunc(cfg *config.Config) gormMigrationFunc {
 			if err := db.AutoMigrate(&models.Client{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
 				return err
 			}
+			if err := db.AutoMigrate(&models.ClientRate{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
 			if err := db.AutoMigrate(&models.Invoice{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
 				return err
 			}
+			if err := db.AutoMigrate(&models.InvoicePayment{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.InvoiceSchedule{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.ExchangeRate{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.InvoiceSettings{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
 			if err := db.AutoMigrate(&models.OTP{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
 				return err
 			}
 			if err := db.AutoMigrate(&models.UserReportSent{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
 				return err
 			}
+			if err := db.AutoMigrate(&models.Project{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.Organization{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.OrganizationMember{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.OrganizationProject{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.OrganizationInvitation{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.ImportJob{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.DataExport{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.Webhook{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.WebhookDelivery{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.OutboxMail{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.NotificationPreference{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.ApiToken{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.Machine{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.TwoFactor{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.Session{}); err != nil && !cfg.Db.AutoMigrateFailSilently {
+				return err
+			}
 			return nil
 		}
 	}
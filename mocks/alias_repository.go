@@ -39,6 +39,11 @@ func (m *AliasRepositoryMock) Insert(s *models.Alias) (*models.Alias, error) {
 	return args.Get(0).(*models.Alias), args.Error(1)
 }
 
+func (m *AliasRepositoryMock) InsertBatch(a []*models.Alias) error {
+	args := m.Called(a)
+	return args.Error(0)
+}
+
 func (m *AliasRepositoryMock) Delete(u uint) error {
 	args := m.Called(u)
 	return args.Error(0)
@@ -44,6 +44,11 @@ func (m *AliasServiceMock) Create(a *models.Alias) (*models.Alias, error) {
 	return args.Get(0).(*models.Alias), args.Error(1)
 }
 
+func (m *AliasServiceMock) CreateMulti(a []*models.Alias) ([]*models.Alias, error) {
+	args := m.Called(a)
+	return args.Get(0).([]*models.Alias), args.Error(1)
+}
+
 func (m *AliasServiceMock) Delete(s *models.Alias) error {
 	args := m.Called(s)
 	return args.Error(0)
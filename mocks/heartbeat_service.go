@@ -67,6 +67,11 @@ func (m *HeartbeatServiceMock) GetLatestByOriginAndUser(s string, user *models.U
 	return args.Get(0).(*models.Heartbeat), args.Error(1)
 }
 
+func (m *HeartbeatServiceMock) FilterExisting(heartbeats []*models.Heartbeat) ([]*models.Heartbeat, error) {
+	args := m.Called(heartbeats)
+	return args.Get(0).([]*models.Heartbeat), args.Error(1)
+}
+
 func (m *HeartbeatServiceMock) GetLatestByFilters(u *models.User, f *models.Filters) (*models.Heartbeat, error) {
 	args := m.Called(u, f)
 	return args.Get(0).(*models.Heartbeat), args.Error(1)
@@ -34,6 +34,11 @@ func (p *ProjectLabelServiceMock) Create(l *models.ProjectLabel) (*models.Projec
 	return args.Get(0).(*models.ProjectLabel), args.Error(1)
 }
 
+func (p *ProjectLabelServiceMock) CreateMulti(l []*models.ProjectLabel) ([]*models.ProjectLabel, error) {
+	args := p.Called(l)
+	return args.Get(0).([]*models.ProjectLabel), args.Error(1)
+}
+
 func (p *ProjectLabelServiceMock) Delete(l *models.ProjectLabel) error {
 	args := p.Called(l)
 	return args.Error(0)
@@ -20,6 +20,21 @@ func (m *UserServiceMock) GetUserByKey(s string) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *UserServiceMock) GetUserByApiToken(s, ip string) (*models.User, *models.ApiToken, error) {
+	args := m.Called(s, ip)
+	return args.Get(0).(*models.User), args.Get(1).(*models.ApiToken), args.Error(2)
+}
+
+func (m *UserServiceMock) GetUserByMachineKey(s string) (*models.User, *models.Machine, error) {
+	args := m.Called(s)
+	return args.Get(0).(*models.User), args.Get(1).(*models.Machine), args.Error(2)
+}
+
+func (m *UserServiceMock) GetUserBySession(s1, s2 string) (*models.User, *models.Session, error) {
+	args := m.Called(s1, s2)
+	return args.Get(0).(*models.User), args.Get(1).(*models.Session), args.Error(2)
+}
+
 func (m *UserServiceMock) GetUserByEmail(s string) (*models.User, error) {
 	args := m.Called(s)
 	return args.Get(0).(*models.User), args.Error(1)
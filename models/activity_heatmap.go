@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// ActivityHeatmap holds coding time per weekday and hour of day, in the user's timezone
+type ActivityHeatmap struct {
+	From         time.Time      `json:"from"`
+	To           time.Time      `json:"to"`
+	Timezone     string         `json:"timezone"`
+	Data         [7][24]float64 `json:"data"` // seconds, indexed by weekday (0 for sunday to 6) and hour
+	MaxSeconds   float64        `json:"max_seconds"`
+	TotalSeconds float64        `json:"total_seconds"`
+}
+
+// NewActivityHeatmap sums up activity per hour (see Durations.HourlyTotals) by weekday and hour in the given timezone
+func NewActivityHeatmap(from, to time.Time, hourly map[time.Time]time.Duration, tz *time.Location) *ActivityHeatmap {
+	heatmap := &ActivityHeatmap{From: from, To: to, Timezone: tz.String()}
+	for t, d := range hourly {
+		if !t.Add(time.Hour).After(from) || !t.Before(to) || d <= 0 { // hours only partially in range are kept
+			continue
+		}
+		t = t.In(tz)
+		heatmap.Data[t.Weekday()][t.Hour()] += d.Seconds()
+		heatmap.TotalSeconds += d.Seconds()
+	}
+	for _, hours := range heatmap.Data {
+		for _, secs := range hours {
+			heatmap.MaxSeconds = max(heatmap.MaxSeconds, secs)
+		}
+	}
+	return heatmap
+}
+
+// Intensity returns the activity in the given slot relative to the busiest one, between 0 and 1
+func (h *ActivityHeatmap) Intensity(weekday time.Weekday, hour int) float64 {
+	if h.MaxSeconds == 0 {
+		return 0
+	}
+	return h.Data[weekday][hour] / h.MaxSeconds
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewActivityHeatmap(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	from, to := time.Date(2024, 3, 11, 0, 30, 0, 0, tokyo), time.Date(2024, 3, 18, 0, 0, 0, 0, tokyo)
+
+	durations := Durations{
+		{Time: CustomTime(time.Date(2024, 3, 10, 15, 40, 0, 0, time.UTC)), Duration: 40 * time.Minute}, // monday, 00:40 - 01:20 in tokyo
+		{Time: CustomTime(time.Date(2024, 3, 13, 2, 0, 0, 0, time.UTC)), Duration: 2 * time.Hour},      // wednesday, 11:00 - 13:00 in tokyo
+		{Time: CustomTime(time.Date(2024, 3, 20, 2, 0, 0, 0, time.UTC)), Duration: time.Hour},          // out of range
+	}
+
+	sut := NewActivityHeatmap(from, to, durations.HourlyTotals(tokyo), tokyo)
+	assert.Equal(t, "Asia/Tokyo", sut.Timezone)
+	assert.Equal(t, float64(20*60), sut.Data[time.Monday][0])
+	assert.Equal(t, float64(20*60), sut.Data[time.Monday][1])
+	assert.Equal(t, float64(60*60), sut.Data[time.Wednesday][11])
+	assert.Equal(t, float64(60*60), sut.Data[time.Wednesday][12])
+	assert.Equal(t, float64(160*60), sut.TotalSeconds)
+	assert.Equal(t, float64(60*60), sut.MaxSeconds)
+	assert.Equal(t, 1.0/3, sut.Intensity(time.Monday, 0))
+	assert.Zero(t, (&ActivityHeatmap{}).Intensity(time.Monday, 0))
+}
@@ -9,12 +9,12 @@ type AliasResolver func(t uint8, k string) string
 type AliasReverseResolver func(t uint8, k string) []string
 
 type Alias struct {
-	ID     uint   `gorm:"primary_key"`
-	Type   uint8  `gorm:"not null; index:idx_alias_type_key"`
+	ID     uint   `json:"id" gorm:"primary_key"`
+	Type   uint8  `json:"type" gorm:"not null; index:idx_alias_type_key"`
 	User   *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	UserID string `gorm:"not null; index:idx_alias_user"`
-	Key    string `gorm:"not null; index:idx_alias_type_key"`
-	Value  string `gorm:"not null"`
+	UserID string `json:"-" gorm:"not null; index:idx_alias_user"`
+	Key    string `json:"key" gorm:"not null; index:idx_alias_type_key"`
+	Value  string `json:"value" gorm:"not null"`
 }
 
 func (a *Alias) IsValid() bool {
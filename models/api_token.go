@@ -0,0 +1,126 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ApiTokenPrefix distinguishes personal access tokens from a user's (legacy) api key
+const ApiTokenPrefix = "wakana_pat_"
+
+const (
+	ApiTokenScopeAdmin = "admin" // full access, including the management of tokens and api keys
+
+	ApiTokenActionRead  = "read"
+	ApiTokenActionWrite = "write"
+	apiTokenActionAll   = "*"
+)
+
+// resources to grant access to by scopes of the form <resource>:<read|write|*>, e.g. heartbeats:write or invoices:*
+const (
+	ApiTokenResourceHeartbeats = "heartbeats"
+	ApiTokenResourceStats      = "stats"
+	ApiTokenResourceProjects   = "projects"
+	ApiTokenResourceGoals      = "goals"
+	ApiTokenResourceClients    = "clients"
+	ApiTokenResourceInvoices   = "invoices"
+	ApiTokenResourceSettings   = "settings"
+	ApiTokenResourceData       = "data"
+	ApiTokenResourceOrgs       = "orgs"
+)
+
+var ApiTokenResources = []string{
+	ApiTokenResourceHeartbeats,
+	ApiTokenResourceStats,
+	ApiTokenResourceProjects,
+	ApiTokenResourceGoals,
+	ApiTokenResourceClients,
+	ApiTokenResourceInvoices,
+	ApiTokenResourceSettings,
+	ApiTokenResourceData,
+	ApiTokenResourceOrgs,
+}
+
+// ApiToken is a named personal access token, restricted to the given scopes. Only a hash of the token is stored, the
+// token itself is returned once upon creation.
+type ApiToken struct {
+	ID         string      `json:"id" gorm:"primary_key"`
+	User       *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string      `json:"user_id" gorm:"not null; index:idx_api_token_user"`
+	Name       string      `json:"name" gorm:"not null"`
+	TokenHash  string      `json:"-" gorm:"not null; uniqueIndex:idx_api_token_hash; size:64"`
+	Hint       string      `json:"hint"` // last characters of the token, to tell tokens apart
+	Scopes     []string    `json:"scopes" gorm:"serializer:json"`
+	ExpiresAt  *CustomTime `json:"expires_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastUsedAt *CustomTime `json:"last_used_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastUsedIP string      `json:"last_used_ip"`
+	CreatedAt  CustomTime  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	Token      string      `json:"token,omitempty" gorm:"-"` // plain token, only set right after creation
+}
+
+type NewApiToken struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"` // optional, never expires if not set
+}
+
+func (t *NewApiToken) IsValid() bool {
+	if strings.TrimSpace(t.Name) == "" || len(t.Scopes) == 0 {
+		return false
+	}
+	if t.ExpiresAt != nil && !t.ExpiresAt.After(time.Now()) {
+		return false
+	}
+	for _, s := range t.Scopes {
+		if !IsValidApiTokenScope(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsValidApiTokenScope reports whether the scope is admin or of the form <resource>:<read|write|*> with a known resource
+func IsValidApiTokenScope(scope string) bool {
+	if scope == ApiTokenScopeAdmin {
+		return true
+	}
+	resource, action, ok := strings.Cut(scope, ":")
+	return ok && slices.Contains(ApiTokenResources, resource) &&
+		(action == ApiTokenActionRead || action == ApiTokenActionWrite || action == apiTokenActionAll)
+}
+
+// ApiTokenActionOf returns the action a request performs, reading for safe methods and writing otherwise
+func ApiTokenActionOf(method string) string {
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+		return ApiTokenActionRead
+	}
+	return ApiTokenActionWrite
+}
+
+// HashApiToken returns the hex-encoded sha256 hash of the token. Tokens are random and long enough to not need salting.
+func HashApiToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Grants reports whether the token allows the given action on the given resource. Admin tokens allow anything,
+// the admin resource itself is only accessible with admin tokens.
+func (t *ApiToken) Grants(resource, action string) bool {
+	for _, s := range t.Scopes {
+		if s == ApiTokenScopeAdmin {
+			return true
+		}
+		if resource != ApiTokenScopeAdmin && (s == resource+":"+apiTokenActionAll || s == resource+":"+action) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *ApiToken) IsExpiredAt(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(t.ExpiresAt.T())
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiToken_Grants(t *testing.T) {
+	sut := &ApiToken{Scopes: []string{"heartbeats:write", "stats:read", "invoices:*"}}
+
+	assert.True(t, sut.Grants(ApiTokenResourceHeartbeats, ApiTokenActionWrite))
+	assert.False(t, sut.Grants(ApiTokenResourceHeartbeats, ApiTokenActionRead))
+	assert.True(t, sut.Grants(ApiTokenResourceStats, ApiTokenActionRead))
+	assert.False(t, sut.Grants(ApiTokenResourceStats, ApiTokenActionWrite))
+	assert.True(t, sut.Grants(ApiTokenResourceInvoices, ApiTokenActionRead))
+	assert.True(t, sut.Grants(ApiTokenResourceInvoices, ApiTokenActionWrite))
+	assert.False(t, sut.Grants(ApiTokenResourceClients, ApiTokenActionRead))
+	assert.False(t, sut.Grants(ApiTokenScopeAdmin, ApiTokenActionRead))
+
+	sut.Scopes = []string{ApiTokenScopeAdmin}
+	assert.True(t, sut.Grants(ApiTokenResourceClients, ApiTokenActionWrite))
+	assert.True(t, sut.Grants(ApiTokenScopeAdmin, ApiTokenActionWrite))
+}
+
+func TestApiToken_IsExpiredAt(t *testing.T) {
+	now := time.Now()
+	expiresAt := CustomTime(now.Add(time.Hour))
+
+	assert.False(t, (&ApiToken{}).IsExpiredAt(now))
+	assert.False(t, (&ApiToken{ExpiresAt: &expiresAt}).IsExpiredAt(now))
+	assert.True(t, (&ApiToken{ExpiresAt: &expiresAt}).IsExpiredAt(now.Add(time.Hour)))
+}
+
+func TestNewApiToken_IsValid(t *testing.T) {
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	assert.True(t, (&NewApiToken{Name: "ci", Scopes: []string{"heartbeats:write"}}).IsValid())
+	assert.True(t, (&NewApiToken{Name: "dashboard", Scopes: []string{"stats:read", "projects:*"}, ExpiresAt: &future}).IsValid())
+	assert.True(t, (&NewApiToken{Name: "all", Scopes: []string{"admin"}}).IsValid())
+
+	assert.False(t, (&NewApiToken{Name: " ", Scopes: []string{"admin"}}).IsValid())
+	assert.False(t, (&NewApiToken{Name: "none"}).IsValid())
+	assert.False(t, (&NewApiToken{Name: "unknown", Scopes: []string{"unknown:read"}}).IsValid())
+	assert.False(t, (&NewApiToken{Name: "action", Scopes: []string{"stats:delete"}}).IsValid())
+	assert.False(t, (&NewApiToken{Name: "resource", Scopes: []string{"stats"}}).IsValid())
+	assert.False(t, (&NewApiToken{Name: "expired", Scopes: []string{"admin"}, ExpiresAt: &past}).IsValid())
+}
+
+func TestApiTokenActionOf(t *testing.T) {
+	assert.Equal(t, ApiTokenActionRead, ApiTokenActionOf(http.MethodGet))
+	assert.Equal(t, ApiTokenActionRead, ApiTokenActionOf(http.MethodHead))
+	assert.Equal(t, ApiTokenActionWrite, ApiTokenActionOf(http.MethodPost))
+	assert.Equal(t, ApiTokenActionWrite, ApiTokenActionOf(http.MethodDelete))
+}
@@ -1,29 +1,77 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Client struct {
-	ID         string     `json:"id" gorm:"primary_key"`
-	UserID     string     `json:"user_id"`
-	Name       string     `json:"name"`
-	Currency   string     `json:"currency"`
-	HourlyRate float64    `json:"hourly_rate"`
-	Projects   []string   `json:"projects" gorm:"serializer:json"`
-	CreatedAt  CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
-	UpdatedAt  CustomTime `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	ID          string         `json:"id" gorm:"primary_key"`
+	UserID      string         `json:"user_id"`
+	Name        string         `json:"name"`
+	Currency    string         `json:"currency"`
+	HourlyRate  float64        `json:"hourly_rate"`
+	Projects    []string       `json:"projects" gorm:"serializer:json"`
+	ContactName string         `json:"contact_name"`
+	Email       string         `json:"email"`
+	Phone       string         `json:"phone"`
+	Address     string         `json:"address"`
+	TaxID       string         `json:"tax_id"`
+	Rounding    RoundingPolicy `json:"rounding" gorm:"embedded; embeddedPrefix:rounding_"`
+	CreatedAt   CustomTime     `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	UpdatedAt   CustomTime     `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
 }
 
 type NewClient struct {
-	UserID     string   `json:"user_id"`
-	Name       string   `json:"name"`
-	Currency   string   `json:"currency"`
-	HourlyRate float64  `json:"hourly_rate"`
-	Projects   []string `json:"projects"`
+	UserID      string         `json:"user_id"`
+	Name        string         `json:"name"`
+	Currency    string         `json:"currency"`
+	HourlyRate  float64        `json:"hourly_rate"`
+	Projects    []string       `json:"projects"`
+	ContactName string         `json:"contact_name"`
+	Email       string         `json:"email"`
+	Phone       string         `json:"phone"`
+	Address     string         `json:"address"`
+	TaxID       string         `json:"tax_id"`
+	Rounding    RoundingPolicy `json:"rounding"`
 }
 
+// ClientUpdate uses pointers for the contact details, so that they can be cleared again
 type ClientUpdate struct {
-	Name       string   `json:"name"`
-	Currency   string   `json:"currency"`
-	HourlyRate float64  `json:"hourly_rate"`
-	Projects   []string `json:"projects" gorm:"serializer:json"`
+	Name        string          `json:"name"`
+	Currency    string          `json:"currency"`
+	HourlyRate  float64         `json:"hourly_rate"`
+	Projects    []string        `json:"projects" gorm:"serializer:json"`
+	ContactName *string         `json:"contact_name"`
+	Email       *string         `json:"email"`
+	Phone       *string         `json:"phone"`
+	Address     *string         `json:"address"`
+	TaxID       *string         `json:"tax_id"`
+	Rounding    *RoundingPolicy `json:"rounding" gorm:"-"` // applied separately, as clearing rules requires zero values to be written
+}
+
+// HasValidContact checks the optional contact details, i.e. passes if no email is given
+func (c *Client) HasValidContact() bool {
+	return c.Email == "" || MailAddress(c.Email).Valid()
+}
+
+func (c *ClientUpdate) HasValidContact() bool {
+	return c.Email == nil || *c.Email == "" || MailAddress(*c.Email).Valid()
+}
+
+// BillingAddress renders the client's name, contact person, address and tax id as used on invoices
+func (c *Client) BillingAddress() string {
+	lines := []string{c.Name}
+	if c.ContactName != "" {
+		lines = append(lines, c.ContactName)
+	}
+	if c.Address != "" {
+		lines = append(lines, strings.TrimSpace(c.Address))
+	}
+	if c.TaxID != "" {
+		lines = append(lines, fmt.Sprintf("Tax ID: %s", c.TaxID))
+	}
+	return strings.Join(lines, "\n")
 }
 
 func (c *Client) GetSummaryFilters() *Filters {
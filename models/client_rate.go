@@ -0,0 +1,101 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// ClientRate is an hourly rate overriding the client's default rate for a certain project and / or category, starting at a given date.
+// Empty project or category match any project or category respectively.
+type ClientRate struct {
+	ID            string     `json:"id" gorm:"primary_key"`
+	Client        *Client    `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ClientID      string     `json:"client_id" gorm:"index:idx_client_rate_client"`
+	Project       string     `json:"project"`
+	Category      string     `json:"category"`
+	HourlyRate    float64    `json:"hourly_rate"`
+	EffectiveFrom *time.Time `json:"effective_from" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	Description   string     `json:"description"`
+	CreatedAt     CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type NewClientRate struct {
+	Project       string  `json:"project"`
+	Category      string  `json:"category"`
+	HourlyRate    float64 `json:"hourly_rate"`
+	EffectiveFrom string  `json:"effective_from"` // date, empty for rates that always applied
+	Description   string  `json:"description"`
+}
+
+type ClientRates []*ClientRate
+
+func (r *NewClientRate) IsValid() bool {
+	return r.HourlyRate >= 0 && len(r.Project) <= 255 && len(r.Category) <= 255 && len(r.Description) <= 255
+}
+
+// Specificity ranks how specific a rate is, project and category are more specific than project only, which is more specific than category only
+func (r *ClientRate) Specificity() int {
+	var score int
+	if r.Project != "" {
+		score += 2
+	}
+	if r.Category != "" {
+		score += 1
+	}
+	return score
+}
+
+func (r *ClientRate) Matches(project, category string, t time.Time) bool {
+	return (r.Project == "" || r.Project == project) &&
+		(r.Category == "" || r.Category == category) &&
+		(r.EffectiveFrom == nil || !r.EffectiveFrom.After(t))
+}
+
+// Resolve picks the rate applicable to time spent on the given project and category at the given time. The most specific rate
+// wins, ties are broken by the most recent effective date. Returns nil if the client's default rate applies.
+func (rates ClientRates) Resolve(project, category string, t time.Time) *ClientRate {
+	var best *ClientRate
+	for _, r := range rates {
+		if !r.Matches(project, category, t) {
+			continue
+		}
+		if best == nil || r.Specificity() > best.Specificity() || (r.Specificity() == best.Specificity() && r.effectiveAfter(best)) {
+			best = r
+		}
+	}
+	return best
+}
+
+// HasCategoryRates returns whether any rate is bound to a category, which requires time to be broken down by category
+func (rates ClientRates) HasCategoryRates() bool {
+	for _, r := range rates {
+		if r.Category != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Boundaries returns the distinct effective dates within (from, to), i.e. the points in time at which billing periods have to be split
+func (rates ClientRates) Boundaries(from, to time.Time) []time.Time {
+	seen := make(map[int64]bool)
+	boundaries := make([]time.Time, 0)
+	for _, r := range rates {
+		if r.EffectiveFrom == nil || !r.EffectiveFrom.After(from) || !r.EffectiveFrom.Before(to) || seen[r.EffectiveFrom.Unix()] {
+			continue
+		}
+		seen[r.EffectiveFrom.Unix()] = true
+		boundaries = append(boundaries, *r.EffectiveFrom)
+	}
+	sort.Slice(boundaries, func(i, j int) bool {
+		return boundaries[i].Before(boundaries[j])
+	})
+	return boundaries
+}
+
+func (r *ClientRate) effectiveAfter(other *ClientRate) bool {
+	if r.EffectiveFrom == nil {
+		return false
+	}
+	return other.EffectiveFrom == nil || r.EffectiveFrom.After(*other.EffectiveFrom)
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientRates_Resolve(t *testing.T) {
+	jan, mar := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	sut := ClientRates{
+		{ID: "category", Category: "debugging", HourlyRate: 60},
+		{ID: "project", Project: "wakapi", HourlyRate: 80},
+		{ID: "project-march", Project: "wakapi", HourlyRate: 90, EffectiveFrom: &mar},
+		{ID: "project-category", Project: "wakapi", Category: "debugging", HourlyRate: 100, EffectiveFrom: &jan},
+	}
+
+	assert.Nil(t, sut.Resolve("anchr", "coding", mar))
+	assert.Equal(t, "category", sut.Resolve("anchr", "debugging", mar).ID)
+	assert.Equal(t, "project", sut.Resolve("wakapi", "coding", jan).ID)
+	assert.Equal(t, "project-march", sut.Resolve("wakapi", "coding", mar).ID)
+	assert.Equal(t, "project-march", sut.Resolve("wakapi", "coding", mar.AddDate(0, 1, 0)).ID)
+	assert.Equal(t, "project-category", sut.Resolve("wakapi", "debugging", mar).ID)
+	assert.Equal(t, "project", sut.Resolve("wakapi", "debugging", jan.Add(-1*time.Second)).ID)
+}
+
+func TestClientRates_Boundaries(t *testing.T) {
+	jan, feb, mar := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	sut := ClientRates{
+		{Project: "wakapi"},
+		{Project: "wakapi", EffectiveFrom: &mar},
+		{Project: "anchr", EffectiveFrom: &feb},
+		{Category: "debugging", EffectiveFrom: &feb},
+		{Category: "coding", EffectiveFrom: &jan},
+	}
+
+	assert.Equal(t, []time.Time{feb, mar}, sut.Boundaries(jan, mar.AddDate(0, 1, 0)))
+	assert.Equal(t, []time.Time{feb}, sut.Boundaries(jan, mar))
+	assert.Empty(t, sut.Boundaries(mar, mar.AddDate(0, 1, 0)))
+	assert.False(t, ClientRates{{Project: "wakapi"}}.HasCategoryRates())
+	assert.True(t, sut.HasCategoryRates())
+}
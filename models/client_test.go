@@ -0,0 +1,27 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_BillingAddress(t *testing.T) {
+	assert.Equal(t, "ACME Inc.", (&Client{Name: "ACME Inc."}).BillingAddress())
+
+	sut := &Client{Name: "ACME Inc.", ContactName: "Jane Doe", Address: "Some Street 1\n12345 Some City\n", TaxID: "DE123456789"}
+	assert.Equal(t, "ACME Inc.\nJane Doe\nSome Street 1\n12345 Some City\nTax ID: DE123456789", sut.BillingAddress())
+}
+
+func TestClient_HasValidContact(t *testing.T) {
+	invalid, valid, empty := "jane", "jane@example.org", ""
+
+	assert.True(t, (&Client{}).HasValidContact())
+	assert.True(t, (&Client{Email: valid}).HasValidContact())
+	assert.False(t, (&Client{Email: invalid}).HasValidContact())
+
+	assert.True(t, (&ClientUpdate{}).HasValidContact())
+	assert.True(t, (&ClientUpdate{Email: &empty}).HasValidContact())
+	assert.True(t, (&ClientUpdate{Email: &valid}).HasValidContact())
+	assert.False(t, (&ClientUpdate{Email: &invalid}).HasValidContact())
+}
@@ -57,7 +57,7 @@ func HeartbeatsToCompat(entries []*models.Heartbeat) []*HeartbeatEntry {
 			IsWrite:       entry.IsWrite,
 			Language:      entry.Language,
 			Project:       entry.Project,
-			Time:          float64(entry.Time.T().Unix()),
+			Time:          float64(entry.Time.T().UnixMilli()) / 1e3, // millisecond precision, same as stored and hashed
 			Type:          entry.Type,
 			UserId:        entry.UserID,
 			MachineNameId: entry.Machine,
@@ -1,5 +1,11 @@
 package v1
 
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+)
+
 // https://wakatime.com/api/v1/users/current/machine_names
 
 type MachineViewModel struct {
@@ -8,6 +14,28 @@ type MachineViewModel struct {
 }
 
 type MachineEntry struct {
-	Id    string `json:"id"`
-	Value string `json:"value"`
+	Id         string     `json:"id"`
+	Value      string     `json:"value"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+func NewMachinesFrom(machines []*models.Machine) *MachineViewModel {
+	data := make([]*MachineEntry, len(machines))
+	for i, m := range machines {
+		createdAt := m.CreatedAt.T()
+		data[i] = &MachineEntry{
+			Id:        m.ID,
+			Value:     m.Name,
+			CreatedAt: &createdAt,
+		}
+		if m.LastHeartbeatAt != nil {
+			lastSeenAt := m.LastHeartbeatAt.T()
+			data[i].LastSeenAt = &lastSeenAt
+		}
+	}
+	return &MachineViewModel{
+		Data:       data,
+		TotalPages: 1, // all machines are returned at once
+	}
 }
@@ -3,7 +3,10 @@ package v1
 import "time"
 
 type ProjectsViewModel struct {
-	Data []*Project `json:"data"`
+	Data       []*Project `json:"data"`
+	Total      int64      `json:"total"`
+	Page       int        `json:"page"`
+	TotalPages int        `json:"total_pages"`
 }
 
 type ProjectViewModel struct {
@@ -13,6 +16,12 @@ type ProjectViewModel struct {
 type Project struct {
 	ID                           string    `json:"id"`
 	Name                         string    `json:"name"`
+	Color                        string    `json:"color"`
+	Description                  string    `json:"description"`
+	RepositoryUrl                string    `json:"repository_url"`
+	IsArchived                   bool      `json:"is_archived"`
+	TopLanguage                  string    `json:"top_language"`
+	FirstHeartbeatAt             time.Time `json:"first_heartbeat_at"`
 	LastHeartbeatAt              time.Time `json:"last_heartbeat_at"`
 	HumanReadableLastHeartbeatAt string    `json:"human_readable_last_heartbeat_at"`
 	UrlencodedName               string    `json:"urlencoded_name"`
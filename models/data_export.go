@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	DataExportQueued    = "queued"
+	DataExportRunning   = "running"
+	DataExportCompleted = "completed"
+	DataExportFailed    = "failed"
+)
+
+// DataExport is an archive of all of a user's data, generated in the background and downloadable for a limited time
+type DataExport struct {
+	ID          string      `json:"id" gorm:"primary_key"`
+	User        *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID      string      `json:"user_id" gorm:"not null; index:idx_data_export_user"`
+	Status      string      `json:"status" gorm:"not null; default:'queued'; size:16"`
+	Size        int64       `json:"size"` // size of the archive in bytes
+	Error       string      `json:"error,omitempty"`
+	DownloadUrl string      `json:"download_url,omitempty" gorm:"-"`
+	CreatedAt   CustomTime  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	FinishedAt  *CustomTime `json:"finished_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	ExpiresAt   *CustomTime `json:"expires_at" gorm:"index:idx_data_export_expires" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	UpdatedAt   CustomTime  `json:"updated_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"` // bumped with every change of status
+}
+
+func (e *DataExport) IsFinished() bool {
+	return e.Status == DataExportCompleted || e.Status == DataExportFailed
+}
+
+// IsDownloadable reports whether the export's archive is ready and not yet expired
+func (e *DataExport) IsDownloadable() bool {
+	return e.Status == DataExportCompleted && e.ExpiresAt != nil && time.Now().Before(e.ExpiresAt.T())
+}
+
+func (e *DataExport) FileName() string {
+	return "wakapi_export_" + e.ID + ".zip"
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataExport_IsDownloadable(t *testing.T) {
+	future := CustomTime(time.Now().Add(time.Hour))
+	past := CustomTime(time.Now().Add(-time.Hour))
+
+	assert.True(t, (&DataExport{Status: DataExportCompleted, ExpiresAt: &future}).IsDownloadable())
+	assert.False(t, (&DataExport{Status: DataExportCompleted, ExpiresAt: &past}).IsDownloadable())
+	assert.False(t, (&DataExport{Status: DataExportCompleted}).IsDownloadable())
+	assert.False(t, (&DataExport{Status: DataExportRunning, ExpiresAt: &future}).IsDownloadable())
+	assert.False(t, (&DataExport{Status: DataExportFailed, ExpiresAt: &future}).IsDownloadable())
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// ExchangeRate is a user-maintained conversion rate, i.e. one unit of the base currency is worth Rate units of the quote currency,
+// starting at the given date. Rates are used in both directions.
+type ExchangeRate struct {
+	ID            uint       `json:"id" gorm:"primary_key"`
+	User          *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserID        string     `json:"-" gorm:"not null; index:idx_exchange_rate_user"`
+	BaseCurrency  string     `json:"base_currency" gorm:"not null; size:3"`
+	QuoteCurrency string     `json:"quote_currency" gorm:"not null; size:3"`
+	Rate          float64    `json:"rate"`
+	ValidFrom     time.Time  `json:"valid_from" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt     CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type NewExchangeRate struct {
+	BaseCurrency  string  `json:"base_currency"`
+	QuoteCurrency string  `json:"quote_currency"`
+	Rate          float64 `json:"rate"`
+	ValidFrom     string  `json:"valid_from"` // date
+}
+
+type ExchangeRates []*ExchangeRate
+
+func (r *NewExchangeRate) IsValid() bool {
+	return IsValidCurrency(r.BaseCurrency) && IsValidCurrency(r.QuoteCurrency) && r.BaseCurrency != r.QuoteCurrency && r.Rate > 0
+}
+
+// Convert converts the amount from one currency into another at the given time, using the most recent rate valid by then.
+// Returns false if no such rate exists.
+func (rates ExchangeRates) Convert(amount float64, from, to string, at time.Time) (float64, bool) {
+	if from == to {
+		return amount, true
+	}
+
+	var best *ExchangeRate
+	for _, r := range rates {
+		matches := (r.BaseCurrency == from && r.QuoteCurrency == to) || (r.BaseCurrency == to && r.QuoteCurrency == from)
+		if !matches || r.ValidFrom.After(at) || r.Rate <= 0 {
+			continue
+		}
+		if best == nil || r.ValidFrom.After(best.ValidFrom) {
+			best = r
+		}
+	}
+
+	if best == nil {
+		return 0, false
+	}
+	if best.BaseCurrency == from {
+		return amount * best.Rate, true
+	}
+	return amount / best.Rate, true
+}
+
+// RevenueMonth sums up the invoices issued within a calendar month
+type RevenueMonth struct {
+	Month       string             `json:"month"` // e.g. 2024-01
+	Invoices    int                `json:"invoices"`
+	Total       float64            `json:"total"`       // in the user's home currency
+	Paid        float64            `json:"paid"`        // in the user's home currency
+	ByCurrency  map[string]float64 `json:"by_currency"` // unconverted totals per invoice currency
+	Unconverted []string           `json:"unconverted"` // currencies without an exchange rate, not included in total and paid
+}
+
+type RevenueReport struct {
+	HomeCurrency string          `json:"home_currency"`
+	Months       []*RevenueMonth `json:"months"`
+	Total        float64         `json:"total"`
+	Paid         float64         `json:"paid"`
+}
+
+// NewRevenueReport sums up issued (i.e. neither draft nor void) invoices per month of issue in the given timezone, converting
+// amounts into the home currency at the rate valid on the day of issue. Months without invoices are left out.
+func NewRevenueReport(invoices []*Invoice, rates ExchangeRates, homeCurrency string, tz *time.Location) *RevenueReport {
+	report := &RevenueReport{HomeCurrency: homeCurrency, Months: make([]*RevenueMonth, 0)}
+	months := make(map[string]*RevenueMonth)
+
+	for _, invoice := range invoices {
+		if invoice.IsEditable() || invoice.Status == InvoiceStatusVoid {
+			continue
+		}
+
+		issued := invoice.CreatedAt.T()
+		if invoice.SentAt != nil {
+			issued = *invoice.SentAt
+		}
+		key := issued.In(tz).Format("2006-01")
+
+		month, ok := months[key]
+		if !ok {
+			month = &RevenueMonth{Month: key, ByCurrency: make(map[string]float64), Unconverted: make([]string, 0)}
+			months[key] = month
+			report.Months = append(report.Months, month)
+		}
+
+		currency := invoice.CurrencyCode()
+		month.Invoices++
+		month.ByCurrency[currency] = roundCents(month.ByCurrency[currency] + invoice.Total())
+
+		total, ok := rates.Convert(invoice.Total(), currency, homeCurrency, issued)
+		if !ok {
+			if !slices.Contains(month.Unconverted, currency) {
+				month.Unconverted = append(month.Unconverted, currency)
+			}
+			continue
+		}
+		paid, _ := rates.Convert(invoice.AmountPaid(), currency, homeCurrency, issued)
+		month.Total = roundCents(month.Total + total)
+		month.Paid = roundCents(month.Paid + paid)
+	}
+
+	sort.Slice(report.Months, func(i, j int) bool {
+		return report.Months[i].Month < report.Months[j].Month
+	})
+	for _, month := range report.Months {
+		report.Total = roundCents(report.Total + month.Total)
+		report.Paid = roundCents(report.Paid + month.Paid)
+	}
+
+	return report
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExchangeRates_Convert(t *testing.T) {
+	jan, feb := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	sut := ExchangeRates{
+		{BaseCurrency: "EUR", QuoteCurrency: "USD", Rate: 1.1, ValidFrom: jan},
+		{BaseCurrency: "EUR", QuoteCurrency: "USD", Rate: 1.25, ValidFrom: feb},
+	}
+
+	amount, ok := sut.Convert(100, "EUR", "USD", jan.AddDate(0, 0, 10))
+	assert.True(t, ok)
+	assert.InDelta(t, 110, amount, 0.001)
+
+	amount, ok = sut.Convert(125, "USD", "EUR", feb)
+	assert.True(t, ok)
+	assert.InDelta(t, 100, amount, 0.001)
+
+	amount, ok = sut.Convert(42, "GBP", "GBP", jan)
+	assert.True(t, ok)
+	assert.Equal(t, 42.0, amount)
+
+	_, ok = sut.Convert(100, "EUR", "USD", jan.Add(-1*time.Second))
+	assert.False(t, ok)
+	_, ok = sut.Convert(100, "GBP", "USD", feb)
+	assert.False(t, ok)
+}
+
+func TestNewRevenueReport(t *testing.T) {
+	jan, feb := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), time.Date(2024, 2, 10, 12, 0, 0, 0, time.UTC)
+	item := func(hours int64) []InvoiceLineItem {
+		return []InvoiceLineItem{{TotalSeconds: hours * 3600}}
+	}
+
+	invoices := []*Invoice{
+		{Status: InvoiceStatusPaid, SentAt: &jan, Currency: "USD", Client: Client{HourlyRate: 100}, LineItems: item(10), Payments: []*InvoicePayment{{Amount: 1000}}},
+		{Status: InvoiceStatusSent, SentAt: &jan, Currency: "EUR", Client: Client{HourlyRate: 100}, LineItems: item(5)},
+		{Status: InvoiceStatusOverdue, SentAt: &feb, Currency: "GBP", Client: Client{HourlyRate: 100}, LineItems: item(1)},
+		{Status: InvoiceStatusVoid, SentAt: &feb, Currency: "USD", Client: Client{HourlyRate: 100}, LineItems: item(100)},
+		{Status: InvoiceStatusDraft, Currency: "USD", Client: Client{HourlyRate: 100}, LineItems: item(100)},
+	}
+	rates := ExchangeRates{{BaseCurrency: "EUR", QuoteCurrency: "USD", Rate: 1.2, ValidFrom: jan.AddDate(0, -1, 0)}}
+
+	sut := NewRevenueReport(invoices, rates, "USD", time.UTC)
+
+	assert.Equal(t, "USD", sut.HomeCurrency)
+	assert.Len(t, sut.Months, 2)
+	assert.Equal(t, "2024-01", sut.Months[0].Month)
+	assert.Equal(t, 2, sut.Months[0].Invoices)
+	assert.Equal(t, 1600.0, sut.Months[0].Total)
+	assert.Equal(t, 1000.0, sut.Months[0].Paid)
+	assert.Equal(t, map[string]float64{"USD": 1000, "EUR": 500}, sut.Months[0].ByCurrency)
+	assert.Equal(t, "2024-02", sut.Months[1].Month)
+	assert.Equal(t, 0.0, sut.Months[1].Total)
+	assert.Equal(t, []string{"GBP"}, sut.Months[1].Unconverted)
+	assert.Equal(t, 1600.0, sut.Total)
+}
+
+func TestIsValidCurrency(t *testing.T) {
+	assert.True(t, IsValidCurrency("EUR"))
+	assert.True(t, IsValidCurrency(NormalizeCurrency(" usd ")))
+	assert.False(t, IsValidCurrency("eur"))
+	assert.False(t, IsValidCurrency("EURO"))
+	assert.False(t, IsValidCurrency("$"))
+}
@@ -191,7 +191,7 @@ func (f *Filters) MatchHeartbeat(h *Heartbeat) bool {
 		(f.Language == nil || f.Language.MatchAny(h.Language)) &&
 		(f.Editor == nil || f.Editor.MatchAny(h.Editor)) &&
 		(f.Machine == nil || f.Machine.MatchAny(h.Machine)) &&
-		(f.Category == nil || f.Machine.MatchAny(h.Category))
+		(f.Category == nil || f.Category.MatchAny(h.Category))
 }
 
 func (f *Filters) MatchDuration(d *Duration) bool {
@@ -84,8 +84,8 @@ func (suite *FiltersTestSuite) TestFilters_IsEmpty() {
 
 func (suite *FiltersTestSuite) TestFilters_Match() {
 	heartbeats := []*Heartbeat{
-		{Project: "wakapi", Language: "Go"},
-		{Project: "anchr", Language: "Javascript"},
+		{Project: "wakapi", Language: "Go", Category: "coding"},
+		{Project: "anchr", Language: "Javascript", Category: "debugging"},
 	}
 
 	sut1 := NewFiltersWith(SummaryProject, "wakapi")
@@ -103,6 +103,10 @@ func (suite *FiltersTestSuite) TestFilters_Match() {
 	sut4 := &Filters{}
 	assert.True(suite.T(), sut4.MatchHeartbeat(heartbeats[0]))
 	assert.True(suite.T(), sut4.MatchHeartbeat(heartbeats[1]))
+
+	sut5 := NewFiltersWith(SummaryCategory, "coding").With(SummaryProject, "wakapi")
+	assert.True(suite.T(), sut5.MatchHeartbeat(heartbeats[0]))
+	assert.False(suite.T(), sut5.MatchHeartbeat(heartbeats[1]))
 }
 
 func (suite *FiltersTestSuite) TestFilters_One() {
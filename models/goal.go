@@ -2,10 +2,29 @@ package models
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 )
 
+const (
+	GoalDeltaDay  = "day"
+	GoalDeltaWeek = "week"
+)
+
+const (
+	GoalDirectionMore = "more"
+	GoalDirectionLess = "less"
+)
+
+const (
+	GoalStatusSuccess = "success"
+	GoalStatusFailed  = "failed"
+	GoalStatusIgnored = "ignored"
+	GoalStatusSnoozed = "snoozed"
+	GoalStatusPartial = "partial"
+)
+
 type Goal struct {
 	ID               string           `json:"id" gorm:"primary_key"`
 	UserID           string           `json:"user_id"`
@@ -23,6 +42,10 @@ type Goal struct {
 	Status           string           `json:"status" gorm:"size:1055"`
 	IsSnoozed        bool             `json:"is_snoozed"`
 	IsEnabled        bool             `json:"is_enabled"`
+	IgnoreDays       []string         `json:"ignore_days" gorm:"serializer:json"`
+	IgnoreZeroDays   bool             `json:"ignore_zero_days"`
+	CurrentStreak    int              `json:"current_streak"`
+	LongestStreak    int              `json:"longest_streak"`
 	Languages        []string         `json:"languages" gorm:"serializer:json"`
 	Projects         []string         `json:"projects" gorm:"serializer:json"`
 	Editors          []string         `json:"editors" gorm:"serializer:json"`
@@ -30,6 +53,20 @@ type Goal struct {
 	ChartData        []*GoalChartData `json:"chart_data" gorm:"-"`
 }
 
+// GoalPeriod is the outcome of evaluating a goal for a single, completed day or week
+type GoalPeriod struct {
+	ID              uint       `json:"id" gorm:"primary_key"`
+	Goal            *Goal      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	GoalID          string     `json:"goal_id" gorm:"not null; uniqueIndex:idx_goal_period"`
+	PeriodStart     CustomTime `json:"period_start" gorm:"not null; uniqueIndex:idx_goal_period" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	PeriodEnd       CustomTime `json:"period_end" gorm:"not null" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	ActualSeconds   int64      `json:"actual_seconds"`
+	GoalSeconds     int64      `json:"goal_seconds"`
+	PreviousSeconds int64      `json:"previous_seconds"` // time spent in the previous period, only for relative goals
+	Status          string     `json:"status" gorm:"not null; size:16"`
+	CreatedAt       CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
 type GoalChartRange struct {
 	Date     string    `json:"date"`
 	End      time.Time `json:"end"`
@@ -73,45 +110,207 @@ func (g *Goal) GetGoalSuffix(prefix string, items []string) string {
 }
 
 func (g *Goal) GetTitle() string {
-	hours := float64(g.Seconds) / float64(3600)
-	suffixes := []string{
+	suffixes := make([]string, 0, 4)
+	for _, suffix := range []string{
 		g.GetGoalSuffix("languages", g.Languages),
 		g.GetGoalSuffix("editors", g.Editors),
 		g.GetGoalSuffix("categories", g.Categories),
 		g.GetGoalSuffix("projects", g.Projects),
+	} {
+		if suffix != "" {
+			suffixes = append(suffixes, " "+suffix)
+		}
+	}
+
+	if g.IsRelative() {
+		direction := GoalDirectionMore
+		if g.IsLimit() {
+			direction = GoalDirectionLess
+		}
+		return fmt.Sprintf("Code %d%% %s than the previous %s%s", g.ImproveByPercent, direction, g.Delta, strings.Join(suffixes, ""))
 	}
-	direction := g.TargetDirection
-	if direction == "less" {
+
+	hours := float64(g.Seconds) / float64(3600)
+	direction := "at least"
+	if g.IsLimit() {
 		direction = "less than"
-	} else {
-		direction = "at least"
 	}
+	return fmt.Sprintf("Code %s %.2f hrs per %s%s", direction, hours, g.Delta, strings.Join(suffixes, ""))
+}
 
-	return fmt.Sprintf("Code %s %.2f hrs per %s %s", direction+" than", hours, g.Delta, strings.Join(suffixes, ""))
+func (g *Goal) DisplayTitle() string {
+	if g.CustomTitle != nil && *g.CustomTitle != "" {
+		return *g.CustomTitle
+	}
+	return g.Title
 }
 
-func (g *Goal) GetGoalSummaryFilter() *Filters {
-	if g.Languages != nil && len(g.Languages) > 0 {
-		return &Filters{
-			Language: OrFilter(g.Languages),
+// PeriodAt returns the day or week (starting on monday) the given time falls into, in the time's location
+func (g *Goal) PeriodAt(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if g.Delta == GoalDeltaWeek {
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	}
+	return start, start.AddDate(0, 0, 1)
+}
+
+// PreviousPeriod returns the period right before the one starting at the given time
+func (g *Goal) PreviousPeriod(start time.Time) (time.Time, time.Time) {
+	return g.PeriodAt(start.Add(-time.Nanosecond))
+}
+
+// IsLimit reports whether the goal is an upper limit (e.g. less than 2 hours of meetings per day) rather than a minimum
+func (g *Goal) IsLimit() bool {
+	return g.TargetDirection == GoalDirectionLess
+}
+
+// IsRelative reports whether the goal's target is derived from the time spent in the respective previous period
+func (g *Goal) IsRelative() bool {
+	return g.ImproveByPercent > 0
+}
+
+// TargetSeconds returns the time to reach, or for limits, not to exceed within a period. Relative goals derive it from
+// the time spent in the previous period, improved by the given percentage, and fall back to the fixed target if there was none.
+func (g *Goal) TargetSeconds(previousSeconds int64) int64 {
+	if !g.IsRelative() || previousSeconds <= 0 {
+		return g.Seconds
+	}
+	factor := 1 + float64(g.ImproveByPercent)/100
+	if g.IsLimit() {
+		factor = math.Max(0, 1-float64(g.ImproveByPercent)/100)
+	}
+	return int64(math.Round(float64(previousSeconds) * factor))
+}
+
+// IsIgnoredDay reports whether the given day is excluded from evaluation. Only applies to daily goals.
+func (g *Goal) IsIgnoredDay(t time.Time) bool {
+	if g.Delta == GoalDeltaWeek {
+		return false
+	}
+	for _, d := range g.IgnoreDays {
+		if strings.EqualFold(d, t.Weekday().String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSnoozedAt reports whether the goal is snoozed at the given time. A snooze without end lasts until disabled.
+func (g *Goal) IsSnoozedAt(t time.Time) bool {
+	return g.IsSnoozed && (g.SnoozeUntil == 0 || t.Unix() < g.SnoozeUntil)
+}
+
+// EvaluatePeriod rates the time spent within a single period against the given target
+func (g *Goal) EvaluatePeriod(start time.Time, actualSeconds, targetSeconds int64) string {
+	if g.IsIgnoredDay(start) || (g.IgnoreZeroDays && actualSeconds == 0) {
+		return GoalStatusIgnored
+	}
+	if g.IsLimit() {
+		if actualSeconds <= targetSeconds {
+			return GoalStatusSuccess
 		}
+		return GoalStatusFailed
 	}
-	if g.Editors != nil && len(g.Editors) > 0 {
-		return &Filters{
-			Editor: OrFilter(g.Editors),
+	if targetSeconds <= 0 {
+		// nothing to reach, e.g. relative goals without any time spent in the previous period
+		return GoalStatusIgnored
+	}
+	if actualSeconds >= targetSeconds {
+		return GoalStatusSuccess
+	}
+	return GoalStatusFailed
+}
+
+// ComputeGoalStreaks returns the current and the longest number of consecutive successful periods.
+// Periods are expected in chronological order, ignored and snoozed periods neither extend nor break a streak.
+func ComputeGoalStreaks(periods []*GoalPeriod) (current int, longest int) {
+	for _, p := range periods {
+		switch p.Status {
+		case GoalStatusSuccess:
+			current++
+			if current > longest {
+				longest = current
+			}
+		case GoalStatusFailed:
+			current = 0
 		}
 	}
-	if g.Projects != nil && len(g.Projects) > 0 {
-		return &Filters{
-			Project: OrFilter(g.Projects),
+	return current, longest
+}
+
+// ComputeGoalCumulativeStatus summarizes the given periods as success, failed or partial, or ignored if none of them counted
+func ComputeGoalCumulativeStatus(periods []*GoalPeriod) string {
+	var succeeded, failed int
+	for _, p := range periods {
+		switch p.Status {
+		case GoalStatusSuccess:
+			succeeded++
+		case GoalStatusFailed:
+			failed++
 		}
 	}
-	if g.Categories != nil && len(g.Categories) > 0 {
-		return &Filters{
-			Category: OrFilter(g.Categories),
+	switch {
+	case succeeded > 0 && failed > 0:
+		return GoalStatusPartial
+	case succeeded > 0:
+		return GoalStatusSuccess
+	case failed > 0:
+		return GoalStatusFailed
+	}
+	return GoalStatusIgnored
+}
+
+// GetGoalSummaryFilter combines all of the goal's dimensions, i.e. time counts if it matches any of the values of every given dimension
+func (g *Goal) GetGoalSummaryFilter() *Filters {
+	filters := &Filters{}
+	if len(g.Languages) > 0 {
+		filters.Language = OrFilter(g.Languages)
+	}
+	if len(g.Editors) > 0 {
+		filters.Editor = OrFilter(g.Editors)
+	}
+	if len(g.Projects) > 0 {
+		filters.Project = OrFilter(g.Projects)
+	}
+	if len(g.Categories) > 0 {
+		filters.Category = OrFilter(g.Categories)
+	}
+	return filters
+}
+
+// GoalUpdate holds the settings of a goal that can be changed after its creation, fields not given are left as they are
+type GoalUpdate struct {
+	Title          *string  `json:"title"`
+	IsEnabled      *bool    `json:"is_enabled"`
+	IsSnoozed      *bool    `json:"is_snoozed"`
+	SnoozeUntil    *int64   `json:"snooze_until"`
+	IgnoreDays     []string `json:"ignore_days"`
+	IgnoreZeroDays *bool    `json:"ignore_zero_days"`
+}
+
+func (u *GoalUpdate) Apply(g *Goal) {
+	if u.Title != nil {
+		g.CustomTitle = u.Title
+	}
+	if u.IsEnabled != nil {
+		g.IsEnabled = *u.IsEnabled
+	}
+	if u.IsSnoozed != nil {
+		g.IsSnoozed = *u.IsSnoozed
+		if !g.IsSnoozed {
+			g.SnoozeUntil = 0
 		}
 	}
-	return &Filters{}
+	if u.SnoozeUntil != nil {
+		g.SnoozeUntil = *u.SnoozeUntil
+	}
+	if u.IgnoreDays != nil {
+		g.IgnoreDays = u.IgnoreDays
+	}
+	if u.IgnoreZeroDays != nil {
+		g.IgnoreZeroDays = *u.IgnoreZeroDays
+	}
 }
 
 type NewGoal struct {
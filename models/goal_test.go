@@ -0,0 +1,149 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoal_PeriodAt(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	ts := time.Date(2026, 10, 15, 13, 30, 0, 0, tz) // thursday
+
+	start, end := (&Goal{Delta: GoalDeltaDay}).PeriodAt(ts)
+	assert.Equal(t, time.Date(2026, 10, 15, 0, 0, 0, 0, tz), start)
+	assert.Equal(t, time.Date(2026, 10, 16, 0, 0, 0, 0, tz), end)
+
+	start, end = (&Goal{Delta: GoalDeltaWeek}).PeriodAt(ts)
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, tz), start)
+	assert.Equal(t, time.Date(2026, 10, 19, 0, 0, 0, 0, tz), end)
+
+	// sunday still belongs to the week starting on the previous monday
+	start, _ = (&Goal{Delta: GoalDeltaWeek}).PeriodAt(time.Date(2026, 10, 18, 23, 0, 0, 0, tz))
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, tz), start)
+
+	// day of daylight saving time change lasts 25 hours
+	start, end = (&Goal{Delta: GoalDeltaDay}).PeriodAt(time.Date(2026, 10, 25, 12, 0, 0, 0, tz))
+	assert.Equal(t, 25*time.Hour, end.Sub(start))
+}
+
+func TestGoal_EvaluatePeriod(t *testing.T) {
+	saturday := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	monday := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	sut := &Goal{Delta: GoalDeltaDay, Seconds: 3600, IgnoreDays: []string{"saturday", "Sunday"}}
+
+	assert.Equal(t, GoalStatusSuccess, sut.EvaluatePeriod(monday, 3600, 3600))
+	assert.Equal(t, GoalStatusFailed, sut.EvaluatePeriod(monday, 3599, 3600))
+	assert.Equal(t, GoalStatusFailed, sut.EvaluatePeriod(monday, 0, 3600))
+	assert.Equal(t, GoalStatusIgnored, sut.EvaluatePeriod(saturday, 0, 3600))
+	assert.Equal(t, GoalStatusIgnored, sut.EvaluatePeriod(monday, 60, 0))
+
+	sut.IgnoreZeroDays = true
+	assert.Equal(t, GoalStatusIgnored, sut.EvaluatePeriod(monday, 0, 3600))
+
+	// ignored days don't apply to weekly goals
+	sut.Delta = GoalDeltaWeek
+	assert.Equal(t, GoalStatusFailed, sut.EvaluatePeriod(saturday, 60, 3600))
+}
+
+func TestGoal_EvaluatePeriod_Limit(t *testing.T) {
+	monday := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	sut := &Goal{Delta: GoalDeltaDay, Seconds: 7200, TargetDirection: GoalDirectionLess}
+
+	assert.Equal(t, GoalStatusSuccess, sut.EvaluatePeriod(monday, 0, 7200))
+	assert.Equal(t, GoalStatusSuccess, sut.EvaluatePeriod(monday, 7200, 7200))
+	assert.Equal(t, GoalStatusFailed, sut.EvaluatePeriod(monday, 7201, 7200))
+	assert.Equal(t, GoalStatusFailed, sut.EvaluatePeriod(monday, 1, 0))
+}
+
+func TestGoal_TargetSeconds(t *testing.T) {
+	assert.Equal(t, int64(3600), (&Goal{Seconds: 3600}).TargetSeconds(1000))
+
+	sut := &Goal{Seconds: 1800, ImproveByPercent: 10}
+	assert.Equal(t, int64(3960), sut.TargetSeconds(3600))
+	assert.Equal(t, int64(1800), sut.TargetSeconds(0)) // falls back to fixed target
+
+	sut.TargetDirection = GoalDirectionLess
+	assert.Equal(t, int64(3240), sut.TargetSeconds(3600))
+
+	sut.ImproveByPercent = 150
+	assert.Equal(t, int64(0), sut.TargetSeconds(3600))
+}
+
+func TestGoal_PreviousPeriod(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+
+	start, end := (&Goal{Delta: GoalDeltaDay}).PreviousPeriod(time.Date(2026, 10, 26, 0, 0, 0, 0, tz))
+	assert.Equal(t, time.Date(2026, 10, 25, 0, 0, 0, 0, tz), start)
+	assert.Equal(t, time.Date(2026, 10, 26, 0, 0, 0, 0, tz), end)
+
+	start, _ = (&Goal{Delta: GoalDeltaWeek}).PreviousPeriod(time.Date(2026, 10, 19, 0, 0, 0, 0, tz))
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, tz), start)
+}
+
+func TestGoal_GetGoalSummaryFilter(t *testing.T) {
+	sut := &Goal{Languages: []string{"Go", "Rust"}, Projects: []string{"wakapi"}, Categories: []string{"meeting"}}
+	filters := sut.GetGoalSummaryFilter()
+
+	assert.Equal(t, OrFilter{"Go", "Rust"}, filters.Language)
+	assert.Equal(t, OrFilter{"wakapi"}, filters.Project)
+	assert.Equal(t, OrFilter{"meeting"}, filters.Category)
+	assert.Nil(t, filters.Editor)
+
+	assert.True(t, filters.MatchDuration(&Duration{Language: "Rust", Project: "wakapi", Category: "meeting"}))
+	assert.False(t, filters.MatchDuration(&Duration{Language: "Rust", Project: "other", Category: "meeting"}))
+	assert.False(t, filters.MatchDuration(&Duration{Language: "Rust", Project: "wakapi", Category: "coding"}))
+
+	assert.True(t, (&Goal{}).GetGoalSummaryFilter().IsEmpty())
+}
+
+func TestGoal_GetTitle(t *testing.T) {
+	assert.Equal(t, "Code at least 2.00 hrs per day", (&Goal{Seconds: 7200, Delta: GoalDeltaDay}).GetTitle())
+	assert.Equal(t, "Code less than 2.00 hrs per day in categories meeting", (&Goal{Seconds: 7200, Delta: GoalDeltaDay, TargetDirection: GoalDirectionLess, Categories: []string{"meeting"}}).GetTitle())
+	assert.Equal(t, "Code 10% more than the previous week in languages Go in projects wakapi", (&Goal{Delta: GoalDeltaWeek, ImproveByPercent: 10, Languages: []string{"Go"}, Projects: []string{"wakapi"}}).GetTitle())
+}
+
+func TestGoal_IsSnoozedAt(t *testing.T) {
+	now := time.Now()
+
+	assert.False(t, (&Goal{}).IsSnoozedAt(now))
+	assert.True(t, (&Goal{IsSnoozed: true}).IsSnoozedAt(now))
+	assert.True(t, (&Goal{IsSnoozed: true, SnoozeUntil: now.Add(time.Hour).Unix()}).IsSnoozedAt(now))
+	assert.False(t, (&Goal{IsSnoozed: true, SnoozeUntil: now.Add(-time.Hour).Unix()}).IsSnoozedAt(now))
+}
+
+func TestComputeGoalStreaks(t *testing.T) {
+	periods := func(statuses ...string) []*GoalPeriod {
+		result := make([]*GoalPeriod, len(statuses))
+		for i, s := range statuses {
+			result[i] = &GoalPeriod{Status: s}
+		}
+		return result
+	}
+
+	current, longest := ComputeGoalStreaks(periods())
+	assert.Equal(t, 0, current)
+	assert.Equal(t, 0, longest)
+
+	current, longest = ComputeGoalStreaks(periods(GoalStatusSuccess, GoalStatusSuccess, GoalStatusSuccess, GoalStatusFailed, GoalStatusSuccess))
+	assert.Equal(t, 1, current)
+	assert.Equal(t, 3, longest)
+
+	current, longest = ComputeGoalStreaks(periods(GoalStatusSuccess, GoalStatusIgnored, GoalStatusSnoozed, GoalStatusSuccess))
+	assert.Equal(t, 2, current)
+	assert.Equal(t, 2, longest)
+
+	current, longest = ComputeGoalStreaks(periods(GoalStatusSuccess, GoalStatusFailed))
+	assert.Equal(t, 0, current)
+	assert.Equal(t, 1, longest)
+}
+
+func TestComputeGoalCumulativeStatus(t *testing.T) {
+	assert.Equal(t, GoalStatusSuccess, ComputeGoalCumulativeStatus([]*GoalPeriod{{Status: GoalStatusSuccess}, {Status: GoalStatusIgnored}}))
+	assert.Equal(t, GoalStatusFailed, ComputeGoalCumulativeStatus([]*GoalPeriod{{Status: GoalStatusFailed}}))
+	assert.Equal(t, GoalStatusPartial, ComputeGoalCumulativeStatus([]*GoalPeriod{{Status: GoalStatusFailed}, {Status: GoalStatusSuccess}}))
+	assert.Equal(t, GoalStatusIgnored, ComputeGoalCumulativeStatus([]*GoalPeriod{{Status: GoalStatusSnoozed}}))
+}
@@ -0,0 +1,36 @@
+package models
+
+const (
+	ImportJobQueued    = "queued"
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+)
+
+const (
+	ImportSourceWakatime     = "wakatime"
+	ImportSourceWakatimeFile = "wakatime_file"
+	ImportSourceWakapiFile   = "wakapi_file"
+	ImportSourceCsvFile      = "csv_file"
+)
+
+// ImportJob tracks the state and progress of a single data import run in the background
+type ImportJob struct {
+	ID         string      `json:"id" gorm:"primary_key"`
+	User       *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string      `json:"user_id" gorm:"not null; index:idx_import_job_user"`
+	Source     string      `json:"source" gorm:"not null; size:32"`
+	Status     string      `json:"status" gorm:"not null; default:'queued'; size:16"`
+	Received   int64       `json:"received"` // heartbeats received from the importer so far
+	Imported   int64       `json:"imported"` // heartbeats actually inserted
+	Skipped    int64       `json:"skipped"`  // heartbeats skipped for already being present
+	Error      string      `json:"error,omitempty"`
+	CreatedAt  CustomTime  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	StartedAt  *CustomTime `json:"started_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	FinishedAt *CustomTime `json:"finished_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	UpdatedAt  CustomTime  `json:"updated_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"` // bumped with every progress update
+}
+
+func (j *ImportJob) IsFinished() bool {
+	return j.Status == ImportJobCompleted || j.Status == ImportJobFailed
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportJob_IsFinished(t *testing.T) {
+	assert.False(t, (&ImportJob{Status: ImportJobQueued}).IsFinished())
+	assert.False(t, (&ImportJob{Status: ImportJobRunning}).IsFinished())
+	assert.True(t, (&ImportJob{Status: ImportJobCompleted}).IsFinished())
+	assert.True(t, (&ImportJob{Status: ImportJobFailed}).IsFinished())
+}
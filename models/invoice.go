@@ -2,15 +2,33 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"strconv"
 	"time"
 )
 
+const (
+	InvoiceStatusDraft   = "draft"
+	InvoiceStatusSent    = "sent"
+	InvoiceStatusPaid    = "paid"
+	InvoiceStatusOverdue = "overdue"
+	InvoiceStatusVoid    = "void"
+)
+
+const (
+	DefaultInvoiceNumberPrefix    = "INV-"
+	DefaultInvoicePaymentTermDays = 30
+)
+
 type Invoice struct {
 	ID             string            `json:"id" gorm:"primary_key"`
-	UserID         string            `json:"user_id"`
+	UserID         string            `json:"user_id" gorm:"uniqueIndex:idx_invoice_user_number"`
+	Number         int               `json:"number" gorm:"uniqueIndex:idx_invoice_user_number"`
 	InvoiceID      string            `json:"invoice_id"`
+	Status         string            `json:"status" gorm:"default:draft; size:16"`
 	ClientID       string            `json:"client_id"`
+	Currency       string            `json:"currency" gorm:"size:3"` // copied from the client when the invoice is created
 	Origin         string            `json:"origin"`
 	Destination    string            `json:"destination"`
 	InvoiceSummary string            `json:"invoice_summary"`
@@ -21,9 +39,196 @@ type Invoice struct {
 	LineItems      []InvoiceLineItem `json:"line_items" gorm:"serializer:json"`
 	StartDate      time.Time         `json:"start_date" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
 	EndDate        time.Time         `json:"end_date" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	DueDate        *time.Time        `json:"due_date" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	SentAt         *time.Time        `json:"sent_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	PaidAt         *time.Time        `json:"paid_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
 	CreatedAt      CustomTime        `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
 	UpdatedAt      CustomTime        `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
 	Client         Client            `json:"client"`
+	Payments       []*InvoicePayment `json:"payments" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+type InvoicePayment struct {
+	ID        string     `json:"id" gorm:"primary_key"`
+	InvoiceID string     `json:"invoice_id" gorm:"index:idx_invoice_payment_invoice"`
+	Amount    float64    `json:"amount"`
+	Method    string     `json:"method"`
+	Note      string     `json:"note"`
+	PaidAt    time.Time  `json:"paid_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type NewInvoicePayment struct {
+	Amount float64 `json:"amount"`
+	Method string  `json:"method"`
+	Note   string  `json:"note"`
+	PaidAt string  `json:"paid_at"` // date or date time, defaults to now
+}
+
+type InvoiceStatusUpdate struct {
+	Status  string `json:"status"`   // either sent or void, paid and overdue follow from payments and due date
+	DueDate string `json:"due_date"` // only considered when sending, defaults to the user's payment terms
+}
+
+type InvoiceSendRequest struct {
+	To      string `json:"to"`       // defaults to the client's email address
+	Message string `json:"message"`  // optional personal note included in the mail
+	DueDate string `json:"due_date"` // see InvoiceStatusUpdate
+}
+
+// InvoiceSettings holds a user's preferences for newly issued invoices
+type InvoiceSettings struct {
+	UserID          string `json:"-" gorm:"primary_key"`
+	User            *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	NumberPrefix    string `json:"number_prefix" gorm:"size:32"`
+	PaymentTermDays int    `json:"payment_term_days"`
+	HomeCurrency    string `json:"home_currency" gorm:"size:3"` // revenue is reported in this currency
+}
+
+func DefaultInvoiceSettings(userID string) *InvoiceSettings {
+	return &InvoiceSettings{
+		UserID:          userID,
+		NumberPrefix:    DefaultInvoiceNumberPrefix,
+		PaymentTermDays: DefaultInvoicePaymentTermDays,
+		HomeCurrency:    DefaultCurrency,
+	}
+}
+
+func (s *InvoiceSettings) IsValid() bool {
+	return len(s.NumberPrefix) <= 32 && s.PaymentTermDays >= 0 && s.PaymentTermDays <= 365 && IsValidCurrency(s.HomeCurrency)
+}
+
+// HomeCurrencyOrDefault accounts for settings stored before a home currency could be chosen
+func (s *InvoiceSettings) HomeCurrencyOrDefault() string {
+	if s.HomeCurrency == "" {
+		return DefaultCurrency
+	}
+	return s.HomeCurrency
+}
+
+// FormatNumber renders the human-readable invoice number, e.g. INV-0042
+func (s *InvoiceSettings) FormatNumber(number int) string {
+	return fmt.Sprintf("%s%04d", s.NumberPrefix, number)
+}
+
+func (i *Invoice) IsEditable() bool {
+	return i.Status == InvoiceStatusDraft || i.Status == ""
+}
+
+// IsOpen returns whether the invoice was issued and is still awaiting (full) payment
+func (i *Invoice) IsOpen() bool {
+	return i.Status == InvoiceStatusSent || i.Status == InvoiceStatusOverdue
+}
+
+func (i *Invoice) IsOverdueAt(t time.Time) bool {
+	// due dates are calendar days, so an invoice is only overdue once its due day has passed entirely
+	return i.DueDate != nil && !t.Before(i.DueDate.AddDate(0, 0, 1))
+}
+
+// CurrencyCode returns the invoice's currency, falling back to the client's for invoices created before it was stored
+func (i *Invoice) CurrencyCode() string {
+	if i.Currency != "" {
+		return i.Currency
+	}
+	return NormalizeCurrency(i.Client.Currency)
+}
+
+func (i *Invoice) TotalHours() float64 {
+	var hours float64
+	for _, item := range i.LineItems {
+		hours += item.Hours()
+	}
+	return hours
+}
+
+func (i *Invoice) Subtotal() float64 {
+	var subtotal float64
+	for _, item := range i.LineItems {
+		subtotal += item.AmountOr(i.Client.HourlyRate)
+	}
+	return roundCents(subtotal)
+}
+
+// MarshalJSON adds the derived money totals to the invoice
+func (i Invoice) MarshalJSON() ([]byte, error) {
+	type Alias Invoice
+
+	lineItems := make([]InvoiceLineItem, len(i.LineItems))
+	for j, item := range i.LineItems {
+		item.Amount = item.AmountOr(i.Client.HourlyRate)
+		lineItems[j] = item
+	}
+	i.LineItems = lineItems
+	i.Currency = i.CurrencyCode()
+
+	return json.Marshal(&struct {
+		Alias
+		Subtotal   float64 `json:"subtotal"`
+		TaxAmount  float64 `json:"tax_amount"`
+		Total      float64 `json:"total"`
+		AmountPaid float64 `json:"amount_paid"`
+		AmountDue  float64 `json:"amount_due"`
+	}{
+		Alias:      Alias(i),
+		Subtotal:   i.Subtotal(),
+		TaxAmount:  i.TaxAmount(),
+		Total:      i.Total(),
+		AmountPaid: i.AmountPaid(),
+		AmountDue:  i.AmountDue(),
+	})
+}
+
+func (i *Invoice) TaxAmount() float64 {
+	if i.ExcludeTax || i.Tax <= 0 {
+		return 0
+	}
+	return roundCents(i.Subtotal() * i.Tax / 100)
+}
+
+func (i *Invoice) Total() float64 {
+	return roundCents(i.Subtotal() + i.TaxAmount())
+}
+
+func (i *Invoice) AmountPaid() float64 {
+	var paid float64
+	for _, p := range i.Payments {
+		paid += p.Amount
+	}
+	return roundCents(paid)
+}
+
+func (i *Invoice) AmountDue() float64 {
+	return math.Max(roundCents(i.Total()-i.AmountPaid()), 0)
+}
+
+// ResolveStatus derives the status of an issued invoice from its payments and due date. Drafts and voided invoices are left untouched.
+func (i *Invoice) ResolveStatus(now time.Time) string {
+	if !i.IsOpen() && i.Status != InvoiceStatusPaid {
+		return i.Status
+	}
+	if len(i.Payments) > 0 && i.AmountDue() == 0 {
+		return InvoiceStatusPaid
+	}
+	if i.IsOverdueAt(now) {
+		return InvoiceStatusOverdue
+	}
+	return InvoiceStatusSent
+}
+
+// CanTransitionTo checks whether the given status can be set explicitly. Paid and overdue are never set by hand, but follow from payments and the due date.
+func (i *Invoice) CanTransitionTo(status string) bool {
+	switch status {
+	case InvoiceStatusSent:
+		// sending an already issued invoice again allows for adjusting its due date
+		return i.IsEditable() || i.IsOpen()
+	case InvoiceStatusVoid:
+		return i.IsEditable() || i.IsOpen()
+	}
+	return false
+}
+
+func (p *NewInvoicePayment) IsValid() bool {
+	return p.Amount > 0 && !math.IsInf(p.Amount, 0) && len(p.Method) <= 64 && len(p.Note) <= 1024
 }
 
 type NewInvoice struct {
@@ -39,9 +244,47 @@ type NewInvoice struct {
 }
 
 type InvoiceLineItem struct {
-	Title         string `json:"title"`
-	TotalSeconds  int64  `json:"total_seconds"`
-	AutoGenerated bool   `json:"auto_generated"`
+	Title         string   `json:"title"`
+	TotalSeconds  int64    `json:"total_seconds"`  // time tracked
+	BilledSeconds *int64   `json:"billed_seconds"` // time billed according to the client's rounding policy, nil if equal to the tracked time
+	AutoGenerated bool     `json:"auto_generated"`
+	Project       string   `json:"project,omitempty"`
+	Category      string   `json:"category,omitempty"`
+	HourlyRate    *float64 `json:"hourly_rate"` // nil for items created before rates were tracked per item, the client's rate applies then
+	Amount        float64  `json:"amount"`      // derived from time and rate, recomputed whenever the invoice is serialized
+}
+
+// Hours returns the billed hours
+func (i *InvoiceLineItem) Hours() float64 {
+	return float64(i.BillableSeconds()) / 3600
+}
+
+func (i *InvoiceLineItem) TrackedHours() float64 {
+	return float64(i.TotalSeconds) / 3600
+}
+
+func (i *InvoiceLineItem) BillableSeconds() int64 {
+	if i.BilledSeconds != nil {
+		return *i.BilledSeconds
+	}
+	return i.TotalSeconds
+}
+
+// IsRounded returns whether billed and tracked time differ
+func (i *InvoiceLineItem) IsRounded() bool {
+	return i.BillableSeconds() != i.TotalSeconds
+}
+
+// RateOr returns the item's hourly rate or the given fallback if none is set
+func (i *InvoiceLineItem) RateOr(fallback float64) float64 {
+	if i.HourlyRate != nil {
+		return *i.HourlyRate
+	}
+	return fallback
+}
+
+func (i *InvoiceLineItem) AmountOr(fallbackRate float64) float64 {
+	return roundCents(i.Hours() * i.RateOr(fallbackRate))
 }
 
 func (i *InvoiceLineItem) UnmarshalJSON(data []byte) error {
@@ -84,6 +327,7 @@ type InvoiceUpdate struct {
 	Heading        *string           `json:"heading"`
 	FinalMessage   *string           `json:"final_message"`
 	LineItems      []InvoiceLineItem `json:"line_items" gorm:"serializer:json"`
+	DueDate        *time.Time        `json:"due_date" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
 }
 
 type NewInvoiceData struct {
@@ -96,4 +340,9 @@ type NewlyGeneratedInvoiceData struct {
 	StartDate time.Time         `json:"start_date"`
 	EndDate   time.Time         `json:"end_date"`
 	LineItems []InvoiceLineItem `json:"line_items"`
+	Subtotal  float64           `json:"subtotal"`
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
 }
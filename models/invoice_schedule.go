@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	InvoiceFrequencyMonthly  = "monthly"
+	InvoiceFrequencyBiweekly = "biweekly"
+	InvoiceFrequencyCron     = "cron"
+)
+
+// upper bound of days searched for the next run of a cron schedule, expressions like "0 0 30 2 *" never fire
+const invoiceScheduleMaxCronDays = 366
+
+// InvoiceSchedule makes a draft invoice be generated for a client at the end of every billing period. Periods always span
+// whole days in the user's timezone, from PeriodStart up to (excluding) NextRunAt.
+type InvoiceSchedule struct {
+	ID             string     `json:"id" gorm:"primary_key"`
+	User           *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserID         string     `json:"user_id" gorm:"not null; index:idx_invoice_schedule_user"`
+	Client         *Client    `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ClientID       string     `json:"client_id" gorm:"not null; uniqueIndex:idx_invoice_schedule_client"`
+	Frequency      string     `json:"frequency" gorm:"not null; size:16"`
+	CronExpression string     `json:"cron_expression"`
+	IsEnabled      bool       `json:"is_enabled" gorm:"type:bool"`
+	PeriodStart    time.Time  `json:"period_start" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	NextRunAt      time.Time  `json:"next_run_at" gorm:"index:idx_invoice_schedule_next_run" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastRunAt      *time.Time `json:"last_run_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt      CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	UpdatedAt      CustomTime `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type InvoiceScheduleUpdate struct {
+	Frequency      string `json:"frequency"`
+	CronExpression string `json:"cron_expression"`
+	StartDate      string `json:"start_date"` // date the first billing period starts at, defaults to the beginning of the current one
+	IsEnabled      *bool  `json:"is_enabled"`
+}
+
+func (u *InvoiceScheduleUpdate) IsValid() bool {
+	switch u.Frequency {
+	case InvoiceFrequencyMonthly, InvoiceFrequencyBiweekly:
+		return true
+	case InvoiceFrequencyCron:
+		_, err := utils.ParseCron(u.CronExpression)
+		return err == nil
+	}
+	return false
+}
+
+// Reset (re-)starts the schedule with a period beginning at the given date or, if nil, at the start of the period now is in.
+// Both are expected in the user's timezone.
+func (s *InvoiceSchedule) Reset(start *time.Time, now time.Time) error {
+	periodStart := datetime.BeginOfDay(now)
+	if start != nil {
+		periodStart = datetime.BeginOfDay(start.In(now.Location()))
+	} else if s.Frequency == InvoiceFrequencyMonthly {
+		periodStart = datetime.BeginOfMonth(now)
+	}
+
+	nextRun, err := s.NextBoundary(periodStart)
+	if err != nil {
+		return err
+	}
+	s.PeriodStart, s.NextRunAt = periodStart, nextRun
+	return nil
+}
+
+// Advance moves on to the subsequent billing period, with days as seen in the given timezone
+func (s *InvoiceSchedule) Advance(tz *time.Location) error {
+	nextRun, err := s.NextBoundary(s.NextRunAt.In(tz))
+	if err != nil {
+		return err
+	}
+	s.PeriodStart, s.NextRunAt = s.NextRunAt.In(tz), nextRun
+	return nil
+}
+
+// NextBoundary returns the start of the billing period following the one starting at t. Cron schedules fire at whatever
+// time of the day, but invoices cover entire days, so their boundaries are truncated to midnight.
+func (s *InvoiceSchedule) NextBoundary(t time.Time) (time.Time, error) {
+	switch s.Frequency {
+	case InvoiceFrequencyMonthly:
+		return datetime.BeginOfMonth(t).AddDate(0, 1, 0), nil
+	case InvoiceFrequencyBiweekly:
+		return datetime.BeginOfDay(t).AddDate(0, 0, 14), nil
+	case InvoiceFrequencyCron:
+		schedule, err := utils.ParseCron(s.CronExpression)
+		if err != nil {
+			return time.Time{}, err
+		}
+		for next := schedule.Next(t); !next.IsZero() && next.Before(t.AddDate(0, 0, invoiceScheduleMaxCronDays)); next = schedule.Next(next) {
+			if boundary := datetime.BeginOfDay(next); boundary.After(t) {
+				return boundary, nil
+			}
+		}
+		return time.Time{}, errors.New("cron expression does not fire within a year")
+	}
+	return time.Time{}, errors.New("invalid invoice frequency")
+}
+
+func (s *InvoiceSchedule) IsDue(now time.Time) bool {
+	return s.IsEnabled && !s.NextRunAt.After(now)
+}
+
+// Period returns the first and the last day of the current billing period, in line with an invoice's (inclusive) end date
+func (s *InvoiceSchedule) Period(tz *time.Location) (time.Time, time.Time) {
+	return s.PeriodStart.In(tz), datetime.BeginOfDay(s.NextRunAt.In(tz).Add(-1 * time.Nanosecond))
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoiceSchedule_NextBoundary(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	jan31 := time.Date(2024, 1, 31, 0, 0, 0, 0, tz)
+
+	monthly := &InvoiceSchedule{Frequency: InvoiceFrequencyMonthly}
+	next, _ := monthly.NextBoundary(jan31)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, tz), next)
+
+	biweekly := &InvoiceSchedule{Frequency: InvoiceFrequencyBiweekly}
+	next, _ = biweekly.NextBoundary(jan31)
+	assert.Equal(t, time.Date(2024, 2, 14, 0, 0, 0, 0, tz), next)
+
+	// every friday at 6 pm, i.e. periods from friday through thursday
+	weekly := &InvoiceSchedule{Frequency: InvoiceFrequencyCron, CronExpression: "0 18 * * 5"}
+	next, _ = weekly.NextBoundary(jan31)
+	assert.Equal(t, time.Date(2024, 2, 2, 0, 0, 0, 0, tz), next)
+	next, _ = weekly.NextBoundary(next)
+	assert.Equal(t, time.Date(2024, 2, 9, 0, 0, 0, 0, tz), next)
+
+	_, err := (&InvoiceSchedule{Frequency: InvoiceFrequencyCron, CronExpression: "0 0 30 2 *"}).NextBoundary(jan31)
+	assert.Error(t, err)
+}
+
+func TestInvoiceSchedule_ResetAndAdvance(t *testing.T) {
+	tz, _ := time.LoadLocation("America/New_York")
+	now := time.Date(2024, 3, 15, 22, 30, 0, 0, tz)
+
+	sut := &InvoiceSchedule{Frequency: InvoiceFrequencyMonthly, IsEnabled: true}
+	assert.Nil(t, sut.Reset(nil, now))
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, tz), sut.PeriodStart)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, tz), sut.NextRunAt)
+	assert.False(t, sut.IsDue(now))
+	assert.True(t, sut.IsDue(sut.NextRunAt))
+
+	start, end := sut.Period(tz)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, tz), start)
+	assert.Equal(t, time.Date(2024, 3, 31, 0, 0, 0, 0, tz), end)
+
+	// as loaded from the database
+	sut.PeriodStart, sut.NextRunAt = sut.PeriodStart.UTC(), sut.NextRunAt.UTC()
+	assert.Nil(t, sut.Advance(tz))
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, tz), sut.PeriodStart)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, tz), sut.NextRunAt)
+
+	customStart := time.Date(2024, 3, 4, 0, 0, 0, 0, tz)
+	sut = &InvoiceSchedule{Frequency: InvoiceFrequencyBiweekly}
+	assert.Nil(t, sut.Reset(&customStart, now))
+	assert.Equal(t, customStart, sut.PeriodStart)
+	assert.Equal(t, time.Date(2024, 3, 18, 0, 0, 0, 0, tz), sut.NextRunAt)
+	assert.False(t, sut.IsDue(sut.NextRunAt))
+}
+
+func TestInvoiceScheduleUpdate_IsValid(t *testing.T) {
+	assert.True(t, (&InvoiceScheduleUpdate{Frequency: InvoiceFrequencyMonthly}).IsValid())
+	assert.True(t, (&InvoiceScheduleUpdate{Frequency: InvoiceFrequencyCron, CronExpression: "0 0 1,15 * *"}).IsValid())
+	assert.True(t, (&InvoiceScheduleUpdate{Frequency: InvoiceFrequencyCron, CronExpression: "@monthly"}).IsValid())
+	assert.False(t, (&InvoiceScheduleUpdate{Frequency: InvoiceFrequencyCron, CronExpression: "every month"}).IsValid())
+	assert.False(t, (&InvoiceScheduleUpdate{Frequency: "weekly"}).IsValid())
+}
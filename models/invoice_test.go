@@ -0,0 +1,93 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoice_Totals(t *testing.T) {
+	sut := &Invoice{
+		Client: Client{HourlyRate: 80},
+		Tax:    19,
+		LineItems: []InvoiceLineItem{
+			{Title: "wakapi", TotalSeconds: 5400},    // 1.5 hrs
+			{Title: "anchr", TotalSeconds: 3600 * 2}, // 2 hrs
+		},
+	}
+
+	assert.Equal(t, 3.5, sut.TotalHours())
+	assert.Equal(t, 280.0, sut.Subtotal())
+	assert.Equal(t, 53.2, sut.TaxAmount())
+	assert.Equal(t, 333.2, sut.Total())
+	assert.Equal(t, 333.2, sut.AmountDue())
+
+	sut.Payments = []*InvoicePayment{{Amount: 100}, {Amount: 33.1}}
+	assert.Equal(t, 133.1, sut.AmountPaid())
+	assert.Equal(t, 200.1, sut.AmountDue())
+
+	sut.ExcludeTax = true
+	assert.Equal(t, 0.0, sut.TaxAmount())
+	assert.Equal(t, 146.9, sut.AmountDue())
+
+	sut.Payments = append(sut.Payments, &InvoicePayment{Amount: 500})
+	assert.Equal(t, 0.0, sut.AmountDue())
+}
+
+func TestInvoice_ResolveStatus(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	sut := &Invoice{
+		Status:    InvoiceStatusSent,
+		DueDate:   &due,
+		Client:    Client{HourlyRate: 100},
+		LineItems: []InvoiceLineItem{{TotalSeconds: 3600}},
+	}
+
+	// still due today
+	assert.Equal(t, InvoiceStatusSent, sut.ResolveStatus(now))
+	assert.Equal(t, InvoiceStatusOverdue, sut.ResolveStatus(now.AddDate(0, 0, 1)))
+
+	sut.Payments = []*InvoicePayment{{Amount: 50}}
+	assert.Equal(t, InvoiceStatusOverdue, sut.ResolveStatus(now.AddDate(0, 0, 1)))
+
+	sut.Payments = append(sut.Payments, &InvoicePayment{Amount: 50})
+	assert.Equal(t, InvoiceStatusPaid, sut.ResolveStatus(now.AddDate(0, 0, 1)))
+
+	// payment deleted again
+	sut.Status = InvoiceStatusPaid
+	sut.Payments = sut.Payments[:1]
+	assert.Equal(t, InvoiceStatusSent, sut.ResolveStatus(now))
+
+	sut.Status = InvoiceStatusVoid
+	assert.Equal(t, InvoiceStatusVoid, sut.ResolveStatus(now))
+	sut.Status = InvoiceStatusDraft
+	assert.Equal(t, InvoiceStatusDraft, sut.ResolveStatus(now))
+}
+
+func TestInvoice_CanTransitionTo(t *testing.T) {
+	for _, c := range []struct {
+		from, to string
+		ok       bool
+	}{
+		{InvoiceStatusDraft, InvoiceStatusSent, true},
+		{InvoiceStatusDraft, InvoiceStatusVoid, true},
+		{InvoiceStatusDraft, InvoiceStatusPaid, false},
+		{InvoiceStatusSent, InvoiceStatusSent, true},
+		{InvoiceStatusOverdue, InvoiceStatusVoid, true},
+		{InvoiceStatusSent, InvoiceStatusOverdue, false},
+		{InvoiceStatusSent, InvoiceStatusDraft, false},
+		{InvoiceStatusPaid, InvoiceStatusVoid, false},
+		{InvoiceStatusVoid, InvoiceStatusSent, false},
+	} {
+		assert.Equal(t, c.ok, (&Invoice{Status: c.from}).CanTransitionTo(c.to), "%s -> %s", c.from, c.to)
+	}
+}
+
+func TestInvoiceSettings_FormatNumber(t *testing.T) {
+	assert.Equal(t, "INV-0007", DefaultInvoiceSettings("").FormatNumber(7))
+	assert.Equal(t, "2026/12345", (&InvoiceSettings{NumberPrefix: "2026/"}).FormatNumber(12345))
+	assert.Equal(t, "0001", (&InvoiceSettings{}).FormatNumber(1))
+}
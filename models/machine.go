@@ -0,0 +1,73 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// MachineKeyPrefix distinguishes machine keys from personal access tokens and a user's (legacy) api key
+const MachineKeyPrefix = "wakana_mk_"
+
+// MachineKeyScopes are what editor plugins need: sending heartbeats and showing today's coding time in the status bar
+var MachineKeyScopes = []string{
+	ApiTokenResourceHeartbeats + ":" + ApiTokenActionWrite,
+	ApiTokenResourceStats + ":" + ApiTokenActionRead,
+}
+
+// Machine is a host a user codes on, registered as soon as heartbeats are received from it. Optionally, it has a
+// dedicated key, which pins the machine name of all heartbeats sent with it and can be revoked independently.
+// Only a hash of the key is stored, the key itself is returned once upon creation.
+type Machine struct {
+	ID               string             `json:"id" gorm:"primary_key"`
+	User             *User              `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID           string             `json:"-" gorm:"not null; uniqueIndex:idx_machine_user_name"`
+	Name             string             `json:"name" gorm:"not null; uniqueIndex:idx_machine_user_name; size:255"`
+	KeyHash          *string            `json:"-" gorm:"uniqueIndex:idx_machine_key; size:64"`
+	KeyHint          string             `json:"key_hint"` // last characters of the key, empty if there is none
+	FirstHeartbeatAt *CustomTime        `json:"first_heartbeat_at" gorm:"timeScale:3" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastHeartbeatAt  *CustomTime        `json:"last_heartbeat_at" gorm:"timeScale:3" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt        CustomTime         `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	UserAgents       []*PluginUserAgent `json:"user_agents" gorm:"-"`   // plugins seen on the machine
+	Key              string             `json:"key,omitempty" gorm:"-"` // plain key, only set right after creation
+}
+
+// NewMachine is a machine to register ahead of its first heartbeat, in order to issue a key for it
+type NewMachine struct {
+	Name string `json:"name"`
+}
+
+func (m *NewMachine) IsValid() bool {
+	return strings.TrimSpace(m.Name) != "" && len(m.Name) <= 255
+}
+
+func NewMachineSeenAt(userId, name string, seenAt time.Time) *Machine {
+	machine := &Machine{UserID: userId, Name: name}
+	machine.Track(seenAt, seenAt)
+	return machine
+}
+
+func (m *Machine) HasKey() bool {
+	return m.KeyHash != nil && *m.KeyHash != ""
+}
+
+// Track extends the period the machine was seen in by the given one
+func (m *Machine) Track(first, last time.Time) {
+	if m.FirstHeartbeatAt == nil || first.Before(m.FirstHeartbeatAt.T()) {
+		firstHeartbeatAt := CustomTime(first)
+		m.FirstHeartbeatAt = &firstHeartbeatAt
+	}
+	if m.LastHeartbeatAt == nil || last.After(m.LastHeartbeatAt.T()) {
+		lastHeartbeatAt := CustomTime(last)
+		m.LastHeartbeatAt = &lastHeartbeatAt
+	}
+}
+
+// ApiToken returns a token to restrict requests authenticated by the machine's key to MachineKeyScopes
+func (m *Machine) ApiToken() *ApiToken {
+	return &ApiToken{
+		ID:     m.ID,
+		UserID: m.UserID,
+		Name:   m.Name,
+		Scopes: MachineKeyScopes,
+	}
+}
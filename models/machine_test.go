@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMachine_Track(t *testing.T) {
+	t0 := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	sut := NewMachineSeenAt("user1", "laptop", t0)
+
+	assert.Equal(t, t0, sut.FirstHeartbeatAt.T())
+	assert.Equal(t, t0, sut.LastHeartbeatAt.T())
+
+	sut.Track(t0.Add(-time.Hour), t0.Add(time.Hour))
+	assert.Equal(t, t0.Add(-time.Hour), sut.FirstHeartbeatAt.T())
+	assert.Equal(t, t0.Add(time.Hour), sut.LastHeartbeatAt.T())
+
+	sut.Track(t0, t0)
+	assert.Equal(t, t0.Add(-time.Hour), sut.FirstHeartbeatAt.T())
+	assert.Equal(t, t0.Add(time.Hour), sut.LastHeartbeatAt.T())
+
+	sut = &Machine{UserID: "user1", Name: "desktop"}
+	sut.Track(t0, t0)
+	assert.Equal(t, t0, sut.FirstHeartbeatAt.T())
+}
+
+func TestMachine_ApiToken(t *testing.T) {
+	hash := "abc"
+	sut := &Machine{ID: "m1", UserID: "user1", Name: "laptop", KeyHash: &hash}
+	token := sut.ApiToken()
+
+	assert.True(t, sut.HasKey())
+	assert.True(t, token.Grants(ApiTokenResourceHeartbeats, ApiTokenActionWrite))
+	assert.True(t, token.Grants(ApiTokenResourceStats, ApiTokenActionRead))
+	assert.False(t, token.Grants(ApiTokenResourceHeartbeats, ApiTokenActionRead))
+	assert.False(t, token.Grants(ApiTokenResourceSettings, ApiTokenActionRead))
+	assert.False(t, token.Grants(ApiTokenScopeAdmin, ApiTokenActionWrite))
+	assert.False(t, (&Machine{}).HasKey())
+}
+
+func TestNewMachine_IsValid(t *testing.T) {
+	assert.True(t, (&NewMachine{Name: "laptop"}).IsValid())
+	assert.False(t, (&NewMachine{Name: " "}).IsValid())
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/base64"
 	"fmt"
 	"github.com/gofrs/uuid/v5"
 	"strings"
@@ -11,13 +12,21 @@ const HtmlType = "text/html; charset=UTF-8"
 const PlainType = "text/html; charset=UTF-8"
 
 type Mail struct {
-	From      MailAddress
-	To        MailAddresses
-	Subject   string
-	Body      string
-	Type      string
-	Date      time.Time
-	MessageID string
+	From        MailAddress
+	To          MailAddresses
+	ReplyTo     MailAddress
+	Subject     string
+	Body        string
+	Type        string
+	Date        time.Time
+	MessageID   string
+	Attachments []*MailAttachment
+}
+
+type MailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
 }
 
 func (m *Mail) WithText(text string) *Mail {
@@ -32,6 +41,11 @@ func (m *Mail) WithHTML(html string) *Mail {
 	return m
 }
 
+func (m *Mail) WithAttachment(filename, contentType string, data []byte) *Mail {
+	m.Attachments = append(m.Attachments, &MailAttachment{Filename: filename, ContentType: contentType, Data: data})
+	return m
+}
+
 func (m *Mail) Sanitized() *Mail {
 	if m.Type == "" {
 		m.Type = PlainType
@@ -46,24 +60,71 @@ func (m *Mail) Sanitized() *Mail {
 }
 
 func (m *Mail) String() string {
-	return fmt.Sprintf("To: %s\r\n"+
-		"From: %s\r\n"+
-		"Subject: %s\r\n"+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "To: %s\r\n"+
+		"From: %s\r\n",
+		strings.Join(m.To.RawStrings(), ", "),
+		m.From.String(),
+	)
+	if m.ReplyTo != "" {
+		fmt.Fprintf(&sb, "Reply-To: %s\r\n", m.ReplyTo.String())
+	}
+	fmt.Fprintf(&sb, "Subject: %s\r\n"+
 		"Message-ID: %s\r\n"+
-		"MIME-Version: 1.0\r\n"+
+		"MIME-Version: 1.0\r\n",
+		m.Subject,
+		m.MessageID,
+	)
+
+	if len(m.Attachments) == 0 {
+		fmt.Fprintf(&sb, "Content-Type: %s\r\n"+
+			"Content-Transfer-Encoding: 8bit\r\n"+
+			"Date: %s\r\n"+
+			"\r\n"+
+			"%s\r\n",
+			m.Type,
+			m.Date.Format(time.RFC1123Z),
+			m.Body,
+		)
+		return sb.String()
+	}
+
+	boundary := strings.ReplaceAll(uuid.Must(uuid.NewV4()).String(), "-", "")
+	fmt.Fprintf(&sb, "Content-Type: multipart/mixed; boundary=\"%s\"\r\n"+
+		"Date: %s\r\n"+
+		"\r\n"+
+		"--%s\r\n"+
 		"Content-Type: %s\r\n"+
 		"Content-Transfer-Encoding: 8bit\r\n"+
-		"Date: %s\r\n"+
 		"\r\n"+
 		"%s\r\n",
-		strings.Join(m.To.RawStrings(), ", "),
-		m.From.String(),
-		m.Subject,
-		m.MessageID,
-		m.Type,
+		boundary,
 		m.Date.Format(time.RFC1123Z),
+		boundary,
+		m.Type,
 		m.Body,
 	)
+	for _, a := range m.Attachments {
+		fmt.Fprintf(&sb, "--%s\r\n"+
+			"Content-Type: %s; name=\"%s\"\r\n"+
+			"Content-Disposition: attachment; filename=\"%s\"\r\n"+
+			"Content-Transfer-Encoding: base64\r\n"+
+			"\r\n",
+			boundary,
+			a.ContentType,
+			a.Filename,
+			a.Filename,
+		)
+		// base64 encoded lines must not exceed 76 characters
+		encoded := base64.StdEncoding.EncodeToString(a.Data)
+		for len(encoded) > 76 {
+			sb.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		sb.WriteString(encoded + "\r\n")
+	}
+	fmt.Fprintf(&sb, "--%s--\r\n", boundary)
+	return sb.String()
 }
 
 func (m *Mail) Reader() *strings.Reader {
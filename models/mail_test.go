@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMail_String(t *testing.T) {
+	sut := (&Mail{
+		From:    "Wakapi <noreply@wakapi.dev>",
+		To:      MailAddresses{"jane@example.org"},
+		Subject: "Hello",
+	}).WithHTML("<p>Hello</p>").Sanitized()
+
+	out := sut.String()
+	assert.Contains(t, out, "Content-Type: text/html; charset=UTF-8\r\n")
+	assert.NotContains(t, out, "Reply-To")
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\n<p>Hello</p>\r\n"))
+}
+
+func TestMail_String_Attachments(t *testing.T) {
+	data := []byte(strings.Repeat("%PDF-1.4 ", 20))
+
+	sut := (&Mail{
+		From:    "Wakapi <noreply@wakapi.dev>",
+		To:      MailAddresses{"jane@example.org"},
+		ReplyTo: "john@example.org",
+		Subject: "Invoice",
+	}).WithHTML("<p>Invoice</p>").WithAttachment("INV-0001.pdf", "application/pdf", data).Sanitized()
+
+	out := sut.String()
+	assert.Contains(t, out, "Reply-To: john@example.org\r\n")
+	assert.Contains(t, out, "Content-Type: multipart/mixed; boundary=")
+	assert.Contains(t, out, "Content-Disposition: attachment; filename=\"INV-0001.pdf\"\r\n")
+
+	// extract and decode the attachment again
+	boundary := strings.TrimSuffix(strings.SplitN(strings.SplitN(out, "boundary=\"", 2)[1], "\"", 2)[0], "\"")
+	parts := strings.Split(out, "--"+boundary)
+	assert.Len(t, parts, 4) // headers, body, attachment, closing
+	assert.Contains(t, parts[1], "<p>Invoice</p>")
+	assert.Equal(t, "--\r\n", parts[3])
+
+	encoded := strings.SplitN(parts[2], "\r\n\r\n", 2)[1]
+	for _, line := range strings.Split(strings.TrimSpace(encoded), "\r\n") {
+		assert.LessOrEqual(t, len(line), 76)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\r\n", ""))
+	assert.Nil(t, err)
+	assert.Equal(t, data, decoded)
+}
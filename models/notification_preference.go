@@ -0,0 +1,162 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+)
+
+const (
+	NotificationWeeklyReport    = "weekly_report"
+	NotificationDailyDigest     = "daily_digest"
+	NotificationMonthlyReport   = "monthly_report"
+	NotificationYearlyReport    = "yearly_report"
+	NotificationGoalAlerts      = "goal_alerts"
+	NotificationWakatimeFailure = "wakatime_failure"
+	NotificationImportFinished  = "import_finished"
+)
+
+const (
+	NotificationChannelNone  = "none"
+	NotificationChannelEmail = "email"
+)
+
+const notificationDeliveryTimeFormat = "15:04"
+
+// scheduled notifications missed by more than this (e.g. while the server was down) are skipped until their next occurrence
+const notificationCatchUpWindow = 12 * time.Hour
+
+var NotificationKinds = []string{
+	NotificationWeeklyReport,
+	NotificationDailyDigest,
+	NotificationMonthlyReport,
+	NotificationYearlyReport,
+	NotificationGoalAlerts,
+	NotificationWakatimeFailure,
+	NotificationImportFinished,
+}
+
+var NotificationChannels = []string{
+	NotificationChannelNone,
+	NotificationChannelEmail,
+}
+
+// NotificationPreference is a user's choice of how (and, for reports and digests, when) to be notified about one kind of
+// event. Delivery times are wall clock times in the user's timezone. Kinds without a stored preference fall back to defaults.
+type NotificationPreference struct {
+	ID              uint       `json:"-" gorm:"primary_key"`
+	User            *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserID          string     `json:"-" gorm:"not null; uniqueIndex:idx_notification_preference_user_kind"`
+	Kind            string     `json:"kind" gorm:"not null; size:32; uniqueIndex:idx_notification_preference_user_kind"`
+	Channel         string     `json:"channel" gorm:"not null; size:16"`
+	DeliveryTime    string     `json:"delivery_time,omitempty" gorm:"size:5"` // e.g. 18:00, reports and digests only
+	DeliveryWeekday int        `json:"delivery_weekday,omitempty"`            // 0 (sunday) to 6, weekly reports only
+	DeliveryDay     int        `json:"delivery_day,omitempty"`                // day of month 1 to 28, monthly and yearly (in january) reports only
+	LastSentAt      *time.Time `json:"last_sent_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type NotificationPreferenceUpdate struct {
+	Kind            string `json:"kind"`
+	Channel         string `json:"channel"`
+	DeliveryTime    string `json:"delivery_time"`
+	DeliveryWeekday *int   `json:"delivery_weekday"`
+	DeliveryDay     *int   `json:"delivery_day"`
+}
+
+func (u *NotificationPreferenceUpdate) IsValid() bool {
+	if !slices.Contains(NotificationKinds, u.Kind) || !slices.Contains(NotificationChannels, u.Channel) {
+		return false
+	}
+	if u.DeliveryTime != "" {
+		if _, err := time.Parse(notificationDeliveryTimeFormat, u.DeliveryTime); err != nil {
+			return false
+		}
+	}
+	if u.DeliveryWeekday != nil && (*u.DeliveryWeekday < 0 || *u.DeliveryWeekday > 6) {
+		return false
+	}
+	if u.DeliveryDay != nil && (*u.DeliveryDay < 1 || *u.DeliveryDay > 28) {
+		return false
+	}
+	return true
+}
+
+// Apply copies the update onto the preference, leaving delivery settings not given or not applicable to the kind untouched
+func (p *NotificationPreference) Apply(u *NotificationPreferenceUpdate) {
+	p.Channel = u.Channel
+	if !p.IsScheduled() {
+		return
+	}
+	if u.DeliveryTime != "" {
+		p.DeliveryTime = u.DeliveryTime
+	}
+	if u.DeliveryWeekday != nil && p.Kind == NotificationWeeklyReport {
+		p.DeliveryWeekday = *u.DeliveryWeekday
+	}
+	if u.DeliveryDay != nil && (p.Kind == NotificationMonthlyReport || p.Kind == NotificationYearlyReport) {
+		p.DeliveryDay = *u.DeliveryDay
+	}
+}
+
+// IsScheduled returns whether notifications of this kind are sent at a chosen time rather than as events occur
+func (p *NotificationPreference) IsScheduled() bool {
+	return p.Kind == NotificationWeeklyReport || p.Kind == NotificationDailyDigest || p.Kind == NotificationMonthlyReport || p.Kind == NotificationYearlyReport
+}
+
+func (p *NotificationPreference) IsEnabled(channel string) bool {
+	return p.Channel == channel
+}
+
+// LastOccurrence returns the most recent scheduled delivery at or before now, in now's timezone
+func (p *NotificationPreference) LastOccurrence(now time.Time) time.Time {
+	clock, err := time.Parse(notificationDeliveryTimeFormat, p.DeliveryTime)
+	if err != nil {
+		clock = time.Time{}
+	}
+	at := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location())
+	}
+
+	switch p.Kind {
+	case NotificationWeeklyReport:
+		occurrence := at(now.AddDate(0, 0, -((int(now.Weekday()) - p.DeliveryWeekday + 7) % 7)))
+		if occurrence.After(now) {
+			occurrence = at(occurrence.AddDate(0, 0, -7))
+		}
+		return occurrence
+	case NotificationMonthlyReport:
+		day := max(p.DeliveryDay, 1)
+		occurrence := at(datetime.BeginOfMonth(now).AddDate(0, 0, day-1))
+		if occurrence.After(now) {
+			occurrence = at(datetime.BeginOfMonth(now).AddDate(0, -1, day-1))
+		}
+		return occurrence
+	case NotificationYearlyReport:
+		day := max(p.DeliveryDay, 1)
+		occurrence := at(datetime.BeginOfYear(now).AddDate(0, 0, day-1))
+		if occurrence.After(now) {
+			occurrence = at(datetime.BeginOfYear(now).AddDate(-1, 0, day-1))
+		}
+		return occurrence
+	default:
+		occurrence := at(now)
+		if occurrence.After(now) {
+			occurrence = at(now.AddDate(0, 0, -1))
+		}
+		return occurrence
+	}
+}
+
+// IsDue returns whether a scheduled notification is to be sent now, i.e. its latest occurrence in the given timezone has
+// passed recently and it was not sent since
+func (p *NotificationPreference) IsDue(now time.Time, tz *time.Location) bool {
+	if !p.IsScheduled() || p.Channel == NotificationChannelNone {
+		return false
+	}
+	occurrence := p.LastOccurrence(now.In(tz))
+	if now.Sub(occurrence) > notificationCatchUpWindow {
+		return false
+	}
+	return p.LastSentAt == nil || p.LastSentAt.Before(occurrence)
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationPreference_LastOccurrence(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	now := time.Date(2024, 3, 13, 12, 0, 0, 0, berlin) // wednesday
+
+	daily := &NotificationPreference{Kind: NotificationDailyDigest, DeliveryTime: "08:30"}
+	assert.Equal(t, time.Date(2024, 3, 13, 8, 30, 0, 0, berlin), daily.LastOccurrence(now))
+	daily.DeliveryTime = "18:00"
+	assert.Equal(t, time.Date(2024, 3, 12, 18, 0, 0, 0, berlin), daily.LastOccurrence(now))
+
+	weekly := &NotificationPreference{Kind: NotificationWeeklyReport, DeliveryTime: "18:00", DeliveryWeekday: int(time.Friday)}
+	assert.Equal(t, time.Date(2024, 3, 8, 18, 0, 0, 0, berlin), weekly.LastOccurrence(now))
+	weekly.DeliveryWeekday, weekly.DeliveryTime = int(time.Wednesday), "11:00"
+	assert.Equal(t, time.Date(2024, 3, 13, 11, 0, 0, 0, berlin), weekly.LastOccurrence(now))
+	weekly.DeliveryTime = "13:00"
+	assert.Equal(t, time.Date(2024, 3, 6, 13, 0, 0, 0, berlin), weekly.LastOccurrence(now))
+
+	monthly := &NotificationPreference{Kind: NotificationMonthlyReport, DeliveryTime: "08:00", DeliveryDay: 1}
+	assert.Equal(t, time.Date(2024, 3, 1, 8, 0, 0, 0, berlin), monthly.LastOccurrence(now))
+	monthly.DeliveryDay = 15
+	assert.Equal(t, time.Date(2024, 2, 15, 8, 0, 0, 0, berlin), monthly.LastOccurrence(now))
+
+	yearly := &NotificationPreference{Kind: NotificationYearlyReport, DeliveryTime: "08:00", DeliveryDay: 2}
+	assert.Equal(t, time.Date(2024, 1, 2, 8, 0, 0, 0, berlin), yearly.LastOccurrence(now))
+	assert.Equal(t, time.Date(2023, 1, 2, 8, 0, 0, 0, berlin), yearly.LastOccurrence(time.Date(2024, 1, 1, 12, 0, 0, 0, berlin)))
+
+	// wall clock time is kept across daylight saving time changes (march 31st in berlin)
+	weekly.DeliveryWeekday, weekly.DeliveryTime = int(time.Monday), "09:00"
+	assert.Equal(t, time.Date(2024, 4, 1, 9, 0, 0, 0, berlin), weekly.LastOccurrence(time.Date(2024, 4, 2, 0, 0, 0, 0, berlin)))
+}
+
+func TestNotificationPreference_IsDue(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	now := time.Date(2024, 3, 13, 10, 0, 0, 0, time.UTC) // 11:00 in berlin, 19:00 in tokyo
+
+	sut := &NotificationPreference{Kind: NotificationDailyDigest, Channel: NotificationChannelEmail, DeliveryTime: "18:00"}
+	assert.False(t, sut.IsDue(now, berlin)) // yesterday's occurrence is more than 12 hours ago
+	assert.True(t, sut.IsDue(now, tokyo))
+
+	sent := now.Add(-30 * time.Minute)
+	sut.LastSentAt = &sent
+	assert.False(t, sut.IsDue(now, tokyo))
+
+	sut.LastSentAt, sut.Channel = nil, NotificationChannelNone
+	assert.False(t, sut.IsDue(now, tokyo))
+
+	assert.False(t, (&NotificationPreference{Kind: NotificationGoalAlerts, Channel: NotificationChannelEmail}).IsDue(now, tokyo))
+}
+
+func TestNotificationPreferenceUpdate_IsValid(t *testing.T) {
+	weekday, day := 7, 29
+
+	assert.True(t, (&NotificationPreferenceUpdate{Kind: NotificationWeeklyReport, Channel: NotificationChannelEmail, DeliveryTime: "07:45"}).IsValid())
+	assert.True(t, (&NotificationPreferenceUpdate{Kind: NotificationGoalAlerts, Channel: NotificationChannelNone}).IsValid())
+	assert.False(t, (&NotificationPreferenceUpdate{Kind: "newsletter", Channel: NotificationChannelEmail}).IsValid())
+	assert.False(t, (&NotificationPreferenceUpdate{Kind: NotificationGoalAlerts, Channel: "sms"}).IsValid())
+	assert.False(t, (&NotificationPreferenceUpdate{Kind: NotificationDailyDigest, Channel: NotificationChannelEmail, DeliveryTime: "25:00"}).IsValid())
+	assert.False(t, (&NotificationPreferenceUpdate{Kind: NotificationWeeklyReport, Channel: NotificationChannelEmail, DeliveryWeekday: &weekday}).IsValid())
+	assert.False(t, (&NotificationPreferenceUpdate{Kind: NotificationMonthlyReport, Channel: NotificationChannelEmail, DeliveryDay: &day}).IsValid())
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+var orgSlugRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}[a-z0-9]$`)
+
+type Organization struct {
+	ID          string     `json:"id" gorm:"primary_key"`
+	Name        string     `json:"name" gorm:"not null; size:255"`
+	Slug        string     `json:"slug" gorm:"not null; uniqueIndex:idx_organization_slug; size:64"`
+	Description string     `json:"description"`
+	Creator     *User      `json:"-" gorm:"foreignKey:CreatedBy; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	CreatedBy   string     `json:"created_by" gorm:"not null; index:idx_organization_created_by"`
+	CreatedAt   CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	UpdatedAt   CustomTime `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type OrganizationMember struct {
+	ID             uint          `json:"id" gorm:"primary_key"`
+	Organization   *Organization `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	OrganizationID string        `json:"organization_id" gorm:"not null; uniqueIndex:idx_organization_member"`
+	User           *User         `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID         string        `json:"user_id" gorm:"not null; uniqueIndex:idx_organization_member; index:idx_organization_member_user"`
+	Role           string        `json:"role" gorm:"not null; default:'member'; size:16"`
+	InvitedBy      string        `json:"invited_by"`
+	JoinedAt       CustomTime    `json:"joined_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type OrganizationProject struct {
+	ID             uint          `json:"id" gorm:"primary_key"`
+	Organization   *Organization `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	OrganizationID string        `json:"organization_id" gorm:"not null; uniqueIndex:idx_organization_project"`
+	ProjectName    string        `json:"project_name" gorm:"not null; uniqueIndex:idx_organization_project; size:255"`
+	AddedBy        string        `json:"added_by"`
+	CreatedAt      CustomTime    `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type OrganizationInvitation struct {
+	ID             uint          `json:"id" gorm:"primary_key"`
+	Organization   *Organization `json:"organization,omitempty" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	OrganizationID string        `json:"organization_id" gorm:"not null; index:idx_organization_invitation_org"`
+	Email          string        `json:"email" gorm:"not null; index:idx_organization_invitation_email; size:255"`
+	Role           string        `json:"role" gorm:"not null; default:'member'; size:16"`
+	InvitedBy      string        `json:"invited_by"`
+	Token          string        `json:"-" gorm:"not null; uniqueIndex:idx_organization_invitation_token; size:64"`
+	CreatedAt      CustomTime    `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	ExpiresAt      CustomTime    `json:"expires_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	AcceptedAt     *CustomTime   `json:"accepted_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type NewOrganization struct {
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+}
+
+type OrganizationUpdate struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type NewOrganizationInvitation struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type OrganizationMemberUpdate struct {
+	Role string `json:"role"`
+}
+
+type NewOrganizationProject struct {
+	ProjectName string `json:"project_name"`
+}
+
+// OrganizationMemberSummary is a single member's share of a team summary
+type OrganizationMemberSummary struct {
+	UserID string        `json:"user_id"`
+	Role   string        `json:"role"`
+	Total  time.Duration `json:"total" swaggertype:"primitive,integer"`
+}
+
+// OrganizationSummary is the combination of all members' summaries, restricted to the organization's projects
+type OrganizationSummary struct {
+	Organization *Organization                `json:"organization"`
+	From         CustomTime                   `json:"from" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	To           CustomTime                   `json:"to" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	Summary      *Summary                     `json:"summary"`
+	Members      []*OrganizationMemberSummary `json:"members,omitempty"` // only shown to owners and admins
+}
+
+func (o *Organization) IsValid() bool {
+	return o.Name != "" && ValidateOrganizationSlug(o.Slug)
+}
+
+func (m *OrganizationMember) IsOwner() bool {
+	return m.Role == OrgRoleOwner
+}
+
+// CanManage returns whether the member may invite others, manage projects and change member roles
+func (m *OrganizationMember) CanManage() bool {
+	return m.Role == OrgRoleOwner || m.Role == OrgRoleAdmin
+}
+
+// CanAssignRole returns whether the member may grant the given role to, or revoke it from, somebody else.
+// Only owners can hand out or take away ownership.
+func (m *OrganizationMember) CanAssignRole(role string) bool {
+	if role == OrgRoleOwner {
+		return m.IsOwner()
+	}
+	return m.CanManage()
+}
+
+func (i *OrganizationInvitation) IsValid() bool {
+	return i.AcceptedAt == nil && time.Now().Before(i.ExpiresAt.T())
+}
+
+func ValidateOrganizationSlug(slug string) bool {
+	return orgSlugRegex.MatchString(slug)
+}
+
+func ValidateOrganizationRole(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleAdmin || role == OrgRoleMember
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOrganizationSlug(t *testing.T) {
+	assert.True(t, ValidateOrganizationSlug("acme"))
+	assert.True(t, ValidateOrganizationSlug("acme-dev-team"))
+	assert.True(t, ValidateOrganizationSlug("a1b"))
+
+	assert.False(t, ValidateOrganizationSlug("ab"))
+	assert.False(t, ValidateOrganizationSlug("Acme"))
+	assert.False(t, ValidateOrganizationSlug("-acme"))
+	assert.False(t, ValidateOrganizationSlug("acme-"))
+	assert.False(t, ValidateOrganizationSlug("acme team"))
+	assert.False(t, ValidateOrganizationSlug(""))
+}
+
+func TestOrganizationMember_CanAssignRole(t *testing.T) {
+	owner := &OrganizationMember{Role: OrgRoleOwner}
+	admin := &OrganizationMember{Role: OrgRoleAdmin}
+	member := &OrganizationMember{Role: OrgRoleMember}
+
+	assert.True(t, owner.CanAssignRole(OrgRoleOwner))
+	assert.True(t, owner.CanAssignRole(OrgRoleAdmin))
+	assert.True(t, admin.CanAssignRole(OrgRoleAdmin))
+	assert.True(t, admin.CanAssignRole(OrgRoleMember))
+	assert.False(t, admin.CanAssignRole(OrgRoleOwner))
+	assert.False(t, member.CanAssignRole(OrgRoleMember))
+}
+
+func TestOrganizationInvitation_IsValid(t *testing.T) {
+	accepted := CustomTime(time.Now())
+
+	sut1 := &OrganizationInvitation{ExpiresAt: CustomTime(time.Now().Add(time.Hour))}
+	sut2 := &OrganizationInvitation{ExpiresAt: CustomTime(time.Now().Add(-time.Hour))}
+	sut3 := &OrganizationInvitation{ExpiresAt: CustomTime(time.Now().Add(time.Hour)), AcceptedAt: &accepted}
+
+	assert.True(t, sut1.IsValid())
+	assert.False(t, sut2.IsValid())
+	assert.False(t, sut3.IsValid())
+}
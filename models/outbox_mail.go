@@ -0,0 +1,82 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	OutboxMailPending = "pending"
+	OutboxMailFailed  = "failed"
+)
+
+const (
+	OutboxMailMaxAttempts = 12
+	outboxMailBaseBackoff = 1 * time.Minute
+	outboxMailMaxBackoff  = 6 * time.Hour
+)
+
+// OutboxMail is a mail that failed to be sent and is waiting to be retried. Mails are removed from the outbox once sent and
+// kept as failed after too many attempts.
+type OutboxMail struct {
+	ID            string     `json:"id" gorm:"primary_key"`
+	Recipients    string     `json:"recipients"`
+	Subject       string     `json:"subject"`
+	Payload       string     `json:"-" gorm:"type:text"` // json-encoded mail
+	Status        string     `json:"status" gorm:"not null; default:'pending'; size:16; index:idx_outbox_mail_status_next"`
+	Attempts      int        `json:"attempts"`
+	Error         string     `json:"error,omitempty" gorm:"type:text"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index:idx_outbox_mail_status_next" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt     CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// NewOutboxMail wraps a mail whose first attempt of being sent failed with the given error
+func NewOutboxMail(mail *Mail, sendErr error, now time.Time) (*OutboxMail, error) {
+	payload, err := json.Marshal(mail)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &OutboxMail{
+		ID:         uuid.Must(uuid.NewV4()).String(),
+		Recipients: strings.Join(mail.To.Strings(), ", "),
+		Subject:    mail.Subject,
+		Payload:    string(payload),
+		Status:     OutboxMailPending,
+	}
+	m.Failed(sendErr, now)
+	return m, nil
+}
+
+func (m *OutboxMail) Mail() (*Mail, error) {
+	var mail Mail
+	if err := json.Unmarshal([]byte(m.Payload), &mail); err != nil {
+		return nil, err
+	}
+	return &mail, nil
+}
+
+// Failed records an unsuccessful attempt and schedules the next one with exponential backoff, or gives up after too many
+func (m *OutboxMail) Failed(err error, now time.Time) {
+	m.Attempts++
+	if err != nil {
+		m.Error = err.Error()
+	}
+	if m.Attempts >= OutboxMailMaxAttempts {
+		m.Status = OutboxMailFailed
+		return
+	}
+
+	backoff := outboxMailBaseBackoff << (m.Attempts - 1)
+	if backoff > outboxMailMaxBackoff {
+		backoff = outboxMailMaxBackoff
+	}
+	m.NextAttemptAt = now.Add(backoff)
+}
+
+func (m *OutboxMail) IsDue(now time.Time) bool {
+	return m.Status == OutboxMailPending && !m.NextAttemptAt.After(now)
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxMail_Failed(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mail := (&Mail{From: "noreply@wakana.io", To: MailAddresses{"john@example.org"}, Subject: "Hi"}).
+		WithAttachment("a.txt", "text/plain", []byte("a"))
+
+	sut, err := NewOutboxMail(mail, errors.New("connection refused"), now)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, sut.Attempts)
+	assert.Equal(t, "connection refused", sut.Error)
+	assert.Equal(t, now.Add(1*time.Minute), sut.NextAttemptAt)
+	assert.False(t, sut.IsDue(now))
+	assert.True(t, sut.IsDue(now.Add(1*time.Minute)))
+
+	restored, err := sut.Mail()
+	assert.Nil(t, err)
+	assert.Equal(t, mail.To, restored.To)
+	assert.Equal(t, []byte("a"), restored.Attachments[0].Data)
+
+	sut.Failed(errors.New("timeout"), now)
+	assert.Equal(t, now.Add(2*time.Minute), sut.NextAttemptAt)
+
+	for sut.Attempts < OutboxMailMaxAttempts-1 {
+		sut.Failed(nil, now)
+	}
+	assert.Equal(t, now.Add(outboxMailMaxBackoff), sut.NextAttemptAt)
+	assert.Equal(t, "timeout", sut.Error)
+
+	sut.Failed(nil, now)
+	assert.Equal(t, OutboxMailFailed, sut.Status)
+	assert.False(t, sut.IsDue(now.Add(24*time.Hour)))
+}
@@ -23,6 +23,7 @@ type PluginUserAgent struct {
 	OS                 string     `json:"os"`
 	Value              string     `json:"value"`
 	Version            *string    `json:"version"`
+	Machine            string     `json:"machine"` // name of the machine the plugin runs on, if known
 	CreatedAt          CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
 	LastSeenAt         CustomTime `json:"last_seen_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
 }
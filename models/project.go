@@ -0,0 +1,133 @@
+package models
+
+import (
+	"hash/fnv"
+	"regexp"
+	"time"
+)
+
+const (
+	ProjectSortLastHeartbeat  = "last_heartbeat_at"
+	ProjectSortFirstHeartbeat = "first_heartbeat_at"
+	ProjectSortName           = "name"
+)
+
+var projectColorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// projectColors is the palette new projects get a color assigned from, based on their name.
+// Do not reorder, otherwise existing projects' default colors would change on re-creation.
+var projectColors = []string{
+	"#3b82f6", "#ef4444", "#10b981", "#f59e0b", "#8b5cf6", "#ec4899", "#14b8a6", "#f97316",
+	"#6366f1", "#84cc16", "#06b6d4", "#e11d48", "#a855f7", "#22c55e", "#eab308", "#0ea5e9",
+}
+
+type Project struct {
+	ID               uint             `json:"id" gorm:"primary_key"`
+	User             *User            `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID           string           `json:"-" gorm:"not null; uniqueIndex:idx_project_user_name; index:idx_project_user_last_heartbeat,priority:1"`
+	Name             string           `json:"name" gorm:"not null; uniqueIndex:idx_project_user_name; size:255"`
+	Color            string           `json:"color" gorm:"size:7"`
+	Description      string           `json:"description"`
+	RepositoryUrl    string           `json:"repository_url"`
+	IsArchived       bool             `json:"is_archived" gorm:"default:false; type:bool"`
+	TopLanguage      string           `json:"top_language"`
+	Languages        map[string]int64 `json:"-" gorm:"serializer:json"` // number of heartbeats per language, used to determine the top language
+	FirstHeartbeatAt CustomTime       `json:"first_heartbeat_at" gorm:"timeScale:3" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastHeartbeatAt  CustomTime       `json:"last_heartbeat_at" gorm:"timeScale:3; index:idx_project_user_last_heartbeat,priority:2" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt        CustomTime       `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	UpdatedAt        CustomTime       `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type ProjectUpdate struct {
+	Description   *string `json:"description"`
+	RepositoryUrl *string `json:"repository_url"`
+	Color         *string `json:"color"`
+	IsArchived    *bool   `json:"is_archived"`
+}
+
+// ProjectQuery describes which of a user's projects to list and in what order
+type ProjectQuery struct {
+	Prefix          string
+	SortBy          string
+	SortDesc        bool
+	IncludeArchived bool
+}
+
+func NewProject(userId, name string, seenAt time.Time) *Project {
+	return &Project{
+		UserID:           userId,
+		Name:             name,
+		Color:            ProjectColor(name),
+		Languages:        map[string]int64{},
+		FirstHeartbeatAt: CustomTime(seenAt),
+		LastHeartbeatAt:  CustomTime(seenAt),
+	}
+}
+
+// Track merges a batch of activity into the project's statistics
+func (p *Project) Track(first, last time.Time, languages map[string]int64) {
+	if p.FirstHeartbeatAt.T().IsZero() || first.Before(p.FirstHeartbeatAt.T()) {
+		p.FirstHeartbeatAt = CustomTime(first)
+	}
+	if last.After(p.LastHeartbeatAt.T()) {
+		p.LastHeartbeatAt = CustomTime(last)
+	}
+
+	p.AddLanguages(languages)
+}
+
+// AddLanguages adds the given numbers of heartbeats per language to the project's counts and updates its top language
+func (p *Project) AddLanguages(languages map[string]int64) {
+	if p.Languages == nil {
+		p.Languages = map[string]int64{}
+	}
+	for lang, n := range languages {
+		if lang == "" {
+			continue
+		}
+		p.Languages[lang] += n
+	}
+
+	p.TopLanguage = p.topLanguage()
+}
+
+func (p *Project) topLanguage() string {
+	var (
+		top      string
+		topCount int64
+	)
+	for lang, count := range p.Languages {
+		// tie-break alphabetically to get a stable result
+		if count > topCount || (count == topCount && lang < top) {
+			top, topCount = lang, count
+		}
+	}
+	return top
+}
+
+func (u *ProjectUpdate) IsValid() bool {
+	return u.Color == nil || ValidateProjectColor(*u.Color)
+}
+
+func (q *ProjectQuery) OrderClause() string {
+	column := ProjectSortLastHeartbeat
+	switch q.SortBy {
+	case ProjectSortFirstHeartbeat, ProjectSortName:
+		column = q.SortBy
+	}
+	if q.SortDesc {
+		return column + " desc"
+	}
+	return column + " asc"
+}
+
+// ProjectColor deterministically picks a color for the project of the given name
+func ProjectColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return projectColors[h.Sum32()%uint32(len(projectColors))]
+}
+
+func ValidateProjectColor(color string) bool {
+	return projectColorRegex.MatchString(color)
+}
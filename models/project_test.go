@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProject_Track(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	sut := NewProject("user1", "wakapi", t0)
+	sut.Track(t0, t0.Add(time.Hour), map[string]int64{"Go": 3, "Markdown": 1})
+	sut.Track(t0.Add(-time.Hour), t0.Add(30*time.Minute), map[string]int64{"Markdown": 5, "": 10})
+
+	assert.Equal(t, t0.Add(-time.Hour), sut.FirstHeartbeatAt.T())
+	assert.Equal(t, t0.Add(time.Hour), sut.LastHeartbeatAt.T())
+	assert.Equal(t, "Markdown", sut.TopLanguage)
+	assert.NotContains(t, sut.Languages, "")
+}
+
+func TestProjectColor(t *testing.T) {
+	assert.Equal(t, ProjectColor("wakapi"), ProjectColor("wakapi"))
+	assert.True(t, ValidateProjectColor(ProjectColor("wakapi")))
+	assert.False(t, ValidateProjectColor("red"))
+	assert.False(t, ValidateProjectColor("#12345"))
+}
+
+func TestProjectQuery_OrderClause(t *testing.T) {
+	assert.Equal(t, "last_heartbeat_at desc", (&ProjectQuery{SortDesc: true}).OrderClause())
+	assert.Equal(t, "name asc", (&ProjectQuery{SortBy: ProjectSortName}).OrderClause())
+	assert.Equal(t, "last_heartbeat_at asc", (&ProjectQuery{SortBy: "password; drop table users"}).OrderClause())
+}
@@ -3,6 +3,7 @@ package models
 import "time"
 
 type Report struct {
+	Title          string // e.g. "Your Weekly Coding Summary"
 	From           time.Time
 	To             time.Time
 	User           *User
@@ -13,6 +14,18 @@ type Report struct {
 	WeeklyTotal time.Duration
 }
 
+func (r *Report) Heading() string {
+	if r.Title == "" {
+		return "Your Weekly Coding Summary"
+	}
+	return r.Title
+}
+
+// ShowsWeekdays returns whether days are few enough to be told apart by their weekday alone
+func (r *Report) ShowsWeekdays() bool {
+	return len(r.DailySummaries) <= 7
+}
+
 func (r *Report) DailyAverage() time.Duration {
 	numberOfDays := len(r.DailySummaries)
 	if numberOfDays == 0 {
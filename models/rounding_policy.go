@@ -0,0 +1,103 @@
+package models
+
+import "sort"
+
+const (
+	RoundingModeNone    = ""
+	RoundingModeUp      = "up"
+	RoundingModeNearest = "nearest"
+	RoundingModeDown    = "down"
+)
+
+const roundingPolicyMaxMinutes = 24 * 60
+
+// RoundingPolicy describes how tracked time translates into billed time as commonly agreed on in contracts, e.g. "billed in
+// 15-minute increments, at least 1 hour per day worked and at most 8 hours per day". All values are given in minutes.
+type RoundingPolicy struct {
+	Mode                string `json:"mode" gorm:"size:16"`
+	IncrementMinutes    int    `json:"increment_minutes"`
+	DailyMinimumMinutes int    `json:"daily_minimum_minutes"`
+	DailyCapMinutes     int    `json:"daily_cap_minutes"`
+}
+
+func (p RoundingPolicy) IsValid() bool {
+	switch p.Mode {
+	case RoundingModeNone:
+		if p.IncrementMinutes != 0 {
+			return false
+		}
+	case RoundingModeUp, RoundingModeNearest, RoundingModeDown:
+		if p.IncrementMinutes <= 0 {
+			return false
+		}
+	default:
+		return false
+	}
+	return p.IncrementMinutes <= roundingPolicyMaxMinutes &&
+		p.DailyMinimumMinutes >= 0 && p.DailyMinimumMinutes <= roundingPolicyMaxMinutes &&
+		p.DailyCapMinutes >= 0 && p.DailyCapMinutes <= roundingPolicyMaxMinutes &&
+		(p.DailyCapMinutes == 0 || p.DailyCapMinutes >= p.DailyMinimumMinutes)
+}
+
+// IsActive returns whether any rule is set, otherwise billed time equals tracked time
+func (p RoundingPolicy) IsActive() bool {
+	return (p.Mode != RoundingModeNone && p.IncrementMinutes > 0) || p.DailyMinimumMinutes > 0 || p.DailyCapMinutes > 0
+}
+
+// Round rounds the given number of seconds to the policy's increment
+func (p RoundingPolicy) Round(seconds int64) int64 {
+	increment := int64(p.IncrementMinutes) * 60
+	if p.Mode == RoundingModeNone || increment <= 0 {
+		return seconds
+	}
+
+	switch p.Mode {
+	case RoundingModeUp:
+		return (seconds + increment - 1) / increment * increment
+	case RoundingModeNearest:
+		return (seconds + increment/2) / increment * increment
+	default:
+		return seconds / increment * increment
+	}
+}
+
+// ApplyDaily computes the billed seconds for a single day's items from their tracked seconds. Every item is rounded on its own,
+// then the daily minimum and cap are applied to the day's total. Time added to meet the minimum goes to the largest item, time
+// exceeding the cap is taken from the largest items first. Days without any tracked time are never billed.
+func (p RoundingPolicy) ApplyDaily(seconds []int64) []int64 {
+	billed := make([]int64, len(seconds))
+	var tracked, total int64
+	for i, s := range seconds {
+		billed[i] = p.Round(s)
+		tracked += s
+		total += billed[i]
+	}
+	if tracked <= 0 {
+		return billed
+	}
+
+	// indices of items, largest tracked time first
+	order := make([]int, len(seconds))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return seconds[order[i]] > seconds[order[j]]
+	})
+
+	if minimum := int64(p.DailyMinimumMinutes) * 60; total < minimum {
+		billed[order[0]] += minimum - total
+		total = minimum
+	}
+
+	if limit := int64(p.DailyCapMinutes) * 60; limit > 0 && total > limit {
+		excess := total - limit
+		for _, i := range order {
+			cut := min(excess, billed[i])
+			billed[i] -= cut
+			excess -= cut
+		}
+	}
+
+	return billed
+}
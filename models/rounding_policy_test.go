@@ -0,0 +1,49 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundingPolicy_Round(t *testing.T) {
+	up := RoundingPolicy{Mode: RoundingModeUp, IncrementMinutes: 15}
+	nearest := RoundingPolicy{Mode: RoundingModeNearest, IncrementMinutes: 6}
+	down := RoundingPolicy{Mode: RoundingModeDown, IncrementMinutes: 15}
+
+	assert.Equal(t, int64(0), up.Round(0))
+	assert.Equal(t, int64(900), up.Round(1))
+	assert.Equal(t, int64(900), up.Round(900))
+	assert.Equal(t, int64(1800), up.Round(901))
+	assert.Equal(t, int64(360), nearest.Round(180))
+	assert.Equal(t, int64(0), nearest.Round(179))
+	assert.Equal(t, int64(900), down.Round(1799))
+	assert.Equal(t, int64(1234), RoundingPolicy{}.Round(1234))
+}
+
+func TestRoundingPolicy_ApplyDaily(t *testing.T) {
+	sut := RoundingPolicy{Mode: RoundingModeUp, IncrementMinutes: 15, DailyMinimumMinutes: 60, DailyCapMinutes: 120}
+
+	assert.Equal(t, []int64{0, 0}, sut.ApplyDaily([]int64{0, 0}))
+	// 10 min and 20 min round up to 15 and 30 min, the remaining 15 min to meet the minimum go to the larger item
+	assert.Equal(t, []int64{900, 2700}, sut.ApplyDaily([]int64{600, 1200}))
+	// 100 min and 50 min round up to 105 and 60 min, the 45 min exceeding the cap are taken from the larger item
+	assert.Equal(t, []int64{3600, 3600}, sut.ApplyDaily([]int64{3000, 6000}))
+	assert.Equal(t, []int64{900, 6300}, sut.ApplyDaily([]int64{600, 36000}))
+
+	// minimum only, time is billed as tracked otherwise
+	assert.Equal(t, []int64{1234}, RoundingPolicy{DailyMinimumMinutes: 15}.ApplyDaily([]int64{1234}))
+	assert.Equal(t, []int64{900}, RoundingPolicy{DailyMinimumMinutes: 15}.ApplyDaily([]int64{5}))
+}
+
+func TestRoundingPolicy_IsValid(t *testing.T) {
+	assert.True(t, RoundingPolicy{}.IsValid())
+	assert.False(t, RoundingPolicy{}.IsActive())
+	assert.True(t, RoundingPolicy{Mode: RoundingModeNearest, IncrementMinutes: 6}.IsValid())
+	assert.True(t, RoundingPolicy{DailyMinimumMinutes: 60, DailyCapMinutes: 480}.IsValid())
+	assert.True(t, RoundingPolicy{DailyCapMinutes: 480}.IsActive())
+	assert.False(t, RoundingPolicy{Mode: RoundingModeUp}.IsValid())
+	assert.False(t, RoundingPolicy{IncrementMinutes: 15}.IsValid())
+	assert.False(t, RoundingPolicy{Mode: "ceil", IncrementMinutes: 15}.IsValid())
+	assert.False(t, RoundingPolicy{DailyMinimumMinutes: 60, DailyCapMinutes: 30}.IsValid())
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Session is a login, identified by the id (jti) of the jwt issued for it. Tokens are only accepted as long as their
+// session is neither revoked nor expired, so logins can be ended before their token expires.
+type Session struct {
+	ID         string      `json:"id" gorm:"primary_key"`
+	User       *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string      `json:"-" gorm:"not null; index:idx_session_user"`
+	UserAgent  string      `json:"user_agent"`
+	IP         string      `json:"ip"`
+	CreatedAt  CustomTime  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastSeenAt CustomTime  `json:"last_seen_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	ExpiresAt  CustomTime  `json:"expires_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	RevokedAt  *CustomTime `json:"-"`
+	Current    bool        `json:"current" gorm:"-"` // set if this is the session the listing was requested with
+}
+
+func (s *Session) IsActiveAt(t time.Time) bool {
+	return s.RevokedAt == nil && t.Before(s.ExpiresAt.T())
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_IsActiveAt(t *testing.T) {
+	t0 := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	sut := &Session{ExpiresAt: CustomTime(t0.Add(time.Hour))}
+
+	assert.True(t, sut.IsActiveAt(t0))
+	assert.False(t, sut.IsActiveAt(t0.Add(time.Hour)))
+
+	revokedAt := CustomTime(t0)
+	sut.RevokedAt = &revokedAt
+	assert.False(t, sut.IsActiveAt(t0))
+}
@@ -18,6 +18,7 @@ const (
 	UserKey               = "user"
 	ImprintKey            = "imprint"
 	AuthCookieKey         = "wakapi_auth"
+	OidcStateCookieKey    = "wakapi_oidc_state"
 	PersistentIntervalKey = "wakapi_summary_interval"
 )
 
@@ -36,6 +37,11 @@ type KeyedInterval struct {
 	Key *IntervalKey
 }
 
+// BulkDelete is the request body of endpoints that delete multiple entities at once
+type BulkDelete struct {
+	IDs []uint `json:"ids"`
+}
+
 // CustomTime is a wrapper type around time.Time, mainly used for the purpose of transparently unmarshalling Python timestamps in the format <sec>.<nsec> (e.g. 1619335137.3324468)
 type CustomTime time.Time
 
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	StatsCardLanguages = "languages"
+	StatsCardProjects  = "projects"
+	StatsCardWeek      = "week"
+	StatsCardGoal      = "goal"
+)
+
+const (
+	StatsCardLayoutBar   = "bar"
+	StatsCardLayoutDonut = "donut"
+)
+
+const (
+	StatsCardDefaultLimit = 6
+	StatsCardMaxLimit     = 10
+)
+
+var cardColorPattern = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// CardTheme holds the colors of embeddable stats cards, see https://github.com/anuraghazra/github-readme-stats#themes
+type CardTheme struct {
+	Background string
+	Border     string
+	Title      string
+	Text       string
+	Muted      string
+	Accent     string // bars and progress
+	HideBorder bool
+}
+
+var CardThemes = map[string]CardTheme{
+	"light":     {Background: "#FFFFFF", Border: "#E4E2E2", Title: "#047857", Text: "#37474F", Muted: "#6B7280", Accent: "#047857"},
+	"dark":      {Background: "#111827", Border: "#242B3A", Title: "#10B981", Text: "#D1D5DB", Muted: "#9CA3AF", Accent: "#047857"},
+	"github":    {Background: "#0D1117", Border: "#30363D", Title: "#58A6FF", Text: "#C9D1D9", Muted: "#8B949E", Accent: "#238636"},
+	"dracula":   {Background: "#282A36", Border: "#44475A", Title: "#FF6E96", Text: "#F8F8F2", Muted: "#6272A4", Accent: "#BD93F9"},
+	"solarized": {Background: "#FDF6E3", Border: "#EEE8D5", Title: "#268BD2", Text: "#657B83", Muted: "#93A1A1", Accent: "#2AA198"},
+}
+
+// GetCardTheme returns the theme of the given name, or the light one if there is none
+func GetCardTheme(name string) CardTheme {
+	if theme, ok := CardThemes[strings.ToLower(name)]; ok {
+		return theme
+	}
+	return CardThemes["light"]
+}
+
+// WithColors overrides the theme's colors by those given as hex codes (with or without leading '#'), ignoring empty or
+// invalid ones. Colors are normalized to lower case, so differently spelled ones make for the same theme.
+func (t CardTheme) WithColors(background, border, title, text, accent string) CardTheme {
+	for _, c := range []struct {
+		target *string
+		value  string
+	}{{&t.Background, background}, {&t.Border, border}, {&t.Title, title}, {&t.Text, text}, {&t.Accent, accent}} {
+		if cardColorPattern.MatchString(c.value) {
+			*c.target = "#" + strings.ToLower(strings.TrimPrefix(c.value, "#"))
+		}
+	}
+	return t
+}
+
+// StatsCardParams describes an embeddable stats card to be rendered
+type StatsCardParams struct {
+	Card   string
+	User   *User
+	From   time.Time
+	To     time.Time
+	Goal   *Goal // goal cards only
+	Theme  CardTheme
+	Title  string // custom title, optional
+	Layout string // languages cards only
+	Limit  int    // number of languages or projects
+}
+
+func IsValidStatsCard(card string) bool {
+	return card == StatsCardLanguages || card == StatsCardProjects || card == StatsCardWeek || card == StatsCardGoal
+}
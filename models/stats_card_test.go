@@ -0,0 +1,26 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCardTheme(t *testing.T) {
+	assert.Equal(t, CardThemes["dark"], GetCardTheme("Dark"))
+	assert.Equal(t, CardThemes["light"], GetCardTheme("unknown"))
+	assert.Equal(t, CardThemes["light"], GetCardTheme(""))
+}
+
+func TestCardTheme_WithColors(t *testing.T) {
+	sut := CardThemes["light"].WithColors("000", "#ff00ff", "", "red", "12345678")
+
+	assert.Equal(t, "#000", sut.Background)
+	assert.Equal(t, "#ff00ff", sut.Border)
+	assert.Equal(t, CardThemes["light"].Title, sut.Title)
+	assert.Equal(t, CardThemes["light"].Text, sut.Text) // named colors are not supported
+	assert.Equal(t, "#12345678", sut.Accent)
+
+	sut = CardThemes["light"].WithColors("fff; } text { display: none", "", "", "", "")
+	assert.Equal(t, CardThemes["light"].Background, sut.Background)
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const TwoFactorRecoveryCodeCount = 10
+
+// TwoFactor is a user's totp-based second factor. It only takes effect once enrollment was confirmed by a valid code.
+// Recovery codes are one-time codes to log in when the authenticator is lost, only their hashes are stored.
+type TwoFactor struct {
+	UserID        string      `json:"-" gorm:"primary_key"`
+	User          *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Secret        string      `json:"-" gorm:"not null"`
+	RecoveryCodes []string    `json:"-" gorm:"serializer:json"`
+	LastUsedStep  int64       `json:"-"` // totp period of the last accepted code, to prevent replays
+	EnabledAt     *CustomTime `json:"enabled_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	CreatedAt     CustomTime  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func (t *TwoFactor) IsEnabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// UseRecoveryCode consumes the given recovery code, if it is one of the remaining ones
+func (t *TwoFactor) UseRecoveryCode(code string) bool {
+	hash := HashRecoveryCode(code)
+	for i, c := range t.RecoveryCodes {
+		if c == hash {
+			t.RecoveryCodes = append(t.RecoveryCodes[:i:i], t.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// HashRecoveryCode hashes a recovery code, ignoring case and the separators it is displayed with
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"` // set if the server requires all users to use 2fa, which then can't be disabled
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorEnrollment is what's needed to add the secret to an authenticator app
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"` // otpauth:// uri, to be shown as qr code
+}
+
+// TwoFactorChallenge is returned upon login instead of a session token if a second factor is required
+type TwoFactorChallenge struct {
+	Token              string     `json:"two_factor_token"` // short-lived token to exchange for a session token along with a valid code
+	ExpiresAt          CustomTime `json:"expires_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	EnrollmentRequired bool       `json:"enrollment_required"` // set if the user has to set up 2fa first, as the server requires it
+}
+
+type TwoFactorCode struct {
+	Code string `json:"code"` // either a totp code or a recovery code
+}
+
+type TwoFactorLogin struct {
+	Token string `json:"two_factor_token"`
+	Code  string `json:"code"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactor_IsEnabled(t *testing.T) {
+	enabledAt := CustomTime(time.Now())
+
+	assert.False(t, (*TwoFactor)(nil).IsEnabled())
+	assert.False(t, (&TwoFactor{Secret: "JBSWY3DPEHPK3PXP"}).IsEnabled())
+	assert.True(t, (&TwoFactor{Secret: "JBSWY3DPEHPK3PXP", EnabledAt: &enabledAt}).IsEnabled())
+}
+
+func TestTwoFactor_UseRecoveryCode(t *testing.T) {
+	sut := &TwoFactor{RecoveryCodes: []string{HashRecoveryCode("a1b2c3-d4e5f6"), HashRecoveryCode("0f0f0f-1e1e1e")}}
+
+	assert.False(t, sut.UseRecoveryCode("a1b2c3-000000"))
+	assert.True(t, sut.UseRecoveryCode("A1B2C3 D4E5F6"))
+	assert.False(t, sut.UseRecoveryCode("a1b2c3-d4e5f6"))
+	assert.Len(t, sut.RecoveryCodes, 1)
+	assert.True(t, sut.UseRecoveryCode("0f0f0f1e1e1e"))
+	assert.Empty(t, sut.RecoveryCodes)
+}
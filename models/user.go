@@ -116,9 +116,9 @@ type ResetPasswordRequest struct {
 }
 
 type CredentialsReset struct {
-	PasswordOld    string `schema:"password_old"`
-	PasswordNew    string `schema:"password_new"`
-	PasswordRepeat string `schema:"password_repeat"`
+	PasswordOld    string `schema:"password_old" json:"password_old"`
+	PasswordNew    string `schema:"password_new" json:"password_new"`
+	PasswordRepeat string `schema:"password_repeat" json:"password_repeat"`
 }
 
 type UserDataUpdate struct {
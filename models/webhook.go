@@ -0,0 +1,127 @@
+package models
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+const webhookWildcard = "*"
+
+// Webhook is a user-configured endpoint that receives a signed http request for every event it is subscribed to.
+// Events are given by name (e.g. heartbeat.create), by topic (e.g. project_label.*) or as * for all events. The secret
+// requests are signed with is only returned upon creation and when changed.
+type Webhook struct {
+	ID          string     `json:"id" gorm:"primary_key"`
+	User        *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID      string     `json:"user_id" gorm:"not null; index:idx_webhook_user"`
+	Url         string     `json:"url" gorm:"not null"`
+	Secret      string     `json:"-" gorm:"not null; size:64"`
+	Events      []string   `json:"events" gorm:"serializer:json"`
+	Description string     `json:"description"`
+	IsActive    bool       `json:"is_active" gorm:"default:true; type:bool"`
+	CreatedAt   CustomTime `json:"created_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	UpdatedAt   CustomTime `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	PlainSecret string     `json:"secret,omitempty" gorm:"-"` // only set right after creation or changing the secret
+}
+
+// WebhookDelivery is a single event sent (or to be sent) to a webhook, kept as a log of past requests
+type WebhookDelivery struct {
+	ID             string      `json:"id" gorm:"primary_key"`
+	Webhook        *Webhook    `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	WebhookID      string      `json:"webhook_id" gorm:"not null; index:idx_webhook_delivery_webhook"`
+	Event          string      `json:"event" gorm:"not null; size:64"`
+	Payload        string      `json:"payload" gorm:"type:text"` // json-encoded event data
+	Status         string      `json:"status" gorm:"not null; default:'pending'; size:16"`
+	Attempts       int         `json:"attempts"`
+	ResponseStatus int         `json:"response_status"`
+	Error          string      `json:"error,omitempty"`
+	RedeliveryOf   string      `json:"redelivery_of,omitempty"`
+	CreatedAt      CustomTime  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP; index:idx_webhook_delivery_created" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	DeliveredAt    *CustomTime `json:"delivered_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type NewWebhook struct {
+	Url         string   `json:"url"`
+	Secret      string   `json:"secret"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+// WebhookPayload is the request body sent to webhook endpoints
+type WebhookPayload struct {
+	ID        string      `json:"id"` // delivery id
+	Event     string      `json:"event"`
+	UserID    string      `json:"user_id"`
+	CreatedAt CustomTime  `json:"created_at" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	Data      interface{} `json:"data"`
+}
+
+// Subscribes reports whether the webhook wants to receive the given event
+func (w *Webhook) Subscribes(event string) bool {
+	if !w.IsActive {
+		return false
+	}
+	for _, e := range w.Events {
+		if e == webhookWildcard || e == event {
+			return true
+		}
+		if strings.HasSuffix(e, "."+webhookWildcard) && strings.HasPrefix(event, strings.TrimSuffix(e, webhookWildcard)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *NewWebhook) IsValid() bool {
+	if len(w.Events) == 0 || len(w.Secret) > 64 {
+		return false
+	}
+	for _, e := range w.Events {
+		if !isValidWebhookEvent(e) {
+			return false
+		}
+	}
+	return isValidWebhookUrl(w.Url)
+}
+
+func (d *WebhookDelivery) IsFinished() bool {
+	return d.Status == WebhookDeliverySucceeded || d.Status == WebhookDeliveryFailed
+}
+
+func isValidWebhookEvent(event string) bool {
+	if event == webhookWildcard {
+		return true
+	}
+	for _, e := range config.WebhookEvents {
+		if e == event || (strings.HasSuffix(event, "."+webhookWildcard) && strings.HasPrefix(e, strings.TrimSuffix(event, webhookWildcard))) {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidWebhookUrl rejects urls pointing to obviously non-public hosts early, host names resolving to such are refused when sending
+func isValidWebhookUrl(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	if host := strings.ToLower(u.Hostname()); host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !utils.IsPublicIP(ip) {
+		return false
+	}
+	return true
+}
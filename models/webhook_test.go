@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhook_Subscribes(t *testing.T) {
+	sut := &Webhook{IsActive: true, Events: []string{"heartbeat.create", "project_label.*"}}
+
+	assert.True(t, sut.Subscribes("heartbeat.create"))
+	assert.True(t, sut.Subscribes("project_label.create"))
+	assert.True(t, sut.Subscribes("project_label.delete"))
+	assert.False(t, sut.Subscribes("project.create"))
+	assert.False(t, sut.Subscribes("user.update"))
+
+	sut.Events = []string{"*"}
+	assert.True(t, sut.Subscribes("user.update"))
+
+	sut.IsActive = false
+	assert.False(t, sut.Subscribes("user.update"))
+}
+
+func TestNewWebhook_IsValid(t *testing.T) {
+	assert.True(t, (&NewWebhook{Url: "https://example.org/hook", Events: []string{"heartbeat.create"}}).IsValid())
+	assert.True(t, (&NewWebhook{Url: "http://hooks.example.org:8080", Events: []string{"goal.*", "import.finished"}}).IsValid())
+	assert.True(t, (&NewWebhook{Url: "https://example.org/hook", Events: []string{"*"}}).IsValid())
+
+	assert.False(t, (&NewWebhook{Url: "https://example.org/hook"}).IsValid())
+	assert.False(t, (&NewWebhook{Url: "https://example.org/hook", Events: []string{"unknown.event"}}).IsValid())
+	assert.False(t, (&NewWebhook{Url: "https://example.org/hook", Events: []string{"unknown.*"}}).IsValid())
+	assert.False(t, (&NewWebhook{Url: "ftp://example.org", Events: []string{"*"}}).IsValid())
+	assert.False(t, (&NewWebhook{Url: "example.org/hook", Events: []string{"*"}}).IsValid())
+	assert.False(t, (&NewWebhook{Url: "http://localhost:8080", Events: []string{"*"}}).IsValid())
+	assert.False(t, (&NewWebhook{Url: "http://127.0.0.1:8080", Events: []string{"*"}}).IsValid())
+	assert.False(t, (&NewWebhook{Url: "http://169.254.169.254/latest/meta-data", Events: []string{"*"}}).IsValid())
+	assert.False(t, (&NewWebhook{Url: "http://[::1]/hook", Events: []string{"*"}}).IsValid())
+}
+
+func TestWebhook_Json(t *testing.T) {
+	sut := &Webhook{ID: "webhook01", Secret: "s3cr3t"}
+
+	data, _ := json.Marshal(sut)
+	assert.NotContains(t, string(data), "s3cr3t")
+	assert.NotContains(t, string(data), `"secret"`)
+
+	sut.PlainSecret = sut.Secret
+	data, _ = json.Marshal(sut)
+	assert.Contains(t, string(data), `"secret":"s3cr3t"`)
+}
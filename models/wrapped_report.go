@@ -0,0 +1,261 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/duke-git/lancet/v2/datetime"
+)
+
+const (
+	WrappedPeriodMonth = "month"
+	WrappedPeriodYear  = "year"
+)
+
+const wrappedTopItems = 5
+
+// WrappedItem is one of the most used projects, languages or editors of a wrapped report along with its trend
+type WrappedItem struct {
+	Key             string   `json:"key"`
+	TotalSeconds    int64    `json:"total_seconds"`
+	PreviousSeconds int64    `json:"previous_seconds"`
+	Trend           *float64 `json:"trend"` // change compared to the previous period in percent, null if not used back then
+}
+
+// WrappedReport is a look back on a user's coding activity throughout a month or year, compared to the period before
+type WrappedReport struct {
+	Period                string         `json:"period"`
+	From                  time.Time      `json:"from"`
+	To                    time.Time      `json:"to"`
+	User                  *User          `json:"-"`
+	TotalSeconds          int64          `json:"total_seconds"`
+	PreviousTotalSeconds  int64          `json:"previous_total_seconds"`
+	Change                *float64       `json:"change"` // in percent, null if there was no activity in the previous period
+	ActiveDays            int            `json:"active_days"`
+	DailyAverageSeconds   int64          `json:"daily_average_seconds"` // per active day
+	TopProjects           []*WrappedItem `json:"top_projects"`
+	TopLanguages          []*WrappedItem `json:"top_languages"`
+	TopEditors            []*WrappedItem `json:"top_editors"`
+	BusiestWeekday        *time.Weekday  `json:"busiest_weekday" swaggertype:"integer"` // 0 (sunday) to 6, null without any activity
+	BusiestWeekdaySeconds int64          `json:"busiest_weekday_seconds"`
+	BusiestHour           *int           `json:"busiest_hour"` // 0 to 23 in the user's timezone, null without any activity
+	BusiestHourSeconds    int64          `json:"busiest_hour_seconds"`
+	LongestStreak         int            `json:"longest_streak"` // consecutive days with activity
+	LongestStreakStart    *time.Time     `json:"longest_streak_start"`
+	NewLanguages          []string       `json:"new_languages"` // languages used for the first time
+}
+
+func IsValidWrappedPeriod(period string) bool {
+	return period == WrappedPeriodMonth || period == WrappedPeriodYear
+}
+
+// WrappedPeriodRange returns start (inclusive) and end (exclusive) of the month or year containing the given date
+func WrappedPeriodRange(period string, date time.Time) (time.Time, time.Time) {
+	if period == WrappedPeriodYear {
+		from := datetime.BeginOfYear(date)
+		return from, from.AddDate(1, 0, 0)
+	}
+	from := datetime.BeginOfMonth(date)
+	return from, from.AddDate(0, 1, 0)
+}
+
+// LatestWrappedPeriodRange returns the most recent complete month or year before now
+func LatestWrappedPeriodRange(period string, now time.Time) (time.Time, time.Time) {
+	current, _ := WrappedPeriodRange(period, now)
+	return WrappedPeriodRange(period, current.Add(-1*time.Second))
+}
+
+// NewWrappedReport builds a wrapped report from summaries of the period and the one before, the user's activity per hour
+// (see Durations.HourlyTotals) and the languages the user had used before the period. Without any earlier languages, none
+// are considered new.
+func NewWrappedReport(period string, from, to time.Time, user *User, current, previous *Summary, hourly map[time.Time]time.Duration, knownLanguages []string) *WrappedReport {
+	if current == nil {
+		current = NewEmptySummary()
+	}
+	if previous == nil {
+		previous = NewEmptySummary()
+	}
+
+	report := &WrappedReport{
+		Period:               period,
+		From:                 from,
+		To:                   to,
+		User:                 user,
+		TotalSeconds:         int64(current.TotalTime().Seconds()),
+		PreviousTotalSeconds: int64(previous.TotalTime().Seconds()),
+		TopProjects:          topWrappedItems(current.Projects, previous, SummaryProject),
+		TopLanguages:         topWrappedItems(current.Languages, previous, SummaryLanguage),
+		TopEditors:           topWrappedItems(current.Editors, previous, SummaryEditor),
+		NewLanguages:         []string{},
+	}
+	report.Change = percentChange(report.TotalSeconds, report.PreviousTotalSeconds)
+
+	report.fillActivity(hourly)
+
+	if len(knownLanguages) > 0 {
+		for _, l := range current.Languages {
+			if l.Key != UnknownSummaryKey && !slices.Contains(knownLanguages, l.Key) {
+				report.NewLanguages = append(report.NewLanguages, l.Key)
+			}
+		}
+	}
+
+	return report
+}
+
+func (r *WrappedReport) fillActivity(hourly map[time.Time]time.Duration) {
+	var (
+		tz       = r.From.Location()
+		daily    = map[string]time.Duration{}
+		weekdays = map[time.Weekday]time.Duration{}
+		hours    = map[int]time.Duration{}
+	)
+	for t, d := range hourly {
+		t = t.In(tz)
+		if t.Before(r.From) || !t.Before(r.To) || d <= 0 {
+			continue
+		}
+		daily[t.Format(time.DateOnly)] += d
+		weekdays[t.Weekday()] += d
+		hours[t.Hour()] += d
+	}
+
+	var total time.Duration
+	for _, d := range daily {
+		total += d
+	}
+	r.ActiveDays = len(daily)
+	if r.ActiveDays > 0 {
+		r.DailyAverageSeconds = int64(total.Seconds()) / int64(r.ActiveDays)
+	}
+
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if d := weekdays[wd]; d > 0 && int64(d.Seconds()) > r.BusiestWeekdaySeconds {
+			weekday := wd
+			r.BusiestWeekday, r.BusiestWeekdaySeconds = &weekday, int64(d.Seconds())
+		}
+	}
+	for h := 0; h < 24; h++ {
+		if d := hours[h]; d > 0 && int64(d.Seconds()) > r.BusiestHourSeconds {
+			hour := h
+			r.BusiestHour, r.BusiestHourSeconds = &hour, int64(d.Seconds())
+		}
+	}
+
+	var streak int
+	for day := r.From; day.Before(r.To); day = day.AddDate(0, 0, 1) {
+		if daily[day.Format(time.DateOnly)] == 0 {
+			streak = 0
+			continue
+		}
+		streak++
+		if streak > r.LongestStreak {
+			start := day.AddDate(0, 0, -(streak - 1))
+			r.LongestStreak, r.LongestStreakStart = streak, &start
+		}
+	}
+}
+
+// DailyAverage returns the average coding time per active day
+func (r *WrappedReport) DailyAverage() time.Duration {
+	return time.Duration(r.DailyAverageSeconds) * time.Second
+}
+
+func (r *WrappedReport) Total() time.Duration {
+	return time.Duration(r.TotalSeconds) * time.Second
+}
+
+// Title returns e.g. "March 2024" or "2024"
+func (r *WrappedReport) Title() string {
+	if r.Period == WrappedPeriodYear {
+		return r.From.Format("2006")
+	}
+	return r.From.Format("January 2006")
+}
+
+// ChangeText returns the change compared to the previous period for display, e.g. "+12%", or nothing if not comparable
+func (r *WrappedReport) ChangeText() string {
+	if r.Change == nil {
+		return ""
+	}
+	return formatChange(*r.Change)
+}
+
+// BusiestTimeText returns e.g. "Mondays, 21:00 - 22:00"
+func (r *WrappedReport) BusiestTimeText() string {
+	if r.BusiestWeekday == nil || r.BusiestHour == nil {
+		return ""
+	}
+	return fmt.Sprintf("%ss, %02d:00 - %02d:00", r.BusiestWeekday.String(), *r.BusiestHour, (*r.BusiestHour+1)%24)
+}
+
+func (i *WrappedItem) Total() time.Duration {
+	return time.Duration(i.TotalSeconds) * time.Second
+}
+
+// TrendText returns the change compared to the previous period for display, e.g. "-5%", or "new" if not used back then
+func (i *WrappedItem) TrendText() string {
+	if i.Trend == nil {
+		return "new"
+	}
+	return formatChange(*i.Trend)
+}
+
+func topWrappedItems(items SummaryItems, previous *Summary, summaryType uint8) []*WrappedItem {
+	sorted := slices.Clone(items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Total > sorted[j].Total
+	})
+
+	result := make([]*WrappedItem, 0, wrappedTopItems)
+	for _, item := range sorted {
+		if len(result) == wrappedTopItems {
+			break
+		}
+		if item.Total <= 0 {
+			continue
+		}
+		wrapped := &WrappedItem{
+			Key:             item.Key,
+			TotalSeconds:    int64(item.TotalFixed().Seconds()),
+			PreviousSeconds: int64(previous.TotalTimeByKey(summaryType, item.Key).Seconds()),
+		}
+		wrapped.Trend = percentChange(wrapped.TotalSeconds, wrapped.PreviousSeconds)
+		result = append(result, wrapped)
+	}
+	return result
+}
+
+func percentChange(current, previous int64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := float64(current-previous) / float64(previous) * 100
+	return &change
+}
+
+func formatChange(change float64) string {
+	return fmt.Sprintf("%+.0f%%", change)
+}
+
+// HourlyTotals sums up durations per hour, keyed by the start of each hour in the given timezone. Durations spanning
+// multiple hours are split at the hour boundaries.
+func (durations Durations) HourlyTotals(tz *time.Location) map[time.Time]time.Duration {
+	totals := map[time.Time]time.Duration{}
+	for _, d := range durations {
+		start, end := d.Time.T().In(tz), d.Time.T().In(tz).Add(d.Duration)
+		for start.Before(end) {
+			// not using time.Date, which is ambiguous when clocks are turned back
+			hour := start.Add(-time.Duration(start.Minute())*time.Minute - time.Duration(start.Second())*time.Second - time.Duration(start.Nanosecond()))
+			next := hour.Add(time.Hour)
+			if next.After(end) {
+				next = end
+			}
+			totals[hour] += next.Sub(start)
+			start = next
+		}
+	}
+	return totals
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrappedPeriodRange(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	now := time.Date(2024, 3, 13, 12, 0, 0, 0, berlin)
+
+	from, to := WrappedPeriodRange(WrappedPeriodMonth, now)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, berlin), from)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, berlin), to)
+
+	from, to = LatestWrappedPeriodRange(WrappedPeriodMonth, now)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, berlin), from)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, berlin), to)
+
+	from, to = LatestWrappedPeriodRange(WrappedPeriodYear, now)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, berlin), from)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, berlin), to)
+}
+
+func TestDurations_HourlyTotals(t *testing.T) {
+	kolkata, _ := time.LoadLocation("Asia/Kolkata") // utc+05:30
+	durations := Durations{
+		{Time: CustomTime(time.Date(2024, 3, 13, 8, 15, 0, 0, time.UTC)), Duration: 90 * time.Minute},
+		{Time: CustomTime(time.Date(2024, 3, 13, 9, 40, 0, 0, time.UTC)), Duration: 10 * time.Minute},
+	}
+
+	totals := durations.HourlyTotals(kolkata)
+	assert.Len(t, totals, 3)
+	assert.Equal(t, 15*time.Minute, totals[time.Date(2024, 3, 13, 13, 0, 0, 0, kolkata)])
+	assert.Equal(t, 60*time.Minute, totals[time.Date(2024, 3, 13, 14, 0, 0, 0, kolkata)])
+	assert.Equal(t, 25*time.Minute, totals[time.Date(2024, 3, 13, 15, 0, 0, 0, kolkata)])
+}
+
+func TestNewWrappedReport(t *testing.T) {
+	from, to := WrappedPeriodRange(WrappedPeriodMonth, time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC))
+
+	current := NewEmptySummary()
+	current.Projects = SummaryItems{{Key: "wakapi", Total: 3600}, {Key: "anchr", Total: 7200}}
+	current.Languages = SummaryItems{{Key: "Go", Total: 9000}, {Key: "Zig", Total: 1800}}
+	previous := NewEmptySummary()
+	previous.Projects = SummaryItems{{Key: "wakapi", Total: 1800}}
+	previous.Languages = SummaryItems{{Key: "Go", Total: 1800}}
+
+	hourly := map[time.Time]time.Duration{
+		time.Date(2024, 2, 5, 9, 0, 0, 0, time.UTC):  time.Hour, // monday
+		time.Date(2024, 2, 6, 9, 0, 0, 0, time.UTC):  time.Hour,
+		time.Date(2024, 2, 7, 22, 0, 0, 0, time.UTC): 30 * time.Minute,
+		time.Date(2024, 2, 12, 9, 0, 0, 0, time.UTC): time.Hour, // monday
+		time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC):  time.Hour, // outside of period
+	}
+
+	sut := NewWrappedReport(WrappedPeriodMonth, from, to, &User{ID: "user1"}, current, previous, hourly, []string{"Go"})
+
+	assert.Equal(t, int64(10800), sut.TotalSeconds)
+	assert.InDelta(t, 500, *sut.Change, 0.01)
+	assert.Equal(t, "anchr", sut.TopProjects[0].Key)
+	assert.Nil(t, sut.TopProjects[0].Trend)
+	assert.InDelta(t, 100, *sut.TopProjects[1].Trend, 0.01)
+	assert.Equal(t, 4, sut.ActiveDays)
+	assert.Equal(t, int64(3150), sut.DailyAverageSeconds)
+	assert.Equal(t, time.Monday, *sut.BusiestWeekday)
+	assert.Equal(t, 9, *sut.BusiestHour)
+	assert.Equal(t, 3, sut.LongestStreak)
+	assert.Equal(t, time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC), *sut.LongestStreakStart)
+	assert.Equal(t, []string{"Zig"}, sut.NewLanguages)
+	assert.Equal(t, "February 2024", sut.Title())
+
+	sut = NewWrappedReport(WrappedPeriodMonth, from, to, &User{ID: "user1"}, current, nil, nil, nil)
+	assert.Nil(t, sut.Change)
+	assert.Nil(t, sut.BusiestWeekday)
+	assert.Empty(t, sut.NewLanguages)
+}
+
+func TestWrappedReport_Texts(t *testing.T) {
+	change, weekday, hour := 12.4, time.Monday, 23
+	sut := &WrappedReport{Change: &change, BusiestWeekday: &weekday, BusiestHour: &hour}
+	assert.Equal(t, "+12%", sut.ChangeText())
+	assert.Equal(t, "Mondays, 23:00 - 00:00", sut.BusiestTimeText())
+	assert.Equal(t, "new", (&WrappedItem{}).TrendText())
+
+	sut = &WrappedReport{}
+	assert.Empty(t, sut.ChangeText())
+	assert.Empty(t, sut.BusiestTimeText())
+}
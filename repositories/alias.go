@@ -96,6 +96,23 @@ func (r *AliasRepository) Insert(alias *models.Alias) (*models.Alias, error) {
 	return alias, nil
 }
 
+// InsertBatch creates either all of the given aliases or, in case any of them fails, none
+func (r *AliasRepository) InsertBatch(aliases []*models.Alias) error {
+	for _, alias := range aliases {
+		if !alias.IsValid() {
+			return errors.New("invalid alias")
+		}
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, alias := range aliases {
+			if err := tx.Create(alias).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (r *AliasRepository) Delete(id uint) error {
 	return r.db.
 		Where("id = ?", id).
@@ -87,6 +87,21 @@ func (r *HeartbeatRepository) GetLatestByOriginAndUser(origin string, user *mode
 	return &heartbeat, nil
 }
 
+// GetExistingHashes returns the subset of the given heartbeat hashes that are already present in the database
+func (r *HeartbeatRepository) GetExistingHashes(hashes []string) ([]string, error) {
+	var existing []string
+	if len(hashes) == 0 {
+		return existing, nil
+	}
+	if err := r.db.
+		Model(&models.Heartbeat{}).
+		Where("hash in ?", hashes).
+		Pluck("hash", &existing).Error; err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
 func (r *HeartbeatRepository) GetAllWithin(from, to time.Time, user *models.User) ([]*models.Heartbeat, error) {
 	// https://stackoverflow.com/a/20765152/3112139
 	var heartbeats []*models.Heartbeat
@@ -56,6 +56,23 @@ func (r *LanguageMappingRepository) Insert(mapping *models.LanguageMapping) (*mo
 	return mapping, nil
 }
 
+// InsertBatch creates either all of the given mappings or, in case any of them fails, none
+func (r *LanguageMappingRepository) InsertBatch(mappings []*models.LanguageMapping) error {
+	for _, mapping := range mappings {
+		if !mapping.IsValid() {
+			return errors.New("invalid mapping")
+		}
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, mapping := range mappings {
+			if err := tx.Create(mapping).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (r *LanguageMappingRepository) Delete(id uint) error {
 	return r.db.
 		Where("id = ?", id).
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// how often to retry updating a project's language counts, which were changed concurrently in the meantime
+const projectLanguagesMaxAttempts = 5
+
+type ProjectRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewProjectRepository(db *gorm.DB) *ProjectRepository {
+	return &ProjectRepository{config: config.Get(), db: db}
+}
+
+func (r *ProjectRepository) GetById(id uint) (*models.Project, error) {
+	project := &models.Project{}
+	if err := r.db.Where(&models.Project{ID: id}).First(project).Error; err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (r *ProjectRepository) GetByUserAndName(userId, name string) (*models.Project, error) {
+	project := &models.Project{}
+	if err := r.db.Where(&models.Project{UserID: userId, Name: name}).First(project).Error; err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (r *ProjectRepository) GetByUser(userId string, query *models.ProjectQuery, pageParams *utils.PageParams) ([]*models.Project, error) {
+	var projects []*models.Project
+
+	q := r.filteredQuery(r.db.Model(&models.Project{}), userId, query).Order(query.OrderClause())
+	if pageParams != nil && pageParams.Limit() > 0 {
+		q = q.Limit(pageParams.Limit()).Offset(pageParams.Offset())
+	}
+
+	if err := q.Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (r *ProjectRepository) CountByUser(userId string, query *models.ProjectQuery) (int64, error) {
+	var count int64
+	if err := r.filteredQuery(r.db.Model(&models.Project{}), userId, query).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// InsertIfMissing creates the project, unless one with the same name already exists for the user, and returns whether
+// it was created
+func (r *ProjectRepository) InsertIfMissing(project *models.Project) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(project)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ExtendHeartbeatRange widens the period the project received heartbeats in to cover the given one. the updates are
+// conditional, so concurrent batches can only ever extend the period
+func (r *ProjectRepository) ExtendHeartbeatRange(userId, name string, first, last time.Time) error {
+	if err := r.db.Model(&models.Project{}).
+		Where(&models.Project{UserID: userId, Name: name}).
+		Where("first_heartbeat_at is null or first_heartbeat_at > ?", models.CustomTime(first)).
+		Update("first_heartbeat_at", models.CustomTime(first)).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&models.Project{}).
+		Where(&models.Project{UserID: userId, Name: name}).
+		Where("last_heartbeat_at is null or last_heartbeat_at < ?", models.CustomTime(last)).
+		Update("last_heartbeat_at", models.CustomTime(last)).Error
+}
+
+// AddLanguages adds the given numbers of heartbeats per language to the project's counts and updates its top language.
+// the update only applies if the counts haven't changed since they were read and is retried otherwise, so concurrent
+// batches, e.g. from the api and from an import running in the job worker, can't overwrite each other's counts
+func (r *ProjectRepository) AddLanguages(userId, name string, languages map[string]int64) error {
+	for attempt := 0; attempt < projectLanguagesMaxAttempts; attempt++ {
+		var current sql.NullString
+		if err := r.db.Model(&models.Project{}).
+			Where(&models.Project{UserID: userId, Name: name}).
+			Select("languages").
+			Row().
+			Scan(&current); err != nil {
+			return err
+		}
+
+		project := &models.Project{Languages: map[string]int64{}}
+		if current.Valid && current.String != "" {
+			if err := json.Unmarshal([]byte(current.String), &project.Languages); err != nil {
+				return err
+			}
+		}
+		project.AddLanguages(languages)
+
+		merged, err := json.Marshal(project.Languages)
+		if err != nil {
+			return err
+		}
+		if current.Valid && current.String == string(merged) {
+			return nil // nothing to count
+		}
+
+		q := r.db.Model(&models.Project{}).Where(&models.Project{UserID: userId, Name: name})
+		if current.Valid {
+			q = q.Where("languages = ?", current.String)
+		} else {
+			q = q.Where("languages is null")
+		}
+		result := q.Updates(map[string]interface{}{
+			"languages":    string(merged),
+			"top_language": project.TopLanguage,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+	}
+	return errors.New("failed to update project languages due to concurrent updates")
+}
+
+func (r *ProjectRepository) UpdateFields(project *models.Project, fields map[string]interface{}) (*models.Project, error) {
+	if err := r.db.Model(project).Updates(fields).Error; err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (r *ProjectRepository) filteredQuery(q *gorm.DB, userId string, query *models.ProjectQuery) *gorm.DB {
+	q = q.Where(&models.Project{UserID: userId})
+	if query.Prefix != "" {
+		q = q.Where("name like ? escape '!'", utils.EscapeLike(query.Prefix)+"%")
+	}
+	if !query.IncludeArchived {
+		q = q.Where("is_archived = ?", false)
+	}
+	return q
+}
@@ -56,6 +56,23 @@ func (r *ProjectLabelRepository) Insert(label *models.ProjectLabel) (*models.Pro
 	return label, nil
 }
 
+// InsertBatch creates either all of the given labels or, in case any of them fails, none
+func (r *ProjectLabelRepository) InsertBatch(labels []*models.ProjectLabel) error {
+	for _, label := range labels {
+		if !label.IsValid() {
+			return errors.New("invalid label")
+		}
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, label := range labels {
+			if err := tx.Create(label).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (r *ProjectLabelRepository) Delete(id uint) error {
 	return r.db.
 		Where("id = ?", id).
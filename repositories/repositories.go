@@ -4,10 +4,12 @@ import (
 	"time"
 
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
 )
 
 type IAliasRepository interface {
 	Insert(*models.Alias) (*models.Alias, error)
+	InsertBatch([]*models.Alias) error
 	Delete(uint) error
 	DeleteBatch([]uint) error
 	GetAll() ([]*models.Alias, error)
@@ -27,6 +29,7 @@ type IHeartbeatRepository interface {
 	GetLastByUsers() ([]*models.TimeByUser, error)
 	GetLatestByUser(*models.User) (*models.Heartbeat, error)
 	GetLatestByOriginAndUser(string, *models.User) (*models.Heartbeat, error)
+	GetExistingHashes([]string) ([]string, error)
 	Count(bool) (int64, error)
 	CountByUser(*models.User) (int64, error)
 	CountByUsers([]*models.User) ([]*models.CountByUser, error)
@@ -55,14 +58,27 @@ type ILanguageMappingRepository interface {
 	GetById(uint) (*models.LanguageMapping, error)
 	GetByUser(string) ([]*models.LanguageMapping, error)
 	Insert(*models.LanguageMapping) (*models.LanguageMapping, error)
+	InsertBatch([]*models.LanguageMapping) error
 	Delete(uint) error
 }
 
+type IProjectRepository interface {
+	GetById(uint) (*models.Project, error)
+	GetByUserAndName(string, string) (*models.Project, error)
+	GetByUser(string, *models.ProjectQuery, *utils.PageParams) ([]*models.Project, error)
+	CountByUser(string, *models.ProjectQuery) (int64, error)
+	InsertIfMissing(*models.Project) (bool, error)
+	ExtendHeartbeatRange(string, string, time.Time, time.Time) error
+	AddLanguages(string, string, map[string]int64) error
+	UpdateFields(*models.Project, map[string]interface{}) (*models.Project, error)
+}
+
 type IProjectLabelRepository interface {
 	GetAll() ([]*models.ProjectLabel, error)
 	GetById(uint) (*models.ProjectLabel, error)
 	GetByUser(string) ([]*models.ProjectLabel, error)
 	Insert(*models.ProjectLabel) (*models.ProjectLabel, error)
+	InsertBatch([]*models.ProjectLabel) error
 	Delete(uint) error
 }
 
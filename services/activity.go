@@ -33,50 +33,158 @@ const (
 	colorMaxLight = "#047857"
 	textDark      = "#D1D5DB"
 	textLight     = "#37474F"
+
+	heatmapLabelWidth = 40
 )
 
 type ActivityService struct {
-	config         *config.Config
-	cache          *cache.Cache
-	summaryService ISummaryService
+	config          *config.Config
+	cache           *cache.Cache
+	db              *gorm.DB
+	summaryService  ISummaryService
+	durationService IDurationService
 }
 
 func NewActivityService(db *gorm.DB) *ActivityService {
 	summaryService := NewSummaryService(db)
+	durationService := NewDurationService(db)
 	return &ActivityService{
-		config:         config.Get(),
-		cache:          cache.New(6*time.Hour, 6*time.Hour),
-		summaryService: summaryService,
+		config:          config.Get(),
+		cache:           cache.New(6*time.Hour, 6*time.Hour),
+		db:              db,
+		summaryService:  summaryService,
+		durationService: durationService,
 	}
 }
 
-// GetChart generates an activity chart for a given user and the given time interval, similar to GitHub's contribution timeline. See https://github.com/muety/wakapi/issues/12.
-// Please note: currently, only yearly charts ("last_12_months") are supported. However, we could fairly easily restructure this to support dynamic intervals.
-func (s *ActivityService) GetChart(user *models.User, interval *models.IntervalKey, darkTheme, hideAttribution, skipCache bool) (string, error) {
-	cacheKey := fmt.Sprintf("chart_%s_%s_%v_%v", user.ID, (*interval)[0], darkTheme, hideAttribution)
+// GetChart generates an activity chart for a given user and the given time range, similar to GitHub's contribution timeline. See https://github.com/muety/wakapi/issues/12.
+// Ranges starting before the user's first heartbeat (e.g. "any") are shortened accordingly.
+func (s *ActivityService) GetChart(user *models.User, from, to time.Time, darkTheme, hideAttribution, skipCache bool) (string, error) {
+	from, to = s.clampRange(user, from, to)
+	if !from.Before(to) {
+		return "", errors.New("invalid range")
+	}
+
+	cacheKey := fmt.Sprintf("chart_%s_%s_%s_%v_%v", user.ID, helpers.FormatDate(from), helpers.FormatDate(to), darkTheme, hideAttribution)
 	if result, found := s.cache.Get(cacheKey); found && !skipCache {
 		return result.(string), nil
 	}
 
-	switch interval {
-	case models.IntervalPast12Months:
-		chart, err := s.getChartPastYear(user, darkTheme, hideAttribution)
-		if err == nil {
-			s.cache.SetDefault(cacheKey, chart) // TODO: cache compressed?
-		}
-		return chart, err
-	default:
-		return "", errors.New("unsupported interval")
+	chart, err := s.getChart(user, from, to, darkTheme, hideAttribution)
+	if err == nil {
+		s.cache.SetDefault(cacheKey, chart) // TODO: cache compressed?
+	}
+	return chart, err
+}
+
+// GetHeatmap computes the user's coding time per weekday and hour of day within the given time range, in the user's timezone
+func (s *ActivityService) GetHeatmap(user *models.User, from, to time.Time, filters *models.Filters, skipCache bool) (*models.ActivityHeatmap, error) {
+	from, to = s.clampRange(user, from, to)
+	if !from.Before(to) {
+		return nil, errors.New("invalid range")
+	}
+	if filters == nil {
+		filters = &models.Filters{}
 	}
+
+	cacheKey := fmt.Sprintf("heatmap_%s_%s_%s_%s", user.ID, helpers.FormatDateTime(from.Truncate(time.Hour)), helpers.FormatDateTime(to.Truncate(time.Hour)), filters.Hash())
+	if result, found := s.cache.Get(cacheKey); found && !skipCache {
+		return result.(*models.ActivityHeatmap), nil
+	}
+
+	hourly, err := getHourlyTotals(s.durationService, user, from, to, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	heatmap := models.NewActivityHeatmap(from, to, hourly, user.TZ())
+	s.cache.SetDefault(cacheKey, heatmap)
+	return heatmap, nil
 }
 
-func (s *ActivityService) getChartPastYear(user *models.User, darkTheme, hideAttribution bool) (string, error) {
-	err, from, to := helpers.ResolveIntervalTZ(models.IntervalPast12Months, user.TZ())
-	from = datetime.BeginOfWeek(from, time.Monday)
+// GetHeatmapChart renders the user's weekday by hour heatmap (see GetHeatmap) as svg
+func (s *ActivityService) GetHeatmapChart(user *models.User, from, to time.Time, filters *models.Filters, darkTheme, hideAttribution, skipCache bool) (string, error) {
+	heatmap, err := s.GetHeatmap(user, from, to, filters, skipCache)
 	if err != nil {
 		return "", err
 	}
 
+	var (
+		colorRGBAMin         = utils.HexToRGBA(condition.TernaryOperator[bool, string](darkTheme, colorMinDark, colorMinLight))
+		colorRGBAMax         = utils.HexToRGBA(condition.TernaryOperator[bool, string](darkTheme, colorMaxDark, colorMaxLight))
+		colorText            = condition.TernaryOperator[bool, string](darkTheme, textDark, textLight)
+		w            float64 = heatmapLabelWidth + 24*(cellWidth+cellSpacing)
+		h            float64 = 25 + gridRows*(cellHeight+cellSpacing) + 20 + 24 + 5
+	)
+
+	buf := &bytes.Buffer{}
+
+	canvas := svg.New(buf)
+	canvas.Start(w, h)
+	canvas.Style("text/css",
+		fmt.Sprintf("text { font-family: 'Source Sans 3', Roboto, Helvetica, Arial, sans-serif; font-size: 0.9rem; font-weight: 500; fill: %s; }", colorText),
+		fmt.Sprintf("rect { fill-opacity: 1; rx: 3px; ry: 3px; }"),
+		fmt.Sprintf("rect:hover { filter: brightness(0.9) }"),
+	)
+
+	canvas.Text(0, 15, fmt.Sprintf("%s to %s (%s)", helpers.FormatDateHuman(heatmap.From), helpers.FormatDateHuman(heatmap.To), heatmap.Timezone))
+
+	for row := 0; row < gridRows; row++ {
+		weekday := time.Weekday((row + 1) % 7) // weeks start on monday
+		y := 25 + float64(row)*(cellHeight+cellSpacing)
+		canvas.Text(0, y+15, weekday.String()[:3])
+
+		for hour := 0; hour < 24; hour++ {
+			fillColor := utils.RGBAToHex(utils.FadeColors(colorRGBAMin, colorRGBAMax, heatmap.Intensity(weekday, hour)))
+
+			canvas.Group()
+			canvas.Title(fmt.Sprintf("%s on %ss, %02d:00 - %02d:00", helpers.FmtWakatimeDuration(time.Duration(heatmap.Data[weekday][hour])*time.Second), weekday.String(), hour, (hour+1)%24))
+			canvas.Rect(heatmapLabelWidth+float64(hour)*(cellWidth+cellSpacing), y, cellWidth, cellHeight, fmt.Sprintf("fill: %s", fillColor))
+			canvas.Gend()
+		}
+	}
+
+	for hour := 0; hour < 24; hour += 3 {
+		canvas.Text(heatmapLabelWidth+float64(hour)*(cellWidth+cellSpacing), 25+gridRows*(cellHeight+cellSpacing)+15, fmt.Sprintf("%02d", hour))
+	}
+
+	if !hideAttribution {
+		canvas.Group()
+		canvas.Title("Wakana.io")
+		canvas.Image(w-60, h-24, 60, 24, "https://wakana.io/assets/images/logo-gh.svg")
+		canvas.Gend()
+	}
+
+	canvas.End()
+
+	return buf.String(), nil
+}
+
+// clampRange shortens ranges longer than a year that reach back before the user's first heartbeat (e.g. "any"), or their
+// sign up if there is none
+func (s *ActivityService) clampRange(user *models.User, from, to time.Time) (time.Time, time.Time) {
+	if !from.Before(to.AddDate(-1, 0, 0)) {
+		return from, to
+	}
+
+	var first models.Heartbeat
+	start := user.CreatedAt.T()
+	if err := s.db.Where("user_id = ?", user.ID).Order("time asc").Limit(1).Find(&first).Error; err == nil && first.ID != 0 {
+		start = first.Time.T()
+	}
+	start = datetime.BeginOfDay(start.In(user.TZ()))
+	if yearAgo := to.AddDate(-1, 0, 0); yearAgo.Before(start) {
+		start = yearAgo
+	}
+	if from.Before(start) {
+		from = start
+	}
+	return from, to
+}
+
+func (s *ActivityService) getChart(user *models.User, from, to time.Time, darkTheme, hideAttribution bool) (string, error) {
+	from = datetime.BeginOfWeek(from.In(user.TZ()), time.Monday)
+
 	intervals := utils.SplitRangeByDays(from, to)
 	summaries := make([]*models.Summary, len(intervals))
 
@@ -7,6 +7,7 @@ import (
 
 	"github.com/becheran/wildmatch-go"
 	datastructure "github.com/duke-git/lancet/v2/datastructure/set"
+	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/repositories"
@@ -15,6 +16,7 @@ import (
 
 type AliasService struct {
 	config     *config.Config
+	eventBus   *hub.Hub
 	repository repositories.IAliasRepository
 }
 
@@ -22,6 +24,7 @@ func NewAliasService(db *gorm.DB) *AliasService {
 	aliasRepository := repositories.NewAliasRepository(db)
 	return &AliasService{
 		config:     config.Get(),
+		eventBus:   config.EventBus(),
 		repository: aliasRepository,
 	}
 }
@@ -29,6 +32,7 @@ func NewAliasService(db *gorm.DB) *AliasService {
 func NewTestAliasService(aliasRepo repositories.IAliasRepository) *AliasService {
 	return &AliasService{
 		config:     config.Get(),
+		eventBus:   config.EventBus(),
 		repository: aliasRepo,
 	}
 }
@@ -111,9 +115,29 @@ func (srv *AliasService) Create(alias *models.Alias) (*models.Alias, error) {
 	// reload entire cache (async, though)
 	go srv.MayInitializeUser(alias.UserID)
 
+	srv.notifyUpdate(alias, false)
 	return result, nil
 }
 
+// CreateMulti creates either all of the given aliases or, in case any of them fails, none
+func (srv *AliasService) CreateMulti(aliases []*models.Alias) ([]*models.Alias, error) {
+	if err := srv.repository.InsertBatch(aliases); err != nil {
+		return nil, err
+	}
+
+	affectedUsers := datastructure.New[string]()
+	for _, a := range aliases {
+		affectedUsers.Add(a.UserID)
+		srv.updateCache(a, false)
+		srv.notifyUpdate(a, false)
+	}
+	for k := range affectedUsers {
+		go srv.MayInitializeUser(k)
+	}
+
+	return aliases, nil
+}
+
 func (srv *AliasService) Delete(alias *models.Alias) error {
 	if alias.UserID == "" {
 		return errors.New("no user id specified")
@@ -122,7 +146,8 @@ func (srv *AliasService) Delete(alias *models.Alias) error {
 
 	// manually update cache
 	if err == nil {
-		srv.updateCache(alias, false)
+		srv.updateCache(alias, true)
+		srv.notifyUpdate(alias, true)
 	}
 	// reload entire cache (async, though)
 	go srv.MayInitializeUser(alias.UserID)
@@ -148,6 +173,7 @@ func (srv *AliasService) DeleteMulti(aliases []*models.Alias) error {
 	if err == nil {
 		for _, a := range aliases {
 			srv.updateCache(a, true)
+			srv.notifyUpdate(a, true)
 		}
 	}
 	// reload entire cache (async, though)
@@ -178,6 +204,17 @@ func (srv *AliasService) updateCache(reason *models.Alias, removal bool) {
 	}
 }
 
+func (srv *AliasService) notifyUpdate(alias *models.Alias, isDelete bool) {
+	name := config.EventAliasCreate
+	if isDelete {
+		name = config.EventAliasDelete
+	}
+	srv.eventBus.Publish(hub.Message{
+		Name:   name,
+		Fields: map[string]interface{}{config.FieldPayload: alias, config.FieldUserId: alias.UserID},
+	})
+}
+
 func (srv *AliasService) getFiltered(userId string, check func(alias *models.Alias) bool) ([]*models.Alias, error) {
 	if !srv.IsInitialized(userId) {
 		srv.MayInitializeUser(userId)
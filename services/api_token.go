@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+	"gorm.io/gorm"
+)
+
+const (
+	apiTokenLength   = 24 // random bytes, hex-encoded
+	apiTokenHintSize = 4
+	// last use is only recorded once in a while, as tokens are used for every single heartbeat
+	apiTokenUsageInterval = 1 * time.Minute
+)
+
+// apiTokenCache is shared by all instances of the service, which are created by several others, so revoking a token
+// takes effect immediately for all of them. Tokens may still be revoked from a different process, so keep them only briefly.
+var apiTokenCache = cache.New(1*time.Minute, 5*time.Minute)
+
+type ApiTokenService struct {
+	config *config.Config
+	cache  *cache.Cache
+	db     *gorm.DB
+}
+
+func NewApiTokenService(db *gorm.DB) *ApiTokenService {
+	return &ApiTokenService{
+		config: config.Get(),
+		cache:  apiTokenCache,
+		db:     db,
+	}
+}
+
+// Create generates a new token for the user. The plain token is only contained in the returned object.
+func (srv *ApiTokenService) Create(user *models.User, newToken *models.NewApiToken) (*models.ApiToken, error) {
+	if !newToken.IsValid() {
+		return nil, errors.New("invalid api token")
+	}
+
+	secret, err := utils.GenerateRandomPassword(apiTokenLength)
+	if err != nil {
+		return nil, err
+	}
+	plain := models.ApiTokenPrefix + secret
+
+	token := &models.ApiToken{
+		ID:        uuid.Must(uuid.NewV4()).String(),
+		UserID:    user.ID,
+		Name:      strings.TrimSpace(newToken.Name),
+		TokenHash: models.HashApiToken(plain),
+		Hint:      secret[len(secret)-apiTokenHintSize:],
+		Scopes:    newToken.Scopes,
+	}
+	if newToken.ExpiresAt != nil {
+		expiresAt := models.CustomTime(*newToken.ExpiresAt)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := srv.db.Create(token).Error; err != nil {
+		return nil, err
+	}
+
+	token.Token = plain
+	return token, nil
+}
+
+func (srv *ApiTokenService) GetById(id string) (*models.ApiToken, error) {
+	token := &models.ApiToken{}
+	if err := srv.db.Where(&models.ApiToken{ID: id}).First(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// GetByToken looks up a token by its plain value, expired tokens are not found
+func (srv *ApiTokenService) GetByToken(plain string) (*models.ApiToken, error) {
+	if !strings.HasPrefix(plain, models.ApiTokenPrefix) {
+		return nil, errors.New("not an api token")
+	}
+
+	hash := models.HashApiToken(plain)
+
+	// cached tokens are shared between concurrent requests, so only ever hand out copies of them
+	token := &models.ApiToken{}
+	if cached, found := srv.cache.Get(hash); found {
+		*token = *cached.(*models.ApiToken)
+	} else {
+		if err := srv.db.Where(&models.ApiToken{TokenHash: hash}).First(token).Error; err != nil {
+			return nil, err
+		}
+		cached := *token
+		srv.cache.SetDefault(hash, &cached)
+	}
+
+	if token.IsExpiredAt(time.Now()) {
+		return nil, errors.New("api token expired")
+	}
+	return token, nil
+}
+
+func (srv *ApiTokenService) FetchUserTokens(userID string) ([]*models.ApiToken, error) {
+	var tokens []*models.ApiToken
+	if err := srv.db.
+		Where(&models.ApiToken{UserID: userID}).
+		Order("created_at asc").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// MarkUsed records the time and source ip of the token's latest use, unless it was recorded only shortly before
+func (srv *ApiTokenService) MarkUsed(token *models.ApiToken, ip string) error {
+	now := time.Now()
+	if token.LastUsedAt != nil && token.LastUsedIP == ip && now.Sub(token.LastUsedAt.T()) < apiTokenUsageInterval {
+		return nil
+	}
+
+	lastUsedAt := models.CustomTime(now)
+	if err := srv.db.Model(&models.ApiToken{ID: token.ID}).Updates(map[string]interface{}{
+		"last_used_at": &lastUsedAt,
+		"last_used_ip": ip,
+	}).Error; err != nil {
+		return err
+	}
+	token.LastUsedAt, token.LastUsedIP = &lastUsedAt, ip
+
+	// let the next lookup see the recorded use instead of recording it again right away
+	srv.cache.Delete(token.TokenHash)
+	return nil
+}
+
+func (srv *ApiTokenService) Delete(token *models.ApiToken) error {
+	if err := srv.db.Delete(token).Error; err != nil {
+		return err
+	}
+	srv.cache.Delete(token.TokenHash)
+	return nil
+}
+
+type IApiTokenService interface {
+	Create(user *models.User, newToken *models.NewApiToken) (*models.ApiToken, error)
+	GetById(id string) (*models.ApiToken, error)
+	GetByToken(plain string) (*models.ApiToken, error)
+	FetchUserTokens(userID string) ([]*models.ApiToken, error)
+	MarkUsed(token *models.ApiToken, ip string) error
+	Delete(token *models.ApiToken) error
+}
@@ -3,9 +3,12 @@ package services
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"time"
 
 	"github.com/duke-git/lancet/v2/datetime"
+	"github.com/gofrs/uuid/v5"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	summarytypes "github.com/muety/wakapi/types"
@@ -41,6 +44,16 @@ func (srv *ClientService) Update(client *models.Client, update *models.ClientUpd
 		return nil, err
 	}
 
+	if update.Rounding != nil {
+		client.Rounding = *update.Rounding
+		if err := srv.db.
+			Model(client).
+			Select("rounding_mode", "rounding_increment_minutes", "rounding_daily_minimum_minutes", "rounding_daily_cap_minutes").
+			Updates(client).Error; err != nil {
+			return nil, err
+		}
+	}
+
 	return client, nil
 }
 
@@ -86,29 +99,216 @@ func (srv *ClientService) FetchUserClients(userID, query string) ([]*models.Clie
 	return clients, nil
 }
 
-func (srv *ClientService) FetchClientInvoiceLineItems(client *models.Client, user *models.User, summarySrvc ISummaryService, start, end time.Time) (*models.Summary, error) {
+// FetchClientInvoiceLineItems breaks the time spent on the client's projects down into line items with one rate each. The period is
+// split at the effective dates of the client's rates and, only if any rate is bound to a category, by category. If the client has
+// a rounding policy, time is rounded per day, so every day is looked at separately.
+func (srv *ClientService) FetchClientInvoiceLineItems(client *models.Client, user *models.User, summarySrvc ISummaryService, start, end time.Time) ([]models.InvoiceLineItem, error) {
 	end = datetime.EndOfDay(end)
 
 	if !end.After(start) {
 		return nil, errors.New("'end' date must be after 'start' date")
 	}
 
-	filters := client.GetSummaryFilters()
+	rates, err := srv.FetchRates(client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	type lineItemKey struct {
+		project, category, rateID string
+	}
+	itemsByKey := make(map[lineItemKey]*invoiceLineItemDraft)
+	keys := make([]lineItemKey, 0)
+
+	resolveItem := func(project, category string, from, to time.Time) *invoiceLineItemDraft {
+		rate := models.ClientRates(rates).Resolve(project, category, from)
+		key := lineItemKey{project: project}
+		hourlyRate := client.HourlyRate
+		if rate != nil {
+			key.rateID, hourlyRate = rate.ID, rate.HourlyRate
+			if rate.Category != "" {
+				key.category = category
+			}
+		}
+
+		item, ok := itemsByKey[key]
+		if !ok {
+			item = &invoiceLineItemDraft{InvoiceLineItem: models.InvoiceLineItem{
+				Project:       project,
+				Category:      key.category,
+				HourlyRate:    &hourlyRate,
+				AutoGenerated: true,
+			}, from: from, to: to}
+			itemsByKey[key] = item
+			keys = append(keys, key)
+		}
+		item.to = to
+		return item
+	}
+
+	boundaries := models.ClientRates(rates).Boundaries(start, end)
+	if client.Rounding.IsActive() {
+		boundaries = mergeBoundaries(boundaries, dayBoundaries(start, end))
+	}
+
+	periodStart := start
+	for _, boundary := range append(boundaries, end) {
+		summary, err := srv.fetchSummary(user, summarySrvc, client.GetSummaryFilters(), periodStart, boundary)
+		if err != nil {
+			return nil, err
+		}
+
+		// time per line item within this period, categories without a rate of their own add up to their project's item
+		periodItems := make([]*invoiceLineItemDraft, 0)
+		periodSeconds := make(map[*invoiceLineItemDraft]int64)
+		addTime := func(project, category string, seconds int64) {
+			item := resolveItem(project, category, periodStart, boundary)
+			if _, ok := periodSeconds[item]; !ok {
+				periodItems = append(periodItems, item)
+			}
+			periodSeconds[item] += seconds
+		}
+
+		for _, project := range summary.Projects {
+			if !models.ClientRates(rates).HasCategoryRates() {
+				addTime(project.Key, "", int64(project.TotalFixed().Seconds()))
+				continue
+			}
+
+			projectSummary, err := srv.fetchSummary(user, summarySrvc, &models.Filters{Project: models.OrFilter{project.Key}}, periodStart, boundary)
+			if err != nil {
+				return nil, err
+			}
+			for _, category := range projectSummary.Categories {
+				addTime(project.Key, category.Key, int64(category.TotalFixed().Seconds()))
+			}
+		}
+
+		tracked := make([]int64, len(periodItems))
+		for i, item := range periodItems {
+			tracked[i] = periodSeconds[item]
+			item.TotalSeconds += tracked[i]
+		}
+		if client.Rounding.IsActive() {
+			for i, billed := range client.Rounding.ApplyDaily(tracked) {
+				periodItems[i].billedSeconds += billed
+			}
+		}
+
+		periodStart = boundary
+	}
+
+	// items of the same project billed at different rates over time are told apart by their period
+	itemsPerProject := make(map[string]int)
+	for _, key := range keys {
+		if key.category == "" {
+			itemsPerProject[key.project]++
+		}
+	}
+
+	lineItems := make([]models.InvoiceLineItem, 0, len(keys))
+	for _, key := range keys {
+		item := itemsByKey[key]
+		if client.Rounding.IsActive() {
+			item.BilledSeconds = &item.billedSeconds
+		}
+		if item.TotalSeconds <= 0 && item.BillableSeconds() <= 0 {
+			continue
+		}
+		item.Title = item.Project
+		if item.Category != "" {
+			item.Title = fmt.Sprintf("%s - %s", item.Project, item.Category)
+		}
+		if item.Category == "" && itemsPerProject[key.project] > 1 {
+			item.Title = fmt.Sprintf("%s (%s - %s)", item.Title, item.from.Format(config.SimpleDateFormat), item.to.Add(-1*time.Second).Format(config.SimpleDateFormat))
+		}
+		item.Amount = item.AmountOr(client.HourlyRate)
+		lineItems = append(lineItems, item.InvoiceLineItem)
+	}
+
+	sort.SliceStable(lineItems, func(i, j int) bool {
+		return lineItems[i].Project < lineItems[j].Project
+	})
+
+	return lineItems, nil
+}
+
+func (srv *ClientService) FetchRates(clientID string) ([]*models.ClientRate, error) {
+	var rates []*models.ClientRate
+	if err := srv.db.
+		Where("client_id = ?", clientID).
+		Order("project asc, category asc, effective_from asc").
+		Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (srv *ClientService) GetRate(rateID, clientID string) (*models.ClientRate, error) {
+	rate := &models.ClientRate{}
+	if err := srv.db.Where(&models.ClientRate{ID: rateID, ClientID: clientID}).First(rate).Error; err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+func (srv *ClientService) CreateRate(rate *models.ClientRate) (*models.ClientRate, error) {
+	rate.ID = uuid.Must(uuid.NewV4()).String()
+	if err := srv.db.Create(rate).Error; err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+func (srv *ClientService) UpdateRate(rate *models.ClientRate) (*models.ClientRate, error) {
+	if err := srv.db.
+		Model(rate).
+		Select("project", "category", "hourly_rate", "effective_from", "description").
+		Updates(rate).Error; err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+func (srv *ClientService) DeleteRate(rateID, clientID string) error {
+	return srv.db.
+		Where("id = ?", rateID).
+		Where("client_id = ?", clientID).
+		Delete(&models.ClientRate{}).Error
+}
+
+func (srv *ClientService) fetchSummary(user *models.User, summarySrvc ISummaryService, filters *models.Filters, start, end time.Time) (*models.Summary, error) {
 	request := summarytypes.NewSummaryRequest(start, end, user).WithFilters(filters)
 	if end.After(time.Now()) {
 		request = request.WithoutCache()
 	}
-	options := summarytypes.DefaultProcessingOptions()
-	summary, err := summarySrvc.Generate(request, options)
+	return summarySrvc.Generate(request, summarytypes.DefaultProcessingOptions())
+}
 
-	if err != nil {
-		return nil, err
-	}
+// invoiceLineItemDraft keeps track of the period a line item covers while it's being assembled
+type invoiceLineItemDraft struct {
+	models.InvoiceLineItem
+	from, to      time.Time
+	billedSeconds int64
+}
 
-	summary.FromTime = models.CustomTime(start)
-	summary.ToTime = models.CustomTime(end.Add(-1 * time.Second))
+// dayBoundaries returns the start of every day after the given start and before the given end
+func dayBoundaries(start, end time.Time) []time.Time {
+	boundaries := make([]time.Time, 0)
+	for day := datetime.BeginOfDay(start).AddDate(0, 0, 1); day.Before(end); day = day.AddDate(0, 0, 1) {
+		boundaries = append(boundaries, day)
+	}
+	return boundaries
+}
 
-	return summary, nil
+func mergeBoundaries(a, b []time.Time) []time.Time {
+	merged := append(append(make([]time.Time, 0, len(a)+len(b)), a...), b...)
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Before(merged[j])
+	})
+	return slices.CompactFunc(merged, func(t1, t2 time.Time) bool {
+		return t1.Equal(t2)
+	})
 }
 
 type IClientService interface {
@@ -117,5 +317,10 @@ type IClientService interface {
 	GetClientForUser(id, userID string) (*models.Client, error)
 	DeleteClient(id, userID string) error
 	FetchUserClients(id, query string) ([]*models.Client, error)
-	FetchClientInvoiceLineItems(client *models.Client, user *models.User, summarySrvc ISummaryService, start, end time.Time) (*models.Summary, error)
+	FetchClientInvoiceLineItems(client *models.Client, user *models.User, summarySrvc ISummaryService, start, end time.Time) ([]models.InvoiceLineItem, error)
+	FetchRates(clientID string) ([]*models.ClientRate, error)
+	GetRate(rateID, clientID string) (*models.ClientRate, error)
+	CreateRate(*models.ClientRate) (*models.ClientRate, error)
+	UpdateRate(*models.ClientRate) (*models.ClientRate, error)
+	DeleteRate(rateID, clientID string) error
 }
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	wakatime "github.com/muety/wakapi/models/compat/wakatime/v1"
+	"github.com/muety/wakapi/repositories"
+	"gorm.io/gorm"
+)
+
+// exportedSettings holds the user's preferences that are hidden from the regular user model's json representation
+type exportedSettings struct {
+	Location               string `json:"location"`
+	ShareDataMaxDays       int    `json:"share_data_max_days"`
+	ShareEditors           bool   `json:"share_editors"`
+	ShareLanguages         bool   `json:"share_languages"`
+	ShareProjects          bool   `json:"share_projects"`
+	ShareOSs               bool   `json:"share_oss"`
+	ShareMachines          bool   `json:"share_machines"`
+	ShareLabels            bool   `json:"share_labels"`
+	ReportsWeekly          bool   `json:"reports_weekly"`
+	ExcludeUnknownProjects bool   `json:"exclude_unknown_projects"`
+	WakatimeApiUrl         string `json:"wakatime_api_url"`
+}
+
+// dataExportStaleTimeout is how long an export may remain queued or running before it's considered lost, e.g. because the
+// process generating it crashed, so it doesn't keep the user from requesting another export forever
+const dataExportStaleTimeout = 6 * time.Hour
+
+const dataExportStaleError = "export timed out"
+
+type DataExportService struct {
+	config            *config.Config
+	db                *gorm.DB
+	summaryRepository repositories.ISummaryRepository
+}
+
+func NewDataExportService(db *gorm.DB) *DataExportService {
+	return &DataExportService{
+		config:            config.Get(),
+		db:                db,
+		summaryRepository: repositories.NewSummaryRepository(db),
+	}
+}
+
+func (srv *DataExportService) Create(user *models.User) (*models.DataExport, error) {
+	export := &models.DataExport{
+		ID:     uuid.Must(uuid.NewV4()).String(),
+		UserID: user.ID,
+		Status: models.DataExportQueued,
+	}
+	if err := srv.db.Create(export).Error; err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (srv *DataExportService) GetById(id string) (*models.DataExport, error) {
+	export := &models.DataExport{}
+	if err := srv.db.Where(&models.DataExport{ID: id}).First(export).Error; err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (srv *DataExportService) FetchUserExports(userID string) ([]*models.DataExport, error) {
+	var exports []*models.DataExport
+	if err := srv.db.
+		Where(&models.DataExport{UserID: userID}).
+		Order("created_at desc").
+		Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// HasActiveExport reports whether an export for the user is waiting or in progress. Exports that have been so for too
+// long are marked as failed first, instead of counting as active.
+func (srv *DataExportService) HasActiveExport(userID string) (bool, error) {
+	if err := srv.failStaleExports(userID); err != nil {
+		return false, err
+	}
+
+	var count int64
+	if err := srv.db.
+		Model(&models.DataExport{}).
+		Where("user_id = ?", userID).
+		Where("status in ?", []string{models.DataExportQueued, models.DataExportRunning}).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// FilePath returns the location of the export's archive on disk
+func (srv *DataExportService) FilePath(export *models.DataExport) string {
+	return filepath.Join(srv.config.App.ExportDir, export.ID+".zip")
+}
+
+// Generate writes the archive containing all of the user's data and marks the export as completed or failed accordingly
+func (srv *DataExportService) Generate(export *models.DataExport, user *models.User) error {
+	if err := srv.db.Model(export).Update("status", models.DataExportRunning).Error; err != nil {
+		return err
+	}
+	export.Status = models.DataExportRunning
+
+	size, err := srv.writeArchive(user, srv.FilePath(export))
+	if err != nil {
+		os.Remove(srv.FilePath(export))
+		return errors.Join(err, srv.finish(export, 0, err))
+	}
+	return srv.finish(export, size, nil)
+}
+
+// DeleteExpired removes all exports whose download period has passed, including their archives
+func (srv *DataExportService) DeleteExpired() (int, error) {
+	var exports []*models.DataExport
+	if err := srv.db.
+		Where("expires_at < ?", time.Now()).
+		Or("status = ? and created_at < ?", models.DataExportFailed, time.Now().Add(-time.Duration(srv.config.App.ExportTtlHours)*time.Hour)).
+		Find(&exports).Error; err != nil {
+		return 0, err
+	}
+
+	for _, export := range exports {
+		if err := os.Remove(srv.FilePath(export)); err != nil && !os.IsNotExist(err) {
+			config.Log().Error("failed to delete data export archive", "exportID", export.ID, "error", err)
+			continue
+		}
+		if err := srv.db.Delete(export).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(exports), nil
+}
+
+func (srv *DataExportService) finish(export *models.DataExport, size int64, exportErr error) error {
+	now := time.Now()
+	finishedAt := models.CustomTime(now)
+	expiresAt := models.CustomTime(now.Add(time.Duration(srv.config.App.ExportTtlHours) * time.Hour))
+
+	export.Status = models.DataExportCompleted
+	export.Size = size
+	export.FinishedAt = &finishedAt
+	export.ExpiresAt = &expiresAt
+	if exportErr != nil {
+		export.Status = models.DataExportFailed
+		export.Error = exportErr.Error()
+		export.ExpiresAt = nil
+	}
+
+	return srv.db.Model(export).Updates(map[string]interface{}{
+		"status":      export.Status,
+		"size":        export.Size,
+		"error":       export.Error,
+		"finished_at": export.FinishedAt,
+		"expires_at":  export.ExpiresAt,
+	}).Error
+}
+
+func (srv *DataExportService) failStaleExports(userID string) error {
+	now := time.Now()
+	return srv.db.
+		Model(&models.DataExport{}).
+		Where("user_id = ?", userID).
+		Where("status in ?", []string{models.DataExportQueued, models.DataExportRunning}).
+		Where("coalesce(updated_at, created_at) < ?", now.Add(-dataExportStaleTimeout)).
+		Updates(map[string]interface{}{
+			"status":      models.DataExportFailed,
+			"error":       dataExportStaleError,
+			"finished_at": models.CustomTime(now),
+		}).Error
+}
+
+func (srv *DataExportService) writeArchive(user *models.User, path string) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return 0, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+
+	if err := srv.writeHeartbeats(archive, user); err != nil {
+		return 0, err
+	}
+	if err := srv.writeSummaries(archive, user); err != nil {
+		return 0, err
+	}
+
+	account := map[string]interface{}{
+		"user": user,
+		"settings": &exportedSettings{
+			Location:               user.Location,
+			ShareDataMaxDays:       user.ShareDataMaxDays,
+			ShareEditors:           user.ShareEditors,
+			ShareLanguages:         user.ShareLanguages,
+			ShareProjects:          user.ShareProjects,
+			ShareOSs:               user.ShareOSs,
+			ShareMachines:          user.ShareMachines,
+			ShareLabels:            user.ShareLabels,
+			ReportsWeekly:          user.ReportsWeekly,
+			ExcludeUnknownProjects: user.ExcludeUnknownProjects,
+			WakatimeApiUrl:         user.WakatimeApiUrl,
+		},
+	}
+	if err := writeJsonEntry(archive, "account.json", account); err != nil {
+		return 0, err
+	}
+
+	var goals []*models.Goal
+	var clients []*models.Client
+	var invoices []*models.Invoice
+	var aliases []*models.Alias
+	var labels []*models.ProjectLabel
+	var languageMappings []*models.LanguageMapping
+	var projects []*models.Project
+
+	entries := []struct {
+		name     string
+		dest     interface{}
+		preloads []string
+	}{
+		{"goals.json", &goals, nil},
+		{"clients.json", &clients, nil},
+		{"invoices.json", &invoices, []string{"Payments"}},
+		{"aliases.json", &aliases, nil},
+		{"project_labels.json", &labels, nil},
+		{"language_mappings.json", &languageMappings, nil},
+		{"projects.json", &projects, nil},
+	}
+
+	for _, entry := range entries {
+		query := srv.db.Where("user_id = ?", user.ID)
+		for _, preload := range entry.preloads {
+			query = query.Preload(preload)
+		}
+		if err := query.Find(entry.dest).Error; err != nil {
+			return 0, err
+		}
+		if err := writeJsonEntry(archive, entry.name, entry.dest); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// writeHeartbeats streams all of the user's heartbeats into the archive in wakatime's data dump format, grouped by day
+func (srv *DataExportService) writeHeartbeats(archive *zip.Writer, user *models.User) error {
+	w, err := archive.Create("heartbeats.json")
+	if err != nil {
+		return err
+	}
+
+	rows, err := srv.db.
+		Model(&models.Heartbeat{}).
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Order("time asc").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if _, err := io.WriteString(w, `{"days":[`); err != nil {
+		return err
+	}
+
+	var start, end time.Time
+	var day *wakatime.JsonExportDay
+	var numDays int
+
+	flush := func() error {
+		if day == nil {
+			return nil
+		}
+		if numDays > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		numDays++
+		return json.NewEncoder(w).Encode(day)
+	}
+
+	for rows.Next() {
+		var hb models.Heartbeat
+		if err := srv.db.ScanRows(rows, &hb); err != nil {
+			return err
+		}
+
+		t := hb.Time.T()
+		if start.IsZero() {
+			start = t
+		}
+		end = t
+
+		date := t.In(user.TZ()).Format(config.SimpleDateFormat)
+		if day == nil || day.Date != date {
+			if err := flush(); err != nil {
+				return err
+			}
+			day = &wakatime.JsonExportDay{Date: date, Heartbeats: []*wakatime.HeartbeatEntry{}}
+		}
+		day.Heartbeats = append(day.Heartbeats, wakatime.HeartbeatsToCompat([]*models.Heartbeat{&hb})...)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	exportRange, _ := json.Marshal(&wakatime.JsonExportRange{Start: start.Unix(), End: end.Unix()})
+	_, err = io.WriteString(w, `],"range":`+string(exportRange)+`}`)
+	return err
+}
+
+// writeSummaries adds the user's persisted summaries to the archive
+func (srv *DataExportService) writeSummaries(archive *zip.Writer, user *models.User) error {
+	summaries, err := srv.summaryRepository.GetByUserWithin(user, config.BeginningOfWakatime(), time.Now())
+	if err != nil {
+		return err
+	}
+	return writeJsonEntry(archive, "summaries.json", summaries)
+}
+
+func writeJsonEntry(archive *zip.Writer, name string, data interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(data)
+}
+
+type IDataExportService interface {
+	Create(user *models.User) (*models.DataExport, error)
+	GetById(id string) (*models.DataExport, error)
+	FetchUserExports(userID string) ([]*models.DataExport, error)
+	HasActiveExport(userID string) (bool, error)
+	FilePath(export *models.DataExport) string
+	Generate(export *models.DataExport, user *models.User) error
+	DeleteExpired() (int, error)
+}
@@ -175,3 +175,27 @@ func (srv *DurationService) GetIntervalTotal(from, to time.Time, user *models.Us
 
 	return total, nil
 }
+
+// getHourlyTotals sums up the user's activity per hour (see models.Durations.HourlyTotals) up until now. Durations are
+// fetched month by month to keep memory usage low for long ranges.
+func getHourlyTotals(durationService IDurationService, user *models.User, from, to time.Time, filters *models.Filters) (map[time.Time]time.Duration, error) {
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+
+	totals := map[time.Time]time.Duration{}
+	for start := from; start.Before(to); start = start.AddDate(0, 1, 0) {
+		end := start.AddDate(0, 1, 0)
+		if end.After(to) {
+			end = to
+		}
+		durations, err := durationService.Get(start, end, user, filters, "")
+		if err != nil {
+			return nil, err
+		}
+		for hour, d := range durations.HourlyTotals(user.TZ()) {
+			totals[hour] += d
+		}
+	}
+	return totals, nil
+}
@@ -12,6 +12,15 @@ import (
 	summarytypes "github.com/muety/wakapi/types"
 	"github.com/muety/wakapi/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	goalEvaluationGracePeriod = 1 * time.Hour
+	// upper bound of periods evaluated at once, e.g. for goals that have been disabled for a long time
+	goalEvaluationMaxPeriods = 14
+	// number of most recent periods the cumulative status is computed from, in line with the chart data
+	goalCumulativeStatusPeriods = 7
 )
 
 type GoalService struct {
@@ -35,11 +44,10 @@ func (srv *GoalService) Create(newGoal *models.Goal) (*models.Goal, error) {
 }
 
 func (srv *GoalService) Update(newGaol *models.Goal) (*models.Goal, error) {
-	updateMap := map[string]interface{}{
-		"custom_title": newGaol.CustomTitle,
-	}
-
-	result := srv.db.Model(newGaol).Updates(updateMap)
+	result := srv.db.
+		Model(newGaol).
+		Select("custom_title", "is_enabled", "is_snoozed", "snooze_until", "ignore_days", "ignore_zero_days").
+		Updates(newGaol)
 	if err := result.Error; err != nil {
 		return nil, err
 	}
@@ -83,6 +91,152 @@ func (srv *GoalService) FetchUserGoals(userID string) ([]*models.Goal, error) {
 	return goals, nil
 }
 
+func (srv *GoalService) FetchEnabledGoals() ([]*models.Goal, error) {
+	var goals []*models.Goal
+	if err := srv.db.
+		Where("is_enabled = ?", true).
+		Order("user_id asc").
+		Find(&goals).Error; err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+// FetchGoalHistory returns the goal's most recently evaluated periods, newest first
+func (srv *GoalService) FetchGoalHistory(goalID string, limit int) ([]*models.GoalPeriod, error) {
+	var periods []*models.GoalPeriod
+	if err := srv.db.
+		Where(&models.GoalPeriod{GoalID: goalID}).
+		Order("period_start desc").
+		Limit(limit).
+		Find(&periods).Error; err != nil {
+		return nil, err
+	}
+	return periods, nil
+}
+
+// Evaluate rates all periods (days or weeks in the user's timezone) that were completed since the goal's last evaluation
+// and updates the goal's status and streaks accordingly. The newly evaluated periods are returned in chronological order.
+func (srv *GoalService) Evaluate(goal *models.Goal, user *models.User, summarySrvc ISummaryService, now time.Time) ([]*models.GoalPeriod, error) {
+	tz := user.TZ()
+
+	// heartbeats may arrive with a little delay, so periods are only evaluated once they've been over for a while
+	currentStart, _ := goal.PeriodAt(now.Add(-goalEvaluationGracePeriod).In(tz))
+
+	var from time.Time
+	last := &models.GoalPeriod{}
+	if err := srv.db.Where(&models.GoalPeriod{GoalID: goal.ID}).Order("period_start desc").First(last).Error; err == nil {
+		var fromEnd time.Time
+		// realign, in case the user's timezone has changed in the meantime
+		from, fromEnd = goal.PeriodAt(last.PeriodEnd.T().In(tz))
+		if from.Before(last.PeriodEnd.T()) {
+			from = fromEnd
+		}
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		from, _ = goal.PeriodAt(goal.CreatedAt.T().In(tz))
+	} else {
+		return nil, err
+	}
+
+	starts := make([]time.Time, 0)
+	for start := from; start.Before(currentStart); _, start = goal.PeriodAt(start) {
+		starts = append(starts, start)
+	}
+	if len(starts) > goalEvaluationMaxPeriods {
+		starts = starts[len(starts)-goalEvaluationMaxPeriods:]
+	}
+
+	filters := goal.GetGoalSummaryFilter()
+	periods := make([]*models.GoalPeriod, 0, len(starts))
+
+	// relative goals need the previous period as well, which usually is the one evaluated right before
+	periodSeconds := make(map[int64]int64)
+	getPeriodSeconds := func(start, end time.Time) (int64, error) {
+		if seconds, ok := periodSeconds[start.Unix()]; ok {
+			return seconds, nil
+		}
+		request := summarytypes.NewSummaryRequest(start, end, user).WithFilters(filters)
+		summary, err := summarySrvc.Generate(request, summarytypes.DefaultProcessingOptions())
+		if err != nil {
+			return 0, err
+		}
+		periodSeconds[start.Unix()] = int64(summary.TotalTime().Seconds())
+		return periodSeconds[start.Unix()], nil
+	}
+
+	for _, start := range starts {
+		_, end := goal.PeriodAt(start)
+
+		actualSeconds, err := getPeriodSeconds(start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		var previousSeconds int64
+		if goal.IsRelative() {
+			if previousSeconds, err = getPeriodSeconds(goal.PreviousPeriod(start)); err != nil {
+				return nil, err
+			}
+		}
+
+		period := &models.GoalPeriod{
+			GoalID:          goal.ID,
+			PeriodStart:     models.CustomTime(start),
+			PeriodEnd:       models.CustomTime(end),
+			ActualSeconds:   actualSeconds,
+			GoalSeconds:     goal.TargetSeconds(previousSeconds),
+			PreviousSeconds: previousSeconds,
+		}
+		if goal.IsSnoozedAt(end) {
+			period.Status = models.GoalStatusSnoozed
+		} else {
+			period.Status = goal.EvaluatePeriod(start, period.ActualSeconds, period.GoalSeconds)
+		}
+		periods = append(periods, period)
+	}
+
+	if len(periods) > 0 {
+		if err := srv.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&periods).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if err := srv.updateGoalStatus(goal, now); err != nil {
+		return nil, err
+	}
+	return periods, nil
+}
+
+// updateGoalStatus recomputes the goal's status and streaks from its evaluation history
+func (srv *GoalService) updateGoalStatus(goal *models.Goal, now time.Time) error {
+	var history []*models.GoalPeriod
+	if err := srv.db.
+		Where(&models.GoalPeriod{GoalID: goal.ID}).
+		Order("period_start asc").
+		Find(&history).Error; err != nil {
+		return err
+	}
+
+	goal.CurrentStreak, goal.LongestStreak = models.ComputeGoalStreaks(history)
+	if len(history) > 0 {
+		goal.Status = history[len(history)-1].Status
+		goal.CumulativeStatus = models.ComputeGoalCumulativeStatus(history[max(0, len(history)-goalCumulativeStatusPeriods):])
+	}
+	if goal.IsSnoozed && !goal.IsSnoozedAt(now) {
+		goal.IsSnoozed = false
+		goal.SnoozeUntil = 0
+	}
+
+	return srv.db.Model(goal).Updates(map[string]interface{}{
+		"status":            goal.Status,
+		"cumulative_status": goal.CumulativeStatus,
+		"current_streak":    goal.CurrentStreak,
+		"longest_streak":    goal.LongestStreak,
+		"is_snoozed":        goal.IsSnoozed,
+		"snooze_until":      goal.SnoozeUntil,
+	}).Error
+}
+
 func (srv *GoalService) LoadGoalChartData(goal *models.Goal, user *models.User, summarySrvc ISummaryService) ([]*models.GoalChartData, error) {
 	rangeParam := "last_7_days"
 
@@ -115,6 +269,18 @@ func (srv *GoalService) LoadGoalChartData(goal *models.Goal, user *models.User,
 
 	filters := goal.GetGoalSummaryFilter()
 
+	// relative goals are compared against the day before, which for the first day of the chart isn't part of the range
+	var previousSeconds int64
+	if goal.IsRelative() && len(intervals) > 0 {
+		from := intervals[0].Start
+		request := summarytypes.NewSummaryRequest(from.AddDate(0, 0, -1), from, user).WithFilters(filters)
+		summary, err := summarySrvc.Generate(request, summarytypes.DefaultProcessingOptions())
+		if err != nil {
+			return nil, err
+		}
+		previousSeconds = int64(summary.TotalTime().Seconds())
+	}
+
 	chartData := make([]*models.GoalChartData, len(intervals))
 	for i, interval := range intervals {
 		request := summarytypes.NewSummaryRequest(interval.Start, interval.End, user).WithFilters(filters)
@@ -129,13 +295,17 @@ func (srv *GoalService) LoadGoalChartData(goal *models.Goal, user *models.User,
 		// wakatime returns requested instead of actual summary range
 		summary.FromTime = models.CustomTime(interval.Start)
 		summary.ToTime = models.CustomTime(interval.End.Add(-1 * time.Second))
-		chartData[i] = prepareGoalSummary(summary, goal)
+		chartData[i] = prepareGoalSummary(summary, goal, goal.TargetSeconds(previousSeconds))
+		previousSeconds = int64(summary.TotalTime().Seconds())
 	}
 
 	return chartData, nil
 }
 
 func formatDuration(seconds int64) string {
+	if seconds < 0 {
+		seconds = -seconds
+	}
 	switch {
 	case seconds < 60:
 		return fmt.Sprintf("%d secs", seconds)
@@ -157,27 +327,27 @@ func moreOrLessText(diff int64) string {
 	return "less"
 }
 
-// TODO: Improve and actually implement
-func getGoalStatus(goal *models.Goal, actualSeconds int64) (string, string) {
+func getGoalStatus(goal *models.Goal, start time.Time, actualSeconds, targetSeconds int64) (string, string) {
 	actualDuration := formatDuration(actualSeconds)
-	goalDuration := formatDuration(goal.Seconds)
+	goalDuration := formatDuration(targetSeconds)
 
-	diff := actualSeconds - goal.Seconds
+	diff := actualSeconds - targetSeconds
 	diffDuration := formatDuration(diff)
 
-	reason := fmt.Sprintf("You coded for %s, which is %s %s than your target of %s", actualDuration, diffDuration, moreOrLessText(diff), goalDuration)
-
-	if diff >= 0 {
-		return "success", reason
+	targetText := "target"
+	if goal.IsLimit() {
+		targetText = "limit"
 	}
-	return "failed", reason
+	reason := fmt.Sprintf("You coded for %s, which is %s %s than your %s of %s", actualDuration, diffDuration, moreOrLessText(diff), targetText, goalDuration)
+
+	return goal.EvaluatePeriod(start, actualSeconds, targetSeconds), reason
 }
 
-func prepareGoalSummary(s *models.Summary, goal *models.Goal) *models.GoalChartData {
+func prepareGoalSummary(s *models.Summary, goal *models.Goal, targetSeconds int64) *models.GoalChartData {
 	zone, _ := time.Now().Zone()
 	total := s.TotalTime()
 
-	status, statusText := getGoalStatus(goal, int64(total))
+	status, statusText := getGoalStatus(goal, s.FromTime.T(), int64(total.Seconds()), targetSeconds)
 
 	// ComputeText
 	goalRange := models.GoalChartRange{
@@ -192,11 +362,11 @@ func prepareGoalSummary(s *models.Summary, goal *models.Goal) *models.GoalChartD
 
 	return &models.GoalChartData{
 		ActualSeconds:          total.Seconds(),
-		GoalSeconds:            float64(goal.Seconds),
-		RangeStatus:            status,     //write func to get range status
-		RangeStatusReason:      statusText, //write func to get range status
+		GoalSeconds:            float64(targetSeconds),
+		RangeStatus:            status,
+		RangeStatusReason:      statusText,
 		RangeStatusReasonShort: statusText,
-		GoalSecondsText:        formatDuration(goal.Seconds),
+		GoalSecondsText:        formatDuration(targetSeconds),
 		ActualSecondsText:      formatDuration(int64(total.Seconds())),
 		Range:                  goalRange,
 	}
@@ -208,5 +378,8 @@ type IGoalService interface {
 	Update(newGoal *models.Goal) (*models.Goal, error)
 	DeleteGoal(id string, userID string) error
 	FetchUserGoals(id string) ([]*models.Goal, error)
+	FetchEnabledGoals() ([]*models.Goal, error)
+	FetchGoalHistory(goalID string, limit int) ([]*models.GoalPeriod, error)
+	Evaluate(goal *models.Goal, user *models.User, summarySrvc ISummaryService, now time.Time) ([]*models.GoalPeriod, error)
 	LoadGoalChartData(goal *models.Goal, user *models.User, summarySrvc ISummaryService) ([]*models.GoalChartData, error)
 }
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	summarytypes "github.com/muety/wakapi/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type GoalServiceTestSuite struct {
+	suite.Suite
+	TestUser       *models.User
+	Now            time.Time
+	DB             *gorm.DB
+	SummaryService *mocks.SummaryServiceMock
+}
+
+func (suite *GoalServiceTestSuite) SetupSuite() {
+	suite.TestUser = &models.User{ID: "testuser01", Location: "UTC"}
+	suite.Now = time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) // a wednesday
+}
+
+func (suite *GoalServiceTestSuite) BeforeTest(suiteName, testName string) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	suite.Require().Nil(err)
+	suite.Require().Nil(db.AutoMigrate(&models.User{}, &models.Goal{}, &models.GoalPeriod{}))
+	suite.Require().Nil(db.Create(suite.TestUser).Error)
+
+	suite.DB = db
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+}
+
+func TestGoalServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(GoalServiceTestSuite))
+}
+
+func (suite *GoalServiceTestSuite) TestGoalService_Evaluate() {
+	sut := NewGoalService(suite.DB)
+	goal := suite.createGoal(&models.Goal{
+		ID:              "goal01",
+		Seconds:         3600,
+		TargetDirection: models.GoalDirectionMore,
+		Delta:           models.GoalDeltaDay,
+		IsEnabled:       true,
+		CreatedAt:       models.CustomTime(time.Date(2026, 10, 11, 10, 0, 0, 0, time.UTC)),
+	})
+	suite.mockDailySeconds(map[int]int64{11: 7200, 12: 1800, 13: 10800})
+
+	periods, err := sut.Evaluate(goal, suite.TestUser, suite.SummaryService, suite.Now)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), periods, 3) // the current day is not over, yet
+	assert.Equal(suite.T(), time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC), periods[0].PeriodStart.T())
+	assert.Equal(suite.T(), time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), periods[0].PeriodEnd.T())
+	assert.Equal(suite.T(), models.GoalStatusSuccess, periods[0].Status)
+	assert.Equal(suite.T(), models.GoalStatusFailed, periods[1].Status)
+	assert.Equal(suite.T(), models.GoalStatusSuccess, periods[2].Status)
+	assert.Equal(suite.T(), int64(10800), periods[2].ActualSeconds)
+	assert.Equal(suite.T(), int64(3600), periods[2].GoalSeconds)
+
+	assert.Equal(suite.T(), models.GoalStatusSuccess, goal.Status)
+	assert.Equal(suite.T(), models.GoalStatusPartial, goal.CumulativeStatus)
+	assert.Equal(suite.T(), 1, goal.CurrentStreak)
+	assert.Equal(suite.T(), 1, goal.LongestStreak)
+
+	persisted, err := sut.GetGoalForUser(goal.ID, suite.TestUser.ID)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), models.GoalStatusSuccess, persisted.Status)
+	assert.Equal(suite.T(), 1, persisted.CurrentStreak)
+
+	// periods evaluated before are not evaluated again
+	periods, err = sut.Evaluate(goal, suite.TestUser, suite.SummaryService, suite.Now.Add(1*time.Hour))
+
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), periods)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Generate", 3)
+
+	history, err := sut.FetchGoalHistory(goal.ID, 10)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), history, 3)
+	assert.Equal(suite.T(), time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC), history[0].PeriodStart.T().UTC()) // read back in local time
+}
+
+func (suite *GoalServiceTestSuite) TestGoalService_Evaluate_Relative() {
+	sut := NewGoalService(suite.DB)
+	goal := suite.createGoal(&models.Goal{
+		ID:               "goal01",
+		Seconds:          3600,
+		ImproveByPercent: 10,
+		TargetDirection:  models.GoalDirectionMore,
+		Delta:            models.GoalDeltaDay,
+		IsEnabled:        true,
+		CreatedAt:        models.CustomTime(time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)),
+	})
+	suite.mockDailySeconds(map[int]int64{11: 3600, 12: 4200, 13: 4200})
+
+	periods, err := sut.Evaluate(goal, suite.TestUser, suite.SummaryService, suite.Now)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), periods, 2)
+	assert.Equal(suite.T(), int64(3960), periods[0].GoalSeconds) // 10 % more than the day before
+	assert.Equal(suite.T(), int64(3600), periods[0].PreviousSeconds)
+	assert.Equal(suite.T(), models.GoalStatusSuccess, periods[0].Status)
+	assert.Equal(suite.T(), int64(4620), periods[1].GoalSeconds)
+	assert.Equal(suite.T(), models.GoalStatusFailed, periods[1].Status)
+	assert.Equal(suite.T(), 0, goal.CurrentStreak)
+	assert.Equal(suite.T(), 1, goal.LongestStreak)
+
+	// the totals of days needed twice are only computed once
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Generate", 3)
+}
+
+func (suite *GoalServiceTestSuite) TestGoalService_Evaluate_Snoozed() {
+	sut := NewGoalService(suite.DB)
+	goal := suite.createGoal(&models.Goal{
+		ID:              "goal01",
+		Seconds:         3600,
+		TargetDirection: models.GoalDirectionLess,
+		Delta:           models.GoalDeltaDay,
+		IsEnabled:       true,
+		IsSnoozed:       true,
+		SnoozeUntil:     time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC).Unix(),
+		CreatedAt:       models.CustomTime(time.Date(2026, 10, 11, 10, 0, 0, 0, time.UTC)),
+	})
+	suite.mockDailySeconds(map[int]int64{11: 7200, 12: 7200, 13: 1800})
+
+	periods, err := sut.Evaluate(goal, suite.TestUser, suite.SummaryService, suite.Now)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), periods, 3)
+	assert.Equal(suite.T(), models.GoalStatusSnoozed, periods[0].Status)
+	assert.Equal(suite.T(), models.GoalStatusFailed, periods[1].Status) // limit exceeded
+	assert.Equal(suite.T(), models.GoalStatusSuccess, periods[2].Status)
+
+	// snoozes are lifted once over
+	assert.False(suite.T(), goal.IsSnoozed)
+	assert.Zero(suite.T(), goal.SnoozeUntil)
+}
+
+func (suite *GoalServiceTestSuite) TestGoalService_Evaluate_MaxPeriods() {
+	sut := NewGoalService(suite.DB)
+	goal := suite.createGoal(&models.Goal{
+		ID:              "goal01",
+		Seconds:         3600,
+		TargetDirection: models.GoalDirectionMore,
+		Delta:           models.GoalDeltaDay,
+		IsEnabled:       true,
+		CreatedAt:       models.CustomTime(suite.Now.AddDate(0, 0, -30)),
+	})
+	suite.SummaryService.On("Generate", mock.Anything, mock.Anything).Return(goalTestSummary(3600), nil)
+
+	periods, err := sut.Evaluate(goal, suite.TestUser, suite.SummaryService, suite.Now)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), periods, goalEvaluationMaxPeriods)
+	assert.Equal(suite.T(), time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC), periods[len(periods)-1].PeriodStart.T())
+	assert.Equal(suite.T(), goalEvaluationMaxPeriods, goal.CurrentStreak)
+}
+
+func (suite *GoalServiceTestSuite) createGoal(goal *models.Goal) *models.Goal {
+	goal.UserID = suite.TestUser.ID
+	suite.Require().Nil(suite.DB.Create(goal).Error)
+	return goal
+}
+
+// mockDailySeconds lets the summary service return the given seconds for the respective days of october 2026
+func (suite *GoalServiceTestSuite) mockDailySeconds(seconds map[int]int64) {
+	for day, s := range seconds {
+		from := time.Date(2026, 10, day, 0, 0, 0, 0, time.UTC)
+		suite.SummaryService.
+			On("Generate", mock.MatchedBy(func(r *summarytypes.SummaryRequest) bool { return r.From.Equal(from) }), mock.Anything).
+			Return(goalTestSummary(s), nil)
+	}
+}
+
+func goalTestSummary(seconds int64) *models.Summary {
+	return &models.Summary{
+		Projects: []*models.SummaryItem{{Type: models.SummaryProject, Key: "wakapi", Total: time.Duration(seconds)}},
+	}
+}
@@ -25,12 +25,16 @@ type HeartbeatService struct {
 	eventBus            *hub.Hub
 	repository          repositories.IHeartbeatRepository
 	languageMappingSrvc ILanguageMappingService
+	projectSrvc         IProjectService
+	machineSrvc         IMachineService
 	entityCacheLock     *sync.RWMutex
 }
 
 func NewHeartbeatService(db *gorm.DB) *HeartbeatService {
 	heartbeatRepo := repositories.NewHeartbeatRepository(db)
 	languageMappingService := NewLanguageMappingService(db)
+	projectService := NewProjectService(db)
+	machineService := NewMachineService(db)
 
 	srv := &HeartbeatService{
 		config:              config.Get(),
@@ -38,6 +42,8 @@ func NewHeartbeatService(db *gorm.DB) *HeartbeatService {
 		eventBus:            config.EventBus(),
 		repository:          heartbeatRepo,
 		languageMappingSrvc: languageMappingService,
+		projectSrvc:         projectService,
+		machineSrvc:         machineService,
 		entityCacheLock:     &sync.RWMutex{},
 	}
 
@@ -59,7 +65,12 @@ func NewHeartbeatService(db *gorm.DB) *HeartbeatService {
 
 func (srv *HeartbeatService) Insert(heartbeat *models.Heartbeat) error {
 	go srv.updateEntityUserCacheByHeartbeat(heartbeat)
-	return srv.repository.InsertBatch([]*models.Heartbeat{heartbeat})
+	err := srv.repository.InsertBatch([]*models.Heartbeat{heartbeat})
+	if err == nil {
+		go srv.updateProjects([]*models.Heartbeat{heartbeat})
+		go srv.updateMachines([]*models.Heartbeat{heartbeat})
+	}
+	return err
 }
 
 func (srv *HeartbeatService) GetHeartbeatsWritePercentage(userID string, start, end time.Time) (float64, error) {
@@ -87,6 +98,8 @@ func (srv *HeartbeatService) InsertBatch(heartbeats []*models.Heartbeat) error {
 	err := srv.repository.InsertBatch(filteredHeartbeats)
 	if err == nil {
 		go srv.notifyBatch(filteredHeartbeats)
+		go srv.updateProjects(filteredHeartbeats)
+		go srv.updateMachines(filteredHeartbeats)
 	}
 	return err
 }
@@ -168,6 +181,30 @@ func (srv *HeartbeatService) GetLatestByOriginAndUser(origin string, user *model
 	return srv.repository.GetLatestByOriginAndUser(origin, user)
 }
 
+// FilterExisting drops all heartbeats that are either already stored or contained in the batch multiple times, based on their hash
+func (srv *HeartbeatService) FilterExisting(heartbeats []*models.Heartbeat) ([]*models.Heartbeat, error) {
+	hashes := make([]string, 0, len(heartbeats))
+	for _, hb := range heartbeats {
+		hashes = append(hashes, hb.Hash)
+	}
+
+	existing, err := srv.repository.GetExistingHashes(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := datastructure.New[string](existing...)
+	filtered := make([]*models.Heartbeat, 0, len(heartbeats))
+	for _, hb := range heartbeats {
+		if seen.Contain(hb.Hash) {
+			continue
+		}
+		seen.Add(hb.Hash)
+		filtered = append(filtered, hb)
+	}
+	return filtered, nil
+}
+
 func (srv *HeartbeatService) GetLatestByFilters(user *models.User, filters *models.Filters) (*models.Heartbeat, error) {
 	return srv.repository.GetLatestByFilters(user, srv.filtersToColumnMap(filters))
 }
@@ -299,11 +336,34 @@ func (srv *HeartbeatService) updateEntityUserCacheByHeartbeat(hb *models.Heartbe
 }
 
 func (srv *HeartbeatService) notifyBatch(heartbeats []*models.Heartbeat) {
+	batches := make(map[string][]*models.Heartbeat)
 	for _, hb := range heartbeats {
 		srv.eventBus.Publish(hub.Message{
 			Name:   config.EventHeartbeatCreate,
 			Fields: map[string]interface{}{config.FieldPayload: hb},
 		})
+		if hb.Origin == "" { // imported heartbeats have an origin
+			batches[hb.UserID] = append(batches[hb.UserID], hb)
+		}
+	}
+
+	for userId, batch := range batches {
+		srv.eventBus.Publish(hub.Message{
+			Name:   config.EventHeartbeatBatchCreate,
+			Fields: map[string]interface{}{config.FieldUserId: userId, config.FieldPayload: batch},
+		})
+	}
+}
+
+func (srv *HeartbeatService) updateProjects(heartbeats []*models.Heartbeat) {
+	if err := srv.projectSrvc.UpsertFromHeartbeats(heartbeats); err != nil {
+		config.Log().Error("failed to update projects from heartbeats", "error", err)
+	}
+}
+
+func (srv *HeartbeatService) updateMachines(heartbeats []*models.Heartbeat) {
+	if err := srv.machineSrvc.UpsertFromHeartbeats(heartbeats); err != nil {
+		config.Log().Error("failed to update machines from heartbeats", "error", err)
 	}
 }
 
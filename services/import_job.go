@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+// importJobStaleTimeout is how long a queued or running job may go without progress before it's considered lost, e.g.
+// because the process running it crashed, so it doesn't keep the user from starting another import forever
+const importJobStaleTimeout = 1 * time.Hour
+
+const importJobStaleError = "import timed out without making progress"
+
+var ErrImportJobAborted = errors.New("import job is no longer running")
+
+type ImportJobService struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewImportJobService(db *gorm.DB) *ImportJobService {
+	return &ImportJobService{
+		config: config.Get(),
+		db:     db,
+	}
+}
+
+func (srv *ImportJobService) Create(user *models.User, source string) (*models.ImportJob, error) {
+	job := &models.ImportJob{
+		ID:     uuid.Must(uuid.NewV4()).String(),
+		UserID: user.ID,
+		Source: source,
+		Status: models.ImportJobQueued,
+	}
+	if err := srv.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (srv *ImportJobService) GetById(id string) (*models.ImportJob, error) {
+	job := &models.ImportJob{}
+	if err := srv.db.Where(&models.ImportJob{ID: id}).First(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (srv *ImportJobService) FetchUserImportJobs(userID string) ([]*models.ImportJob, error) {
+	var jobs []*models.ImportJob
+	if err := srv.db.
+		Where(&models.ImportJob{UserID: userID}).
+		Order("created_at desc").
+		Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// HasActiveJob reports whether the user already has an import waiting or in progress. Jobs that haven't made any
+// progress for a while are marked as failed first, instead of counting as active.
+func (srv *ImportJobService) HasActiveJob(userID string) (bool, error) {
+	if err := srv.failStaleJobs(userID); err != nil {
+		return false, err
+	}
+
+	var count int64
+	if err := srv.db.
+		Model(&models.ImportJob{}).
+		Where("user_id = ?", userID).
+		Where("status in ?", []string{models.ImportJobQueued, models.ImportJobRunning}).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (srv *ImportJobService) MarkRunning(job *models.ImportJob) error {
+	now := models.CustomTime(time.Now())
+	job.Status = models.ImportJobRunning
+	job.StartedAt = &now
+	job.Error = ""
+	return srv.db.Model(job).Updates(map[string]interface{}{
+		"status":     job.Status,
+		"started_at": job.StartedAt,
+		"error":      job.Error,
+	}).Error
+}
+
+// UpdateProgress persists the job's current heartbeat counts. Fails with ErrImportJobAborted if the job isn't running
+// anymore, e.g. because it has been given up on as stale, in which case the import must not continue.
+func (srv *ImportJobService) UpdateProgress(job *models.ImportJob) error {
+	return srv.updateRunning(job, map[string]interface{}{
+		"received": job.Received,
+		"imported": job.Imported,
+		"skipped":  job.Skipped,
+	})
+}
+
+// KeepAlive marks the job as still being worked on, so it isn't considered stale while the importer is busy otherwise,
+// e.g. waiting for an external api. Fails with ErrImportJobAborted if the job isn't running anymore.
+func (srv *ImportJobService) KeepAlive(job *models.ImportJob) error {
+	return srv.updateRunning(job, map[string]interface{}{
+		"updated_at": models.CustomTime(time.Now()),
+	})
+}
+
+// Finish marks the job as completed, or as failed in case an error is given
+func (srv *ImportJobService) Finish(job *models.ImportJob, jobErr error) error {
+	now := models.CustomTime(time.Now())
+	job.Status = models.ImportJobCompleted
+	job.FinishedAt = &now
+	if jobErr != nil {
+		job.Status = models.ImportJobFailed
+		job.Error = jobErr.Error()
+	}
+	// a job given up on as stale must remain failed, as the user might have started another import in the meantime
+	return srv.db.
+		Model(job).
+		Where("status in ?", []string{models.ImportJobQueued, models.ImportJobRunning}).
+		Updates(map[string]interface{}{
+			"status":      job.Status,
+			"received":    job.Received,
+			"imported":    job.Imported,
+			"skipped":     job.Skipped,
+			"error":       job.Error,
+			"finished_at": job.FinishedAt,
+		}).Error
+}
+
+func (srv *ImportJobService) updateRunning(job *models.ImportJob, values map[string]interface{}) error {
+	result := srv.db.
+		Model(job).
+		Where("status = ?", models.ImportJobRunning).
+		Updates(values)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrImportJobAborted
+	}
+	return nil
+}
+
+func (srv *ImportJobService) failStaleJobs(userID string) error {
+	now := time.Now()
+	return srv.db.
+		Model(&models.ImportJob{}).
+		Where("user_id = ?", userID).
+		Where("status in ?", []string{models.ImportJobQueued, models.ImportJobRunning}).
+		Where("coalesce(updated_at, started_at, created_at) < ?", now.Add(-importJobStaleTimeout)).
+		Updates(map[string]interface{}{
+			"status":      models.ImportJobFailed,
+			"error":       importJobStaleError,
+			"finished_at": models.CustomTime(now),
+		}).Error
+}
+
+type IImportJobService interface {
+	Create(user *models.User, source string) (*models.ImportJob, error)
+	GetById(id string) (*models.ImportJob, error)
+	FetchUserImportJobs(userID string) ([]*models.ImportJob, error)
+	HasActiveJob(userID string) (bool, error)
+	MarkRunning(job *models.ImportJob) error
+	UpdateProgress(job *models.ImportJob) error
+	KeepAlive(job *models.ImportJob) error
+	Finish(job *models.ImportJob, jobErr error) error
+}
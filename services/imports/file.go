@@ -0,0 +1,380 @@
+package imports
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	wakatime "github.com/muety/wakapi/models/compat/wakatime/v1"
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	OriginWakapi = "wakapi"
+	OriginCsv    = "csv"
+)
+
+const (
+	FileFormatWakatime = "wakatime"
+	FileFormatWakapi   = "wakapi"
+	FileFormatCsv      = "csv"
+)
+
+const (
+	wakapiExportHeartbeatsFile = "heartbeats.json"
+	wakapiExportMaxSize        = 2 << 30 // maximum uncompressed size of the heartbeats file to protect against zip bombs
+)
+
+// NewFileImporter returns the importer for the given file format, reading from the file at the given path
+func NewFileImporter(format, path string) (DataImporter, error) {
+	switch format {
+	case FileFormatWakatime:
+		return NewWakatimeFileImporter(path), nil
+	case FileFormatWakapi:
+		return NewWakapiFileImporter(path), nil
+	case FileFormatCsv:
+		return NewCsvFileImporter(path), nil
+	}
+	return nil, fmt.Errorf("unsupported file format '%s'", format)
+}
+
+// WakatimeFileImporter reads a heartbeats data dump (json) as downloaded from wakatime.com
+type WakatimeFileImporter struct {
+	path string
+}
+
+func NewWakatimeFileImporter(path string) *WakatimeFileImporter {
+	return &WakatimeFileImporter{path: path}
+}
+
+func (w *WakatimeFileImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) (<-chan *models.Heartbeat, error) {
+	return streamFile(func(yield func(*models.Heartbeat)) error {
+		file, err := os.Open(w.path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := decodeJsonExport(file, func(h *wakatime.HeartbeatEntry) {
+			// user agents and machine names can't be resolved offline, so they're treated the same as by the api importer in that case
+			yield(mapHeartbeat(h, nil, nil, user))
+		}); err != nil {
+			return fmt.Errorf("failed to decode wakatime data dump: %w", err)
+		}
+		return nil
+	}, minFrom, maxTo)
+}
+
+func (w *WakatimeFileImporter) ImportAll(user *models.User) (<-chan *models.Heartbeat, error) {
+	return w.Import(user, config.BeginningOfWakatime(), time.Now())
+}
+
+// WakapiFileImporter reads the heartbeats from an archive created through a wakapi data export
+type WakapiFileImporter struct {
+	path string
+}
+
+func NewWakapiFileImporter(path string) *WakapiFileImporter {
+	return &WakapiFileImporter{path: path}
+}
+
+func (w *WakapiFileImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) (<-chan *models.Heartbeat, error) {
+	return streamFile(func(yield func(*models.Heartbeat)) error {
+		archive, err := zip.OpenReader(w.path)
+		if err != nil {
+			return fmt.Errorf("failed to open wakapi export archive: %w", err)
+		}
+		defer archive.Close()
+
+		var entry *zip.File
+		for _, f := range archive.File {
+			if f.Name == wakapiExportHeartbeatsFile {
+				entry = f
+				break
+			}
+		}
+		if entry == nil {
+			return fmt.Errorf("archive does not contain %s", wakapiExportHeartbeatsFile)
+		}
+		if entry.UncompressedSize64 > wakapiExportMaxSize {
+			return fmt.Errorf("%s exceeds the maximum size of %d bytes", wakapiExportHeartbeatsFile, wakapiExportMaxSize)
+		}
+
+		file, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", wakapiExportHeartbeatsFile, err)
+		}
+		defer file.Close()
+
+		// the declared size can't be trusted, so don't read past it either
+		if err := decodeJsonExport(io.LimitReader(file, wakapiExportMaxSize), func(h *wakatime.HeartbeatEntry) {
+			hb := mapHeartbeat(h, nil, nil, user)
+			// wakapi exports hold actual user agent strings and machine names instead of ids
+			hb.UserAgent = h.UserAgentId
+			hb.Time = parseUnixTime(h.Time)
+			hb.Origin = OriginWakapi
+			hb.Hash = "" // hash is part of the hashed struct itself, so reset before recomputing
+			yield(hb.Hashed())
+		}); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", wakapiExportHeartbeatsFile, err)
+		}
+		return nil
+	}, minFrom, maxTo)
+}
+
+func (w *WakapiFileImporter) ImportAll(user *models.User) (<-chan *models.Heartbeat, error) {
+	return w.Import(user, config.BeginningOfWakatime(), time.Now())
+}
+
+// CsvFileImporter reads heartbeats from a csv file with a header row.
+// Columns are matched by name (case-insensitive), unknown columns are ignored:
+//
+//	time             (required) unix timestamp in seconds, optionally with fraction, or RFC 3339 date
+//	entity           (required) file path, url or app name
+//	type             file, domain or app (default: file)
+//	category         e.g. coding, debugging, ...
+//	project, branch, language
+//	is_write         true / false
+//	editor, operating_system, machine
+//	user_agent       used to derive editor and operating system if these are not given
+type CsvFileImporter struct {
+	path string
+}
+
+func NewCsvFileImporter(path string) *CsvFileImporter {
+	return &CsvFileImporter{path: path}
+}
+
+func (w *CsvFileImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) (<-chan *models.Heartbeat, error) {
+	now := time.Now()
+
+	return streamFile(func(yield func(*models.Heartbeat)) error {
+		file, err := os.Open(w.path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		reader.ReuseRecord = true
+
+		header, err := reader.Read()
+		if err != nil {
+			return fmt.Errorf("failed to read csv header: %w", err)
+		}
+
+		columns := make(map[string]int, len(header))
+		for i, name := range header {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		for _, required := range []string{"time", "entity"} {
+			if _, ok := columns[required]; !ok {
+				return fmt.Errorf("csv is missing required column '%s'", required)
+			}
+		}
+
+		for line := 2; ; line++ {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read csv line %d: %w", line, err)
+			}
+
+			get := func(column string) string {
+				if i, ok := columns[column]; ok && i < len(record) {
+					return strings.TrimSpace(record[i])
+				}
+				return ""
+			}
+
+			hb, err := mapCsvRecord(get, user, now)
+			if err != nil {
+				return fmt.Errorf("invalid csv line %d: %w", line, err)
+			}
+			yield(hb)
+		}
+	}, minFrom, maxTo)
+}
+
+func (w *CsvFileImporter) ImportAll(user *models.User) (<-chan *models.Heartbeat, error) {
+	return w.Import(user, config.BeginningOfWakatime(), time.Now())
+}
+
+func mapCsvRecord(get func(string) string, user *models.User, now time.Time) (*models.Heartbeat, error) {
+	t, err := parseCsvTime(get("time"))
+	if err != nil {
+		return nil, err
+	}
+
+	entity := get("entity")
+	if entity == "" {
+		return nil, errors.New("entity must not be empty")
+	}
+
+	var isWrite bool
+	if v := get("is_write"); v != "" {
+		if isWrite, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid is_write value '%s'", v)
+		}
+	}
+
+	entityType := get("type")
+	if entityType == "" {
+		entityType = "file"
+	}
+
+	editor, operatingSystem, userAgent := get("editor"), get("operating_system"), get("user_agent")
+	if editor == "" && operatingSystem == "" && userAgent != "" {
+		if parsedOs, parsedEditor, err := utils.ParseUserAgent(userAgent); err == nil {
+			editor, operatingSystem = parsedEditor, parsedOs
+		}
+	}
+
+	return (&models.Heartbeat{
+		User:            user,
+		UserID:          user.ID,
+		Entity:          entity,
+		Type:            entityType,
+		Category:        get("category"),
+		Project:         get("project"),
+		Branch:          get("branch"),
+		Language:        get("language"),
+		IsWrite:         isWrite,
+		Editor:          editor,
+		OperatingSystem: operatingSystem,
+		Machine:         get("machine"),
+		UserAgent:       userAgent,
+		Time:            t,
+		Origin:          OriginCsv,
+		CreatedAt:       models.CustomTime(now),
+	}).Hashed(), nil
+}
+
+func parseCsvTime(value string) (models.CustomTime, error) {
+	if value == "" {
+		return models.CustomTime{}, errors.New("time must not be empty")
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return parseUnixTime(seconds), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return models.CustomTime{}, fmt.Errorf("invalid time '%s'", value)
+	}
+	return models.CustomTime(t), nil
+}
+
+// parseUnixTime converts fractional unix seconds to a timestamp of millisecond precision, as heartbeats are stored and hashed with
+func parseUnixTime(seconds float64) models.CustomTime {
+	return models.CustomTime(time.UnixMilli(int64(math.Round(seconds * 1e3))))
+}
+
+// streamFile reads the file once to validate it, so that malformed files fail before anything was imported, and a second
+// time to stream the heartbeats within the given range. either way, heartbeats are read one by one instead of all at once.
+func streamFile(read func(yield func(*models.Heartbeat)) error, minFrom time.Time, maxTo time.Time) (<-chan *models.Heartbeat, error) {
+	if err := read(func(*models.Heartbeat) {}); err != nil {
+		return nil, err
+	}
+
+	out := make(chan *models.Heartbeat)
+	go func() {
+		defer close(out)
+		if err := read(func(hb *models.Heartbeat) {
+			if hb.Time.T().Before(minFrom) || hb.Time.T().After(maxTo) {
+				return
+			}
+			out <- hb
+		}); err != nil {
+			config.Log().Error("failed to read import file a second time", "error", err)
+		}
+	}()
+	return out, nil
+}
+
+// decodeJsonExport walks through the days of a json data dump, decoding one heartbeat at a time
+func decodeJsonExport(r io.Reader, yield func(*wakatime.HeartbeatEntry)) error {
+	decoder := json.NewDecoder(r)
+
+	return decodeJsonObject(decoder, func(key string) error {
+		if key != "days" {
+			return skipJsonValue(decoder)
+		}
+		return decodeJsonArray(decoder, func() error {
+			return decodeJsonObject(decoder, func(key string) error {
+				if key != "heartbeats" {
+					return skipJsonValue(decoder)
+				}
+				return decodeJsonArray(decoder, func() error {
+					var h wakatime.HeartbeatEntry
+					if err := decoder.Decode(&h); err != nil {
+						return err
+					}
+					yield(&h)
+					return nil
+				})
+			})
+		})
+	})
+}
+
+func decodeJsonObject(decoder *json.Decoder, field func(key string) error) error {
+	if err := expectJsonDelim(decoder, '{'); err != nil {
+		return err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if err := field(token.(string)); err != nil {
+			return err
+		}
+	}
+	return expectJsonDelim(decoder, '}')
+}
+
+func decodeJsonArray(decoder *json.Decoder, element func() error) error {
+	if token, err := decoder.Token(); err != nil {
+		return err
+	} else if token == nil {
+		return nil // null
+	} else if token != json.Delim('[') {
+		return fmt.Errorf("expected array, got %v", token)
+	}
+	for decoder.More() {
+		if err := element(); err != nil {
+			return err
+		}
+	}
+	return expectJsonDelim(decoder, ']')
+}
+
+func skipJsonValue(decoder *json.Decoder) error {
+	var value json.RawMessage
+	return decoder.Decode(&value)
+}
+
+func expectJsonDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected '%v', got %v", delim, token)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package imports
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	wakatime "github.com/muety/wakapi/models/compat/wakatime/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func collect(stream <-chan *models.Heartbeat) []*models.Heartbeat {
+	heartbeats := make([]*models.Heartbeat, 0)
+	for hb := range stream {
+		heartbeats = append(heartbeats, hb)
+	}
+	return heartbeats
+}
+
+func TestCsvFileImporter_ImportAll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heartbeats.csv")
+	content := "Time,Entity,Project,Language,is_write,user_agent,ignored\n" +
+		"1700000000.123,main.go,wakapi,Go,true,wakatime/v1.35.4 (linux-5.15.0) go1.20.1 vscode/1.77.0 vscode-wakatime/24.0.10,x\n" +
+		"2023-11-14T22:15:00Z,README.md,wakapi,Markdown,,,\n"
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+
+	heartbeats := collect(must(NewCsvFileImporter(path).ImportAll(&models.User{ID: "user1"})))
+
+	assert.Len(t, heartbeats, 2)
+	assert.Equal(t, "main.go", heartbeats[0].Entity)
+	assert.Equal(t, "Go", heartbeats[0].Language)
+	assert.True(t, heartbeats[0].IsWrite)
+	assert.Equal(t, "vscode", heartbeats[0].Editor)
+	assert.Equal(t, "Linux", heartbeats[0].OperatingSystem)
+	assert.Equal(t, time.UnixMilli(1700000000123), heartbeats[0].Time.T())
+	assert.Equal(t, "file", heartbeats[0].Type)
+	assert.Equal(t, OriginCsv, heartbeats[0].Origin)
+	assert.NotEmpty(t, heartbeats[0].Hash)
+	assert.False(t, heartbeats[1].IsWrite)
+	assert.Equal(t, time.Date(2023, 11, 14, 22, 15, 0, 0, time.UTC).Unix(), heartbeats[1].Time.T().Unix())
+}
+
+func TestCsvFileImporter_ImportAll_Invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	missingColumn := filepath.Join(dir, "missing.csv")
+	assert.Nil(t, os.WriteFile(missingColumn, []byte("time,project\n1700000000,wakapi\n"), 0o600))
+	_, err := NewCsvFileImporter(missingColumn).ImportAll(&models.User{ID: "user1"})
+	assert.ErrorContains(t, err, "entity")
+
+	invalidTime := filepath.Join(dir, "invalid.csv")
+	assert.Nil(t, os.WriteFile(invalidTime, []byte("time,entity\n1700000000,main.go\nyesterday,main.go\n"), 0o600))
+	_, err = NewCsvFileImporter(invalidTime).ImportAll(&models.User{ID: "user1"})
+	assert.ErrorContains(t, err, "line 3")
+}
+
+func TestWakatimeFileImporter_Import(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "dump.json")
+	content := `{"user": {"username": "someone"}, "range": {"start": 1699920000, "end": 1700092799}, "days": [` +
+		`{"date": "2023-11-14", "grand_total": {}, "heartbeats": [` +
+		`{"entity": "main.go", "project": "wakapi", "language": "Go", "type": "file", "time": 1700000000.5},` +
+		`{"entity": "README.md", "project": "wakapi", "type": "file", "time": 1700050000}]},` +
+		`{"date": "2023-11-15", "heartbeats": null}]}`
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+
+	heartbeats := collect(must(NewWakatimeFileImporter(path).Import(&models.User{ID: "user1"}, time.Unix(1700000000, 0), time.Unix(1700010000, 0))))
+
+	assert.Len(t, heartbeats, 1)
+	assert.Equal(t, "main.go", heartbeats[0].Entity)
+	assert.Equal(t, "Go", heartbeats[0].Language)
+
+	truncated := filepath.Join(dir, "truncated.json")
+	assert.Nil(t, os.WriteFile(truncated, []byte(content[:len(content)/2]), 0o600))
+	_, err := NewWakatimeFileImporter(truncated).ImportAll(&models.User{ID: "user1"})
+	assert.Error(t, err)
+}
+
+func TestWakapiFileImporter_ImportAll(t *testing.T) {
+	user := &models.User{ID: "user1"}
+	original := (&models.Heartbeat{
+		User:      user,
+		UserID:    user.ID,
+		Entity:    "main.go",
+		Type:      "file",
+		Project:   "wakapi",
+		Language:  "Go",
+		UserAgent: "wakatime/v1.35.4 (linux-5.15.0) go1.20.1 vscode/1.77.0 vscode-wakatime/24.0.10",
+		Time:      models.CustomTime(time.UnixMilli(1700000000123)),
+		CreatedAt: models.CustomTime(time.UnixMilli(1700000000123)),
+	}).Hashed()
+
+	path := filepath.Join(t.TempDir(), "export.zip")
+	file, _ := os.Create(path)
+	archive := zip.NewWriter(file)
+	w, _ := archive.Create(wakapiExportHeartbeatsFile)
+	assert.Nil(t, json.NewEncoder(w).Encode(&wakatime.JsonExportViewModel{
+		Days: []*wakatime.JsonExportDay{{Date: "2023-11-14", Heartbeats: wakatime.HeartbeatsToCompat([]*models.Heartbeat{original})}},
+	}))
+	assert.Nil(t, archive.Close())
+	assert.Nil(t, file.Close())
+
+	heartbeats := collect(must(NewWakapiFileImporter(path).ImportAll(user)))
+
+	assert.Len(t, heartbeats, 1)
+	assert.Equal(t, original.Hash, heartbeats[0].Hash) // re-importing an export into the same account must not create duplicates
+	assert.Equal(t, "vscode", heartbeats[0].Editor)
+	assert.Equal(t, OriginWakapi, heartbeats[0].Origin)
+}
+
+func TestNewFileImporter(t *testing.T) {
+	_, err := NewFileImporter("xml", "heartbeats.xml")
+	assert.Error(t, err)
+
+	importer, err := NewFileImporter(FileFormatCsv, "heartbeats.csv")
+	assert.Nil(t, err)
+	assert.IsType(t, &CsvFileImporter{}, importer)
+}
+
+func must(stream <-chan *models.Heartbeat, err error) <-chan *models.Heartbeat {
+	if err != nil {
+		panic(err)
+	}
+	return stream
+}
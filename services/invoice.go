@@ -1,14 +1,24 @@
 package services
 
 import (
-	"fmt"
+	"errors"
 	"time"
 
-	gonanoid "github.com/matoous/go-nanoid/v2"
+	"github.com/gofrs/uuid/v5"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"github.com/patrickmn/go-cache"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const invoiceNumberMaxAttempts = 3
+
+var (
+	ErrInvoiceNotEditable       = errors.New("only draft invoices can be modified")
+	ErrInvoiceInvalidTransition = errors.New("invalid invoice status transition")
+	ErrInvoiceNotOpen           = errors.New("payments can only be recorded for sent or overdue invoices")
+	ErrInvoicePaymentExceeds    = errors.New("payment exceeds the amount due")
 )
 
 type InvoiceService struct {
@@ -25,20 +35,45 @@ func NewInvoiceService(db *gorm.DB) IInvoiceService {
 	}
 }
 
+// Create stores a new draft invoice, assigning it the user's next sequential number
 func (srv *InvoiceService) Create(newInvoice *models.Invoice) (*models.Invoice, error) {
-	nanoID, err := gonanoid.Generate("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ", 8) // Custom length/charset
+	settings, err := srv.GetSettings(newInvoice.UserID)
 	if err != nil {
 		return nil, err
 	}
-	newInvoice.InvoiceID = fmt.Sprintf("INV-%s", nanoID)
-	result := srv.db.Create(newInvoice)
-	if err := result.Error; err != nil {
+
+	// numbers are unique per user, so if a concurrent request took the same one, simply try again with the next
+	for attempt := 1; ; attempt++ {
+		err = srv.db.Transaction(func(tx *gorm.DB) error {
+			var lastNumber int
+			if err := tx.
+				Model(&models.Invoice{}).
+				Where("user_id = ?", newInvoice.UserID).
+				Select("coalesce(max(number), 0)").
+				Scan(&lastNumber).Error; err != nil {
+				return err
+			}
+
+			newInvoice.Number = lastNumber + 1
+			newInvoice.InvoiceID = settings.FormatNumber(newInvoice.Number)
+			newInvoice.Status = models.InvoiceStatusDraft
+			return tx.Create(newInvoice).Error
+		})
+		if err == nil || attempt >= invoiceNumberMaxAttempts || !srv.isNumberTaken(newInvoice.UserID, newInvoice.Number) {
+			break
+		}
+	}
+	if err != nil {
 		return nil, err
 	}
 	return newInvoice, nil
 }
 
 func (srv *InvoiceService) Update(invoice *models.Invoice, update *models.InvoiceUpdate) (*models.Invoice, error) {
+	if !invoice.IsEditable() {
+		return nil, ErrInvoiceNotEditable
+	}
+
 	result := srv.db.Model(invoice).Updates(update)
 	if err := result.Error; err != nil {
 		return nil, err
@@ -47,10 +82,69 @@ func (srv *InvoiceService) Update(invoice *models.Invoice, update *models.Invoic
 	return invoice, nil
 }
 
+// UpdateStatus sends or voids an invoice. When sending, the due date defaults to the user's payment terms.
+func (srv *InvoiceService) UpdateStatus(invoice *models.Invoice, status string, dueDate *time.Time) (*models.Invoice, error) {
+	if status == models.InvoiceStatusSent {
+		return srv.Send(invoice, dueDate, nil)
+	}
+	if !invoice.CanTransitionTo(status) {
+		return nil, ErrInvoiceInvalidTransition
+	}
+
+	invoice.Status = status
+	if err := srv.saveStatus(srv.db, invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// Send marks the invoice as sent after delivering it, e.g. by mail. Nothing is persisted if the delivery fails.
+func (srv *InvoiceService) Send(invoice *models.Invoice, dueDate *time.Time, deliver func(*models.Invoice) error) (*models.Invoice, error) {
+	if !invoice.CanTransitionTo(models.InvoiceStatusSent) {
+		return nil, ErrInvoiceInvalidTransition
+	}
+
+	now := time.Now()
+	previous := *invoice
+
+	if invoice.SentAt == nil {
+		invoice.SentAt = &now
+	}
+	if dueDate != nil {
+		invoice.DueDate = dueDate
+	} else if invoice.DueDate == nil {
+		settings, err := srv.GetSettings(invoice.UserID)
+		if err != nil {
+			return nil, err
+		}
+		due := invoice.SentAt.AddDate(0, 0, settings.PaymentTermDays)
+		invoice.DueDate = &due
+	}
+	invoice.Status = models.InvoiceStatusSent
+	invoice.Status = invoice.ResolveStatus(now)
+
+	// the delivered document already shows the invoice as sent, including its due date
+	if deliver != nil {
+		if err := deliver(invoice); err != nil {
+			*invoice = previous
+			return nil, err
+		}
+	}
+
+	if err := srv.saveStatus(srv.db, invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
 func (srv *InvoiceService) GetInvoiceForUser(invoiceID, userID string) (*models.Invoice, error) {
 	invoice := &models.Invoice{}
 
-	result := srv.db.Where(models.Invoice{ID: invoiceID, UserID: userID}).Preload("Client").First(invoice)
+	result := srv.db.
+		Where(models.Invoice{ID: invoiceID, UserID: userID}).
+		Preload("Client").
+		Preload("Payments", func(db *gorm.DB) *gorm.DB { return db.Order("paid_at asc") }).
+		First(invoice)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, nil // No record found
@@ -60,13 +154,19 @@ func (srv *InvoiceService) GetInvoiceForUser(invoiceID, userID string) (*models.
 	return invoice, nil
 }
 
+// DeleteInvoice removes a draft invoice. Issued invoices have to be voided instead to keep numbering free of gaps.
 func (srv *InvoiceService) DeleteInvoice(invoiceID, userID string) error {
-	if err := srv.db.
+	result := srv.db.
 		Where("id = ?", invoiceID).
 		Where("user_id = ?", userID).
-		Delete(models.Invoice{}).Error; err != nil {
+		Where("status = ?", models.InvoiceStatusDraft).
+		Delete(models.Invoice{})
+	if err := result.Error; err != nil {
 		return err
 	}
+	if result.RowsAffected == 0 {
+		return ErrInvoiceNotEditable
+	}
 	return nil
 }
 
@@ -78,16 +178,270 @@ func (srv *InvoiceService) FetchUserInvoices(userID, query string) ([]*models.In
 		Limit(100). // TODO: paginate - when this becomes necessary. The average user has a limited number of invoices - more an enterprise thingy?
 		Where(&models.Invoice{UserID: userID})
 
-	if err := builtQuery.Preload("Client").Find(&invoices).Error; err != nil {
+	if err := builtQuery.Preload("Client").Preload("Payments").Find(&invoices).Error; err != nil {
 		return nil, err
 	}
 	return invoices, nil
 }
 
+// AddPayment records a (partial) payment, marking the invoice as paid once nothing is due anymore
+func (srv *InvoiceService) AddPayment(invoice *models.Invoice, payment *models.InvoicePayment) (*models.Invoice, error) {
+	if !invoice.IsOpen() {
+		return nil, ErrInvoiceNotOpen
+	}
+	if payment.Amount-invoice.AmountDue() > 0.005 {
+		return nil, ErrInvoicePaymentExceeds
+	}
+
+	payment.ID = uuid.Must(uuid.NewV4()).String()
+	payment.InvoiceID = invoice.ID
+
+	err := srv.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+		invoice.Payments = append(invoice.Payments, payment)
+		return srv.saveResolvedStatus(tx, invoice, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// DeletePayment removes a payment record, e.g. one entered by mistake, reopening the invoice if needed
+func (srv *InvoiceService) DeletePayment(invoice *models.Invoice, paymentID string) (*models.Invoice, error) {
+	if invoice.Status == models.InvoiceStatusVoid {
+		return nil, ErrInvoiceNotOpen
+	}
+
+	payments := make([]*models.InvoicePayment, 0, len(invoice.Payments))
+	for _, p := range invoice.Payments {
+		if p.ID != paymentID {
+			payments = append(payments, p)
+		}
+	}
+	if len(payments) == len(invoice.Payments) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	err := srv.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("id = ?", paymentID).
+			Where("invoice_id = ?", invoice.ID).
+			Delete(&models.InvoicePayment{}).Error; err != nil {
+			return err
+		}
+		invoice.Payments = payments
+		return srv.saveResolvedStatus(tx, invoice, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// MarkOverdue flags all sent invoices whose due date has passed as overdue
+func (srv *InvoiceService) MarkOverdue(now time.Time) (int64, error) {
+	// due dates are calendar days, see models.Invoice.IsOverdueAt
+	result := srv.db.
+		Model(&models.Invoice{}).
+		Where("status = ?", models.InvoiceStatusSent).
+		Where("due_date < ?", now.AddDate(0, 0, -1)).
+		Update("status", models.InvoiceStatusOverdue)
+	return result.RowsAffected, result.Error
+}
+
+func (srv *InvoiceService) isNumberTaken(userID string, number int) bool {
+	var count int64
+	srv.db.Model(&models.Invoice{}).Where("user_id = ? and number = ?", userID, number).Count(&count)
+	return count > 0
+}
+
+func (srv *InvoiceService) GetSettings(userID string) (*models.InvoiceSettings, error) {
+	settings := &models.InvoiceSettings{}
+	if err := srv.db.Where("user_id = ?", userID).First(settings).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.DefaultInvoiceSettings(userID), nil
+		}
+		return nil, err
+	}
+	settings.HomeCurrency = settings.HomeCurrencyOrDefault()
+	return settings, nil
+}
+
+func (srv *InvoiceService) UpdateSettings(settings *models.InvoiceSettings) (*models.InvoiceSettings, error) {
+	if !settings.IsValid() {
+		return nil, errors.New("invalid invoice settings")
+	}
+	if err := srv.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"number_prefix", "payment_term_days", "home_currency"}),
+	}).Create(settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (srv *InvoiceService) GetSchedule(clientID, userID string) (*models.InvoiceSchedule, error) {
+	schedule := &models.InvoiceSchedule{}
+	if err := srv.db.Where(&models.InvoiceSchedule{ClientID: clientID, UserID: userID}).First(schedule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (srv *InvoiceService) SaveSchedule(schedule *models.InvoiceSchedule) (*models.InvoiceSchedule, error) {
+	if schedule.ID == "" {
+		schedule.ID = uuid.Must(uuid.NewV4()).String()
+		if err := srv.db.Create(schedule).Error; err != nil {
+			return nil, err
+		}
+		return schedule, nil
+	}
+	if err := srv.db.
+		Model(schedule).
+		Select("frequency", "cron_expression", "is_enabled", "period_start", "next_run_at", "last_run_at").
+		Updates(schedule).Error; err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (srv *InvoiceService) DeleteSchedule(clientID, userID string) error {
+	return srv.db.
+		Where("client_id = ?", clientID).
+		Where("user_id = ?", userID).
+		Delete(&models.InvoiceSchedule{}).Error
+}
+
+// FetchDueSchedules returns all enabled schedules with a billing period that has ended
+func (srv *InvoiceService) FetchDueSchedules(now time.Time) ([]*models.InvoiceSchedule, error) {
+	var schedules []*models.InvoiceSchedule
+	if err := srv.db.
+		Where("is_enabled = ?", true).
+		Where("next_run_at <= ?", now).
+		Order("user_id asc").
+		Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// HasInvoiceForPeriod checks whether any invoice for the client overlaps with the given days, including voided ones
+func (srv *InvoiceService) HasInvoiceForPeriod(clientID string, start, end time.Time) (bool, error) {
+	var count int64
+	if err := srv.db.
+		Model(&models.Invoice{}).
+		Where("client_id = ?", clientID).
+		Where("start_date <= ?", end).
+		Where("end_date >= ?", start).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (srv *InvoiceService) FetchExchangeRates(userID string) ([]*models.ExchangeRate, error) {
+	var rates []*models.ExchangeRate
+	if err := srv.db.
+		Where("user_id = ?", userID).
+		Order("base_currency asc, quote_currency asc, valid_from desc").
+		Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (srv *InvoiceService) CreateExchangeRate(rate *models.ExchangeRate) (*models.ExchangeRate, error) {
+	if err := srv.db.Create(rate).Error; err != nil {
+		return nil, err
+	}
+	return rate, nil
+}
+
+func (srv *InvoiceService) DeleteExchangeRate(rateID uint, userID string) error {
+	result := srv.db.
+		Where("id = ?", rateID).
+		Where("user_id = ?", userID).
+		Delete(&models.ExchangeRate{})
+	if err := result.Error; err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetRevenueReport sums up the invoices the user issued within the given time range per month, in their home currency
+func (srv *InvoiceService) GetRevenueReport(user *models.User, from, to time.Time) (*models.RevenueReport, error) {
+	settings, err := srv.GetSettings(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := srv.FetchExchangeRates(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoices []*models.Invoice
+	if err := srv.db.
+		Where("user_id = ?", user.ID).
+		Where("status IN ?", []string{models.InvoiceStatusSent, models.InvoiceStatusOverdue, models.InvoiceStatusPaid}).
+		Where("sent_at >= ?", from).
+		Where("sent_at < ?", to).
+		Preload("Client").
+		Preload("Payments").
+		Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+
+	return models.NewRevenueReport(invoices, rates, settings.HomeCurrency, user.TZ()), nil
+}
+
+func (srv *InvoiceService) saveResolvedStatus(tx *gorm.DB, invoice *models.Invoice, now time.Time) error {
+	status := invoice.ResolveStatus(now)
+	if status == models.InvoiceStatusPaid && invoice.PaidAt == nil {
+		invoice.PaidAt = &now
+	} else if status != models.InvoiceStatusPaid {
+		invoice.PaidAt = nil
+	}
+	invoice.Status = status
+	return srv.saveStatus(tx, invoice)
+}
+
+func (srv *InvoiceService) saveStatus(tx *gorm.DB, invoice *models.Invoice) error {
+	return tx.
+		Model(invoice).
+		Select("status", "sent_at", "due_date", "paid_at").
+		Updates(invoice).Error
+}
+
 type IInvoiceService interface {
 	Create(*models.Invoice) (*models.Invoice, error)
 	Update(*models.Invoice, *models.InvoiceUpdate) (*models.Invoice, error)
+	UpdateStatus(*models.Invoice, string, *time.Time) (*models.Invoice, error)
+	Send(*models.Invoice, *time.Time, func(*models.Invoice) error) (*models.Invoice, error)
 	GetInvoiceForUser(invoiceID, userID string) (*models.Invoice, error)
 	DeleteInvoice(invoiceID, userID string) error
 	FetchUserInvoices(userID, query string) ([]*models.Invoice, error)
+	AddPayment(*models.Invoice, *models.InvoicePayment) (*models.Invoice, error)
+	DeletePayment(*models.Invoice, string) (*models.Invoice, error)
+	MarkOverdue(time.Time) (int64, error)
+	GetSettings(userID string) (*models.InvoiceSettings, error)
+	UpdateSettings(*models.InvoiceSettings) (*models.InvoiceSettings, error)
+	GetSchedule(clientID, userID string) (*models.InvoiceSchedule, error)
+	SaveSchedule(*models.InvoiceSchedule) (*models.InvoiceSchedule, error)
+	DeleteSchedule(clientID, userID string) error
+	FetchDueSchedules(time.Time) ([]*models.InvoiceSchedule, error)
+	HasInvoiceForPeriod(clientID string, start, end time.Time) (bool, error)
+	FetchExchangeRates(userID string) ([]*models.ExchangeRate, error)
+	CreateExchangeRate(*models.ExchangeRate) (*models.ExchangeRate, error)
+	DeleteExchangeRate(rateID uint, userID string) error
+	GetRevenueReport(user *models.User, from, to time.Time) (*models.RevenueReport, error)
 }
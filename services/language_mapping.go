@@ -4,6 +4,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/repositories"
@@ -14,16 +15,28 @@ import (
 type LanguageMappingService struct {
 	config     *config.Config
 	cache      *cache.Cache
+	eventBus   *hub.Hub
 	repository repositories.ILanguageMappingRepository
 }
 
 func NewLanguageMappingService(db *gorm.DB) *LanguageMappingService {
 	languageMappingsRepo := repositories.NewLanguageMappingRepository(db)
-	return &LanguageMappingService{
+	srv := &LanguageMappingService{
 		config:     config.Get(),
+		eventBus:   config.EventBus(),
 		repository: languageMappingsRepo,
 		cache:      cache.New(24*time.Hour, 24*time.Hour),
 	}
+
+	// there are multiple instances of this service (e.g. one inside the heartbeat service), all of which need to drop their cache
+	sub1 := srv.eventBus.Subscribe(0, config.TopicLanguageMapping)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.cache.Delete(m.Fields[config.FieldUserId].(string))
+		}
+	}(&sub1)
+
+	return srv
 }
 
 func (srv *LanguageMappingService) GetById(id uint) (*models.LanguageMapping, error) {
@@ -63,18 +76,44 @@ func (srv *LanguageMappingService) Create(mapping *models.LanguageMapping) (*mod
 	}
 
 	srv.cache.Delete(result.UserID)
+	srv.notifyUpdate(mapping, false)
 	return result, nil
 }
 
+// CreateMulti creates either all of the given mappings or, in case any of them fails, none
+func (srv *LanguageMappingService) CreateMulti(mappings []*models.LanguageMapping) ([]*models.LanguageMapping, error) {
+	if err := srv.repository.InsertBatch(mappings); err != nil {
+		return nil, err
+	}
+
+	for _, mapping := range mappings {
+		srv.cache.Delete(mapping.UserID)
+		srv.notifyUpdate(mapping, false)
+	}
+	return mappings, nil
+}
+
 func (srv *LanguageMappingService) Delete(mapping *models.LanguageMapping) error {
 	if mapping.UserID == "" {
 		return errors.New("no user id specified")
 	}
 	err := srv.repository.Delete(mapping.ID)
 	srv.cache.Delete(mapping.UserID)
+	srv.notifyUpdate(mapping, true)
 	return err
 }
 
+func (srv *LanguageMappingService) notifyUpdate(mapping *models.LanguageMapping, isDelete bool) {
+	name := config.EventLanguageMappingCreate
+	if isDelete {
+		name = config.EventLanguageMappingDelete
+	}
+	srv.eventBus.Publish(hub.Message{
+		Name:   name,
+		Fields: map[string]interface{}{config.FieldPayload: mapping, config.FieldUserId: mapping.UserID},
+	})
+}
+
 func (srv *LanguageMappingService) getServerMappings() map[string]string {
 	// https://dave.cheney.net/2017/04/30/if-a-map-isnt-a-reference-variable-what-is-it
 	return srv.config.App.GetCustomLanguages()
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrMachineHasKey = errors.New("machine already has a key, rotate it instead")
+
+// machineKeyCache is shared by all instances of the service, like apiTokenCache, so revoking a key takes effect immediately
+// for all of them. Keys may still be revoked from a different process, so keep them only briefly.
+var machineKeyCache = cache.New(1*time.Minute, 5*time.Minute)
+
+type MachineService struct {
+	config        *config.Config
+	cache         *cache.Cache
+	db            *gorm.DB
+	userAgentSrvc IPluginUserAgentService
+}
+
+// machineActivity is the period a batch of heartbeats was sent from a single machine in
+type machineActivity struct {
+	first time.Time
+	last  time.Time
+}
+
+func NewMachineService(db *gorm.DB) *MachineService {
+	return &MachineService{
+		config:        config.Get(),
+		cache:         machineKeyCache,
+		db:            db,
+		userAgentSrvc: NewPluginUserAgentService(db),
+	}
+}
+
+func (srv *MachineService) GetById(id string) (*models.Machine, error) {
+	machine := &models.Machine{}
+	if err := srv.db.Where(&models.Machine{ID: id}).First(machine).Error; err != nil {
+		return nil, err
+	}
+	return machine, nil
+}
+
+// GetByKey looks up a machine by its plain key
+func (srv *MachineService) GetByKey(plain string) (*models.Machine, error) {
+	if !strings.HasPrefix(plain, models.MachineKeyPrefix) {
+		return nil, errors.New("not a machine key")
+	}
+
+	hash := models.HashApiToken(plain)
+	machine := &models.Machine{}
+	if cached, found := srv.cache.Get(hash); found {
+		*machine = *cached.(*models.Machine)
+		return machine, nil
+	}
+
+	if err := srv.db.Where(&models.Machine{KeyHash: &hash}).First(machine).Error; err != nil {
+		return nil, err
+	}
+	cached := *machine
+	srv.cache.SetDefault(hash, &cached)
+	return machine, nil
+}
+
+// FetchUserMachines returns the user's machines along with the plugins seen on each of them, most recently used first
+func (srv *MachineService) FetchUserMachines(userID string) ([]*models.Machine, error) {
+	var machines []*models.Machine
+	if err := srv.db.
+		Where(&models.Machine{UserID: userID}).
+		Order("last_heartbeat_at desc").
+		Find(&machines).Error; err != nil {
+		return nil, err
+	}
+
+	userAgents, err := srv.userAgentSrvc.FetchMachineUserAgents(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range machines {
+		m.UserAgents = userAgents[m.Name]
+		if m.UserAgents == nil {
+			m.UserAgents = []*models.PluginUserAgent{}
+		}
+	}
+	return machines, nil
+}
+
+// Create registers the machine, unless already known from its heartbeats, and issues a new key for it.
+// The plain key is only contained in the returned object.
+func (srv *MachineService) Create(user *models.User, newMachine *models.NewMachine) (*models.Machine, error) {
+	if !newMachine.IsValid() {
+		return nil, errors.New("invalid machine")
+	}
+
+	machine, err := srv.insertOrGet(&models.Machine{UserID: user.ID, Name: strings.TrimSpace(newMachine.Name)})
+	if err != nil {
+		return nil, err
+	}
+	if machine.HasKey() {
+		return nil, ErrMachineHasKey
+	}
+	return srv.RotateKey(machine)
+}
+
+// RotateKey replaces the machine's key by a new one. The old key stops working right away in this process, but may still
+// be accepted by other processes for up to a minute.
+func (srv *MachineService) RotateKey(machine *models.Machine) (*models.Machine, error) {
+	secret, err := utils.GenerateRandomPassword(apiTokenLength)
+	if err != nil {
+		return nil, err
+	}
+	plain := models.MachineKeyPrefix + secret
+	hash := models.HashApiToken(plain)
+
+	previousHash := machine.KeyHash
+	machine.KeyHash, machine.KeyHint = &hash, secret[len(secret)-apiTokenHintSize:]
+	if err := srv.db.Model(machine).Select("key_hash", "key_hint").Updates(machine).Error; err != nil {
+		return nil, err
+	}
+	if previousHash != nil {
+		srv.cache.Delete(*previousHash)
+	}
+
+	machine.Key = plain
+	return machine, nil
+}
+
+// RevokeKey removes the machine's key, while the machine itself remains registered
+func (srv *MachineService) RevokeKey(machine *models.Machine) error {
+	previousHash := machine.KeyHash
+	if err := srv.db.Model(machine).Updates(map[string]interface{}{"key_hash": nil, "key_hint": ""}).Error; err != nil {
+		return err
+	}
+	machine.KeyHash, machine.KeyHint = nil, ""
+	if previousHash != nil {
+		srv.cache.Delete(*previousHash)
+	}
+	return nil
+}
+
+// Delete removes the machine from the registry, revoking its key. It is registered again upon its next heartbeat.
+func (srv *MachineService) Delete(machine *models.Machine) error {
+	if err := srv.db.Delete(machine).Error; err != nil {
+		return err
+	}
+	if machine.KeyHash != nil {
+		srv.cache.Delete(*machine.KeyHash)
+	}
+	return nil
+}
+
+// UpsertFromHeartbeats registers the machines referenced by the given heartbeats and updates when they were last seen
+func (srv *MachineService) UpsertFromHeartbeats(heartbeats []*models.Heartbeat) error {
+	activities := make(map[string]map[string]*machineActivity)
+
+	for _, hb := range heartbeats {
+		if hb.Machine == "" {
+			continue
+		}
+		if _, ok := activities[hb.UserID]; !ok {
+			activities[hb.UserID] = make(map[string]*machineActivity)
+		}
+
+		t := hb.Time.T()
+		activity, ok := activities[hb.UserID][hb.Machine]
+		if !ok {
+			activity = &machineActivity{first: t, last: t}
+			activities[hb.UserID][hb.Machine] = activity
+		}
+		if t.Before(activity.first) {
+			activity.first = t
+		}
+		if t.After(activity.last) {
+			activity.last = t
+		}
+	}
+
+	for userId, userActivities := range activities {
+		for name, activity := range userActivities {
+			machine := models.NewMachineSeenAt(userId, name, activity.first)
+			machine.Track(activity.first, activity.last)
+			if _, err := srv.insertOrGet(machine); err != nil {
+				return err
+			}
+
+			// conditional, so concurrent batches can only ever extend the period the machine was seen in
+			if err := srv.db.Model(&models.Machine{}).
+				Where("user_id = ? and name = ?", userId, name).
+				Where("first_heartbeat_at is null or first_heartbeat_at > ?", machine.FirstHeartbeatAt).
+				Update("first_heartbeat_at", machine.FirstHeartbeatAt).Error; err != nil {
+				return err
+			}
+			if err := srv.db.Model(&models.Machine{}).
+				Where("user_id = ? and name = ?", userId, name).
+				Where("last_heartbeat_at is null or last_heartbeat_at < ?", machine.LastHeartbeatAt).
+				Update("last_heartbeat_at", machine.LastHeartbeatAt).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// insertOrGet creates the machine unless one with the same name already exists for the user, in which case that one is returned
+func (srv *MachineService) insertOrGet(machine *models.Machine) (*models.Machine, error) {
+	if machine.ID == "" {
+		machine.ID = uuid.Must(uuid.NewV4()).String()
+	}
+	if err := srv.db.Clauses(clause.OnConflict{DoNothing: true}).Create(machine).Error; err != nil {
+		return nil, err
+	}
+
+	existing := &models.Machine{}
+	if err := srv.db.Where(&models.Machine{UserID: machine.UserID, Name: machine.Name}).First(existing).Error; err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+type IMachineService interface {
+	GetById(id string) (*models.Machine, error)
+	GetByKey(plain string) (*models.Machine, error)
+	FetchUserMachines(userID string) ([]*models.Machine, error)
+	Create(user *models.User, newMachine *models.NewMachine) (*models.Machine, error)
+	RotateKey(machine *models.Machine) (*models.Machine, error)
+	RevokeKey(machine *models.Machine) error
+	Delete(machine *models.Machine) error
+	UpsertFromHeartbeats(heartbeats []*models.Heartbeat) error
+}
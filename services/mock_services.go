@@ -82,11 +82,22 @@ func (m *ServicesMock) Users() IUserService {
 	return new(mocks.UserServiceMock)
 }
 
+func (m *ServicesMock) LanguageMapping() ILanguageMappingService {
+	if m.LanguageMappingFunc != nil {
+		return m.LanguageMappingFunc()
+	}
+	return new(mocks.LanguageMappingServiceMock)
+}
+
 // NOt Implemented
 func (s *ServicesMock) Activity() IActivityService {
 	return nil
 }
 
+func (s *ServicesMock) StatsCard() IStatsCardService {
+	return nil
+}
+
 func (s *ServicesMock) Aggregation() IAggregationService {
 	return nil
 }
@@ -95,6 +106,10 @@ func (s *ServicesMock) Client() IClientService {
 	return nil
 }
 
+func (s *ServicesMock) DataExport() IDataExportService {
+	return nil
+}
+
 func (s *ServicesMock) Diagnostics() IDiagnosticsService {
 	return nil
 }
@@ -107,11 +122,11 @@ func (s *ServicesMock) HouseKeeping() IHousekeepingService {
 	return nil
 }
 
-func (s *ServicesMock) Invoice() IInvoiceService {
+func (s *ServicesMock) ImportJob() IImportJobService {
 	return nil
 }
 
-func (s *ServicesMock) LanguageMapping() ILanguageMappingService {
+func (s *ServicesMock) Invoice() IInvoiceService {
 	return nil
 }
 
@@ -127,10 +142,18 @@ func (s *ServicesMock) OAuth() IUserOauthService {
 	return nil
 }
 
+func (s *ServicesMock) Organization() IOrganizationService {
+	return nil
+}
+
 func (s *ServicesMock) Otp() IOTPService {
 	return nil
 }
 
+func (s *ServicesMock) Project() IProjectService {
+	return nil
+}
+
 func (s *ServicesMock) Report() IReportService {
 	return nil
 }
@@ -138,3 +161,31 @@ func (s *ServicesMock) Report() IReportService {
 func (s *ServicesMock) UserAgentPlugin() IPluginUserAgentService {
 	return nil
 }
+
+func (s *ServicesMock) Webhook() IWebhookService {
+	return nil
+}
+
+func (s *ServicesMock) Notification() INotificationService {
+	return nil
+}
+
+func (s *ServicesMock) ApiToken() IApiTokenService {
+	return nil
+}
+
+func (s *ServicesMock) Machine() IMachineService {
+	return nil
+}
+
+func (s *ServicesMock) TwoFactor() ITwoFactorService {
+	return nil
+}
+
+func (s *ServicesMock) Oidc() IOidcService {
+	return nil
+}
+
+func (s *ServicesMock) Sessions() ISessionService {
+	return nil
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/patrickmn/go-cache"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultDigestDeliveryTime = "08:00"
+	defaultWeeklyDeliveryTime = "18:00"
+)
+
+type NotificationService struct {
+	config *config.Config
+	cache  *cache.Cache
+	db     *gorm.DB
+}
+
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{
+		config: config.Get(),
+		// preferences are looked up for every notification, but may be modified from a different process
+		cache: cache.New(1*time.Minute, 5*time.Minute),
+		db:    db,
+	}
+}
+
+// GetPreferences returns the user's preferences for all kinds of notifications, with defaults for those never changed
+func (srv *NotificationService) GetPreferences(user *models.User) ([]*models.NotificationPreference, error) {
+	if prefs, found := srv.cache.Get(user.ID); found {
+		return prefs.([]*models.NotificationPreference), nil
+	}
+
+	var stored []*models.NotificationPreference
+	if err := srv.db.Where(&models.NotificationPreference{UserID: user.ID}).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	prefs := make([]*models.NotificationPreference, len(models.NotificationKinds))
+	for i, kind := range models.NotificationKinds {
+		prefs[i] = srv.defaultPreference(user, kind)
+		for _, p := range stored {
+			if p.Kind == kind {
+				prefs[i] = p
+				break
+			}
+		}
+	}
+
+	srv.cache.SetDefault(user.ID, prefs)
+	return prefs, nil
+}
+
+func (srv *NotificationService) GetPreference(user *models.User, kind string) (*models.NotificationPreference, error) {
+	prefs, err := srv.GetPreferences(user)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range prefs {
+		if p.Kind == kind {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown notification kind '%s'", kind)
+}
+
+// IsEnabled returns whether the user wants to receive the given kind of notification through the given channel. Fails open,
+// i.e. if preferences can't be loaded, the default applies.
+func (srv *NotificationService) IsEnabled(user *models.User, kind, channel string) bool {
+	pref, err := srv.GetPreference(user, kind)
+	if err != nil {
+		config.Log().Error("failed to get notification preference", "userID", user.ID, "kind", kind, "error", err)
+		pref = srv.defaultPreference(user, kind)
+	}
+	return pref.IsEnabled(channel)
+}
+
+func (srv *NotificationService) UpdatePreferences(user *models.User, updates []*models.NotificationPreferenceUpdate) ([]*models.NotificationPreference, error) {
+	for _, u := range updates {
+		if !u.IsValid() {
+			return nil, errors.New("invalid notification preference")
+		}
+	}
+
+	prefs, err := srv.GetPreferences(user)
+	if err != nil {
+		return nil, err
+	}
+
+	err = srv.db.Transaction(func(tx *gorm.DB) error {
+		for _, u := range updates {
+			for _, p := range prefs {
+				if p.Kind != u.Kind {
+					continue
+				}
+				p.Apply(u)
+				if err := tx.Save(p).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// keep the legacy flag in line, it's still the default for users who never changed their preferences
+		for _, p := range prefs {
+			if p.Kind == models.NotificationWeeklyReport {
+				user.ReportsWeekly = p.IsEnabled(models.NotificationChannelEmail)
+				return tx.Model(&models.User{}).Where("id = ?", user.ID).Update("reports_weekly", user.ReportsWeekly).Error
+			}
+		}
+		return nil
+	})
+
+	srv.cache.Delete(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return srv.GetPreferences(user)
+}
+
+// MarkSent records the time a scheduled notification was last sent
+func (srv *NotificationService) MarkSent(pref *models.NotificationPreference, at time.Time) error {
+	pref.LastSentAt = &at
+	srv.cache.Delete(pref.UserID)
+	return srv.db.Omit(clause.Associations).Save(pref).Error
+}
+
+// FetchScheduled returns all enabled preferences of scheduled notifications along with their users, including the weekly
+// reports of users who have only set the legacy flag
+func (srv *NotificationService) FetchScheduled() ([]*models.NotificationPreference, error) {
+	var prefs []*models.NotificationPreference
+	if err := srv.db.
+		Preload("User").
+		Where("kind IN ? AND channel != ?", []string{models.NotificationWeeklyReport, models.NotificationDailyDigest, models.NotificationMonthlyReport, models.NotificationYearlyReport}, models.NotificationChannelNone).
+		Find(&prefs).Error; err != nil {
+		return nil, err
+	}
+
+	var legacyUsers []*models.User
+	if err := srv.db.
+		Where("reports_weekly = ?", true).
+		Where("id NOT IN (?)", srv.db.Model(&models.NotificationPreference{}).Select("user_id").Where("kind = ?", models.NotificationWeeklyReport)).
+		Find(&legacyUsers).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range legacyUsers {
+		pref := srv.defaultPreference(u, models.NotificationWeeklyReport)
+		pref.User = u
+		prefs = append(prefs, pref)
+	}
+
+	return prefs, nil
+}
+
+func (srv *NotificationService) defaultPreference(user *models.User, kind string) *models.NotificationPreference {
+	pref := &models.NotificationPreference{UserID: user.ID, Kind: kind, Channel: models.NotificationChannelEmail}
+
+	switch kind {
+	case models.NotificationWeeklyReport:
+		if !user.ReportsWeekly {
+			pref.Channel = models.NotificationChannelNone
+		}
+		pref.DeliveryWeekday, pref.DeliveryTime = srv.defaultWeeklyDelivery()
+	case models.NotificationDailyDigest:
+		pref.Channel = models.NotificationChannelNone
+		pref.DeliveryTime = defaultDigestDeliveryTime
+	case models.NotificationMonthlyReport, models.NotificationYearlyReport:
+		pref.Channel = models.NotificationChannelNone
+		pref.DeliveryTime = defaultDigestDeliveryTime
+		pref.DeliveryDay = 1
+	}
+
+	return pref
+}
+
+// defaultWeeklyDelivery derives weekday and time of weekly reports from the configured cron expression, e.g. "0 0 18 * * 5"
+func (srv *NotificationService) defaultWeeklyDelivery() (int, string) {
+	fields := strings.Fields(srv.config.App.GetWeeklyReportCron())
+	if len(fields) != 6 {
+		return int(time.Friday), defaultWeeklyDeliveryTime
+	}
+	minute, errMinute := strconv.Atoi(fields[1])
+	hour, errHour := strconv.Atoi(fields[2])
+	weekday, errWeekday := strconv.Atoi(fields[5])
+	if errMinute != nil || errHour != nil || errWeekday != nil || hour > 23 || minute > 59 || weekday < 0 || weekday > 7 {
+		return int(time.Friday), defaultWeeklyDeliveryTime
+	}
+	return weekday % 7, fmt.Sprintf("%02d:%02d", hour, minute)
+}
+
+type INotificationService interface {
+	GetPreferences(user *models.User) ([]*models.NotificationPreference, error)
+	GetPreference(user *models.User, kind string) (*models.NotificationPreference, error)
+	IsEnabled(user *models.User, kind, channel string) bool
+	UpdatePreferences(user *models.User, updates []*models.NotificationPreferenceUpdate) ([]*models.NotificationPreference, error)
+	MarkSent(pref *models.NotificationPreference, at time.Time) error
+	FetchScheduled() ([]*models.NotificationPreference, error)
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/integrations/oidc"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+	"gorm.io/gorm"
+)
+
+const OidcLoginTTL = 10 * time.Minute
+
+var (
+	ErrOidcDisabled      = errors.New("single sign-on is not configured on this server")
+	ErrOidcInvalidState  = errors.New("login request is invalid or has expired")
+	ErrOidcNoAccount     = errors.New("no account is linked to this identity")
+	ErrOidcAlreadyLinked = errors.New("identity is already linked to another account")
+	ErrOidcRejected      = errors.New("login was rejected by the identity provider")
+)
+
+// oidcLogin is what's remembered between sending the user to the provider and them coming back
+type oidcLogin struct {
+	codeVerifier string
+	nonce        string
+	linkUserId   string // set if an existing user links the identity to their account
+}
+
+type OidcService struct {
+	config   *config.Config
+	cache    *cache.Cache // pending logins by state
+	db       *gorm.DB
+	provider *oidc.Provider
+}
+
+func NewOidcService(db *gorm.DB) *OidcService {
+	srv := &OidcService{
+		config: config.Get(),
+		cache:  cache.New(OidcLoginTTL, 2*OidcLoginTTL),
+		db:     db,
+	}
+
+	if oidcConfig := srv.config.Security.Oidc; oidcConfig.IsEnabled() {
+		redirectUri := oidcConfig.RedirectUri
+		if redirectUri == "" {
+			frontendUri := srv.config.Security.FrontendUri
+			if frontendUri == "" {
+				frontendUri = srv.config.Server.GetFrontendUri()
+			}
+			redirectUri = strings.TrimSuffix(frontendUri, "/") + "/api/oauth/callback/" + oidcConfig.Name
+		}
+		srv.provider = oidc.NewProvider(oidcConfig.Issuer, oidcConfig.ClientId, oidcConfig.ClientSecret, redirectUri, oidcConfig.GetScopes())
+	}
+	return srv
+}
+
+func (srv *OidcService) IsEnabled() bool {
+	return srv.provider != nil
+}
+
+// BeginLogin returns the url to send the user to at the provider and the state to bind the login to the user's browser
+// with. If linkUserId is given, the identity is to be linked to that user using CompleteLink instead of logging in.
+func (srv *OidcService) BeginLogin(linkUserId string) (string, string, error) {
+	if !srv.IsEnabled() {
+		return "", "", ErrOidcDisabled
+	}
+
+	var state, nonce, codeVerifier string
+	for _, v := range []*string{&state, &nonce, &codeVerifier} {
+		token, err := oidc.RandomToken()
+		if err != nil {
+			return "", "", err
+		}
+		*v = token
+	}
+
+	authUrl, err := srv.provider.AuthCodeUrl(state, nonce, codeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	srv.cache.SetDefault(state, &oidcLogin{codeVerifier: codeVerifier, nonce: nonce, linkUserId: linkUserId})
+	return authUrl, state, nil
+}
+
+// CompleteLogin redeems the code the provider redirected back with and returns the user to log in, which is either
+// the one already linked to the identity, an existing one with the same, verified email or, if enabled, a new one
+func (srv *OidcService) CompleteLogin(code, state string) (*models.User, *models.UserOauth, bool, error) {
+	login, err := srv.popLogin(state, "")
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	claims, err := srv.redeem(code, login)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	var user *models.User
+	var userOauth *models.UserOauth
+	var isNewUser bool
+
+	err = srv.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if userOauth, err = srv.findLink(tx, claims.Subject); err != nil {
+			return err
+		}
+
+		if userOauth != nil {
+			user, err = srv.findUser(tx, models.User{ID: userOauth.UserID})
+		} else {
+			user, isNewUser, err = srv.matchOrProvision(tx, claims)
+		}
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return ErrOidcNoAccount
+		}
+
+		userOauth, err = srv.saveLink(tx, userOauth, user, claims)
+		return err
+	})
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return user, userOauth, isNewUser, nil
+}
+
+// CompleteLink redeems the code the provider redirected back with and links the identity to the given user, who must be
+// the one that started linking it
+func (srv *OidcService) CompleteLink(code, state string, user *models.User) (*models.UserOauth, error) {
+	if user == nil {
+		return nil, ErrOidcInvalidState
+	}
+
+	login, err := srv.popLogin(state, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := srv.redeem(code, login)
+	if err != nil {
+		return nil, err
+	}
+
+	var userOauth *models.UserOauth
+
+	err = srv.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if userOauth, err = srv.findLink(tx, claims.Subject); err != nil {
+			return err
+		}
+		if userOauth != nil && userOauth.UserID != user.ID {
+			return ErrOidcAlreadyLinked
+		}
+
+		userOauth, err = srv.saveLink(tx, userOauth, user, claims)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userOauth, nil
+}
+
+// popLogin looks up the pending login for the state, which must have been started for linking to the given user, or
+// for logging in if linkUserId is empty. States are single-use.
+func (srv *OidcService) popLogin(state, linkUserId string) (*oidcLogin, error) {
+	if !srv.IsEnabled() {
+		return nil, ErrOidcDisabled
+	}
+
+	item, found := srv.cache.Get(state)
+	if !found || state == "" {
+		return nil, ErrOidcInvalidState
+	}
+	login := item.(*oidcLogin)
+	if login.linkUserId != linkUserId {
+		return nil, ErrOidcInvalidState
+	}
+
+	srv.cache.Delete(state)
+	return login, nil
+}
+
+// redeem exchanges the code for tokens and returns the verified identity
+func (srv *OidcService) redeem(code string, login *oidcLogin) (*oidc.Claims, error) {
+	token, err := srv.provider.Exchange(code, login.codeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOidcRejected, err)
+	}
+	claims, err := srv.provider.VerifyIdToken(token.IdToken, login.nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOidcRejected, err)
+	}
+	if claims.Email == "" && token.AccessToken != "" {
+		if userInfo, err := srv.provider.UserInfo(token.AccessToken); err == nil && userInfo.Subject == claims.Subject {
+			claims = userInfo
+		}
+	}
+	return claims, nil
+}
+
+// matchOrProvision finds an existing user with the identity's email, if verified by the provider, or creates a new one
+func (srv *OidcService) matchOrProvision(tx *gorm.DB, claims *oidc.Claims) (*models.User, bool, error) {
+	oidcConfig := srv.config.Security.Oidc
+	if claims.Email == "" {
+		return nil, false, nil
+	}
+
+	existing, err := srv.findUser(tx, models.User{Email: claims.Email})
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		// an unverified email could be anyone's, so it must never take over an existing account
+		if oidcConfig.LinkByEmail && claims.IsEmailVerified() {
+			return existing, false, nil
+		}
+		return nil, false, nil
+	}
+
+	if !oidcConfig.AutoProvision {
+		return nil, false, nil
+	}
+
+	password, err := utils.GenerateRandomPassword(24)
+	if err != nil {
+		return nil, false, err
+	}
+	hash, err := utils.HashPassword(password, srv.config.Security.PasswordSalt)
+	if err != nil {
+		return nil, false, err
+	}
+	user := &models.User{
+		ID:            uuid.Must(uuid.NewV4()).String(),
+		ApiKey:        uuid.Must(uuid.NewV4()).String(),
+		Email:         claims.Email,
+		Password:      hash,
+		EmailVerified: claims.IsEmailVerified(),
+	}
+	if err := tx.Create(user).Error; err != nil {
+		return nil, false, err
+	}
+	return user, true, nil
+}
+
+// saveLink creates or refreshes the link between the user and the identity, keeping profile details up to date
+func (srv *OidcService) saveLink(tx *gorm.DB, userOauth *models.UserOauth, user *models.User, claims *oidc.Claims) (*models.UserOauth, error) {
+	if userOauth == nil {
+		userOauth = &models.UserOauth{
+			ID:         uuid.Must(uuid.NewV4()).String(),
+			Provider:   srv.config.Security.Oidc.Name,
+			ProviderID: claims.Subject,
+		}
+	}
+
+	handle := claims.PreferredUsername
+	if handle == "" {
+		handle = claims.Name
+	}
+	userOauth.UserID = user.ID
+	userOauth.Email = &claims.Email
+	userOauth.Handle = &handle
+	userOauth.AvatarUrl = &claims.Picture
+	userOauth.UpdatedAt = models.CustomTime(time.Now())
+
+	if err := tx.Save(userOauth).Error; err != nil {
+		return nil, err
+	}
+	return userOauth, nil
+}
+
+func (srv *OidcService) findLink(tx *gorm.DB, subject string) (*models.UserOauth, error) {
+	userOauth := &models.UserOauth{}
+	if err := tx.Where(&models.UserOauth{Provider: srv.config.Security.Oidc.Name, ProviderID: subject}).First(userOauth).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return userOauth, nil
+}
+
+func (srv *OidcService) findUser(tx *gorm.DB, params models.User) (*models.User, error) {
+	user := &models.User{}
+	if err := tx.Where(&params).First(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+type IOidcService interface {
+	IsEnabled() bool
+	BeginLogin(linkUserId string) (string, string, error)
+	CompleteLogin(code, state string) (*models.User, *models.UserOauth, bool, error)
+	CompleteLink(code, state string, user *models.User) (*models.UserOauth, error)
+}
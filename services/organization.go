@@ -0,0 +1,396 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	summarytypes "github.com/muety/wakapi/types"
+	"gorm.io/gorm"
+)
+
+const (
+	organizationInvitationTtl = 7 * 24 * time.Hour
+	teamSummaryMaxRange       = 366 * 24 * time.Hour // every member's summary is generated on the fly, so limit it to about a year
+)
+
+var (
+	ErrLastOrganizationOwner    = errors.New("an organization must keep at least one owner")
+	ErrTeamSummaryInvalidRange  = errors.New("'to' date must be after 'from' date")
+	ErrTeamSummaryRangeTooLarge = errors.New("team summaries can't span more than one year")
+)
+
+type OrganizationService struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewOrganizationService(db *gorm.DB) *OrganizationService {
+	return &OrganizationService{
+		config: config.Get(),
+		db:     db,
+	}
+}
+
+// Create persists a new organization and makes the given user its first owner
+func (srv *OrganizationService) Create(newOrganization *models.Organization, owner *models.User) (*models.Organization, error) {
+	newOrganization.ID = uuid.Must(uuid.NewV4()).String()
+	newOrganization.CreatedBy = owner.ID
+
+	err := srv.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(newOrganization).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OrganizationMember{
+			OrganizationID: newOrganization.ID,
+			UserID:         owner.ID,
+			Role:           models.OrgRoleOwner,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newOrganization, nil
+}
+
+// Update changes the organization's name and description, fields left empty are kept. The organization is returned as
+// persisted afterwards.
+func (srv *OrganizationService) Update(organization *models.Organization, update *models.OrganizationUpdate) (*models.Organization, error) {
+	result := srv.db.Model(organization).Updates(update)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+
+	updated := &models.Organization{}
+	if err := srv.db.Where(&models.Organization{ID: organization.ID}).First(updated).Error; err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (srv *OrganizationService) GetBySlug(slug string) (*models.Organization, error) {
+	organization := &models.Organization{}
+	if err := srv.db.Where(&models.Organization{Slug: slug}).First(organization).Error; err != nil {
+		return nil, err
+	}
+	return organization, nil
+}
+
+func (srv *OrganizationService) DeleteOrganization(organizationID string) error {
+	return srv.db.
+		Where("id = ?", organizationID).
+		Delete(models.Organization{}).Error
+}
+
+func (srv *OrganizationService) FetchUserOrganizations(userID string) ([]*models.Organization, error) {
+	var organizations []*models.Organization
+	if err := srv.db.
+		Joins("inner join organization_members on organization_members.organization_id = organizations.id").
+		Where("organization_members.user_id = ?", userID).
+		Order("organizations.name asc").
+		Find(&organizations).Error; err != nil {
+		return nil, err
+	}
+	return organizations, nil
+}
+
+func (srv *OrganizationService) GetMember(organizationID, userID string) (*models.OrganizationMember, error) {
+	member := &models.OrganizationMember{}
+	if err := srv.db.
+		Where(&models.OrganizationMember{OrganizationID: organizationID, UserID: userID}).
+		First(member).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (srv *OrganizationService) FetchMembers(organizationID string) ([]*models.OrganizationMember, error) {
+	var members []*models.OrganizationMember
+	if err := srv.db.
+		Where(&models.OrganizationMember{OrganizationID: organizationID}).
+		Order("joined_at asc").
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (srv *OrganizationService) UpdateMemberRole(member *models.OrganizationMember, role string) (*models.OrganizationMember, error) {
+	if !models.ValidateOrganizationRole(role) {
+		return nil, errors.New("invalid role")
+	}
+	if member.IsOwner() && role != models.OrgRoleOwner {
+		if err := srv.checkNotLastOwner(member.OrganizationID); err != nil {
+			return nil, err
+		}
+	}
+	if err := srv.db.Model(member).Update("role", role).Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (srv *OrganizationService) RemoveMember(member *models.OrganizationMember) error {
+	if member.IsOwner() {
+		if err := srv.checkNotLastOwner(member.OrganizationID); err != nil {
+			return err
+		}
+	}
+	return srv.db.
+		Where("id = ?", member.ID).
+		Delete(models.OrganizationMember{}).Error
+}
+
+func (srv *OrganizationService) CreateInvitation(organization *models.Organization, inviter *models.User, email, role string) (*models.OrganizationInvitation, error) {
+	if !models.ValidateOrganizationRole(role) {
+		return nil, errors.New("invalid role")
+	}
+	if !models.ValidateEmail(email) {
+		return nil, errors.New("invalid email address")
+	}
+
+	invitation := &models.OrganizationInvitation{
+		OrganizationID: organization.ID,
+		Email:          strings.ToLower(email),
+		Role:           role,
+		InvitedBy:      inviter.ID,
+		Token:          uuid.Must(uuid.NewV4()).String(),
+		ExpiresAt:      models.CustomTime(time.Now().Add(organizationInvitationTtl)),
+	}
+	if err := srv.db.Create(invitation).Error; err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (srv *OrganizationService) GetInvitationByToken(token string) (*models.OrganizationInvitation, error) {
+	invitation := &models.OrganizationInvitation{}
+	if err := srv.db.
+		Where(&models.OrganizationInvitation{Token: token}).
+		Preload("Organization").
+		First(invitation).Error; err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (srv *OrganizationService) FetchPendingInvitations(organizationID string) ([]*models.OrganizationInvitation, error) {
+	var invitations []*models.OrganizationInvitation
+	if err := srv.db.
+		Where(&models.OrganizationInvitation{OrganizationID: organizationID}).
+		Where("accepted_at is null").
+		Where("expires_at > ?", time.Now()).
+		Order("created_at desc").
+		Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (srv *OrganizationService) DeleteInvitation(organizationID string, invitationID uint) error {
+	return srv.db.
+		Where("id = ?", invitationID).
+		Where("organization_id = ?", organizationID).
+		Delete(models.OrganizationInvitation{}).Error
+}
+
+// AcceptInvitation turns a pending invitation into a membership for the given user
+func (srv *OrganizationService) AcceptInvitation(invitation *models.OrganizationInvitation, user *models.User) (*models.OrganizationMember, error) {
+	if !invitation.IsValid() {
+		return nil, errors.New("invitation is expired or was already accepted")
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, errors.New("invitation was issued for a different email address")
+	}
+
+	member := &models.OrganizationMember{
+		OrganizationID: invitation.OrganizationID,
+		UserID:         user.ID,
+		Role:           invitation.Role,
+		InvitedBy:      invitation.InvitedBy,
+	}
+
+	err := srv.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.OrganizationMember{}).
+			Where(&models.OrganizationMember{OrganizationID: invitation.OrganizationID, UserID: user.ID}).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("user is already a member of this organization")
+		}
+		if err := tx.Create(member).Error; err != nil {
+			return err
+		}
+		return tx.Model(invitation).Update("accepted_at", models.CustomTime(time.Now())).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (srv *OrganizationService) AddProject(organization *models.Organization, projectName string, addedBy *models.User) (*models.OrganizationProject, error) {
+	if projectName == "" {
+		return nil, errors.New("project name must not be empty")
+	}
+	project := &models.OrganizationProject{
+		OrganizationID: organization.ID,
+		ProjectName:    projectName,
+		AddedBy:        addedBy.ID,
+	}
+	if err := srv.db.Create(project).Error; err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func (srv *OrganizationService) FetchProjects(organizationID string) ([]*models.OrganizationProject, error) {
+	var projects []*models.OrganizationProject
+	if err := srv.db.
+		Where(&models.OrganizationProject{OrganizationID: organizationID}).
+		Order("project_name asc").
+		Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (srv *OrganizationService) RemoveProject(organizationID string, projectID uint) error {
+	return srv.db.
+		Where("id = ?", projectID).
+		Where("organization_id = ?", organizationID).
+		Delete(models.OrganizationProject{}).Error
+}
+
+// GenerateTeamSummary generates every member's summary, restricted to the organization's projects, and merges them into a single team summary
+func (srv *OrganizationService) GenerateTeamSummary(organization *models.Organization, from, to time.Time, summarySrvc ISummaryService, userSrvc IUserService) (*models.OrganizationSummary, error) {
+	if !to.After(from) {
+		return nil, ErrTeamSummaryInvalidRange
+	}
+	if to.Sub(from) > teamSummaryMaxRange {
+		return nil, ErrTeamSummaryRangeTooLarge
+	}
+
+	members, err := srv.FetchMembers(organization.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := srv.FetchProjects(organization.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	teamSummary := &models.OrganizationSummary{
+		Organization: organization,
+		From:         models.CustomTime(from),
+		To:           models.CustomTime(to),
+		Summary:      models.NewEmptySummary(),
+		Members:      make([]*models.OrganizationMemberSummary, 0, len(members)),
+	}
+	teamSummary.Summary.FromTime = models.CustomTime(from)
+	teamSummary.Summary.ToTime = models.CustomTime(to)
+
+	if len(projects) == 0 {
+		return teamSummary, nil
+	}
+
+	projectNames := make([]string, len(projects))
+	for i, p := range projects {
+		projectNames[i] = p.ProjectName
+	}
+
+	memberSummaries := make([]*models.Summary, 0, len(members))
+	for _, m := range members {
+		user, err := userSrvc.GetUserById(m.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		request := summarytypes.NewSummaryRequest(from, to, user).WithFilters(models.NewFilterWithMultiple(models.SummaryProject, projectNames))
+		if to.After(time.Now()) {
+			request = request.WithoutCache()
+		}
+		summary, err := summarySrvc.Generate(request, summarytypes.DefaultProcessingOptions())
+		if err != nil {
+			return nil, err
+		}
+
+		memberSummaries = append(memberSummaries, summary)
+		teamSummary.Members = append(teamSummary.Members, &models.OrganizationMemberSummary{
+			UserID: m.UserID,
+			Role:   m.Role,
+			Total:  summary.TotalTime(),
+		})
+	}
+
+	sort.Slice(teamSummary.Members, func(i, j int) bool {
+		return teamSummary.Members[i].Total > teamSummary.Members[j].Total
+	})
+
+	mergeTeamSummaries(teamSummary.Summary, memberSummaries)
+	return teamSummary, nil
+}
+
+func (srv *OrganizationService) checkNotLastOwner(organizationID string) error {
+	var count int64
+	if err := srv.db.Model(&models.OrganizationMember{}).
+		Where(&models.OrganizationMember{OrganizationID: organizationID, Role: models.OrgRoleOwner}).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastOrganizationOwner
+	}
+	return nil
+}
+
+// mergeTeamSummaries adds up the items of summaries that belong to different users but cover the same time interval
+func mergeTeamSummaries(target *models.Summary, summaries []*models.Summary) {
+	for _, t := range models.SummaryTypes() {
+		totals := make(map[string]time.Duration)
+		for _, s := range summaries {
+			for _, item := range *s.GetByType(t) {
+				totals[item.Key] += item.Total
+			}
+		}
+
+		items := make(models.SummaryItems, 0, len(totals))
+		for k, v := range totals {
+			items = append(items, &models.SummaryItem{Type: t, Key: k, Total: v})
+		}
+		sort.Sort(sort.Reverse(items))
+		target.SetByType(t, &items)
+	}
+
+	for _, s := range summaries {
+		target.NumHeartbeats += s.NumHeartbeats
+	}
+}
+
+type IOrganizationService interface {
+	Create(organization *models.Organization, owner *models.User) (*models.Organization, error)
+	Update(organization *models.Organization, update *models.OrganizationUpdate) (*models.Organization, error)
+	GetBySlug(slug string) (*models.Organization, error)
+	DeleteOrganization(organizationID string) error
+	FetchUserOrganizations(userID string) ([]*models.Organization, error)
+	GetMember(organizationID, userID string) (*models.OrganizationMember, error)
+	FetchMembers(organizationID string) ([]*models.OrganizationMember, error)
+	UpdateMemberRole(member *models.OrganizationMember, role string) (*models.OrganizationMember, error)
+	RemoveMember(member *models.OrganizationMember) error
+	CreateInvitation(organization *models.Organization, inviter *models.User, email, role string) (*models.OrganizationInvitation, error)
+	GetInvitationByToken(token string) (*models.OrganizationInvitation, error)
+	FetchPendingInvitations(organizationID string) ([]*models.OrganizationInvitation, error)
+	DeleteInvitation(organizationID string, invitationID uint) error
+	AcceptInvitation(invitation *models.OrganizationInvitation, user *models.User) (*models.OrganizationMember, error)
+	AddProject(organization *models.Organization, projectName string, addedBy *models.User) (*models.OrganizationProject, error)
+	FetchProjects(organizationID string) ([]*models.OrganizationProject, error)
+	RemoveProject(organizationID string, projectID uint) error
+	GenerateTeamSummary(organization *models.Organization, from, to time.Time, summarySrvc ISummaryService, userSrvc IUserService) (*models.OrganizationSummary, error)
+}
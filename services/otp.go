@@ -20,6 +20,7 @@ import (
 	"github.com/muety/wakapi/helpers"
 	"github.com/muety/wakapi/internal/mail"
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -314,7 +315,7 @@ func CreateOTPHandler(service IOTPService) http.HandlerFunc {
 	}
 }
 
-func VerifyOTPHandler(service IOTPService) http.HandlerFunc {
+func VerifyOTPHandler(service IOTPService, twoFactorService ITwoFactorService, sessionService ISessionService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var validateOtpRequest models.ValidateOTPRequest
 		if err := json.NewDecoder(r.Body).Decode(&validateOtpRequest); err != nil {
@@ -359,12 +360,40 @@ func VerifyOTPHandler(service IOTPService) http.HandlerFunc {
 			return
 		}
 
+		// a login pin only proves access to the mailbox, so the second factor is still required
+		challenge, err := twoFactorService.Challenge(resp.User)
+		if err != nil {
+			helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+				"message":       "An unexpected error occurred. Try again later",
+				"error_message": err.Error(),
+			})
+			return
+		}
+		if challenge != nil {
+			helpers.RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+				"message": "Two-factor authentication required",
+				"status":  http.StatusOK,
+				"data":    challenge,
+			})
+			return
+		}
+
+		session, err := sessionService.Create(resp.User, r.UserAgent(), utils.ReadRemoteIP(r, config.Get().Security.TrustReverseProxyIPs()))
+		if err != nil {
+			helpers.RespondJSON(w, r, http.StatusInternalServerError, map[string]interface{}{
+				"message":       "An unexpected error occurred. Try again later",
+				"error_message": err.Error(),
+			})
+			return
+		}
+
 		response, err := helpers.MakeAuthSuccessResponse(
 			&helpers.AuthSuccessResponse{
 				Message:   resp.Message,
 				User:      resp.User,
 				OauthUser: nil,
 				IsNewUser: resp.IsNewUser,
+				Session:   session,
 			},
 		)
 
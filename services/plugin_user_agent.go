@@ -44,25 +44,30 @@ func (srv *PluginUserAgentService) FindOne(attributes models.PluginUserAgent) (*
 	return u, nil
 }
 
-func (srv *PluginUserAgentService) CreateOrUpdate(useragent_string, user_id string) (*models.PluginUserAgent, error) {
+// CreateOrUpdate records the user agent of an editor plugin on the given machine, keeping track of its latest version
+func (srv *PluginUserAgentService) CreateOrUpdate(useragent_string, user_id, machine string) (*models.PluginUserAgent, error) {
 	useragent, err := models.NewPluginUserAgent(useragent_string, user_id)
 	if err != nil {
 		return nil, err
 	}
 
 	useragent.Editor = formatEditor(useragent.Editor)
+	useragent.Machine = machine
 
-	findQuery := models.PluginUserAgent{
-		UserID: useragent.UserID,
-		Editor: useragent.Editor,
-	}
-	existing, err := srv.FindOne(findQuery)
+	existing, err := srv.findByMachine(useragent.UserID, useragent.Editor, machine)
 	if err != nil {
 		return nil, err
 	}
 
 	if existing != nil {
-		result := srv.db.Model(existing).Updates(map[string]interface{}{"last_seen_at": time.Now()}) // use json tags and avoid this rotten fix.
+		result := srv.db.Model(existing).Updates(map[string]interface{}{
+			"last_seen_at": time.Now(),
+			"value":        useragent.Value,
+			"cli_version":  useragent.CliVersion,
+			"go_version":   useragent.GoVersion,
+			"plugin":       useragent.Plugin,
+			"version":      useragent.Version,
+		}) // use json tags and avoid this rotten fix.
 		if err := result.Error; err != nil {
 			return nil, err
 		}
@@ -76,6 +81,40 @@ func (srv *PluginUserAgentService) CreateOrUpdate(useragent_string, user_id stri
 	return useragent, nil
 }
 
+// FetchMachineUserAgents returns the user agents seen on any of the user's machines, by machine name
+func (srv *PluginUserAgentService) FetchMachineUserAgents(userID string) (map[string][]*models.PluginUserAgent, error) {
+	var plugins []*models.PluginUserAgent
+	if err := srv.db.
+		Order("last_seen_at desc").
+		Where(&models.PluginUserAgent{UserID: userID}).
+		Where("machine != ''").
+		Find(&plugins).Error; err != nil {
+		return nil, err
+	}
+
+	byMachine := make(map[string][]*models.PluginUserAgent)
+	for _, p := range plugins {
+		byMachine[p.Machine] = append(byMachine[p.Machine], p)
+	}
+	return byMachine, nil
+}
+
+// findByMachine looks up the user agent of an editor on a machine, where the empty machine name doesn't match a specific one
+func (srv *PluginUserAgentService) findByMachine(userID, editor, machine string) (*models.PluginUserAgent, error) {
+	u := &models.PluginUserAgent{}
+	result := srv.db.
+		Where(&models.PluginUserAgent{UserID: userID, Editor: editor}).
+		Where("machine = ?", machine).
+		First(u)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil // No record found
+		}
+		return nil, result.Error
+	}
+	return u, nil
+}
+
 func (srv *PluginUserAgentService) FetchUserAgents(userID string) ([]*models.PluginUserAgent, error) {
 	var plugins []*models.PluginUserAgent
 	if err := srv.db.
@@ -89,6 +128,7 @@ func (srv *PluginUserAgentService) FetchUserAgents(userID string) ([]*models.Plu
 }
 
 type IPluginUserAgentService interface {
-	CreateOrUpdate(useragent, user_id string) (*models.PluginUserAgent, error)
+	CreateOrUpdate(useragent, user_id, machine string) (*models.PluginUserAgent, error)
 	FetchUserAgents(user_id string) ([]*models.PluginUserAgent, error)
+	FetchMachineUserAgents(user_id string) (map[string][]*models.PluginUserAgent, error)
 }
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"gorm.io/gorm"
+)
+
+type ProjectService struct {
+	config     *config.Config
+	repository repositories.IProjectRepository
+}
+
+// projectActivity is the aggregate of a batch of heartbeats belonging to a single project
+type projectActivity struct {
+	first     time.Time
+	last      time.Time
+	languages map[string]int64
+}
+
+func NewProjectService(db *gorm.DB) *ProjectService {
+	return &ProjectService{
+		config:     config.Get(),
+		repository: repositories.NewProjectRepository(db),
+	}
+}
+
+func (srv *ProjectService) GetById(id uint) (*models.Project, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *ProjectService) GetByUserAndName(userId, name string) (*models.Project, error) {
+	return srv.repository.GetByUserAndName(userId, name)
+}
+
+// GetByUser returns a page of the user's projects along with the total number of projects matching the query
+func (srv *ProjectService) GetByUser(userId string, query *models.ProjectQuery, pageParams *utils.PageParams) ([]*models.Project, int64, error) {
+	total, err := srv.repository.CountByUser(userId, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	projects, err := srv.repository.GetByUser(userId, query, pageParams)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return projects, total, nil
+}
+
+func (srv *ProjectService) Update(project *models.Project, update *models.ProjectUpdate) (*models.Project, error) {
+	if !update.IsValid() {
+		return nil, errors.New("invalid project update")
+	}
+
+	fields := map[string]interface{}{}
+	if update.Description != nil {
+		fields["description"] = *update.Description
+	}
+	if update.RepositoryUrl != nil {
+		fields["repository_url"] = *update.RepositoryUrl
+	}
+	if update.Color != nil {
+		fields["color"] = *update.Color
+	}
+	if update.IsArchived != nil {
+		fields["is_archived"] = *update.IsArchived
+	}
+	if len(fields) == 0 {
+		return project, nil
+	}
+
+	return srv.repository.UpdateFields(project, fields)
+}
+
+// UpsertFromHeartbeats creates or updates the projects referenced by the given heartbeats
+func (srv *ProjectService) UpsertFromHeartbeats(heartbeats []*models.Heartbeat) error {
+	activities := make(map[string]map[string]*projectActivity)
+
+	for _, hb := range heartbeats {
+		if hb.Project == "" {
+			continue
+		}
+		if _, ok := activities[hb.UserID]; !ok {
+			activities[hb.UserID] = make(map[string]*projectActivity)
+		}
+
+		t := hb.Time.T()
+		activity, ok := activities[hb.UserID][hb.Project]
+		if !ok {
+			activity = &projectActivity{first: t, last: t, languages: map[string]int64{}}
+			activities[hb.UserID][hb.Project] = activity
+		}
+		if t.Before(activity.first) {
+			activity.first = t
+		}
+		if t.After(activity.last) {
+			activity.last = t
+		}
+		activity.languages[hb.Language]++
+	}
+
+	for userId, userActivities := range activities {
+		for name, activity := range userActivities {
+			project := models.NewProject(userId, name, activity.first)
+			project.Track(activity.first, activity.last, activity.languages)
+
+			created, err := srv.repository.InsertIfMissing(project)
+			if err != nil {
+				return err
+			}
+			if created {
+				continue
+			}
+
+			// the statistics of existing projects are merged by the database, as batches may be processed concurrently
+			// by different processes, e.g. the api and the job worker running an import
+			if err := srv.repository.ExtendHeartbeatRange(userId, name, activity.first, activity.last); err != nil {
+				return err
+			}
+			if err := srv.repository.AddLanguages(userId, name, activity.languages); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
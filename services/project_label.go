@@ -22,12 +22,22 @@ type ProjectLabelService struct {
 
 func NewProjectLabelService(db *gorm.DB) *ProjectLabelService {
 	projectLabelRepository := repositories.NewProjectLabelRepository(db)
-	return &ProjectLabelService{
+	srv := &ProjectLabelService{
 		config:     config.Get(),
 		eventBus:   config.EventBus(),
 		repository: projectLabelRepository,
 		cache:      cache.New(24*time.Hour, 24*time.Hour),
 	}
+
+	// there are multiple instances of this service (e.g. one inside the summary service), all of which need to drop their cache
+	sub1 := srv.eventBus.Subscribe(0, config.TopicProjectLabel)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.cache.Delete(m.Fields[config.FieldUserId].(string))
+		}
+	}(&sub1)
+
+	return srv
 }
 
 func (srv *ProjectLabelService) GetById(id uint) (*models.ProjectLabel, error) {
@@ -82,6 +92,19 @@ func (srv *ProjectLabelService) Create(label *models.ProjectLabel) (*models.Proj
 	return result, nil
 }
 
+// CreateMulti creates either all of the given labels or, in case any of them fails, none
+func (srv *ProjectLabelService) CreateMulti(labels []*models.ProjectLabel) ([]*models.ProjectLabel, error) {
+	if err := srv.repository.InsertBatch(labels); err != nil {
+		return nil, err
+	}
+
+	for _, label := range labels {
+		srv.cache.Delete(label.UserID)
+		srv.notifyUpdate(label, false)
+	}
+	return labels, nil
+}
+
 func (srv *ProjectLabelService) Delete(label *models.ProjectLabel) error {
 	if label.UserID == "" {
 		return errors.New("no user id specified")
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"fmt"
 	"log/slog"
 	"math/rand"
 	"time"
@@ -15,20 +16,36 @@ import (
 	"github.com/muety/wakapi/models"
 	summarytypes "github.com/muety/wakapi/types"
 	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
 	"gorm.io/gorm"
 )
 
+// how long a queued scheduled report is assumed to be on its way, before it may be queued again
+const scheduledReportPendingTimeout = 1 * time.Hour
+
+// reports sent for each kind of scheduled notification, covering the latest complete period
+var reportKinds = map[string]struct {
+	interval *models.IntervalKey
+	title    string
+}{
+	models.NotificationDailyDigest:  {models.IntervalYesterday, "Your Daily Coding Digest"},
+	models.NotificationWeeklyReport: {models.IntervalPreviousWeek, "Your Weekly Coding Summary"},
+}
+
 type ReportService struct {
-	config          *config.Config
-	eventBus        *hub.Hub
-	summaryService  ISummaryService
-	durationService IDurationService
-	userService     IUserService
-	mailService     mail.IMailService
-	rand            *rand.Rand
-	queueDefault    *artifex.Dispatcher
-	queueWorkers    *artifex.Dispatcher
-	db              *gorm.DB
+	config              *config.Config
+	cache               *cache.Cache
+	eventBus            *hub.Hub
+	summaryService      ISummaryService
+	durationService     IDurationService
+	userService         IUserService
+	notificationService INotificationService
+	mailService         mail.IMailService
+	rand                *rand.Rand
+	queueDefault        *artifex.Dispatcher
+	queueWorkers        *artifex.Dispatcher
+	queueMails          *artifex.Dispatcher
+	db                  *gorm.DB
 }
 
 // ReportDeduplicator ensures a report is not sent multiple times for the same user in the same week
@@ -84,27 +101,46 @@ func NewReportService(db *gorm.DB) *ReportService {
 	userService := NewUserService(db)
 
 	srv := &ReportService{
-		config:          config.Get(),
-		eventBus:        config.EventBus(),
-		summaryService:  summaryService,
-		durationService: durationService,
-		userService:     userService,
-		mailService:     mail.NewMailService(),
-		rand:            rand.New(rand.NewSource(time.Now().Unix())),
-		queueDefault:    config.GetDefaultQueue(),
-		queueWorkers:    config.GetQueue(config.QueueReports),
-		db:              db,
+		config:              config.Get(),
+		cache:               cache.New(6*time.Hour, 6*time.Hour),
+		eventBus:            config.EventBus(),
+		summaryService:      summaryService,
+		durationService:     durationService,
+		userService:         userService,
+		notificationService: NewNotificationService(db),
+		mailService:         mail.NewMailService(),
+		rand:                rand.New(rand.NewSource(time.Now().Unix())),
+		queueDefault:        config.GetDefaultQueue(),
+		queueWorkers:        config.GetQueue(config.QueueReports),
+		queueMails:          config.GetQueue(config.QueueMails),
+		db:                  db,
 	}
 
 	return srv
 }
 
+// SendReport sends the user's weekly report, unless already sent this week when tracking
 func (srv *ReportService) SendReport(user *models.User, skipTracking bool) error {
+	return srv.sendReport(user, models.NotificationWeeklyReport, skipTracking)
+}
+
+func (srv *ReportService) sendReport(user *models.User, kind string, skipTracking bool) error {
+	if period, ok := wrappedReportKinds[kind]; ok {
+		return srv.SendWrappedReport(user, period)
+	}
+
+	reportKind, ok := reportKinds[kind]
+	if !ok {
+		return fmt.Errorf("no report for notification kind '%s'", kind)
+	}
+
 	if user.Email == "" {
 		slog.Warn("not generating report as no e-mail address is set", "userID", user.ID)
 		return nil
 	}
 
+	// weekly reports used to be sent by a global cron job, their tracking still guards against duplicates in the same week
+	skipTracking = skipTracking || kind != models.NotificationWeeklyReport
 	if !skipTracking {
 		tracker := ReportSentTracker(srv.db, user)
 		if tracker.IsReportSent() {
@@ -113,12 +149,11 @@ func (srv *ReportService) SendReport(user *models.User, skipTracking bool) error
 		}
 	}
 
-	slog.Info("generating report for user", "userID", user.ID)
+	slog.Info("generating report for user", "userID", user.ID, "kind", kind)
 
-	// Use previous week interval
-	err, start, end := helpers.ResolveIntervalTZ(models.IntervalPreviousWeek, user.TZ())
+	err, start, end := helpers.ResolveIntervalTZ(reportKind.interval, user.TZ())
 	if err != nil {
-		config.Log().Error("failed to resolve previous week interval", "userID", user.ID, "error", err)
+		config.Log().Error("failed to resolve report interval", "userID", user.ID, "kind", kind, "error", err)
 		return err
 	}
 
@@ -157,6 +192,7 @@ func (srv *ReportService) SendReport(user *models.User, skipTracking bool) error
 	}
 
 	report := &models.Report{
+		Title:          reportKind.title,
 		From:           start,
 		To:             end,
 		User:           user,
@@ -170,7 +206,8 @@ func (srv *ReportService) SendReport(user *models.User, skipTracking bool) error
 		return err
 	}
 
-	slog.Info("sent report to user", "userID", user.ID)
+	slog.Info("sent report to user", "userID", user.ID, "kind", kind)
+	srv.notifySent(report)
 	if !skipTracking {
 		tracker := ReportSentTracker(srv.db, user)
 		err = tracker.MarkReportAsSent()
@@ -181,6 +218,70 @@ func (srv *ReportService) SendReport(user *models.User, skipTracking bool) error
 	return nil
 }
 
+// SendScheduledReports queues all reports and digests that are due according to the users' preferences, at the delivery
+// time each user chose in their own timezone
+func (srv *ReportService) SendScheduledReports(now time.Time) error {
+	prefs, err := srv.notificationService.FetchScheduled()
+	if err != nil {
+		config.Log().Error("failed to get scheduled notifications", "error", err)
+		return err
+	}
+
+	var queued int
+	for _, pref := range prefs {
+		if pref.User == nil || pref.User.Email == "" || !pref.IsDue(now, pref.User.TZ()) {
+			continue
+		}
+
+		// reports still waiting in the mail queue are not queued again by the next run
+		pendingKey := fmt.Sprintf("scheduled_report_%d", pref.ID)
+		if err := srv.cache.Add(pendingKey, true, scheduledReportPendingTimeout); err != nil {
+			continue
+		}
+
+		if err := srv.queueMails.Dispatch(func() {
+			defer srv.cache.Delete(pendingKey)
+			srv.sendScheduledReport(pref, now)
+		}); err != nil {
+			config.Log().Error("failed to dispatch scheduled report", "userID", pref.UserID, "kind", pref.Kind, "error", err)
+			srv.cache.Delete(pendingKey)
+			continue
+		}
+		queued++
+	}
+
+	if queued > 0 {
+		config.Log().Info("queued scheduled reports", "queuedCount", queued)
+	}
+	return nil
+}
+
+// sendScheduledReport sends a single scheduled report and marks it as sent, or leaves it to be retried by the next run
+// within the catch-up window otherwise
+func (srv *ReportService) sendScheduledReport(pref *models.NotificationPreference, now time.Time) {
+	if err := srv.sendReport(pref.User, pref.Kind, false); err != nil {
+		config.Log().Error("failed to send scheduled report", "userID", pref.UserID, "kind", pref.Kind, "error", err)
+		return
+	}
+	if err := srv.notificationService.MarkSent(pref, now); err != nil {
+		config.Log().Error("failed to mark scheduled report as sent", "userID", pref.UserID, "kind", pref.Kind, "error", err)
+	}
+}
+
+func (srv *ReportService) notifySent(report *models.Report) {
+	srv.eventBus.Publish(hub.Message{
+		Name: config.EventReportSent,
+		Fields: map[string]interface{}{
+			config.FieldPayload: map[string]interface{}{
+				"from":          report.From,
+				"to":            report.To,
+				"total_seconds": int64(report.WeeklyTotal.Seconds()),
+			},
+			config.FieldUserId: report.User.ID,
+		},
+	})
+}
+
 func (srv *ReportService) SendWeeklyReports() error {
 	users, err := srv.userService.GetAllByReports(true)
 	if err != nil {
@@ -21,6 +21,7 @@ type IMiscService interface {
 
 type IAliasService interface {
 	Create(*models.Alias) (*models.Alias, error)
+	CreateMulti([]*models.Alias) ([]*models.Alias, error)
 	Delete(*models.Alias) error
 	DeleteMulti([]*models.Alias) error
 	IsInitialized(string) bool
@@ -42,6 +43,7 @@ type IHeartbeatService interface {
 	GetFirstByUsers() ([]*models.TimeByUser, error)
 	GetLatestByUser(*models.User) (*models.Heartbeat, error)
 	GetLatestByOriginAndUser(string, *models.User) (*models.Heartbeat, error)
+	FilterExisting([]*models.Heartbeat) ([]*models.Heartbeat, error)
 	GetLatestByFilters(*models.User, *models.Filters) (*models.Heartbeat, error)
 	GetEntitySetByUser(uint8, string) ([]string, error)
 	DeleteBefore(time.Time) error
@@ -68,6 +70,7 @@ type ILanguageMappingService interface {
 	GetByUser(string) ([]*models.LanguageMapping, error)
 	ResolveByUser(string) (map[string]string, error)
 	Create(*models.LanguageMapping) (*models.LanguageMapping, error)
+	CreateMulti([]*models.LanguageMapping) ([]*models.LanguageMapping, error)
 	Delete(mapping *models.LanguageMapping) error
 }
 
@@ -77,9 +80,18 @@ type IProjectLabelService interface {
 	GetByUserGrouped(string) (map[string][]*models.ProjectLabel, error)
 	GetByUserGroupedInverted(string) (map[string][]*models.ProjectLabel, error)
 	Create(*models.ProjectLabel) (*models.ProjectLabel, error)
+	CreateMulti([]*models.ProjectLabel) ([]*models.ProjectLabel, error)
 	Delete(*models.ProjectLabel) error
 }
 
+type IProjectService interface {
+	GetById(uint) (*models.Project, error)
+	GetByUserAndName(string, string) (*models.Project, error)
+	GetByUser(string, *models.ProjectQuery, *utils.PageParams) ([]*models.Project, int64, error)
+	Update(*models.Project, *models.ProjectUpdate) (*models.Project, error)
+	UpsertFromHeartbeats([]*models.Heartbeat) error
+}
+
 type IDurationService interface {
 	Get(time.Time, time.Time, *models.User, *models.Filters, string) (models.Durations, error)
 	MakeDurationsFromHeartbeats(models.ProcessHeartbeatsArgs, *models.Filters) (models.Durations, error)
@@ -107,12 +119,22 @@ type ISummaryService interface {
 }
 
 type IActivityService interface {
-	GetChart(*models.User, *models.IntervalKey, bool, bool, bool) (string, error)
+	GetChart(*models.User, time.Time, time.Time, bool, bool, bool) (string, error)
+	GetHeatmap(*models.User, time.Time, time.Time, *models.Filters, bool) (*models.ActivityHeatmap, error)
+	GetHeatmapChart(*models.User, time.Time, time.Time, *models.Filters, bool, bool, bool) (string, error)
+}
+
+type IStatsCardService interface {
+	GetCard(*models.StatsCardParams, bool) (string, error)
 }
 
 type IReportService interface {
 	SendReport(*models.User, bool) error
 	SendWeeklyReports() error
+	SendScheduledReports(time.Time) error
+	GetWrappedReport(*models.User, string, time.Time, bool) (*models.WrappedReport, error)
+	GetWrappedCard(*models.User, string, time.Time, bool, bool) (string, error)
+	SendWrappedReport(*models.User, string) error
 }
 
 type IHousekeepingService interface {
@@ -148,6 +170,7 @@ type IServices interface {
 	KeyValue() IKeyValueService
 	Report() IReportService
 	Activity() IActivityService
+	StatsCard() IStatsCardService
 	Diagnostics() IDiagnosticsService
 	HouseKeeping() IHousekeepingService
 	Misc() IMiscService
@@ -158,6 +181,17 @@ type IServices interface {
 	Invoice() IInvoiceService
 	Heartbeat() IHeartbeatService
 	Otp() IOTPService
+	Organization() IOrganizationService
+	Project() IProjectService
+	ImportJob() IImportJobService
+	DataExport() IDataExportService
+	Webhook() IWebhookService
+	Notification() INotificationService
+	ApiToken() IApiTokenService
+	Machine() IMachineService
+	TwoFactor() ITwoFactorService
+	Oidc() IOidcService
+	Sessions() ISessionService
 }
 
 type Services struct {
@@ -172,6 +206,7 @@ type Services struct {
 	keyValue        IKeyValueService
 	report          IReportService
 	activity        IActivityService
+	statsCard       IStatsCardService
 	diagnostics     IDiagnosticsService
 	houseKeeping    IHousekeepingService
 	misc            IMiscService
@@ -182,6 +217,17 @@ type Services struct {
 	invoice         IInvoiceService
 	heartbeat       IHeartbeatService
 	otp             IOTPService
+	organization    IOrganizationService
+	project         IProjectService
+	importJob       IImportJobService
+	dataExport      IDataExportService
+	webhook         IWebhookService
+	notification    INotificationService
+	apiToken        IApiTokenService
+	machine         IMachineService
+	twoFactor       ITwoFactorService
+	oidc            IOidcService
+	session         ISessionService
 }
 
 // Implement the IServices interface
@@ -229,6 +275,10 @@ func (s *Services) Activity() IActivityService {
 	return s.activity
 }
 
+func (s *Services) StatsCard() IStatsCardService {
+	return s.statsCard
+}
+
 func (s *Services) Diagnostics() IDiagnosticsService {
 	return s.diagnostics
 }
@@ -269,8 +319,53 @@ func (s *Services) Otp() IOTPService {
 	return s.otp
 }
 
+func (s *Services) Organization() IOrganizationService {
+	return s.organization
+}
+
+func (s *Services) Project() IProjectService {
+	return s.project
+}
+
+func (s *Services) ImportJob() IImportJobService {
+	return s.importJob
+}
+
+func (s *Services) DataExport() IDataExportService {
+	return s.dataExport
+}
+
+func (s *Services) Webhook() IWebhookService {
+	return s.webhook
+}
+
+func (s *Services) Notification() INotificationService {
+	return s.notification
+}
+
+func (s *Services) ApiToken() IApiTokenService {
+	return s.apiToken
+}
+
+func (s *Services) Machine() IMachineService {
+	return s.machine
+}
+
+func (s *Services) TwoFactor() ITwoFactorService {
+	return s.twoFactor
+}
+
+func (s *Services) Oidc() IOidcService {
+	return s.oidc
+}
+
+func (s *Services) Sessions() ISessionService {
+	return s.session
+}
+
 func NewServices(db *gorm.DB) IServices {
 	return &Services{
+		alias:           NewAliasService(db),
 		users:           NewUserService(db),
 		languageMapping: NewLanguageMappingService(db),
 		projectLabel:    NewProjectLabelService(db),
@@ -281,6 +376,7 @@ func NewServices(db *gorm.DB) IServices {
 		keyValue:        NewKeyValueService(db),
 		report:          NewReportService(db),
 		activity:        NewActivityService(db),
+		statsCard:       NewStatsCardService(db),
 		diagnostics:     NewDiagnosticsService(db),
 		houseKeeping:    NewHousekeepingService(db),
 		misc:            NewMiscService(db),
@@ -291,5 +387,16 @@ func NewServices(db *gorm.DB) IServices {
 		invoice:         NewInvoiceService(db),
 		heartbeat:       NewHeartbeatService(db),
 		otp:             NewOTPService(db),
+		organization:    NewOrganizationService(db),
+		project:         NewProjectService(db),
+		importJob:       NewImportJobService(db),
+		dataExport:      NewDataExportService(db),
+		webhook:         NewWebhookService(db),
+		notification:    NewNotificationService(db),
+		apiToken:        NewApiTokenService(db),
+		machine:         NewMachineService(db),
+		twoFactor:       NewTwoFactorService(db),
+		oidc:            NewOidcService(db),
+		session:         NewSessionService(db),
 	}
 }
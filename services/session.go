@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+const (
+	sessionUserAgentMaxLength = 255
+	// like for api tokens, last activity is only recorded once in a while, as sessions are checked for every request
+	sessionActivityInterval = 1 * time.Minute
+)
+
+var ErrSessionInactive = errors.New("session is revoked or expired")
+
+// SessionService deliberately doesn't cache sessions, so revoking one takes effect immediately, across processes
+type SessionService struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewSessionService(db *gorm.DB) *SessionService {
+	return &SessionService{
+		config: config.Get(),
+		db:     db,
+	}
+}
+
+// Create records a new login for the user, whose id is to be included in the token issued for it
+func (srv *SessionService) Create(user *models.User, userAgent, ip string) (*models.Session, error) {
+	if len(userAgent) > sessionUserAgentMaxLength {
+		userAgent = userAgent[:sessionUserAgentMaxLength]
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:         uuid.Must(uuid.NewV4()).String(),
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  models.CustomTime(now),
+		LastSeenAt: models.CustomTime(now),
+		ExpiresAt:  models.CustomTime(now.Add(helpers.JWT_TOKEN_DURATION)),
+	}
+	if err := srv.db.Create(session).Error; err != nil {
+		return nil, err
+	}
+
+	// piggyback on logins to get rid of the user's sessions, that can't be used anymore
+	if err := srv.db.
+		Where("user_id = ? and expires_at < ?", user.ID, now).
+		Delete(&models.Session{}).Error; err != nil {
+		config.Log().Error("failed to delete expired sessions", "userID", user.ID, "error", err)
+	}
+
+	return session, nil
+}
+
+// GetActive looks up a session by its id, revoked or expired sessions are not found
+func (srv *SessionService) GetActive(id string) (*models.Session, error) {
+	if id == "" {
+		return nil, errors.New("session id must not be empty")
+	}
+
+	session := &models.Session{}
+	if err := srv.db.Where(&models.Session{ID: id}).First(session).Error; err != nil {
+		return nil, err
+	}
+
+	if !session.IsActiveAt(time.Now()) {
+		return nil, ErrSessionInactive
+	}
+	return session, nil
+}
+
+// FetchUserSessions returns the user's active sessions, most recently used first
+func (srv *SessionService) FetchUserSessions(userID string) ([]*models.Session, error) {
+	var sessions []*models.Session
+	if err := srv.db.
+		Where("user_id = ? and revoked_at is null and expires_at > ?", userID, time.Now()).
+		Order("last_seen_at desc").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// MarkSeen records the time and source ip of the session's latest request, unless it was recorded only shortly before
+func (srv *SessionService) MarkSeen(session *models.Session, ip string) error {
+	now := time.Now()
+	if session.IP == ip && now.Sub(session.LastSeenAt.T()) < sessionActivityInterval {
+		return nil
+	}
+
+	session.LastSeenAt, session.IP = models.CustomTime(now), ip
+	return srv.db.Model(session).Select("last_seen_at", "ip").Updates(session).Error
+}
+
+func (srv *SessionService) Revoke(session *models.Session) error {
+	revokedAt := models.CustomTime(time.Now())
+	session.RevokedAt = &revokedAt
+	return srv.db.Model(session).Select("revoked_at").Updates(session).Error
+}
+
+// RevokeAll ends all of the user's sessions except for the given one, e.g. after changing credentials
+func (srv *SessionService) RevokeAll(userID string, exceptID string) (int64, error) {
+	result := srv.db.Model(&models.Session{}).
+		Where("user_id = ? and id <> ? and revoked_at is null", userID, exceptID).
+		Update("revoked_at", models.CustomTime(time.Now()))
+	return result.RowsAffected, result.Error
+}
+
+type ISessionService interface {
+	Create(user *models.User, userAgent, ip string) (*models.Session, error)
+	GetActive(id string) (*models.Session, error)
+	FetchUserSessions(userID string) ([]*models.Session, error)
+	MarkSeen(session *models.Session, ip string) error
+	Revoke(session *models.Session) error
+	RevokeAll(userID string, exceptID string) (int64, error)
+}
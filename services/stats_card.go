@@ -0,0 +1,368 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	svg "github.com/ajstarks/svgo/float"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	summarytypes "github.com/muety/wakapi/types"
+	"github.com/patrickmn/go-cache"
+	"gorm.io/gorm"
+)
+
+const (
+	statsCardWidth       = 350
+	statsCardPadding     = 25
+	statsCardHeaderSize  = 55
+	statsCardMaxChars    = 28
+	statsCardFailedColor = "#E11D48"
+	// cards are requested anonymously and with arbitrary colors, so there's no telling how many variants there are
+	statsCardCacheMaxItems = 1000
+	// stands in for the title in cached cards, it can't be confused with escaped user content, which has no '<'
+	statsCardTitlePlaceholder = "<!--title-->"
+)
+
+// colors for items without a configured language color
+var statsCardPalette = []string{"#047857", "#3B82F6", "#F59E0B", "#8B5CF6", "#EC4899", "#14B8A6", "#F97316", "#6366F1", "#84CC16", "#64748B"}
+
+type StatsCardService struct {
+	config          *config.Config
+	cache           *cache.Cache
+	summaryService  ISummaryService
+	durationService IDurationService
+}
+
+func NewStatsCardService(db *gorm.DB) *StatsCardService {
+	return &StatsCardService{
+		config:          config.Get(),
+		cache:           cache.New(1*time.Hour, 1*time.Hour),
+		summaryService:  NewSummaryService(db),
+		durationService: NewDurationService(db),
+	}
+}
+
+// GetCard renders an embeddable svg card of the user's top languages or projects, their last seven days or a goal's
+// progress, similar to https://github.com/anuraghazra/github-readme-stats
+func (srv *StatsCardService) GetCard(params *models.StatsCardParams, skipCache bool) (string, error) {
+	if !models.IsValidStatsCard(params.Card) {
+		return "", fmt.Errorf("unsupported card '%s'", params.Card)
+	}
+	if params.Card == models.StatsCardGoal && params.Goal == nil {
+		return "", fmt.Errorf("missing goal")
+	}
+
+	var goalID string
+	if params.Goal != nil {
+		goalID = params.Goal.ID
+	}
+	// the free text title is left out of the key, but filled in after looking up the card
+	cacheKey := fmt.Sprintf("card_%s_%s_%s_%d_%d_%v_%s_%d", params.Card, params.User.ID, goalID, params.From.Truncate(time.Hour).Unix(), params.To.Truncate(time.Hour).Unix(), params.Theme, params.Layout, params.Limit)
+	if result, found := srv.cache.Get(cacheKey); found && !skipCache {
+		return withStatsCardTitle(result.(string), params), nil
+	}
+
+	var (
+		card string
+		err  error
+	)
+	switch params.Card {
+	case models.StatsCardLanguages:
+		card, err = srv.getLanguagesCard(params)
+	case models.StatsCardProjects:
+		card, err = srv.getProjectsCard(params)
+	case models.StatsCardWeek:
+		card, err = srv.getWeekCard(params)
+	case models.StatsCardGoal:
+		card, err = srv.getGoalCard(params)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if srv.cache.ItemCount() < statsCardCacheMaxItems {
+		srv.cache.SetDefault(cacheKey, card)
+	}
+	return withStatsCardTitle(card, params), nil
+}
+
+func (srv *StatsCardService) getLanguagesCard(params *models.StatsCardParams) (string, error) {
+	summary, err := srv.summaryService.Generate(summarytypes.NewSummaryRequest(params.From, params.To, params.User), summarytypes.DefaultProcessingOptions())
+	if err != nil {
+		return "", err
+	}
+
+	items := topCardItems(summary.Languages, params.Limit)
+	total := summary.TotalTimeBy(models.SummaryLanguage)
+	colors := srv.config.App.GetLanguageColors()
+	itemColor := func(i int, item *models.SummaryItem) string {
+		if color, ok := colors[strings.ToLower(item.Key)]; ok {
+			return color
+		}
+		return statsCardPalette[i%len(statsCardPalette)]
+	}
+	share := func(item *models.SummaryItem) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(item.TotalFixed()) / float64(total)
+	}
+
+	if params.Layout == models.StatsCardLayoutDonut {
+		const radius, strokeWidth = 45.0, 18.0
+		height := math.Max(float64(statsCardHeaderSize+len(items)*22+10), statsCardHeaderSize+2*radius+strokeWidth+10)
+		canvas, buf := startStatsCard(params, height)
+
+		cx, cy := statsCardWidth-statsCardPadding-radius-strokeWidth/2, statsCardHeaderSize+radius+strokeWidth/2
+		circumference := 2 * math.Pi * radius
+		var offset float64
+		canvas.Circle(cx, cy, radius, fmt.Sprintf("fill: none; stroke: %s; stroke-width: %.0f", params.Theme.Border, strokeWidth))
+		for i, item := range items {
+			length := share(item) * circumference
+			// segments are drawn as dashes of the circle's outline, starting at twelve o'clock
+			canvas.Circle(cx, cy, radius, fmt.Sprintf("fill: none; stroke: %s; stroke-width: %.0f; stroke-dasharray: %.2f %.2f; stroke-dashoffset: %.2f", itemColor(i, item), strokeWidth, length, circumference-length, -offset), fmt.Sprintf(`transform="rotate(-90 %.2f %.2f)"`, cx, cy))
+			offset += length
+		}
+
+		for i, item := range items {
+			y := float64(statsCardHeaderSize + 10 + i*22)
+			canvas.Circle(statsCardPadding+5, y-4, 5, fmt.Sprintf("fill: %s", itemColor(i, item)))
+			canvas.Text(statsCardPadding+16, y, fmt.Sprintf("%s %.1f%%", truncateStatsCardText(item.Key, 18), share(item)*100))
+		}
+		if len(items) == 0 {
+			canvas.Text(statsCardPadding, statsCardHeaderSize+10, "No coding activity yet", `class="muted"`)
+		}
+
+		return endStatsCard(canvas, buf), nil
+	}
+
+	rows := (len(items) + 1) / 2
+	canvas, buf := startStatsCard(params, float64(statsCardHeaderSize+20+max(rows, 1)*24+10))
+
+	barWidth := float64(statsCardWidth - 2*statsCardPadding)
+	canvas.Roundrect(statsCardPadding, statsCardHeaderSize, barWidth, 8, 4, 4, fmt.Sprintf("fill: %s", params.Theme.Border))
+	var x float64 = statsCardPadding
+	for i, item := range items {
+		width := share(item) * barWidth
+		canvas.Rect(x, statsCardHeaderSize, width, 8, fmt.Sprintf("fill: %s", itemColor(i, item)))
+		x += width
+	}
+
+	for i, item := range items {
+		x, y := float64(statsCardPadding+(i%2)*150), float64(statsCardHeaderSize+34+(i/2)*24)
+		canvas.Circle(x+5, y-4, 5, fmt.Sprintf("fill: %s", itemColor(i, item)))
+		canvas.Text(x+16, y, fmt.Sprintf("%s %.1f%%", truncateStatsCardText(item.Key, 14), share(item)*100))
+	}
+	if len(items) == 0 {
+		canvas.Text(statsCardPadding, statsCardHeaderSize+34, "No coding activity yet", `class="muted"`)
+	}
+
+	return endStatsCard(canvas, buf), nil
+}
+
+func (srv *StatsCardService) getProjectsCard(params *models.StatsCardParams) (string, error) {
+	summary, err := srv.summaryService.Generate(summarytypes.NewSummaryRequest(params.From, params.To, params.User), summarytypes.DefaultProcessingOptions())
+	if err != nil {
+		return "", err
+	}
+
+	items := topCardItems(summary.Projects, params.Limit)
+	canvas, buf := startStatsCard(params, float64(statsCardHeaderSize+max(len(items), 1)*36+5))
+
+	barWidth := float64(statsCardWidth - 2*statsCardPadding)
+	for i, item := range items {
+		y := float64(statsCardHeaderSize + 10 + i*36)
+		canvas.Text(statsCardPadding, y, truncateStatsCardText(item.Key, statsCardMaxChars))
+		canvas.Text(statsCardWidth-statsCardPadding, y, helpers.FmtWakatimeDuration(item.TotalFixed()), `class="muted"`, `text-anchor="end"`)
+		canvas.Roundrect(statsCardPadding, y+8, barWidth, 8, 4, 4, fmt.Sprintf("fill: %s", params.Theme.Border))
+		canvas.Roundrect(statsCardPadding, y+8, math.Max(8, barWidth*float64(item.Total)/float64(items[0].Total)), 8, 4, 4, fmt.Sprintf("fill: %s", params.Theme.Accent))
+	}
+	if len(items) == 0 {
+		canvas.Text(statsCardPadding, statsCardHeaderSize+10, "No coding activity yet", `class="muted"`)
+	}
+
+	return endStatsCard(canvas, buf), nil
+}
+
+func (srv *StatsCardService) getWeekCard(params *models.StatsCardParams) (string, error) {
+	tz := params.User.TZ()
+	hourly, err := getHourlyTotals(srv.durationService, params.User, params.From, params.To, &models.Filters{})
+	if err != nil {
+		return "", err
+	}
+
+	days := make([]time.Time, 0, 7)
+	for day := params.From.In(tz); day.Before(params.To); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	daily := make(map[string]time.Duration, len(days))
+	var total, maxDaily time.Duration
+	for hour, d := range hourly {
+		if hour.Before(params.From) || !hour.Before(params.To) {
+			continue
+		}
+		key := hour.In(tz).Format(time.DateOnly)
+		daily[key] += d
+		total += d
+		maxDaily = max(maxDaily, daily[key])
+	}
+
+	const chartHeight, labelHeight = 80.0, 20.0
+	canvas, buf := startStatsCard(params, statsCardHeaderSize+20+chartHeight+labelHeight+10)
+	canvas.Text(statsCardPadding, statsCardHeaderSize, fmt.Sprintf("Total: %s", helpers.FmtWakatimeDuration(total)), `class="muted"`)
+
+	slot := float64(statsCardWidth-2*statsCardPadding) / float64(max(len(days), 1))
+	baseline := statsCardHeaderSize + 20 + chartHeight
+	for i, day := range days {
+		d := daily[day.Format(time.DateOnly)]
+		x := statsCardPadding + float64(i)*slot
+		height := 2.0
+		if maxDaily > 0 {
+			height = math.Max(height, chartHeight*float64(d)/float64(maxDaily))
+		}
+		canvas.Group()
+		canvas.Title(fmt.Sprintf("%s on %s", helpers.FmtWakatimeDuration(d), helpers.FormatDateHuman(day)))
+		canvas.Roundrect(x+slot*0.2, baseline-height, slot*0.6, height, 3, 3, fmt.Sprintf("fill: %s", params.Theme.Accent))
+		canvas.Gend()
+		canvas.Text(x+slot/2, baseline+labelHeight-4, day.Format("Mon"), `class="muted"`, `text-anchor="middle"`)
+	}
+
+	return endStatsCard(canvas, buf), nil
+}
+
+func (srv *StatsCardService) getGoalCard(params *models.StatsCardParams) (string, error) {
+	goal := params.Goal
+	filters := goal.GetGoalSummaryFilter()
+
+	periodSeconds := func(from, to time.Time) (int64, error) {
+		summary, err := srv.summaryService.Generate(summarytypes.NewSummaryRequest(from, to, params.User).WithFilters(filters), summarytypes.DefaultProcessingOptions())
+		if err != nil {
+			return 0, err
+		}
+		return int64(summary.TotalTime().Seconds()), nil
+	}
+
+	actualSeconds, err := periodSeconds(params.From, params.To)
+	if err != nil {
+		return "", err
+	}
+	var previousSeconds int64
+	if goal.IsRelative() {
+		if previousSeconds, err = periodSeconds(goal.PreviousPeriod(params.From)); err != nil {
+			return "", err
+		}
+	}
+	targetSeconds := goal.TargetSeconds(previousSeconds)
+
+	progress := 1.0
+	if targetSeconds > 0 {
+		progress = float64(actualSeconds) / float64(targetSeconds)
+	}
+	barColor := params.Theme.Accent
+	if goal.IsLimit() && actualSeconds > targetSeconds {
+		barColor = statsCardFailedColor
+	}
+
+	periodText := "Today"
+	if goal.Delta == models.GoalDeltaWeek {
+		periodText = "This week"
+	}
+	targetText := "of"
+	if goal.IsLimit() {
+		targetText = "of at most"
+	}
+
+	canvas, buf := startStatsCard(params, statsCardHeaderSize+95)
+
+	barWidth := float64(statsCardWidth - 2*statsCardPadding)
+	canvas.Text(statsCardPadding, statsCardHeaderSize, periodText, `class="muted"`)
+	canvas.Text(statsCardWidth-statsCardPadding, statsCardHeaderSize, fmt.Sprintf("%.0f%%", progress*100), `class="value"`, `text-anchor="end"`)
+	canvas.Roundrect(statsCardPadding, statsCardHeaderSize+12, barWidth, 12, 6, 6, fmt.Sprintf("fill: %s", params.Theme.Border))
+	canvas.Roundrect(statsCardPadding, statsCardHeaderSize+12, math.Max(12, barWidth*math.Min(progress, 1)), 12, 6, 6, fmt.Sprintf("fill: %s", barColor))
+	canvas.Text(statsCardPadding, statsCardHeaderSize+50, fmt.Sprintf("%s %s %s", helpers.FmtWakatimeDuration(time.Duration(actualSeconds)*time.Second), targetText, helpers.FmtWakatimeDuration(time.Duration(targetSeconds)*time.Second)))
+	canvas.Text(statsCardPadding, statsCardHeaderSize+75, fmt.Sprintf("Current streak: %d · Longest streak: %d", goal.CurrentStreak, goal.LongestStreak), `class="muted"`)
+
+	return endStatsCard(canvas, buf), nil
+}
+
+func startStatsCard(params *models.StatsCardParams, height float64) (*svg.SVG, *bytes.Buffer) {
+	theme := params.Theme
+	buf := &bytes.Buffer{}
+
+	canvas := svg.New(buf)
+	canvas.Start(statsCardWidth, height)
+	canvas.Style("text/css",
+		fmt.Sprintf("text { font-family: 'Segoe UI', Ubuntu, 'Helvetica Neue', Sans-Serif; font-size: 0.8rem; fill: %s; }", theme.Text),
+		fmt.Sprintf(".title { font-size: 1.1rem; font-weight: 600; fill: %s; }", theme.Title),
+		fmt.Sprintf(".muted { fill: %s; }", theme.Muted),
+		".value { font-weight: 600; }",
+	)
+
+	border := fmt.Sprintf("stroke: %s", theme.Border)
+	if theme.HideBorder {
+		border = "stroke-opacity: 0"
+	}
+	canvas.Roundrect(0.5, 0.5, statsCardWidth-1, height-1, 4.5, 4.5, fmt.Sprintf("fill: %s; %s", theme.Background, border))
+
+	canvas.Textspan(statsCardPadding, 32, "", `class="title"`)
+	fmt.Fprint(canvas.Writer, statsCardTitlePlaceholder)
+	canvas.TextEnd()
+
+	return canvas, buf
+}
+
+// withStatsCardTitle fills in the card's custom title, or its default one
+func withStatsCardTitle(card string, params *models.StatsCardParams) string {
+	title := params.Title
+	if title == "" {
+		switch params.Card {
+		case models.StatsCardLanguages:
+			title = "Most Used Languages"
+		case models.StatsCardProjects:
+			title = "Top Projects"
+		case models.StatsCardWeek:
+			title = "Last 7 Days"
+		case models.StatsCardGoal:
+			title = params.Goal.DisplayTitle()
+		}
+	}
+
+	var escaped strings.Builder
+	xml.Escape(&escaped, []byte(truncateStatsCardText(title, statsCardMaxChars+6)))
+	return strings.Replace(card, statsCardTitlePlaceholder, escaped.String(), 1)
+}
+
+func endStatsCard(canvas *svg.SVG, buf *bytes.Buffer) string {
+	canvas.End()
+	return buf.String()
+}
+
+// topCardItems returns up to limit items with the most time spent, longest first
+func topCardItems(items models.SummaryItems, limit int) models.SummaryItems {
+	sorted := slices.Clone(items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Total > sorted[j].Total
+	})
+	sorted = slices.DeleteFunc(sorted, func(item *models.SummaryItem) bool {
+		return item.Total <= 0
+	})
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
+
+func truncateStatsCardText(s string, maxChars int) string {
+	if runes := []rune(s); len(runes) > maxChars {
+		return string(runes[:maxChars-1]) + "…"
+	}
+	return s
+}
@@ -49,7 +49,7 @@ func NewSummaryService(db *gorm.DB) *SummaryService {
 		projectLabelService: projectLabelService,
 	}
 
-	sub1 := srv.eventBus.Subscribe(0, config.TopicProjectLabel)
+	sub1 := srv.eventBus.Subscribe(0, config.TopicProjectLabel, config.TopicAlias, config.TopicLanguageMapping)
 	go func(sub *hub.Subscription) {
 		for m := range sub.Receiver {
 			srv.invalidateUserCache(m.Fields[config.FieldUserId].(string))
@@ -71,7 +71,7 @@ func NewTestSummaryService(summaryRepo repositories.ISummaryRepository, heartbea
 		projectLabelService: projectLabelService,
 	}
 
-	sub1 := srv.eventBus.Subscribe(0, config.TopicProjectLabel)
+	sub1 := srv.eventBus.Subscribe(0, config.TopicProjectLabel, config.TopicAlias, config.TopicLanguageMapping)
 	go func(sub *hub.Subscription) {
 		for m := range sub.Receiver {
 			srv.invalidateUserCache(m.Fields[config.FieldUserId].(string))
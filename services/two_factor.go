@@ -0,0 +1,324 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+	"gorm.io/gorm"
+)
+
+const (
+	twoFactorIssuer        = "Wakana"
+	twoFactorChallengeTTL  = 5 * time.Minute
+	twoFactorMaxFailures   = 5
+	twoFactorLockDuration  = 15 * time.Minute
+	twoFactorTokenPurpose  = "two_factor"
+	recoveryCodeHalfLength = 3 // random bytes, hex-encoded, per half of a code
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolled = errors.New("two-factor enrollment has not been started")
+	ErrTwoFactorInvalidCode = errors.New("invalid two-factor code")
+	ErrTwoFactorLocked      = errors.New("too many invalid two-factor codes, try again later")
+	ErrTwoFactorRequired    = errors.New("two-factor authentication is required on this server")
+)
+
+type TwoFactorService struct {
+	config *config.Config
+	cache  *cache.Cache // counts recent failed attempts per user
+	db     *gorm.DB
+}
+
+func NewTwoFactorService(db *gorm.DB) *TwoFactorService {
+	return &TwoFactorService{
+		config: config.Get(),
+		cache:  cache.New(twoFactorLockDuration, 2*twoFactorLockDuration),
+		db:     db,
+	}
+}
+
+func (srv *TwoFactorService) GetStatus(user *models.User) (*models.TwoFactorStatus, error) {
+	tf, err := srv.get(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.TwoFactorStatus{Required: srv.config.Security.RequireTwoFactor}
+	if tf.IsEnabled() {
+		status.Enabled = true
+		status.RecoveryCodesLeft = len(tf.RecoveryCodes)
+	}
+	return status, nil
+}
+
+// BeginEnrollment generates a new secret for the user, which takes effect once confirmed by a valid code.
+// Starting over replaces a previous, unconfirmed secret.
+func (srv *TwoFactorService) BeginEnrollment(user *models.User) (*models.TwoFactorEnrollment, error) {
+	tf, err := srv.get(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.IsEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := utils.GenerateTotpSecret()
+	if err != nil {
+		return nil, err
+	}
+	tf = &models.TwoFactor{UserID: user.ID, Secret: secret, RecoveryCodes: []string{}}
+	if err := srv.db.Save(tf).Error; err != nil {
+		return nil, err
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.ID
+	}
+	return &models.TwoFactorEnrollment{
+		Secret: secret,
+		Uri:    utils.TotpUri(twoFactorIssuer, account, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables the second factor if the code matches the secret and returns a fresh set of recovery codes
+func (srv *TwoFactorService) ConfirmEnrollment(user *models.User, code string) ([]string, error) {
+	tf, err := srv.get(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if tf.IsEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	if err := srv.checkTotp(tf, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := srv.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabledAt := models.CustomTime(time.Now())
+	tf.EnabledAt, tf.RecoveryCodes = &enabledAt, hashes
+	if err := srv.db.Save(tf).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a totp or recovery code, the latter being used up. Repeated failures lock the user out for a while.
+func (srv *TwoFactorService) Verify(user *models.User, code string) error {
+	tf, err := srv.get(user.ID)
+	if err != nil {
+		return err
+	}
+	if !tf.IsEnabled() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if len(strings.ReplaceAll(strings.TrimSpace(code), " ", "")) > utils.TotpDigits {
+		return srv.checkRecoveryCode(tf, code)
+	}
+	return srv.checkTotp(tf, code)
+}
+
+// RegenerateRecoveryCodes replaces all remaining recovery codes by new ones, given a valid code
+func (srv *TwoFactorService) RegenerateRecoveryCodes(user *models.User, code string) ([]string, error) {
+	if err := srv.Verify(user, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := srv.generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := srv.db.Model(&models.TwoFactor{UserID: user.ID}).Select("recovery_codes").Updates(&models.TwoFactor{RecoveryCodes: hashes}).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable removes the second factor, given a valid code, unless the server requires one
+func (srv *TwoFactorService) Disable(user *models.User, code string) error {
+	if srv.config.Security.RequireTwoFactor {
+		return ErrTwoFactorRequired
+	}
+	if err := srv.Verify(user, code); err != nil {
+		return err
+	}
+	return srv.db.Delete(&models.TwoFactor{UserID: user.ID}).Error
+}
+
+// Challenge returns a challenge to complete before logging the user in, or nil if no second factor is needed
+func (srv *TwoFactorService) Challenge(user *models.User) (*models.TwoFactorChallenge, error) {
+	tf, err := srv.get(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !tf.IsEnabled() && !srv.config.Security.RequireTwoFactor {
+		return nil, nil
+	}
+
+	expiresAt := time.Now().Add(twoFactorChallengeTTL)
+	claims := jwt.MapClaims{
+		"uid":     user.ID,
+		"purpose": twoFactorTokenPurpose,
+		"exp":     expiresAt.Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(srv.challengeKey())
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorChallenge{
+		Token:              token,
+		ExpiresAt:          models.CustomTime(expiresAt),
+		EnrollmentRequired: !tf.IsEnabled(),
+	}, nil
+}
+
+// ResolveChallenge returns the id of the user a challenge token was issued to, unless it is invalid or expired
+func (srv *TwoFactorService) ResolveChallenge(token string) (string, error) {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return srv.challengeKey(), nil
+	}); err != nil {
+		return "", err
+	}
+
+	userId, _ := claims["uid"].(string)
+	if purpose, _ := claims["purpose"].(string); purpose != twoFactorTokenPurpose || userId == "" {
+		return "", errors.New("invalid two-factor token")
+	}
+	return userId, nil
+}
+
+// challengeKey is derived from the jwt secret, so challenge tokens are never accepted as session tokens
+func (srv *TwoFactorService) challengeKey() []byte {
+	return []byte(utils.SignValues(srv.config.Security.JWT_SECRET, twoFactorTokenPurpose))
+}
+
+func (srv *TwoFactorService) get(userId string) (*models.TwoFactor, error) {
+	tf := &models.TwoFactor{}
+	if err := srv.db.Where(&models.TwoFactor{UserID: userId}).First(tf).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return tf, nil
+}
+
+func (srv *TwoFactorService) checkTotp(tf *models.TwoFactor, code string) error {
+	if srv.isLocked(tf.UserID) {
+		return ErrTwoFactorLocked
+	}
+
+	step, ok := utils.ValidateTotp(tf.Secret, code, time.Now())
+	if !ok || step <= tf.LastUsedStep {
+		srv.recordFailure(tf.UserID)
+		return ErrTwoFactorInvalidCode
+	}
+
+	// conditional, so concurrent requests can't both use the same code
+	result := srv.db.Model(&models.TwoFactor{}).
+		Where("user_id = ? and last_used_step < ?", tf.UserID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorInvalidCode
+	}
+	tf.LastUsedStep = step
+	return nil
+}
+
+func (srv *TwoFactorService) checkRecoveryCode(tf *models.TwoFactor, code string) error {
+	if srv.isLocked(tf.UserID) {
+		return ErrTwoFactorLocked
+	}
+
+	previousCodes, err := json.Marshal(tf.RecoveryCodes)
+	if err != nil {
+		return err
+	}
+	if !tf.UseRecoveryCode(code) {
+		srv.recordFailure(tf.UserID)
+		return ErrTwoFactorInvalidCode
+	}
+
+	// conditional, so concurrent requests can't both use the same code
+	result := srv.db.Model(&models.TwoFactor{}).
+		Where("user_id = ? and recovery_codes = ?", tf.UserID, string(previousCodes)).
+		Select("recovery_codes").
+		Updates(&models.TwoFactor{RecoveryCodes: tf.RecoveryCodes})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return ErrTwoFactorInvalidCode
+	}
+	return nil
+}
+
+func (srv *TwoFactorService) isLocked(userId string) bool {
+	failures, found := srv.cache.Get(srv.failuresCacheKey(userId))
+	return found && failures.(int) >= twoFactorMaxFailures
+}
+
+func (srv *TwoFactorService) recordFailure(userId string) {
+	key := srv.failuresCacheKey(userId)
+	if _, err := srv.cache.IncrementInt(key, 1); err != nil {
+		srv.cache.SetDefault(key, 1)
+	}
+}
+
+func (srv *TwoFactorService) failuresCacheKey(userId string) string {
+	return "two-factor-failures-" + userId
+}
+
+// generateRecoveryCodes returns a set of plain codes, formatted for display, along with their hashes
+func (srv *TwoFactorService) generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, models.TwoFactorRecoveryCodeCount)
+	hashes := make([]string, models.TwoFactorRecoveryCodeCount)
+	for i := range codes {
+		first, err := utils.GenerateRandomPassword(recoveryCodeHalfLength)
+		if err != nil {
+			return nil, nil, err
+		}
+		second, err := utils.GenerateRandomPassword(recoveryCodeHalfLength)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i] = first + "-" + second
+		hashes[i] = models.HashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+type ITwoFactorService interface {
+	GetStatus(user *models.User) (*models.TwoFactorStatus, error)
+	BeginEnrollment(user *models.User) (*models.TwoFactorEnrollment, error)
+	ConfirmEnrollment(user *models.User, code string) ([]string, error)
+	Verify(user *models.User, code string) error
+	RegenerateRecoveryCodes(user *models.User, code string) ([]string, error)
+	Disable(user *models.User, code string) error
+	Challenge(user *models.User) (*models.TwoFactorChallenge, error)
+	ResolveChallenge(token string) (string, error)
+}
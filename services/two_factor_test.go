@@ -0,0 +1,110 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const invalidTotpCode = "abcdef" // never matches, as totp codes consist of digits only
+
+type TwoFactorServiceTestSuite struct {
+	suite.Suite
+	TestUser *models.User
+	DB       *gorm.DB
+}
+
+func (suite *TwoFactorServiceTestSuite) SetupSuite() {
+	config.Set(config.Empty())
+	suite.TestUser = &models.User{ID: "testuser01"}
+}
+
+func (suite *TwoFactorServiceTestSuite) BeforeTest(suiteName, testName string) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	suite.Require().Nil(err)
+	suite.Require().Nil(db.AutoMigrate(&models.User{}, &models.TwoFactor{}))
+	suite.Require().Nil(db.Create(suite.TestUser).Error)
+
+	suite.DB = db
+}
+
+func TestTwoFactorServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(TwoFactorServiceTestSuite))
+}
+
+func (suite *TwoFactorServiceTestSuite) TestTwoFactorService_Verify_TotpCodeUsedOnce() {
+	sut := NewTwoFactorService(suite.DB)
+	secret, recoveryCodes, step := suite.enroll(sut)
+	assert.Len(suite.T(), recoveryCodes, models.TwoFactorRecoveryCodeCount)
+
+	// code was used to confirm the enrollment already
+	assert.ErrorIs(suite.T(), sut.Verify(suite.TestUser, suite.totpCode(secret, step)), ErrTwoFactorInvalidCode)
+
+	assert.Nil(suite.T(), sut.Verify(suite.TestUser, suite.totpCode(secret, step+1)))
+	assert.ErrorIs(suite.T(), sut.Verify(suite.TestUser, suite.totpCode(secret, step+1)), ErrTwoFactorInvalidCode)
+
+	// codes of earlier periods are not accepted anymore either, even though still within the allowed skew
+	assert.ErrorIs(suite.T(), sut.Verify(suite.TestUser, suite.totpCode(secret, step)), ErrTwoFactorInvalidCode)
+}
+
+func (suite *TwoFactorServiceTestSuite) TestTwoFactorService_Verify_RecoveryCodeUsedOnce() {
+	sut := NewTwoFactorService(suite.DB)
+	_, recoveryCodes, _ := suite.enroll(sut)
+
+	assert.Nil(suite.T(), sut.Verify(suite.TestUser, recoveryCodes[0]))
+	assert.ErrorIs(suite.T(), sut.Verify(suite.TestUser, recoveryCodes[0]), ErrTwoFactorInvalidCode)
+
+	status, err := sut.GetStatus(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), models.TwoFactorRecoveryCodeCount-1, status.RecoveryCodesLeft)
+
+	assert.Nil(suite.T(), sut.Verify(suite.TestUser, recoveryCodes[1]))
+}
+
+func (suite *TwoFactorServiceTestSuite) TestTwoFactorService_Verify_LockedAfterFailures() {
+	sut := NewTwoFactorService(suite.DB)
+	secret, recoveryCodes, step := suite.enroll(sut)
+
+	for i := 0; i < twoFactorMaxFailures; i++ {
+		assert.ErrorIs(suite.T(), sut.Verify(suite.TestUser, invalidTotpCode), ErrTwoFactorInvalidCode)
+	}
+
+	// even valid codes are rejected while locked, without being used up
+	assert.ErrorIs(suite.T(), sut.Verify(suite.TestUser, suite.totpCode(secret, step+1)), ErrTwoFactorLocked)
+	assert.ErrorIs(suite.T(), sut.Verify(suite.TestUser, recoveryCodes[0]), ErrTwoFactorLocked)
+
+	status, err := sut.GetStatus(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), models.TwoFactorRecoveryCodeCount, status.RecoveryCodesLeft)
+
+	// other users are not affected
+	otherUser := &models.User{ID: "testuser02"}
+	suite.Require().Nil(suite.DB.Create(otherUser).Error)
+	assert.ErrorIs(suite.T(), sut.Verify(otherUser, invalidTotpCode), ErrTwoFactorNotEnabled)
+	assert.False(suite.T(), sut.isLocked(otherUser.ID))
+}
+
+// enroll enables the second factor for the test user, confirming it with the code of the current period
+func (suite *TwoFactorServiceTestSuite) enroll(sut *TwoFactorService) (string, []string, int64) {
+	enrollment, err := sut.BeginEnrollment(suite.TestUser)
+	suite.Require().Nil(err)
+
+	step := utils.TotpStep(time.Now())
+	recoveryCodes, err := sut.ConfirmEnrollment(suite.TestUser, suite.totpCode(enrollment.Secret, step))
+	suite.Require().Nil(err)
+	return enrollment.Secret, recoveryCodes, step
+}
+
+func (suite *TwoFactorServiceTestSuite) totpCode(secret string, step int64) string {
+	code, err := utils.TotpCode(secret, step)
+	suite.Require().Nil(err)
+	return code
+}
@@ -21,22 +21,29 @@ import (
 )
 
 type UserService struct {
-	config      *config.Config
-	cache       *cache.Cache
-	eventBus    *hub.Hub
-	mailService mail.IMailService
-	repository  repositories.IUserRepository
+	config          *config.Config
+	cache           *cache.Cache
+	eventBus        *hub.Hub
+	mailService     mail.IMailService
+	apiTokenService IApiTokenService
+	machineService  IMachineService
+	sessionService  ISessionService
+	repository      repositories.IUserRepository
 }
 
 func NewUserService(db *gorm.DB) *UserService {
 	mailService := mail.NewMailService()
+	notificationService := NewNotificationService(db)
 	userRepo := repositories.NewUserRepository(db)
 	srv := &UserService{
-		config:      config.Get(),
-		eventBus:    config.EventBus(),
-		cache:       cache.New(1*time.Hour, 2*time.Hour),
-		mailService: mailService,
-		repository:  userRepo,
+		config:          config.Get(),
+		eventBus:        config.EventBus(),
+		cache:           cache.New(1*time.Hour, 2*time.Hour),
+		mailService:     mailService,
+		apiTokenService: NewApiTokenService(db),
+		machineService:  NewMachineService(db),
+		sessionService:  NewSessionService(db),
+		repository:      userRepo,
 	}
 
 	sub1 := srv.eventBus.Subscribe(0, config.EventWakatimeFailure)
@@ -51,7 +58,7 @@ func NewUserService(db *gorm.DB) *UserService {
 				config.Log().Error("failed to set wakatime api key for user", "userID", user.ID)
 			}
 
-			if user.Email != "" {
+			if user.Email != "" && notificationService.IsEnabled(user, models.NotificationWakatimeFailure, models.NotificationChannelEmail) {
 				if err := mailService.SendWakatimeFailureNotification(user, n); err != nil {
 					config.Log().Error("failed to send wakatime failure notification mail to user", "userID", user.ID)
 				} else {
@@ -100,6 +107,57 @@ func (srv *UserService) GetUserByKey(key string) (*models.User, error) {
 	return u, nil
 }
 
+// GetUserByApiToken returns the owner of the given personal access token along with the token itself and records its use
+func (srv *UserService) GetUserByApiToken(plain, ip string) (*models.User, *models.ApiToken, error) {
+	token, err := srv.apiTokenService.GetByToken(plain)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u, err := srv.GetUserById(token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := srv.apiTokenService.MarkUsed(token, ip); err != nil {
+		config.Log().Error("failed to record api token usage", "tokenID", token.ID, "error", err)
+	}
+	return u, token, nil
+}
+
+// GetUserByMachineKey returns the owner of the given machine key along with the machine it belongs to
+func (srv *UserService) GetUserByMachineKey(plain string) (*models.User, *models.Machine, error) {
+	machine, err := srv.machineService.GetByKey(plain)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u, err := srv.GetUserById(machine.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return u, machine, nil
+}
+
+// GetUserBySession returns the owner of the given login session, unless revoked or expired, along with the session itself
+// and records its activity
+func (srv *UserService) GetUserBySession(id, ip string) (*models.User, *models.Session, error) {
+	session, err := srv.sessionService.GetActive(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u, err := srv.GetUserById(session.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := srv.sessionService.MarkSeen(session, ip); err != nil {
+		config.Log().Error("failed to record session activity", "sessionID", session.ID, "error", err)
+	}
+	return u, session, nil
+}
+
 func (srv *UserService) GetUserByEmail(email string) (*models.User, error) {
 	if email == "" {
 		return nil, errors.New("email must not be empty")
@@ -294,6 +352,9 @@ func (srv *UserService) notifyDelete(user *models.User) {
 type IUserService interface {
 	GetUserById(string) (*models.User, error)
 	GetUserByKey(string) (*models.User, error)
+	GetUserByApiToken(string, string) (*models.User, *models.ApiToken, error)
+	GetUserByMachineKey(string) (*models.User, *models.Machine, error)
+	GetUserBySession(string, string) (*models.User, *models.Session, error)
 	GetUserByEmail(string) (*models.User, error)
 	GetUserByResetToken(string) (*models.User, error)
 	GetUserByStripeCustomerId(string) (*models.User, error)
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+	"gorm.io/gorm"
+)
+
+const webhookDeliveryLogLimit = 100
+
+type WebhookService struct {
+	config *config.Config
+	cache  *cache.Cache
+	db     *gorm.DB
+}
+
+func NewWebhookService(db *gorm.DB) *WebhookService {
+	return &WebhookService{
+		config: config.Get(),
+		// webhooks are looked up for every single event, but may be modified from a different process, so keep them only briefly
+		cache: cache.New(1*time.Minute, 5*time.Minute),
+		db:    db,
+	}
+}
+
+func (srv *WebhookService) Create(user *models.User, newWebhook *models.NewWebhook) (*models.Webhook, error) {
+	if !newWebhook.IsValid() {
+		return nil, errors.New("invalid webhook")
+	}
+
+	secret := newWebhook.Secret
+	if secret == "" {
+		var err error
+		if secret, err = utils.GenerateRandomPassword(32); err != nil {
+			return nil, err
+		}
+	}
+
+	webhook := &models.Webhook{
+		ID:          uuid.Must(uuid.NewV4()).String(),
+		UserID:      user.ID,
+		Url:         newWebhook.Url,
+		Secret:      secret,
+		Events:      newWebhook.Events,
+		Description: newWebhook.Description,
+		IsActive:    newWebhook.IsActive == nil || *newWebhook.IsActive,
+	}
+	if err := srv.db.Create(webhook).Error; err != nil {
+		return nil, err
+	}
+	webhook.PlainSecret = secret
+	srv.cache.Delete(user.ID)
+	return webhook, nil
+}
+
+func (srv *WebhookService) GetById(id string) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	if err := srv.db.Where(&models.Webhook{ID: id}).First(webhook).Error; err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (srv *WebhookService) FetchUserWebhooks(userID string) ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	if err := srv.db.
+		Where(&models.Webhook{UserID: userID}).
+		Order("created_at asc").
+		Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetSubscribers returns the user's active webhooks subscribed to the given event
+func (srv *WebhookService) GetSubscribers(userID, event string) ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	if cached, found := srv.cache.Get(userID); found {
+		webhooks = cached.([]*models.Webhook)
+	} else {
+		if err := srv.db.
+			Where("user_id = ?", userID).
+			Where("is_active = ?", true).
+			Find(&webhooks).Error; err != nil {
+			return nil, err
+		}
+		srv.cache.SetDefault(userID, webhooks)
+	}
+
+	subscribers := make([]*models.Webhook, 0)
+	for _, w := range webhooks {
+		if w.Subscribes(event) {
+			subscribers = append(subscribers, w)
+		}
+	}
+	return subscribers, nil
+}
+
+func (srv *WebhookService) Update(webhook *models.Webhook, update *models.NewWebhook) (*models.Webhook, error) {
+	rotated := update.Secret != ""
+	if !rotated {
+		update.Secret = webhook.Secret
+	}
+	if !update.IsValid() {
+		return nil, errors.New("invalid webhook")
+	}
+
+	webhook.Url = update.Url
+	webhook.Secret = update.Secret
+	webhook.Events = update.Events
+	webhook.Description = update.Description
+	if update.IsActive != nil {
+		webhook.IsActive = *update.IsActive
+	}
+
+	if err := srv.db.Model(webhook).Select("url", "secret", "events", "description", "is_active", "updated_at").Updates(webhook).Error; err != nil {
+		return nil, err
+	}
+	if rotated {
+		webhook.PlainSecret = webhook.Secret
+	}
+	srv.cache.Delete(webhook.UserID)
+	return webhook, nil
+}
+
+func (srv *WebhookService) Delete(webhook *models.Webhook) error {
+	if err := srv.db.
+		Where("webhook_id = ?", webhook.ID).
+		Delete(&models.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	if err := srv.db.Delete(webhook).Error; err != nil {
+		return err
+	}
+	srv.cache.Delete(webhook.UserID)
+	return nil
+}
+
+// CreateDelivery adds a pending delivery of the given json-encoded event data to the webhook's log
+func (srv *WebhookService) CreateDelivery(webhook *models.Webhook, event, payload string) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{
+		ID:        uuid.Must(uuid.NewV4()).String(),
+		WebhookID: webhook.ID,
+		Event:     event,
+		Payload:   payload,
+		Status:    models.WebhookDeliveryPending,
+	}
+	if err := srv.db.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Redeliver creates a new delivery of a previous delivery's event, the original is kept untouched
+func (srv *WebhookService) Redeliver(delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	redelivery := &models.WebhookDelivery{
+		ID:           uuid.Must(uuid.NewV4()).String(),
+		WebhookID:    delivery.WebhookID,
+		Event:        delivery.Event,
+		Payload:      delivery.Payload,
+		Status:       models.WebhookDeliveryPending,
+		RedeliveryOf: delivery.ID,
+	}
+	if err := srv.db.Create(redelivery).Error; err != nil {
+		return nil, err
+	}
+	return redelivery, nil
+}
+
+func (srv *WebhookService) GetDeliveryById(id string) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	if err := srv.db.
+		Where(&models.WebhookDelivery{ID: id}).
+		Preload("Webhook").
+		First(delivery).Error; err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// FetchDeliveries returns the webhook's most recent deliveries, newest first
+func (srv *WebhookService) FetchDeliveries(webhookID string) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	if err := srv.db.
+		Where(&models.WebhookDelivery{WebhookID: webhookID}).
+		Order("created_at desc").
+		Limit(webhookDeliveryLogLimit).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// UpdateDelivery persists the outcome of a delivery attempt
+func (srv *WebhookService) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	return srv.db.Model(delivery).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"response_status": delivery.ResponseStatus,
+		"error":           delivery.Error,
+		"delivered_at":    delivery.DeliveredAt,
+	}).Error
+}
+
+func (srv *WebhookService) DeleteDeliveriesBefore(t time.Time) error {
+	return srv.db.
+		Where("created_at < ?", t).
+		Delete(&models.WebhookDelivery{}).Error
+}
+
+type IWebhookService interface {
+	Create(user *models.User, newWebhook *models.NewWebhook) (*models.Webhook, error)
+	GetById(id string) (*models.Webhook, error)
+	FetchUserWebhooks(userID string) ([]*models.Webhook, error)
+	GetSubscribers(userID, event string) ([]*models.Webhook, error)
+	Update(webhook *models.Webhook, update *models.NewWebhook) (*models.Webhook, error)
+	Delete(webhook *models.Webhook) error
+	CreateDelivery(webhook *models.Webhook, event, payload string) (*models.WebhookDelivery, error)
+	Redeliver(delivery *models.WebhookDelivery) (*models.WebhookDelivery, error)
+	GetDeliveryById(id string) (*models.WebhookDelivery, error)
+	FetchDeliveries(webhookID string) ([]*models.WebhookDelivery, error)
+	UpdateDelivery(delivery *models.WebhookDelivery) error
+	DeleteDeliveriesBefore(t time.Time) error
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"time"
+
+	svg "github.com/ajstarks/svgo/float"
+	"github.com/duke-git/lancet/v2/condition"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/helpers"
+	"github.com/muety/wakapi/models"
+	summarytypes "github.com/muety/wakapi/types"
+)
+
+const (
+	wrappedCardWidth     = 480
+	wrappedCardHeight    = 220
+	wrappedCardBgDark    = "#111827"
+	wrappedCardBgLight   = "#FFFFFF"
+	wrappedCardMutedText = "#9CA3AF"
+	wrappedCardMaxChars  = 26
+)
+
+// wrapped reports sent for each kind of scheduled notification instead of a regular report
+var wrappedReportKinds = map[string]string{
+	models.NotificationMonthlyReport: models.WrappedPeriodMonth,
+	models.NotificationYearlyReport:  models.WrappedPeriodYear,
+}
+
+// GetWrappedReport builds the user's wrapped report of the month or year containing the given date, or of the latest
+// complete one, if no date is given
+func (srv *ReportService) GetWrappedReport(user *models.User, period string, date time.Time, skipCache bool) (*models.WrappedReport, error) {
+	if !models.IsValidWrappedPeriod(period) {
+		return nil, fmt.Errorf("unsupported period '%s'", period)
+	}
+
+	var from, to time.Time
+	if date.IsZero() {
+		from, to = models.LatestWrappedPeriodRange(period, time.Now().In(user.TZ()))
+	} else {
+		from, to = models.WrappedPeriodRange(period, date.In(user.TZ()))
+	}
+
+	cacheKey := fmt.Sprintf("wrapped_%s_%s_%d", user.ID, period, from.Unix())
+	if result, found := srv.cache.Get(cacheKey); found && !skipCache {
+		return result.(*models.WrappedReport), nil
+	}
+
+	previousFrom, _ := models.WrappedPeriodRange(period, from.Add(-1*time.Second))
+
+	current, err := srv.summaryService.Generate(summarytypes.NewSummaryRequest(from, to, user), summarytypes.DefaultProcessingOptions())
+	if err != nil {
+		return nil, err
+	}
+	previous, err := srv.summaryService.Generate(summarytypes.NewSummaryRequest(previousFrom, from, user), summarytypes.DefaultProcessingOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	hourly, err := getHourlyTotals(srv.durationService, user, from, to, &models.Filters{})
+	if err != nil {
+		return nil, err
+	}
+
+	var knownLanguages []string
+	if err := srv.db.
+		Model(&models.SummaryItem{}).
+		Distinct("summary_items.key").
+		Joins("JOIN summaries ON summaries.id = summary_items.summary_id").
+		Where("summaries.user_id = ? AND summaries.to_time <= ? AND summary_items.type = ?", user.ID, from, models.SummaryLanguage).
+		Pluck("summary_items.key", &knownLanguages).Error; err != nil {
+		return nil, err
+	}
+
+	report := models.NewWrappedReport(period, from, to, user, current, previous, hourly, knownLanguages)
+	srv.cache.SetDefault(cacheKey, report)
+	return report, nil
+}
+
+// SendWrappedReport mails the user's wrapped report of the latest complete month or year
+func (srv *ReportService) SendWrappedReport(user *models.User, period string) error {
+	if user.Email == "" {
+		slog.Warn("not generating wrapped report as no e-mail address is set", "userID", user.ID)
+		return nil
+	}
+
+	slog.Info("generating wrapped report for user", "userID", user.ID, "period", period)
+
+	report, err := srv.GetWrappedReport(user, period, time.Time{}, false)
+	if err != nil {
+		config.Log().Error("failed to generate wrapped report", "userID", user.ID, "period", period, "error", err)
+		return err
+	}
+
+	if err := srv.mailService.SendWrappedReport(user, report); err != nil {
+		config.Log().Error("failed to send wrapped report", "userID", user.ID, "period", period, "error", err)
+		return err
+	}
+
+	slog.Info("sent wrapped report to user", "userID", user.ID, "period", period)
+	return nil
+}
+
+// GetWrappedCard renders the user's wrapped report as a shareable svg card
+func (srv *ReportService) GetWrappedCard(user *models.User, period string, date time.Time, darkTheme, skipCache bool) (string, error) {
+	report, err := srv.GetWrappedReport(user, period, date, skipCache)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		colorBg    = condition.TernaryOperator[bool, string](darkTheme, wrappedCardBgDark, wrappedCardBgLight)
+		colorText  = condition.TernaryOperator[bool, string](darkTheme, textDark, textLight)
+		colorLines = condition.TernaryOperator[bool, string](darkTheme, colorMinDark, colorMinLight)
+	)
+
+	buf := &bytes.Buffer{}
+
+	canvas := svg.New(buf)
+	canvas.Start(wrappedCardWidth, wrappedCardHeight)
+	canvas.Style("text/css",
+		fmt.Sprintf("text { font-family: 'Source Sans 3', Roboto, Helvetica, Arial, sans-serif; font-size: 0.8rem; fill: %s; }", colorText),
+		fmt.Sprintf(".title { font-size: 1.2rem; font-weight: 600; fill: %s; }", colorMaxDark),
+		fmt.Sprintf(".label { fill: %s; }", wrappedCardMutedText),
+		".value { font-size: 1rem; font-weight: 600; }",
+	)
+	canvas.Roundrect(0.5, 0.5, wrappedCardWidth-1, wrappedCardHeight-1, 6, 6, fmt.Sprintf("fill: %s; stroke: %s", colorBg, colorLines))
+
+	canvas.Text(20, 34, fmt.Sprintf("%s's %s Wrapped", user.ID, report.Title()), `class="title"`)
+
+	topProject, topLanguage := "-", "-"
+	if len(report.TopProjects) > 0 {
+		topProject = report.TopProjects[0].Key
+	}
+	if len(report.TopLanguages) > 0 {
+		topLanguage = report.TopLanguages[0].Key
+	}
+	total := helpers.FmtWakatimeDuration(report.Total())
+	if change := report.ChangeText(); change != "" {
+		total = fmt.Sprintf("%s (%s)", total, change)
+	}
+	busiest := report.BusiestTimeText()
+	if busiest == "" {
+		busiest = "-"
+	}
+
+	cells := [][2]string{
+		{"Total coding time", total},
+		{"Active days", fmt.Sprintf("%d (longest streak: %d)", report.ActiveDays, report.LongestStreak)},
+		{"Top project", topProject},
+		{"Top language", topLanguage},
+		{"Busiest time", busiest},
+		{"Average per active day", helpers.FmtWakatimeDuration(report.DailyAverage())},
+	}
+	for i, cell := range cells {
+		x, y := float64(20+(i%2)*230), float64(70+(i/2)*50)
+		canvas.Text(x, y, cell[0], `class="label"`)
+		canvas.Text(x, y+20, truncateCardText(cell[1]), `class="value"`)
+	}
+
+	canvas.End()
+
+	return buf.String(), nil
+}
+
+func truncateCardText(s string) string {
+	if runes := []rune(s); len(runes) > wrappedCardMaxChars {
+		return string(runes[:wrappedCardMaxChars-1]) + "…"
+	}
+	return s
+}
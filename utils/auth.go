@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"net/http"
 	"regexp"
 	"strings"
@@ -16,6 +19,7 @@ import (
 
 type AuthJwtClaim struct {
 	UID string `json:"uid"`
+	JTI string `json:"jti"` // id of the session the token was issued for
 }
 
 var md5Regex = regexp.MustCompile(`^[a-f0-9]{32}$`)
@@ -51,19 +55,6 @@ func ExtractBearerAuth(r *http.Request) (key string, err error) {
 	return string(keyBytes), err
 }
 
-func ExtractUserIDFromAuthToken(token string, authSecret string) (key string, err error) {
-	if token == "" {
-		return key, errors.New("invalid api token provided")
-	}
-
-	claims, err := GetTokenClaims(token, authSecret)
-	if err != nil {
-		return key, errors.New("Invalid JWT token")
-	}
-
-	return claims.UID, err
-}
-
 // password hashing
 
 func ComparePassword(hashed, plain, pepper string) bool {
@@ -139,9 +130,23 @@ func GetTokenClaims(token string, jwtSecret string) (*AuthJwtClaim, error) {
 		}
 
 		uid = claims["uid"].(string)
+		jti, _ := claims["jti"].(string)
 
-		return &AuthJwtClaim{UID: uid}, nil
+		return &AuthJwtClaim{UID: uid, JTI: jti}, nil
 	}
 
 	return nil, nil
 }
+
+// url signing
+
+// SignValues computes a hex-encoded hmac over the given values, e.g. to create time-limited download links
+func SignValues(secret string, values ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join(values, "|")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func VerifySignature(secret, signature string, values ...string) bool {
+	return hmac.Equal([]byte(signature), []byte(SignValues(secret, values...)))
+}
@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignValues(t *testing.T) {
+	signature := SignValues("secret", "export-id", "1700000000")
+
+	assert.Len(t, signature, 64)
+	assert.Equal(t, signature, SignValues("secret", "export-id", "1700000000"))
+	assert.NotEqual(t, signature, SignValues("other-secret", "export-id", "1700000000"))
+	assert.NotEqual(t, signature, SignValues("secret", "export-id", "1700000001"))
+
+	assert.True(t, VerifySignature("secret", signature, "export-id", "1700000000"))
+	assert.False(t, VerifySignature("secret", signature, "other-export-id", "1700000000"))
+	assert.False(t, VerifySignature("secret", "", "export-id", "1700000000"))
+}
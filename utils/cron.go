@@ -1,6 +1,12 @@
 package utils
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/robfig/cron/v3"
+)
+
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
 
 func CronPadToSecondly(expr string) string {
 	parts := strings.Split(expr, " ")
@@ -9,3 +15,12 @@ func CronPadToSecondly(expr string) string {
 	}
 	return "0 " + expr
 }
+
+// ParseCron parses standard (minutely) as well as secondly cron expressions and descriptors like @monthly
+func ParseCron(expr string) (cron.Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "@") {
+		expr = CronPadToSecondly(expr)
+	}
+	return cronParser.Parse(expr)
+}
@@ -9,6 +9,9 @@ import (
 	"log/slog"
 )
 
+// '[' is a wildcard in mssql only, but escaping other characters is harmless
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_", "[", "![")
+
 func IsCleanDB(db *gorm.DB) bool {
 	if db.Dialector.Name() == "sqlite" {
 		var count int64
@@ -42,6 +45,11 @@ func WhereNullable(query *gorm.DB, col string, val any) *gorm.DB {
 	return query.Where(fmt.Sprintf("%s = ?", col), val)
 }
 
+// EscapeLike escapes the wildcards of a LIKE pattern in the given string, to be used along with "ESCAPE '!'"
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
 func WithPaging(query *gorm.DB, limit, skip int) *gorm.DB {
 	if limit >= 0 {
 		query = query.Limit(limit)
@@ -8,11 +8,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	nUrl "net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	nerrors "github.com/pkg/errors"
@@ -29,6 +31,8 @@ var (
 	cacheMaxAgeRe *regexp.Regexp
 )
 
+var ErrNonPublicAddress = errors.New("refusing to connect to non-public address")
+
 func init() {
 	cacheMaxAgeRe = regexp.MustCompile(cacheMaxAgePattern)
 }
@@ -226,3 +230,85 @@ func GenerateRandomPassword(length int) (string, error) {
 
 	return password, nil
 }
+
+// ReadRemoteIP returns the client's ip address. Headers set by proxies are only taken into account if the request came
+// from one of the trusted proxies, as they can be set by anyone otherwise.
+func ReadRemoteIP(r *http.Request, trustedProxies []net.IPNet) string {
+	remoteIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	if !isTrustedProxy(remoteIP, trustedProxies) {
+		return remoteIP
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		// proxies append the address they got the request from, so the client is the last one not added by a trusted proxy
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			if hop := strings.TrimSpace(hops[i]); !isTrustedProxy(hop, trustedProxies) || i == 0 {
+				return hop
+			}
+		}
+	}
+	if ip := r.Header.Get("X-Real-Ip"); ip != "" {
+		return ip
+	}
+	return remoteIP
+}
+
+func isTrustedProxy(ip string, trustedProxies []net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPublicIP tells whether the ip is publicly routable, i.e. neither loopback, private, link-local, multicast nor unspecified
+func IsPublicIP(ip net.IP) bool {
+	return ip != nil &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
+
+// NewPublicHttpClient returns a client for requests to user-provided urls, which only connects to public addresses.
+// Addresses are checked after resolving them at dial time, so neither dns rebinding nor redirects get around the check,
+// and redirects aren't followed at all.
+func NewPublicHttpClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !IsPublicIP(net.ParseIP(host)) {
+				return ErrNonPublicAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // the proxy would be dialed instead of the actual host
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	assert.True(t, IsPublicIP(net.ParseIP("93.184.216.34")))
+	assert.True(t, IsPublicIP(net.ParseIP("2606:2800:220:1::")))
+
+	assert.False(t, IsPublicIP(nil))
+	assert.False(t, IsPublicIP(net.ParseIP("127.0.0.1")))
+	assert.False(t, IsPublicIP(net.ParseIP("::1")))
+	assert.False(t, IsPublicIP(net.ParseIP("10.0.0.1")))
+	assert.False(t, IsPublicIP(net.ParseIP("192.168.1.1")))
+	assert.False(t, IsPublicIP(net.ParseIP("fd00::1")))
+	assert.False(t, IsPublicIP(net.ParseIP("169.254.169.254")))
+	assert.False(t, IsPublicIP(net.ParseIP("224.0.0.1")))
+	assert.False(t, IsPublicIP(net.ParseIP("0.0.0.0")))
+}
+
+func TestNewPublicHttpClient_RefusesNonPublic(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	_, err := NewPublicHttpClient(time.Second).Get(server.URL)
+
+	assert.True(t, errors.Is(err, ErrNonPublicAddress))
+	assert.False(t, called)
+}
+
+func TestReadRemoteIP(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/24")
+	trusted := []net.IPNet{*proxies}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "93.184.216.34:1234"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	r.Header.Set("X-Real-Ip", "1.2.3.4")
+	assert.Equal(t, "93.184.216.34", ReadRemoteIP(r, trusted))
+	assert.Equal(t, "93.184.216.34", ReadRemoteIP(r, nil))
+
+	r.RemoteAddr = "10.0.0.1:1234"
+	assert.Equal(t, "1.2.3.4", ReadRemoteIP(r, trusted))
+	assert.Equal(t, "10.0.0.1", ReadRemoteIP(r, nil))
+
+	r.Header.Set("X-Forwarded-For", "6.6.6.6, 1.2.3.4, 10.0.0.2")
+	assert.Equal(t, "1.2.3.4", ReadRemoteIP(r, trusted))
+
+	r.Header.Del("X-Forwarded-For")
+	r.Header.Set("X-Real-Ip", "5.6.7.8")
+	assert.Equal(t, "5.6.7.8", ReadRemoteIP(r, trusted))
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// test vectors from https://datatracker.ietf.org/doc/html/rfc6238#appendix-B, truncated to six digits
+func TestTotpCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, expected := range vectors {
+		code, err := TotpCode(secret, TotpStep(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code)
+	}
+}
+
+func TestValidateTotp(t *testing.T) {
+	secret, err := GenerateTotpSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	code, _ := TotpCode(secret, TotpStep(now))
+	previous, _ := TotpCode(secret, TotpStep(now)-1)
+
+	step, ok := ValidateTotp(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, TotpStep(now), step)
+
+	step, ok = ValidateTotp(secret, previous[:3]+" "+previous[3:], now)
+	assert.True(t, ok)
+	assert.Equal(t, TotpStep(now)-1, step)
+
+	_, ok = ValidateTotp(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestTotpUri(t *testing.T) {
+	uri := TotpUri("Wakana", "user@example.org", "JBSWY3DPEHPK3PXP")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Wakana:user@example.org?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Wakana")
+}